@@ -0,0 +1,94 @@
+// Package apierror classifies usecase errors once so REST, GraphQL and gRPC
+// report the same failure the same way.
+package apierror
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+
+	"task_manager/Domain"
+)
+
+// Kind is the transport-independent class of an error
+type Kind int
+
+const (
+	Internal Kind = iota
+	Invalid
+	NotFound
+	Conflict
+	Forbidden
+)
+
+// Classify returns the kind of err; unknown errors are Internal
+func Classify(err error) Kind {
+	var verr *Domain.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		return Invalid
+	case errors.Is(err, Domain.ErrNotFound):
+		return NotFound
+	case errors.Is(err, Domain.ErrPreconditionFailed):
+		return Conflict
+	case errors.Is(err, Domain.ErrForbidden), errors.Is(err, Domain.ErrNoWorkspace):
+		return Forbidden
+	}
+	return Internal
+}
+
+// Fields lists the field violations in err, if it is a validation error
+func Fields(err error) []Domain.FieldError {
+	var verr *Domain.ValidationError
+	if errors.As(err, &verr) {
+		return verr.Errors
+	}
+	return nil
+}
+
+// HTTPStatus is the response status for k
+func (k Kind) HTTPStatus() int {
+	switch k {
+	case Invalid:
+		return http.StatusBadRequest
+	case NotFound:
+		return http.StatusNotFound
+	case Conflict:
+		return http.StatusConflict
+	case Forbidden:
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCCode is the status code for k
+func (k Kind) GRPCCode() codes.Code {
+	switch k {
+	case Invalid:
+		return codes.InvalidArgument
+	case NotFound:
+		return codes.NotFound
+	case Conflict:
+		return codes.FailedPrecondition
+	case Forbidden:
+		return codes.PermissionDenied
+	}
+	return codes.Internal
+}
+
+// Message is the client-facing summary for k; details stay in logs or
+// per-field violations
+func (k Kind) Message() string {
+	switch k {
+	case Invalid:
+		return "validation failed"
+	case NotFound:
+		return "not found"
+	case Conflict:
+		return "task changed since it was read"
+	case Forbidden:
+		return "forbidden"
+	}
+	return "internal error"
+}
@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"task_manager/Domain"
+)
+
+// --- Attachment endpoints ---
+
+// uploadForm documents the multipart body of an upload
+type uploadForm struct {
+	File []byte `json:"file"`
+}
+
+// multipartOverhead allows for the form's boundaries and part headers on top
+// of the file itself
+const multipartOverhead = 1 << 20
+
+// UploadAttachment stores the multipart "file" field on the task. The same
+// content uploaded to the task again returns the existing attachment with 200.
+func (ctr *Controller) UploadAttachment(c *gin.Context) {
+	taskID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	max := ctr.attachUC.MaxSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, max+multipartOverhead)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file must be at most %d bytes", max)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": "expected a multipart form with a file field"})
+		return
+	}
+	if fh.Size > max {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file must be at most %d bytes", max)})
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+	defer f.Close()
+	a, created, err := ctr.attachUC.Upload(c.Request.Context(), actor(c), taskID, fh.Filename, f)
+	if err != nil {
+		respondError(c, err, "failed to upload")
+		return
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, a)
+}
+
+func (ctr *Controller) ListAttachments(c *gin.Context) {
+	taskID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	as, err := ctr.attachUC.List(c.Request.Context(), taskID)
+	if err != nil {
+		respondError(c, err, "failed")
+		return
+	}
+	if as == nil {
+		as = []Domain.Attachment{}
+	}
+	c.JSON(http.StatusOK, as)
+}
+
+func (ctr *Controller) GetAttachment(c *gin.Context) {
+	taskID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "attachmentId")
+	if !ok {
+		return
+	}
+	a, err := ctr.attachUC.Get(c.Request.Context(), taskID, id)
+	if err != nil {
+		respondError(c, err, "failed")
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// DeleteAttachment removes an attachment; the uploader or an admin may do so
+func (ctr *Controller) DeleteAttachment(c *gin.Context) {
+	taskID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "attachmentId")
+	if !ok {
+		return
+	}
+	if err := ctr.attachUC.Delete(c.Request.Context(), actor(c), taskID, id); err != nil {
+		respondError(c, err, "failed to delete")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AttachmentLink returns a signed download URL, relative to the server and
+// under the same API version as the request
+func (ctr *Controller) AttachmentLink(c *gin.Context) {
+	taskID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "attachmentId")
+	if !ok {
+		return
+	}
+	link, err := ctr.attachUC.Link(c.Request.Context(), taskID, id)
+	if err != nil {
+		respondError(c, err, "failed")
+		return
+	}
+	prefix, _, _ := strings.Cut(c.FullPath(), "/tasks/")
+	link.URL = prefix + "/attachments/download?token=" + url.QueryEscape(link.Token)
+	c.JSON(http.StatusOK, link)
+}
+
+// DownloadAttachment serves the content behind a signed link; the link is
+// the only credential
+func (ctr *Controller) DownloadAttachment(c *gin.Context) {
+	a, body, err := ctr.attachUC.Open(c.Request.Context(), c.Query("token"))
+	if err != nil {
+		respondError(c, err, "failed to download")
+		return
+	}
+	defer body.Close()
+	h := c.Writer.Header()
+	h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Cache-Control", "private, no-store")
+	h.Set("ETag", `"`+a.SHA256+`"`)
+	c.DataFromReader(http.StatusOK, a.Size, a.ContentType, body, nil)
+}
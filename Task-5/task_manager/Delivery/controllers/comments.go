@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"task_manager/Domain"
+)
+
+// --- Comment endpoints ---
+
+type commentReq struct {
+	Body string `json:"body" binding:"required"`
+	// ParentID makes the comment a reply to a top-level comment
+	ParentID string `json:"parent_id,omitempty"`
+}
+
+type commentPatchReq struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// actor is who the request speaks for, with their role in the active
+// workspace
+func actor(c *gin.Context) Domain.Session {
+	return Domain.Session{Username: c.GetString("username"), Role: c.GetString("role")}
+}
+
+func (ctr *Controller) CreateComment(c *gin.Context) {
+	taskID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req commentReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+	var parentID *primitive.ObjectID
+	if req.ParentID != "" {
+		id, err := primitive.ObjectIDFromHex(req.ParentID)
+		if err != nil {
+			respondValidation(c, Domain.CommentError("parent_id", "must be a comment id"))
+			return
+		}
+		parentID = &id
+	}
+	created, err := ctr.comUC.Create(c.Request.Context(), actor(c), taskID, parentID, req.Body)
+	if err != nil {
+		respondError(c, err, "failed to create")
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// ListComments pages through a task's top-level comments, oldest first, with
+// their replies (?limit, default 20, max 100; ?after is the previous page's
+// next cursor)
+func (ctr *Controller) ListComments(c *gin.Context) {
+	taskID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	limit := int64(Domain.DefaultCommentPage)
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 || n > Domain.MaxCommentPage {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		limit = n
+	}
+	var after primitive.ObjectID
+	if v := c.Query("after"); v != "" {
+		id, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid after"})
+			return
+		}
+		after = id
+	}
+	page, err := ctr.comUC.List(c.Request.Context(), taskID, after, limit)
+	if err != nil {
+		respondError(c, err, "failed")
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// GetComment returns a comment with its edit history and, for a top-level
+// comment, its replies
+func (ctr *Controller) GetComment(c *gin.Context) {
+	taskID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "commentId")
+	if !ok {
+		return
+	}
+	comment, err := ctr.comUC.Get(c.Request.Context(), taskID, id)
+	if err != nil {
+		respondError(c, err, "failed")
+		return
+	}
+	c.JSON(http.StatusOK, comment)
+}
+
+// UpdateComment edits the body; the author or an admin may do so
+func (ctr *Controller) UpdateComment(c *gin.Context) {
+	taskID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "commentId")
+	if !ok {
+		return
+	}
+	var req commentPatchReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+	updated, err := ctr.comUC.Edit(c.Request.Context(), actor(c), taskID, id, req.Body)
+	if err != nil {
+		respondError(c, err, "failed to update")
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteComment removes a comment and its replies; the author or an admin may
+// do so
+func (ctr *Controller) DeleteComment(c *gin.Context) {
+	taskID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "commentId")
+	if !ok {
+		return
+	}
+	if err := ctr.comUC.Delete(c.Request.Context(), actor(c), taskID, id); err != nil {
+		respondError(c, err, "failed to delete")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
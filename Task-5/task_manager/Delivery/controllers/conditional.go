@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondConditional writes v as JSON with a weak ETag over the body and,
+// when modified is known, Last-Modified. A request whose If-None-Match
+// matches, or (when useModifiedSince is set and If-None-Match is absent)
+// whose If-Modified-Since is no older than modified, gets 304 instead.
+func respondConditional(c *gin.Context, v interface{}, modified time.Time, useModifiedSince bool) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode response"})
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+
+	h := c.Writer.Header()
+	h.Set("ETag", etag)
+	// responses depend on the caller's token, so shared caches must not
+	// keep them, and clients revalidate every time
+	h.Set("Cache-Control", "private, no-cache")
+	modified = modified.UTC().Truncate(time.Second)
+	if !modified.IsZero() {
+		h.Set("Last-Modified", modified.Format(http.TimeFormat))
+	}
+
+	if notModified(c.Request, etag, modified, useModifiedSince) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// notModified applies RFC 9110 section 13.2.2: If-None-Match wins over
+// If-Modified-Since
+func notModified(r *http.Request, etag string, modified time.Time, useModifiedSince bool) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, etag)
+	}
+	if !useModifiedSince || modified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !modified.After(since)
+}
+
+// etagMatches uses the weak comparison If-None-Match calls for
+func etagMatches(header, etag string) bool {
+	want := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
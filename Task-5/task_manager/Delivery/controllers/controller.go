@@ -1,11 +1,18 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"reflect"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"task_manager/Delivery/apierror"
+	"task_manager/Delivery/patch"
 	"task_manager/Domain"
 	"task_manager/Infrastructure"
 	"task_manager/Usecases"
@@ -15,10 +22,30 @@ type Controller struct {
 	userUC   Usecases.UserUsecase
 	taskUC   Usecases.TaskUsecase
 	jwtSvc   Infrastructure.JWTService
+	events   *Infrastructure.EventHub
+	hookUC   Usecases.WebhookUsecase
+	wsUC     Usecases.WorkspaceUsecase
+	labelUC  Usecases.LabelUsecase
+	comUC    Usecases.CommentUsecase
+	attachUC Usecases.AttachmentUsecase
+	recurUC  Usecases.RecurrenceUsecase
+	// shutdown ends TaskEvents streams when closed, so they don't hold the
+	// server's shutdown open until its timeout
+	shutdown <-chan struct{}
 }
 
-func NewController(u Usecases.UserUsecase, t Usecases.TaskUsecase, j Infrastructure.JWTService) *Controller {
-	return &Controller{userUC: u, taskUC: t, jwtSvc: j}
+func NewController(u Usecases.UserUsecase, t Usecases.TaskUsecase, j Infrastructure.JWTService, events *Infrastructure.EventHub, w Usecases.WebhookUsecase, ws Usecases.WorkspaceUsecase, l Usecases.LabelUsecase, cm Usecases.CommentUsecase, at Usecases.AttachmentUsecase, rc Usecases.RecurrenceUsecase, shutdown <-chan struct{}) *Controller {
+	return &Controller{userUC: u, taskUC: t, jwtSvc: j, events: events, hookUC: w, wsUC: ws, labelUC: l, comUC: cm, attachUC: at, recurUC: rc, shutdown: shutdown}
+}
+
+// respondValidation writes a 400 listing every field violation; it reports
+// whether err was a validation error
+func respondValidation(c *gin.Context, err error) bool {
+	if err == nil || apierror.Classify(err) != apierror.Invalid {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": apierror.Invalid.Message(), "fields": apierror.Fields(err)})
+	return true
 }
 
 // --- Auth / User endpoints ---
@@ -34,17 +61,35 @@ func (ctr *Controller) Register(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
 		return
 	}
-	user, err := ctr.userUC.Register(req.Username, req.Password)
+	user, err := ctr.userUC.Register(c.Request.Context(), req.Username, req.Password)
+	if respondValidation(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"username": user.Username, "role": user.Role})
+	c.JSON(http.StatusCreated, userResp{Username: user.Username, Role: user.Role})
 }
 
 type loginReq struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// Workspace is the hex ID to activate; defaults to the oldest membership
+	Workspace string `json:"workspace,omitempty"`
+}
+
+type userResp struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// loginResp describes the issued token; Role is the role in Workspace
+type loginResp struct {
+	Token     string `json:"token"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	Workspace string `json:"workspace,omitempty"`
 }
 
 func (ctr *Controller) Login(c *gin.Context) {
@@ -53,40 +98,108 @@ func (ctr *Controller) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 		return
 	}
-	user, err := ctr.userUC.Login(req.Username, req.Password)
+	var wsID primitive.ObjectID
+	if req.Workspace != "" {
+		id, err := primitive.ObjectIDFromHex(req.Workspace)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspace"})
+			return
+		}
+		wsID = id
+	}
+	user, err := ctr.userUC.Login(c.Request.Context(), req.Username, req.Password)
 	if err != nil {
+		Infrastructure.AuthFailed(c, Infrastructure.AuthBadCredentials)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
-	token, err := ctr.jwtSvc.GenerateToken(user.Username, user.Role)
+	ctr.issueToken(c, user.Username, wsID)
+}
+
+// issueToken answers with a token for the user in the workspace; a zero ID
+// picks their default
+func (ctr *Controller) issueToken(c *gin.Context, username string, wsID primitive.ObjectID) {
+	session, err := ctr.wsUC.Session(c.Request.Context(), username, wsID)
+	if errors.Is(err, Domain.ErrNotFound) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not a member of that workspace"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token", "details": err.Error()})
+		return
+	}
+	token, err := ctr.jwtSvc.GenerateToken(session)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"token": token, "username": user.Username, "role": user.Role})
+	resp := loginResp{Token: token, Username: session.Username, Role: session.Role}
+	if !session.Workspace.IsZero() {
+		resp.Workspace = session.Workspace.Hex()
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
-// Promote (admin only)
+// Promote (instance admin only) makes the user an instance admin
 func (ctr *Controller) Promote(c *gin.Context) {
 	username := c.Param("username")
 	if username == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "username required"})
 		return
 	}
-	if err := ctr.userUC.Promote(username); err != nil {
+	if err := ctr.userUC.Promote(c.Request.Context(), username); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "promoted", "username": username})
+	c.JSON(http.StatusOK, promoteResp{Message: "promoted", Username: username})
+}
+
+type promoteResp struct {
+	Message  string `json:"message"`
+	Username string `json:"username"`
 }
 
 // --- Task endpoints ---
 
-type createTaskReq struct {
+// taskReq is a full task, as PUT replaces it
+type taskReq struct {
 	Title       string `json:"title" binding:"required"`
 	Description string `json:"description"`
 	DueDate     string `json:"due_date"`
 	Status      string `json:"status" binding:"required"`
+	// Estimate is a duration such as "4h30m"
+	Estimate string `json:"estimate"`
+}
+
+func (r taskReq) toTask() Domain.Task {
+	return Domain.Task{
+		Title:       r.Title,
+		Description: r.Description,
+		DueDate:     r.DueDate,
+		Status:      r.Status,
+		Estimate:    r.Estimate,
+	}
+}
+
+type createTaskReq struct {
+	taskReq
+	// ParentID makes the new task a subtask
+	ParentID string `json:"parent_id,omitempty"`
+	// Labels are names from the label catalog
+	Labels []string `json:"labels,omitempty"`
+}
+
+func (r createTaskReq) toTask() (Domain.Task, error) {
+	t := r.taskReq.toTask()
+	t.Labels = r.Labels
+	if r.ParentID != "" {
+		id, err := primitive.ObjectIDFromHex(r.ParentID)
+		if err != nil {
+			return Domain.Task{}, Domain.ParentError("must be a task id")
+		}
+		t.ParentID = &id
+	}
+	return t, nil
 }
 
 func (ctr *Controller) CreateTask(c *gin.Context) {
@@ -95,13 +208,14 @@ func (ctr *Controller) CreateTask(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
 		return
 	}
-	task := Domain.Task{
-		Title:       req.Title,
-		Description: req.Description,
-		DueDate:     req.DueDate,
-		Status:      req.Status,
+	task, err := req.toTask()
+	if respondValidation(c, err) {
+		return
+	}
+	created, err := ctr.taskUC.CreateTask(c.Request.Context(), task)
+	if respondValidation(c, err) {
+		return
 	}
-	created, err := ctr.taskUC.CreateTask(task)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create", "details": err.Error()})
 		return
@@ -109,13 +223,27 @@ func (ctr *Controller) CreateTask(c *gin.Context) {
 	c.JSON(http.StatusCreated, created)
 }
 
+// GetTasks lists the tasks, optionally only those carrying the labels in
+// ?labels=a,b: all of them, or any with ?match=any
 func (ctr *Controller) GetTasks(c *gin.Context) {
-	tasks, err := ctr.taskUC.ListTasks()
+	filter, ok := labelFilter(c)
+	if !ok {
+		return
+	}
+	tasks, err := ctr.taskUC.ListTasks(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed", "details": err.Error()})
+		respondError(c, err, "failed")
 		return
 	}
-	c.JSON(http.StatusOK, tasks)
+	var modified time.Time
+	for _, t := range tasks {
+		if m := t.LastModified(); m.After(modified) {
+			modified = m
+		}
+	}
+	// a deleted task leaves no trace in the newest time, so only the ETag
+	// decides whether the list changed
+	respondConditional(c, tasks, modified, false)
 }
 
 func (ctr *Controller) GetTaskByID(c *gin.Context) {
@@ -125,12 +253,13 @@ func (ctr *Controller) GetTaskByID(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	task, err := ctr.taskUC.GetTaskByID(objID)
+	task, err := ctr.taskUC.GetTaskByID(c.Request.Context(), objID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 		return
 	}
-	c.JSON(http.StatusOK, task)
+	// progress changes with the subtasks, which the task's own time misses
+	respondConditional(c, task, task.LastModified(), task.Progress == nil || task.Progress.Subtasks == 0)
 }
 
 type updateTaskReq struct {
@@ -138,8 +267,65 @@ type updateTaskReq struct {
 	Description *string `json:"description"`
 	DueDate     *string `json:"due_date"`
 	Status      *string `json:"status"`
+	Estimate    *string `json:"estimate"`
+}
+
+// toPatch keys the fields that were sent by their bson names
+func (r updateTaskReq) toPatch() map[string]interface{} {
+	patch := make(map[string]interface{})
+	if r.Title != nil {
+		patch["title"] = *r.Title
+	}
+	if r.Description != nil {
+		patch["description"] = *r.Description
+	}
+	if r.DueDate != nil {
+		patch["due_date"] = *r.DueDate
+	}
+	if r.Status != nil {
+		patch["status"] = *r.Status
+	}
+	if r.Estimate != nil {
+		patch["estimate"] = *r.Estimate
+	}
+	return patch
+}
+
+// respondError maps the usecase errors shared by write endpoints; anything
+// unrecognised is a 500 with the fallback message
+func respondError(c *gin.Context, err error, fallback string) {
+	switch kind := apierror.Classify(err); kind {
+	case apierror.Invalid:
+		respondValidation(c, err)
+	case apierror.NotFound:
+		c.JSON(kind.HTTPStatus(), gin.H{"error": kind.Message()})
+	case apierror.Conflict:
+		c.JSON(kind.HTTPStatus(), gin.H{"error": kind.Message(), "details": err.Error()})
+	case apierror.Forbidden:
+		c.JSON(kind.HTTPStatus(), gin.H{"error": err.Error()})
+	default:
+		c.JSON(kind.HTTPStatus(), gin.H{"error": fallback, "details": err.Error()})
+	}
+}
+
+// forceParam reads ?force=, which lets a task with open subtasks be
+// completed and one with open blockers be started; it writes a 400 when the
+// value isn't a boolean
+func forceParam(c *gin.Context) (force, ok bool) {
+	raw := c.Query("force")
+	if raw == "" {
+		return false, true
+	}
+	force, err := strconv.ParseBool(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid force"})
+		return false, false
+	}
+	return force, true
 }
 
+// UpdateTask (PUT) replaces the whole task; optional fields that are left
+// out are removed
 func (ctr *Controller) UpdateTask(c *gin.Context) {
 	id := c.Param("id")
 	objID, err := primitive.ObjectIDFromHex(id)
@@ -147,32 +333,165 @@ func (ctr *Controller) UpdateTask(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	var req updateTaskReq
+	force, ok := forceParam(c)
+	if !ok {
+		return
+	}
+	var req taskReq
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
 		return
 	}
-	patch := make(map[string]interface{})
-	if req.Title != nil {
-		patch["title"] = *req.Title
+	updated, err := ctr.taskUC.ReplaceTask(c.Request.Context(), objID, req.toTask(), force)
+	if err != nil {
+		respondError(c, err, "failed to update")
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// PatchTask applies an RFC 7396 merge patch (application/merge-patch+json or
+// plain JSON) or an RFC 6902 JSON Patch (application/json-patch+json)
+func (ctr *Controller) PatchTask(c *gin.Context) {
+	id := c.Param("id")
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	force, ok := forceParam(c)
+	if !ok {
+		return
 	}
-	if req.Description != nil {
-		patch["description"] = *req.Description
+	var change Domain.TaskChange
+	switch c.ContentType() {
+	case mergePatchType, "application/json", "":
+		change, err = mergePatchChange(c)
+	case jsonPatchType:
+		change, err = ctr.jsonPatchChange(c, objID)
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "use " + mergePatchType + " or " + jsonPatchType})
+		return
+	}
+	if errors.Is(err, patch.ErrTestFailed) {
+		c.JSON(http.StatusConflict, gin.H{"error": "patch test failed", "details": err.Error()})
+		return
 	}
-	if req.DueDate != nil {
-		patch["due_date"] = *req.DueDate
+	if errors.Is(err, Domain.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
 	}
-	if req.Status != nil {
-		patch["status"] = *req.Status
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid patch", "details": err.Error()})
+		return
 	}
-	updated, err := ctr.taskUC.UpdateTask(objID, patch)
+	change.Force = force
+	updated, err := ctr.taskUC.UpdateTask(c.Request.Context(), objID, change)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update", "details": err.Error()})
+		respondError(c, err, "failed to update")
 		return
 	}
 	c.JSON(http.StatusOK, updated)
 }
 
+// mergePatchChange maps top-level members to $set, and null members to $unset
+func mergePatchChange(c *gin.Context) (Domain.TaskChange, error) {
+	var body map[string]interface{}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		return Domain.TaskChange{}, err
+	}
+	change := Domain.TaskChange{Set: map[string]interface{}{}}
+	for k, v := range body {
+		if v == nil {
+			change.Unset = append(change.Unset, k)
+		} else {
+			change.Set[k] = v
+		}
+	}
+	return change, nil
+}
+
+// jsonPatchChange applies the operations to the current task and diffs the
+// result. The change expects every field to still hold the value that was
+// read, so test operations and the write happen atomically.
+func (ctr *Controller) jsonPatchChange(c *gin.Context, id primitive.ObjectID) (Domain.TaskChange, error) {
+	var ops []patch.Operation
+	if err := c.ShouldBindJSON(&ops); err != nil {
+		return Domain.TaskChange{}, err
+	}
+	current, err := ctr.taskUC.GetTaskByID(c.Request.Context(), id)
+	if err != nil {
+		return Domain.TaskChange{}, err
+	}
+	raw, err := json.Marshal(current)
+	if err != nil {
+		return Domain.TaskChange{}, err
+	}
+	var before map[string]interface{}
+	if err := json.Unmarshal(raw, &before); err != nil {
+		return Domain.TaskChange{}, err
+	}
+	result, err := patch.Apply(before, ops)
+	if err != nil {
+		return Domain.TaskChange{}, err
+	}
+	after, ok := result.(map[string]interface{})
+	if !ok {
+		return Domain.TaskChange{}, errors.New("patched document must be an object")
+	}
+	for _, k := range readOnlyTaskFields {
+		if !reflect.DeepEqual(after[k], before[k]) {
+			return Domain.TaskChange{}, errors.New(k + " cannot be changed")
+		}
+	}
+	change := Domain.TaskChange{Set: map[string]interface{}{}, Expect: map[string]interface{}{}}
+	for k, v := range before {
+		if patchManaged(k) {
+			continue
+		}
+		change.Expect[k] = v
+		if _, kept := after[k]; !kept {
+			change.Unset = append(change.Unset, k)
+		}
+	}
+	for _, k := range []string{"description", "due_date", "estimate"} {
+		if _, ok := before[k]; !ok {
+			change.Expect[k] = nil
+		}
+	}
+	for k, v := range after {
+		if !patchManaged(k) && !reflect.DeepEqual(before[k], v) {
+			change.Set[k] = v
+		}
+	}
+	return change, nil
+}
+
+// readOnlyTaskFields can't be changed by a JSON Patch: a task moves with
+// POST /tasks/:id/move, its checklist, blockers and labels have their own
+// endpoints, an occurrence stays tied to its recurring task and progress is
+// computed
+var readOnlyTaskFields = []string{"id", "workspace_id", "parent_id", "checklist", "blocked_by", "labels", "recurrence_id", "occurrence", "progress"}
+
+// patchManaged reports fields a JSON Patch neither expects nor writes;
+// besides the read-only ones that is updated_at, which the server stamps
+func patchManaged(k string) bool {
+	if k == "updated_at" {
+		return true
+	}
+	for _, ro := range readOnlyTaskFields {
+		if k == ro {
+			return true
+		}
+	}
+	return false
+}
+
 func (ctr *Controller) DeleteTask(c *gin.Context) {
 	id := c.Param("id")
 	objID, err := primitive.ObjectIDFromHex(id)
@@ -180,9 +499,100 @@ func (ctr *Controller) DeleteTask(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	if err := ctr.taskUC.DeleteTask(objID); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	if err := ctr.taskUC.DeleteTask(c.Request.Context(), objID); err != nil {
+		respondError(c, err, "failed to delete")
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
+
+// --- Bulk task endpoint ---
+
+type bulkOpReq struct {
+	Op    string         `json:"op" binding:"required,oneof=create update delete"`
+	ID    string         `json:"id,omitempty"`
+	Task  *createTaskReq `json:"task,omitempty"`
+	Patch *updateTaskReq `json:"patch,omitempty"`
+	// Force completes a task with open subtasks or starts one with open
+	// blockers
+	Force bool `json:"force,omitempty"`
+}
+
+type bulkReq struct {
+	StopOnError bool        `json:"stop_on_error"`
+	Operations  []bulkOpReq `json:"operations" binding:"required,dive"`
+}
+
+type bulkResultResp struct {
+	Index  int          `json:"index"`
+	Op     string       `json:"op"`
+	ID     string       `json:"id,omitempty"`
+	Status string       `json:"status"`
+	Task   *Domain.Task `json:"task,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+type bulkResp struct {
+	Transactional bool             `json:"transactional"`
+	Results       []bulkResultResp `json:"results"`
+}
+
+func bulkFieldError(field, msg string) error {
+	return &Domain.ValidationError{Errors: []Domain.FieldError{{Field: field, Message: msg}}}
+}
+
+func (ctr *Controller) BulkTasks(c *gin.Context) {
+	var req bulkReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+	// an op that can't be read fails on its own, like one the usecase rejects
+	ops := make([]Usecases.BulkTaskOp, len(req.Operations))
+	for i, o := range req.Operations {
+		op := Usecases.BulkTaskOp{Op: o.Op}
+		if o.Op != "create" {
+			objID, err := primitive.ObjectIDFromHex(o.ID)
+			if err != nil {
+				op.Invalid = bulkFieldError("id", "is not a valid id")
+			}
+			op.ID = objID
+		}
+		switch {
+		case op.Invalid != nil:
+		case o.Op == "create" && o.Task == nil:
+			op.Invalid = bulkFieldError("task", "is required")
+		case o.Op == "update" && o.Patch == nil:
+			op.Invalid = bulkFieldError("patch", "is required")
+		case o.Op == "create":
+			task, err := o.Task.toTask()
+			if err != nil {
+				op.Invalid = bulkFieldError("task.parent_id", "is not a valid id")
+			}
+			op.Task = task
+		case o.Op == "update":
+			op.Change = Domain.TaskChange{Set: o.Patch.toPatch(), Force: o.Force}
+		}
+		ops[i] = op
+	}
+	report, err := ctr.taskUC.BulkTasks(c.Request.Context(), ops, req.StopOnError)
+	if respondValidation(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "bulk failed", "details": err.Error()})
+		return
+	}
+	resp := bulkResp{Transactional: report.Transactional, Results: make([]bulkResultResp, len(report.Results))}
+	for i, r := range report.Results {
+		item := bulkResultResp{Index: i, Op: r.Op, Status: r.Status, Task: r.Task}
+		if !r.ID.IsZero() {
+			item.ID = r.ID.Hex()
+		}
+		if r.Err != nil {
+			item.Error = r.Err.Error()
+		}
+		resp.Results[i] = item
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"task_manager/Domain"
+)
+
+// --- Dependency endpoints ---
+
+type addBlockerReq struct {
+	// BlockedBy is the task that must be done first
+	BlockedBy string `json:"blocked_by" binding:"required"`
+}
+
+// ListDependencies lists a task's blockers and the tasks it blocks
+func (ctr *Controller) ListDependencies(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	deps, err := ctr.taskUC.ListDependencies(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "failed")
+		return
+	}
+	var modified time.Time
+	for _, list := range [][]Domain.Task{deps.BlockedBy, deps.Blocking} {
+		for _, t := range list {
+			if m := t.LastModified(); m.After(modified) {
+				modified = m
+			}
+		}
+	}
+	respondConditional(c, deps, modified, false)
+}
+
+// AddBlocker makes the task wait on another one and returns it
+func (ctr *Controller) AddBlocker(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req addBlockerReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+	blocker, err := primitive.ObjectIDFromHex(req.BlockedBy)
+	if err != nil {
+		respondValidation(c, Domain.DependencyError("must be a task id"))
+		return
+	}
+	task, err := ctr.taskUC.AddBlocker(c.Request.Context(), id, blocker)
+	if err != nil {
+		respondError(c, err, "failed to update")
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+func (ctr *Controller) RemoveBlocker(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	blocker, ok := paramID(c, "blockerId")
+	if !ok {
+		return
+	}
+	task, err := ctr.taskUC.RemoveBlocker(c.Request.Context(), id, blocker)
+	if err != nil {
+		respondError(c, err, "failed to update")
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+// Schedule plans the workspace's open tasks. The plan starts now, so it is
+// never answered from a cache.
+func (ctr *Controller) Schedule(c *gin.Context) {
+	sched, err := ctr.taskUC.Schedule(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "failed")
+		return
+	}
+	c.JSON(http.StatusOK, sched)
+}
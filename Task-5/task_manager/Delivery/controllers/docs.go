@@ -0,0 +1,346 @@
+package controllers
+
+import (
+	"net/http"
+
+	"task_manager/Delivery/openapi"
+	"task_manager/Delivery/patch"
+	"task_manager/Domain"
+	"task_manager/Usecases"
+)
+
+// APIDocs describes each route for the OpenAPI generator, keyed by
+// "METHOD /gin/path". Request and response types are the ones the handlers
+// actually bind and write, so the document follows the code.
+func APIDocs() map[string]openapi.Operation {
+	id := map[string]*openapi.Schema{"id": openapi.ObjectIDSchema()}
+	delivery := map[string]*openapi.Schema{"id": openapi.ObjectIDSchema(), "deliveryId": openapi.ObjectIDSchema()}
+	item := map[string]*openapi.Schema{"id": openapi.ObjectIDSchema(), "itemId": openapi.ObjectIDSchema()}
+	blocker := map[string]*openapi.Schema{"id": openapi.ObjectIDSchema(), "blockerId": openapi.ObjectIDSchema()}
+	taskLabel := map[string]*openapi.Schema{"id": openapi.ObjectIDSchema(), "name": {Type: "string"}}
+	comment := map[string]*openapi.Schema{"id": openapi.ObjectIDSchema(), "commentId": openapi.ObjectIDSchema()}
+	attachment := map[string]*openapi.Schema{"id": openapi.ObjectIDSchema(), "attachmentId": openapi.ObjectIDSchema()}
+	force := map[string]*openapi.Schema{"force": {Type: "string", Pattern: "^(true|false)$"}}
+	future := map[string]*openapi.Schema{"future": {Type: "string", Pattern: "^(true|false)$"}}
+	adminErrs := []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound}
+	return map[string]openapi.Operation{
+		"GET /healthz": {Summary: "Liveness probe", Tags: []string{"ops"}},
+		"POST /graphql": {
+			Summary: "Run a GraphQL query or mutation; see Delivery/graphql/schema.graphql", Tags: []string{"graphql"}, Auth: true,
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized},
+		},
+		"GET /metrics": {Summary: "Prometheus metrics in text exposition format", Tags: []string{"ops"}},
+		"GET /readyz": {
+			Summary: "Readiness probe: pings Mongo and background workers", Tags: []string{"ops"},
+			Errors: []int{http.StatusServiceUnavailable},
+		},
+		"POST /register": {
+			Summary: "Register a user; the first user becomes instance admin", Tags: []string{"users"},
+			Request: registerReq{}, Response: userResp{}, Status: http.StatusCreated,
+			Errors: []int{http.StatusBadRequest},
+		},
+		"POST /login": {
+			Summary: "Exchange credentials for a JWT scoped to a workspace", Tags: []string{"users"},
+			Request: loginReq{}, Response: loginResp{},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized},
+		},
+		"POST /users/:username/promote": {
+			Summary: "Make a user an instance admin", Tags: []string{"users"}, Auth: true,
+			Response: promoteResp{},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+		},
+		"GET /tasks": {
+			Summary: "List tasks, optionally those carrying all (match=all, the default) or any (match=any) of the comma-separated labels",
+			Tags:    []string{"tasks"}, Auth: true,
+			Query: map[string]*openapi.Schema{
+				"labels": {Type: "string"},
+				"match":  {Type: "string", Enum: []string{Domain.LabelMatchAll, Domain.LabelMatchAny}},
+			},
+			Response:    []Domain.Task{},
+			Conditional: true,
+			Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError},
+		},
+		"GET /tasks/events": {
+			Summary: "Stream task changes (Server-Sent Events, or WebSocket on upgrade)", Tags: []string{"tasks"}, Auth: true,
+			Query: map[string]*openapi.Schema{
+				"last_event_id": {Type: "string", Pattern: "^[0-9]+$"},
+				"access_token":  {Type: "string"},
+			},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+		},
+		"GET /tasks/:id": {
+			Summary: "Get a task", Tags: []string{"tasks"}, Auth: true, Params: id,
+			Response:    Domain.Task{},
+			Conditional: true,
+			Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		},
+		"POST /tasks": {
+			Summary: "Create a task", Tags: []string{"tasks"}, Auth: true,
+			Request: createTaskReq{}, Response: Domain.Task{}, Status: http.StatusCreated,
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+		},
+		"GET /tasks/:id/subtasks": {
+			Summary: "List a task's direct subtasks, oldest first", Tags: []string{"tasks"}, Auth: true, Params: id,
+			Response:    []Domain.Task{},
+			Conditional: true,
+			Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		},
+		"GET /tasks/:id/dependencies": {
+			Summary: "List the tasks blocking a task and the tasks it blocks", Tags: []string{"tasks"}, Auth: true, Params: id,
+			Response:    Domain.Dependencies{},
+			Conditional: true,
+			Errors:      []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		},
+		"GET /tasks/schedule": {
+			Summary: "Order the open tasks by their dependencies and find the critical path from estimates and due dates",
+			Tags:    []string{"tasks"}, Auth: true,
+			Response: Domain.Schedule{},
+			Errors:   []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError},
+		},
+		"PUT /tasks/:id": {
+			Summary: "Replace a task; optional fields left out are removed. Completing a task with open subtasks, or starting one with open blockers, needs force=true",
+			Tags:    []string{"tasks"}, Auth: true, Params: id, Query: force,
+			Request: taskReq{}, Response: Domain.Task{},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		},
+		"PATCH /tasks/:id": {
+			Summary: "Partially update a task with a JSON Merge Patch or a JSON Patch. Completing a task with open subtasks, or starting one with open blockers, needs force=true",
+			Tags:    []string{"tasks"}, Auth: true, Params: id, Query: force,
+			Bodies: map[string]interface{}{
+				"application/json": updateTaskReq{},
+				mergePatchType:     updateTaskReq{},
+				jsonPatchType:      []patch.Operation{},
+			},
+			Response: Domain.Task{},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+				http.StatusConflict, http.StatusUnsupportedMediaType},
+		},
+		"POST /tasks/bulk": {
+			Summary: "Create, update and delete tasks in one batch", Tags: []string{"tasks"}, Auth: true,
+			Request: bulkReq{}, Response: bulkResp{},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError},
+		},
+		"DELETE /tasks/:id": {
+			Summary: "Delete a task that has no subtasks; tasks it blocked stop waiting on it", Tags: []string{"tasks"}, Auth: true, Params: id,
+			Status: http.StatusNoContent,
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		},
+		"POST /tasks/:id/move": {
+			Summary: "Move a task and its subtasks under another parent, or to the top level", Tags: []string{"tasks"}, Auth: true, Params: id,
+			Request: moveTaskReq{}, Response: Domain.Task{},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		},
+		"POST /tasks/:id/checklist": {
+			Summary: "Add a checklist item; returns the task", Tags: []string{"tasks"}, Auth: true, Params: id,
+			Request: checklistItemReq{}, Response: Domain.Task{}, Status: http.StatusCreated,
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		},
+		"PATCH /tasks/:id/checklist/:itemId": {
+			Summary: "Change a checklist item's text or tick it off; returns the task", Tags: []string{"tasks"}, Auth: true, Params: item,
+			Request: checklistItemPatchReq{}, Response: Domain.Task{},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		},
+		"DELETE /tasks/:id/checklist/:itemId": {
+			Summary: "Remove a checklist item; returns the task", Tags: []string{"tasks"}, Auth: true, Params: item,
+			Response: Domain.Task{},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		},
+		"POST /tasks/:id/dependencies": {
+			Summary: "Make a task wait on another; refused if it would create a cycle. Returns the task", Tags: []string{"tasks"}, Auth: true, Params: id,
+			Request: addBlockerReq{}, Response: Domain.Task{},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		},
+		"DELETE /tasks/:id/dependencies/:blockerId": {
+			Summary: "Stop a task waiting on a blocker; returns the task", Tags: []string{"tasks"}, Auth: true, Params: blocker,
+			Response: Domain.Task{},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		},
+		"POST /tasks/:id/labels": {
+			Summary: "Tag a task with a label from the catalog; returns the task", Tags: []string{"tasks"}, Auth: true, Params: id,
+			Request: taskLabelReq{}, Response: Domain.Task{},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		},
+		"DELETE /tasks/:id/labels/:name": {
+			Summary: "Take a label off a task; returns the task", Tags: []string{"tasks"}, Auth: true, Params: taskLabel,
+			Response: Domain.Task{},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		},
+		"POST /tasks/:id/comments": {
+			Summary: "Comment on a task, or reply to a top-level comment with parent_id; @username mentions are resolved",
+			Tags:    []string{"comments"}, Auth: true, Params: id,
+			Request: commentReq{}, Response: Domain.Comment{}, Status: http.StatusCreated,
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		},
+		"GET /tasks/:id/comments": {
+			Summary: "Page through a task's top-level comments, oldest first, each with its replies; pass next as after for the following page",
+			Tags:    []string{"comments"}, Auth: true, Params: id,
+			Query: map[string]*openapi.Schema{
+				"limit": {Type: "string", Pattern: "^[0-9]+$"},
+				"after": openapi.ObjectIDSchema(),
+			},
+			Response: Domain.CommentPage{},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound},
+		},
+		"GET /tasks/:id/comments/:commentId": {
+			Summary: "Get a comment with its edit history and replies", Tags: []string{"comments"}, Auth: true, Params: comment,
+			Response: Domain.Comment{}, Errors: adminErrs,
+		},
+		"PATCH /tasks/:id/comments/:commentId": {
+			Summary: "Edit a comment, keeping the old body in its history; authors edit their own, admins any",
+			Tags:    []string{"comments"}, Auth: true, Params: comment,
+			Request: commentPatchReq{}, Response: Domain.Comment{},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		},
+		"DELETE /tasks/:id/comments/:commentId": {
+			Summary: "Delete a comment and its replies; authors delete their own, admins any",
+			Tags:    []string{"comments"}, Auth: true, Params: comment,
+			Status: http.StatusNoContent, Errors: adminErrs,
+		},
+		"POST /tasks/:id/attachments": {
+			Summary: "Attach a file, sent as the multipart field file; its type is detected from the content. Content already on the task returns the existing attachment with 200",
+			Tags:    []string{"attachments"}, Auth: true, Params: id,
+			Bodies:   map[string]interface{}{"multipart/form-data": uploadForm{}},
+			Response: Domain.Attachment{}, Status: http.StatusCreated,
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+				http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType},
+		},
+		"GET /tasks/:id/attachments": {
+			Summary: "List a task's attachments", Tags: []string{"attachments"}, Auth: true, Params: id,
+			Response: []Domain.Attachment{}, Errors: adminErrs,
+		},
+		"GET /tasks/:id/attachments/:attachmentId": {
+			Summary: "Get an attachment's metadata", Tags: []string{"attachments"}, Auth: true, Params: attachment,
+			Response: Domain.Attachment{}, Errors: adminErrs,
+		},
+		"GET /tasks/:id/attachments/:attachmentId/link": {
+			Summary: "Sign a short-lived download URL for an attachment; the URL works without a bearer token",
+			Tags:    []string{"attachments"}, Auth: true, Params: attachment,
+			Response: Domain.AttachmentLink{}, Errors: adminErrs,
+		},
+		"DELETE /tasks/:id/attachments/:attachmentId": {
+			Summary: "Delete an attachment; uploaders delete their own, admins any",
+			Tags:    []string{"attachments"}, Auth: true, Params: attachment,
+			Status: http.StatusNoContent, Errors: adminErrs,
+		},
+		"GET /attachments/download": {
+			Summary: "Download an attachment through a signed link", Tags: []string{"attachments"},
+			Query:  map[string]*openapi.Schema{"token": {Type: "string"}},
+			Errors: []int{http.StatusForbidden, http.StatusNotFound},
+		},
+		"POST /labels": {
+			Summary: "Add a label to the catalog", Tags: []string{"labels"}, Auth: true,
+			Request: labelReq{}, Response: Domain.Label{}, Status: http.StatusCreated,
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+		},
+		"GET /labels": {
+			Summary: "List the label catalog with the number of tasks carrying each label", Tags: []string{"labels"}, Auth: true,
+			Response: []Domain.Label{},
+			Errors:   []int{http.StatusUnauthorized, http.StatusForbidden},
+		},
+		"GET /labels/:id": {
+			Summary: "Get a label with its task count", Tags: []string{"labels"}, Auth: true, Params: id,
+			Response: Domain.Label{}, Errors: adminErrs,
+		},
+		"PATCH /labels/:id": {
+			Summary: "Change a label's color or description, or rename it on every task carrying it", Tags: []string{"labels"}, Auth: true, Params: id,
+			Request: labelPatchReq{}, Response: Domain.Label{}, Errors: adminErrs,
+		},
+		"DELETE /labels/:id": {
+			Summary: "Delete a label and take it off every task", Tags: []string{"labels"}, Auth: true, Params: id,
+			Status: http.StatusNoContent, Errors: adminErrs,
+		},
+		"POST /labels/:id/merge": {
+			Summary: "Move every task carrying the label to another label, then delete it; returns the label merged into",
+			Tags:    []string{"labels"}, Auth: true, Params: id,
+			Request: mergeLabelReq{}, Response: Domain.Label{}, Errors: adminErrs,
+		},
+		"POST /recurring-tasks": {
+			Summary: "Add a recurring task: a template whose RRULE occurrences are created as tasks ahead of time", Tags: []string{"recurring"}, Auth: true,
+			Request: recurringReq{}, Response: Domain.RecurringTask{}, Status: http.StatusCreated,
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+		},
+		"GET /recurring-tasks": {
+			Summary: "List recurring tasks with their next occurrences", Tags: []string{"recurring"}, Auth: true,
+			Response: []Domain.RecurringTask{},
+			Errors:   []int{http.StatusUnauthorized, http.StatusForbidden},
+		},
+		"GET /recurring-tasks/:id": {
+			Summary: "Get a recurring task with its next occurrences", Tags: []string{"recurring"}, Auth: true, Params: id,
+			Response: Domain.RecurringTask{}, Errors: adminErrs,
+		},
+		"GET /recurring-tasks/:id/tasks": {
+			Summary: "List the tasks generated from a recurring task, by occurrence", Tags: []string{"recurring"}, Auth: true, Params: id,
+			Response: []Domain.Task{}, Errors: adminErrs,
+		},
+		"PATCH /recurring-tasks/:id": {
+			Summary: "Change a recurring task. Occurrences generated from now on follow it; future=true also updates the pending occurrences already generated and deletes those the new rule drops",
+			Tags:    []string{"recurring"}, Auth: true, Params: id, Query: future,
+			Request: recurringPatchReq{}, Response: Domain.RecurringTask{},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict},
+		},
+		"DELETE /recurring-tasks/:id": {
+			Summary: "Delete a recurring task; future=true also deletes its pending occurrences still ahead",
+			Tags:    []string{"recurring"}, Auth: true, Params: id, Query: future,
+			Status: http.StatusNoContent, Errors: adminErrs,
+		},
+		"POST /webhooks": {
+			Summary: "Register a webhook; the response carries the signing secret", Tags: []string{"webhooks"}, Auth: true,
+			Request: webhookReq{}, Response: Domain.Webhook{}, Status: http.StatusCreated,
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+		},
+		"GET /webhooks": {
+			Summary: "List webhooks", Tags: []string{"webhooks"}, Auth: true,
+			Response: []Domain.Webhook{},
+			Errors:   []int{http.StatusUnauthorized, http.StatusForbidden},
+		},
+		"GET /webhooks/:id": {
+			Summary: "Get a webhook", Tags: []string{"webhooks"}, Auth: true, Params: id,
+			Response: Domain.Webhook{}, Errors: adminErrs,
+		},
+		"PATCH /webhooks/:id": {
+			Summary: "Update a webhook's URL, events, secret or active flag", Tags: []string{"webhooks"}, Auth: true, Params: id,
+			Request: webhookPatchReq{}, Response: Domain.Webhook{}, Errors: adminErrs,
+		},
+		"DELETE /webhooks/:id": {
+			Summary: "Delete a webhook and its delivery log", Tags: []string{"webhooks"}, Auth: true, Params: id,
+			Status: http.StatusNoContent, Errors: adminErrs,
+		},
+		"GET /webhooks/:id/deliveries": {
+			Summary: "List recent deliveries, newest first", Tags: []string{"webhooks"}, Auth: true, Params: id,
+			Query:    map[string]*openapi.Schema{"limit": {Type: "string", Pattern: "^[0-9]+$"}},
+			Response: []Domain.WebhookDelivery{}, Errors: adminErrs,
+		},
+		"POST /webhooks/:id/deliveries/:deliveryId/redeliver": {
+			Summary: "Queue a delivery again", Tags: []string{"webhooks"}, Auth: true, Params: delivery,
+			Response: Domain.WebhookDelivery{}, Status: http.StatusAccepted, Errors: adminErrs,
+		},
+		"POST /workspaces": {
+			Summary: "Create a workspace with the caller as admin (instance admin only)", Tags: []string{"workspaces"}, Auth: true,
+			Request: workspaceReq{}, Response: Domain.Workspace{}, Status: http.StatusCreated,
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+		},
+		"GET /workspaces": {
+			Summary: "List the caller's workspaces and role in each", Tags: []string{"workspaces"}, Auth: true,
+			Response: []Usecases.WorkspaceRole{},
+			Errors:   []int{http.StatusUnauthorized},
+		},
+		"POST /workspaces/:id/switch": {
+			Summary: "Get a token with this workspace active", Tags: []string{"workspaces"}, Auth: true, Params: id,
+			Response: loginResp{},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden},
+		},
+		"GET /workspaces/:id/members": {
+			Summary: "List a workspace's members", Tags: []string{"workspaces"}, Auth: true, Params: id,
+			Response: []Domain.Membership{},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound},
+		},
+		"PUT /workspaces/:id/members/:username": {
+			Summary: "Add a member or change their role (workspace admin)", Tags: []string{"workspaces"}, Auth: true, Params: id,
+			Request: memberReq{}, Response: Domain.Membership{}, Errors: adminErrs,
+		},
+		"DELETE /workspaces/:id/members/:username": {
+			Summary: "Remove a member (workspace admin, or the member leaving)", Tags: []string{"workspaces"}, Auth: true, Params: id,
+			Status: http.StatusNoContent, Errors: adminErrs,
+		},
+	}
+}
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"task_manager/Domain"
+	"task_manager/Infrastructure"
+)
+
+const heartbeatInterval = 15 * time.Second
+
+var upgrader = websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 1024}
+
+// TaskEvents streams task changes as Server-Sent Events, or over a WebSocket
+// when the request asks for an upgrade. Clients resume with Last-Event-ID
+// (header or last_event_id query); a "reset" event means changes were missed
+// and the client should reload the task list. A subscriber the hub drops for
+// falling behind gets a final reset too, since by the time it reconnects its
+// Last-Event-ID may have left the replay buffer.
+func (ctr *Controller) TaskEvents(c *gin.Context) {
+	lastID := c.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.Query("last_event_id")
+	}
+	var since uint64
+	if lastID != "" {
+		v, err := strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Last-Event-ID"})
+			return
+		}
+		since = v
+	}
+
+	// members see every task in their workspace, as with GET /tasks
+	ws, _ := Domain.WorkspaceFrom(c.Request.Context())
+	visible := func(e Domain.TaskEvent) bool { return e.WorkspaceID == ws }
+	sub, missed, complete := ctr.events.Subscribe(since, visible)
+	defer ctr.events.Unsubscribe(sub)
+
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		streamWebSocket(c, sub, missed, !complete, ctr.shutdown)
+		return
+	}
+	streamSSE(c, sub, missed, !complete, ctr.shutdown)
+}
+
+func streamSSE(c *gin.Context, sub *Infrastructure.Subscription, missed []Domain.TaskEvent, reset bool, shutdown <-chan struct{}) {
+	w := c.Writer
+	// the stream outlives the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if reset {
+		fmt.Fprint(w, sseReset)
+	}
+	for _, e := range missed {
+		writeSSE(w, e)
+	}
+	w.Flush()
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-shutdown:
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				fmt.Fprint(w, sseReset)
+				w.Flush()
+				return
+			}
+			writeSSE(w, e)
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		w.Flush()
+	}
+}
+
+// sseReset tells the client it missed events; it has no id, so the client's
+// Last-Event-ID is kept
+const sseReset = "event: reset\ndata: {}\n\n"
+
+func writeSSE(w gin.ResponseWriter, e Domain.TaskEvent) {
+	data, _ := json.Marshal(e)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+}
+
+func streamWebSocket(c *gin.Context, sub *Infrastructure.Subscription, missed []Domain.TaskEvent, reset bool, shutdown <-chan struct{}) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return // Upgrade has already written the error response
+	}
+	defer conn.Close()
+
+	// the reader only handles control frames; it ends when the client goes away
+	done := make(chan struct{})
+	conn.SetReadDeadline(time.Now().Add(2 * heartbeatInterval))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * heartbeatInterval))
+	})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	write := func(v interface{}) bool {
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return conn.WriteJSON(v) == nil
+	}
+	if reset && !write(gin.H{"type": "reset"}) {
+		return
+	}
+	for _, e := range missed {
+		if !write(e) {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-shutdown:
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+				time.Now().Add(time.Second))
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				write(gin.H{"type": "reset"})
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "subscriber fell behind"),
+					time.Now().Add(time.Second))
+				return
+			}
+			if !write(e) {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"task_manager/Domain"
+	"task_manager/Usecases"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// replayedHeaders are stored with a response and sent again on replay
+var replayedHeaders = []string{"Content-Type", "Location", "ETag", "Last-Modified"}
+
+// Idempotency makes mutating requests that carry an Idempotency-Key safe to
+// retry: the first response per user and key is stored and replayed, a key
+// reused with a different request gets 409, and so does a retry that arrives
+// while the first request is still running. 5xx responses are not stored so
+// the retry runs again. Must run after authentication.
+func Idempotency(uc Usecases.IdempotencyUsecase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || !mutating(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read body", "details": err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// keys are per user and workspace, so a replay never crosses tenants
+		user := c.GetString("username")
+		if ws := c.GetString("workspace"); ws != "" {
+			user += "@" + ws
+		}
+		stored, err := uc.Begin(c.Request.Context(), user, key, fingerprint(c.Request.Method, c.Request.URL.Path, body))
+		switch {
+		case errors.Is(err, Domain.ErrIdempotencyKeyReused):
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, Domain.ErrIdempotencyInFlight):
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "idempotency check failed", "details": err.Error()})
+			return
+		case stored != nil:
+			for k, v := range stored.Header {
+				c.Header(k, v)
+			}
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(stored.Status, stored.Header["Content-Type"], stored.Body)
+			c.Abort()
+			return
+		}
+
+		// the outcome is recorded even if the client has gone away
+		ctx := context.WithoutCancel(c.Request.Context())
+		defer func() {
+			if p := recover(); p != nil {
+				abandon(ctx, uc, user, key)
+				panic(p)
+			}
+		}()
+		rec := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+		c.Next()
+
+		status := rec.Status()
+		if status >= http.StatusInternalServerError {
+			abandon(ctx, uc, user, key)
+			return
+		}
+		header := map[string]string{}
+		for _, h := range replayedHeaders {
+			if v := rec.Header().Get(h); v != "" {
+				header[h] = v
+			}
+		}
+		if err := uc.Complete(ctx, user, key, status, header, rec.body.Bytes()); err != nil {
+			slog.ErrorContext(ctx, "idempotency: store response", "key", key, "error", err)
+		}
+	}
+}
+
+func abandon(ctx context.Context, uc Usecases.IdempotencyUsecase, user, key string) {
+	if err := uc.Abandon(ctx, user, key); err != nil {
+		slog.ErrorContext(ctx, "idempotency: release key", "key", key, "error", err)
+	}
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// fingerprint identifies a request so a reused key can be detected
+func fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// bodyRecorder keeps a copy of everything written to the response
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *bodyRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
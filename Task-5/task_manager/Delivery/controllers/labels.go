@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"task_manager/Domain"
+	"task_manager/Usecases"
+)
+
+// --- Label endpoints ---
+
+type labelReq struct {
+	Name string `json:"name" binding:"required"`
+	// Color is a hex color such as "#1f77b4"; defaults to grey
+	Color       string `json:"color"`
+	Description string `json:"description"`
+}
+
+type labelPatchReq struct {
+	// Name renames the label on every task carrying it
+	Name        *string `json:"name"`
+	Color       *string `json:"color"`
+	Description *string `json:"description"`
+}
+
+type mergeLabelReq struct {
+	// Into is the label that takes over the tasks
+	Into string `json:"into" binding:"required"`
+}
+
+type taskLabelReq struct {
+	// Label is a name from the catalog, matched ignoring case
+	Label string `json:"label" binding:"required"`
+}
+
+// labelFilter reads ?labels=a,b and ?match=all|any (default all) for task
+// listings, writing a 400 when match is neither
+func labelFilter(c *gin.Context) (Domain.TaskFilter, bool) {
+	var f Domain.TaskFilter
+	if raw := c.Query("labels"); raw != "" {
+		f.Labels = strings.Split(raw, ",")
+	}
+	switch c.DefaultQuery("match", Domain.LabelMatchAll) {
+	case Domain.LabelMatchAll:
+		f.MatchAll = true
+	case Domain.LabelMatchAny:
+	default:
+		respondValidation(c, Domain.LabelError("match", "must be all or any"))
+		return Domain.TaskFilter{}, false
+	}
+	return f, true
+}
+
+func (ctr *Controller) CreateLabel(c *gin.Context) {
+	var req labelReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+	created, err := ctr.labelUC.Create(c.Request.Context(), Domain.Label{Name: req.Name, Color: req.Color, Description: req.Description})
+	if err != nil {
+		respondError(c, err, "failed to create")
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// ListLabels returns the catalog with the number of tasks carrying each label
+func (ctr *Controller) ListLabels(c *gin.Context) {
+	labels, err := ctr.labelUC.List(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "failed")
+		return
+	}
+	if labels == nil {
+		labels = []Domain.Label{}
+	}
+	c.JSON(http.StatusOK, labels)
+}
+
+func (ctr *Controller) GetLabel(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	label, err := ctr.labelUC.Get(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "failed")
+		return
+	}
+	c.JSON(http.StatusOK, label)
+}
+
+func (ctr *Controller) UpdateLabel(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req labelPatchReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+	updated, err := ctr.labelUC.Update(c.Request.Context(), id, Usecases.LabelPatch{Name: req.Name, Color: req.Color, Description: req.Description})
+	if err != nil {
+		respondError(c, err, "failed to update")
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+func (ctr *Controller) DeleteLabel(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	if err := ctr.labelUC.Delete(c.Request.Context(), id); err != nil {
+		respondError(c, err, "failed to delete")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// MergeLabel moves the label's tasks to another label, deletes it and
+// returns the label merged into
+func (ctr *Controller) MergeLabel(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req mergeLabelReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+	into, err := primitive.ObjectIDFromHex(req.Into)
+	if err != nil {
+		respondValidation(c, Domain.LabelError("into", "must be a label id"))
+		return
+	}
+	merged, err := ctr.labelUC.Merge(c.Request.Context(), id, into)
+	if err != nil {
+		respondError(c, err, "failed to merge")
+		return
+	}
+	c.JSON(http.StatusOK, merged)
+}
+
+// AddTaskLabel tags a task and returns it
+func (ctr *Controller) AddTaskLabel(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req taskLabelReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+	task, err := ctr.taskUC.AddLabel(c.Request.Context(), id, req.Label)
+	if err != nil {
+		respondError(c, err, "failed to update")
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+func (ctr *Controller) RemoveTaskLabel(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	task, err := ctr.taskUC.RemoveLabel(c.Request.Context(), id, c.Param("name"))
+	if err != nil {
+		respondError(c, err, "failed to update")
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"task_manager/Domain"
+	"task_manager/Usecases"
+)
+
+// --- Recurring task endpoints ---
+
+type recurringReq struct {
+	Title       string   `json:"title" binding:"required"`
+	Description string   `json:"description"`
+	Estimate    string   `json:"estimate"`
+	Labels      []string `json:"labels"`
+	// RRule is an iCalendar RRULE such as "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=10"
+	RRule string `json:"rrule" binding:"required"`
+	// Start is the first possible occurrence, a date or an RFC3339 time
+	Start string `json:"start" binding:"required"`
+}
+
+type recurringPatchReq struct {
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	Estimate    *string   `json:"estimate"`
+	Labels      *[]string `json:"labels"`
+	RRule       *string   `json:"rrule"`
+	Start       *string   `json:"start"`
+}
+
+// futureParam reads ?future=, which makes a template edit or deletion reach
+// the pending occurrences already generated; it writes a 400 when the value
+// isn't a boolean
+func futureParam(c *gin.Context) (future, ok bool) {
+	raw := c.Query("future")
+	if raw == "" {
+		return false, true
+	}
+	future, err := strconv.ParseBool(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid future"})
+		return false, false
+	}
+	return future, true
+}
+
+func (ctr *Controller) CreateRecurringTask(c *gin.Context) {
+	var req recurringReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+	created, err := ctr.recurUC.Create(c.Request.Context(), Domain.RecurringTask{
+		Title:       req.Title,
+		Description: req.Description,
+		Estimate:    req.Estimate,
+		Labels:      req.Labels,
+		RRule:       req.RRule,
+		Start:       req.Start,
+	})
+	if err != nil {
+		respondError(c, err, "failed to create")
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+func (ctr *Controller) ListRecurringTasks(c *gin.Context) {
+	rs, err := ctr.recurUC.List(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "failed")
+		return
+	}
+	if rs == nil {
+		rs = []Domain.RecurringTask{}
+	}
+	c.JSON(http.StatusOK, rs)
+}
+
+func (ctr *Controller) GetRecurringTask(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	r, err := ctr.recurUC.Get(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "failed")
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// UpdateRecurringTask changes the template; ?future=true also updates the
+// pending occurrences still ahead
+func (ctr *Controller) UpdateRecurringTask(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	future, ok := futureParam(c)
+	if !ok {
+		return
+	}
+	var req recurringPatchReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+	updated, err := ctr.recurUC.Update(c.Request.Context(), id, Usecases.RecurrencePatch{
+		Title:       req.Title,
+		Description: req.Description,
+		Estimate:    req.Estimate,
+		Labels:      req.Labels,
+		RRule:       req.RRule,
+		Start:       req.Start,
+	}, future)
+	if err != nil {
+		respondError(c, err, "failed to update")
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteRecurringTask stops generating occurrences; ?future=true also
+// deletes the pending occurrences still ahead
+func (ctr *Controller) DeleteRecurringTask(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	future, ok := futureParam(c)
+	if !ok {
+		return
+	}
+	if err := ctr.recurUC.Delete(c.Request.Context(), id, future); err != nil {
+		respondError(c, err, "failed to delete")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListRecurringOccurrences lists the tasks generated from the template
+func (ctr *Controller) ListRecurringOccurrences(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	tasks, err := ctr.recurUC.ListOccurrences(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "failed")
+		return
+	}
+	if tasks == nil {
+		tasks = []Domain.Task{}
+	}
+	c.JSON(http.StatusOK, tasks)
+}
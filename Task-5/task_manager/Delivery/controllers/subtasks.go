@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"task_manager/Domain"
+)
+
+// --- Subtask and checklist endpoints ---
+
+type moveTaskReq struct {
+	// ParentID is the new parent; null or missing makes the task top-level
+	ParentID *string `json:"parent_id"`
+}
+
+type checklistItemReq struct {
+	Text string `json:"text" binding:"required"`
+}
+
+type checklistItemPatchReq struct {
+	Text *string `json:"text"`
+	Done *bool   `json:"done"`
+}
+
+// ListSubtasks lists a task's direct subtasks, each with its own progress
+func (ctr *Controller) ListSubtasks(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	subtasks, err := ctr.taskUC.ListSubtasks(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "failed")
+		return
+	}
+	if subtasks == nil {
+		subtasks = []Domain.Task{}
+	}
+	var modified time.Time
+	for _, t := range subtasks {
+		if m := t.LastModified(); m.After(modified) {
+			modified = m
+		}
+	}
+	respondConditional(c, subtasks, modified, false)
+}
+
+// MoveTask moves a task, with its subtasks, under another parent
+func (ctr *Controller) MoveTask(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req moveTaskReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+	var parent primitive.ObjectID
+	if req.ParentID != nil {
+		p, err := primitive.ObjectIDFromHex(*req.ParentID)
+		if err != nil {
+			respondValidation(c, Domain.ParentError("must be a task id"))
+			return
+		}
+		parent = p
+	}
+	moved, err := ctr.taskUC.MoveTask(c.Request.Context(), id, parent)
+	if err != nil {
+		respondError(c, err, "failed to move")
+		return
+	}
+	c.JSON(http.StatusOK, moved)
+}
+
+// AddChecklistItem appends an item and returns the whole task
+func (ctr *Controller) AddChecklistItem(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req checklistItemReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+	task, err := ctr.taskUC.AddChecklistItem(c.Request.Context(), id, req.Text)
+	if err != nil {
+		respondError(c, err, "failed to update")
+		return
+	}
+	c.JSON(http.StatusCreated, task)
+}
+
+// UpdateChecklistItem renames an item or ticks it off
+func (ctr *Controller) UpdateChecklistItem(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := paramID(c, "itemId")
+	if !ok {
+		return
+	}
+	var req checklistItemPatchReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+	task, err := ctr.taskUC.UpdateChecklistItem(c.Request.Context(), id, itemID, Domain.ChecklistItemPatch{Text: req.Text, Done: req.Done})
+	if err != nil {
+		respondError(c, err, "failed to update")
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+func (ctr *Controller) DeleteChecklistItem(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := paramID(c, "itemId")
+	if !ok {
+		return
+	}
+	task, err := ctr.taskUC.DeleteChecklistItem(c.Request.Context(), id, itemID)
+	if err != nil {
+		respondError(c, err, "failed to update")
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
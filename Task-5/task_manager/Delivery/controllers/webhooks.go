@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"task_manager/Domain"
+	"task_manager/Usecases"
+)
+
+// --- Webhook endpoints (admin only) ---
+
+type webhookReq struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events" binding:"required"`
+	Secret string   `json:"secret"`
+	Active *bool    `json:"active"`
+}
+
+type webhookPatchReq struct {
+	URL    *string   `json:"url"`
+	Events *[]string `json:"events"`
+	Secret *string   `json:"secret"`
+	Active *bool     `json:"active"`
+}
+
+// paramID parses an ObjectID path parameter, writing a 400 when it is malformed
+func paramID(c *gin.Context, name string) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return primitive.NilObjectID, false
+	}
+	return id, true
+}
+
+// CreateWebhook returns the signing secret; it is not shown again
+func (ctr *Controller) CreateWebhook(c *gin.Context) {
+	var req webhookReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+	hook := Domain.Webhook{URL: req.URL, Events: req.Events, Secret: req.Secret, Active: true}
+	if req.Active != nil {
+		hook.Active = *req.Active
+	}
+	created, err := ctr.hookUC.Create(c.Request.Context(), hook)
+	if err != nil {
+		respondError(c, err, "failed to create")
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+func (ctr *Controller) ListWebhooks(c *gin.Context) {
+	hooks, err := ctr.hookUC.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed", "details": err.Error()})
+		return
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	c.JSON(http.StatusOK, hooks)
+}
+
+func (ctr *Controller) GetWebhook(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	hook, err := ctr.hookUC.Get(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "failed")
+		return
+	}
+	hook.Secret = ""
+	c.JSON(http.StatusOK, hook)
+}
+
+// UpdateWebhook changes the fields sent; the secret is echoed back only when
+// it was rotated by this request
+func (ctr *Controller) UpdateWebhook(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req webhookPatchReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+	updated, err := ctr.hookUC.Update(c.Request.Context(), id, Usecases.WebhookPatch{URL: req.URL, Events: req.Events, Secret: req.Secret, Active: req.Active})
+	if err != nil {
+		respondError(c, err, "failed to update")
+		return
+	}
+	if req.Secret == nil {
+		updated.Secret = ""
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+func (ctr *Controller) DeleteWebhook(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	if err := ctr.hookUC.Delete(c.Request.Context(), id); err != nil {
+		respondError(c, err, "failed to delete")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries returns the newest deliveries first (?limit, default 50, max 200)
+func (ctr *Controller) ListWebhookDeliveries(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	limit := int64(50)
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 || n > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+			return
+		}
+		limit = n
+	}
+	ds, err := ctr.hookUC.ListDeliveries(c.Request.Context(), id, limit)
+	if err != nil {
+		respondError(c, err, "failed")
+		return
+	}
+	if ds == nil {
+		ds = []Domain.WebhookDelivery{}
+	}
+	c.JSON(http.StatusOK, ds)
+}
+
+func (ctr *Controller) RedeliverWebhook(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := paramID(c, "deliveryId")
+	if !ok {
+		return
+	}
+	d, err := ctr.hookUC.Redeliver(c.Request.Context(), id, deliveryID)
+	if err != nil {
+		respondError(c, err, "failed to redeliver")
+		return
+	}
+	c.JSON(http.StatusAccepted, d)
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"task_manager/Domain"
+)
+
+// --- Workspace endpoints (any authenticated user, active workspace or not) ---
+
+type workspaceReq struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type memberReq struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// CreateWorkspace (instance admin only) makes the caller its first admin
+func (ctr *Controller) CreateWorkspace(c *gin.Context) {
+	var req workspaceReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+	created, err := ctr.wsUC.Create(c.Request.Context(), c.GetString("username"), Domain.Workspace{Name: req.Name})
+	if err != nil {
+		respondError(c, err, "failed to create")
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// ListWorkspaces lists the caller's workspaces with their role in each
+func (ctr *Controller) ListWorkspaces(c *gin.Context) {
+	out, err := ctr.wsUC.ListForUser(c.Request.Context(), c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// SwitchWorkspace issues a new token with the given workspace active
+func (ctr *Controller) SwitchWorkspace(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	ctr.issueToken(c, c.GetString("username"), id)
+}
+
+func (ctr *Controller) ListMembers(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	members, err := ctr.wsUC.Members(c.Request.Context(), c.GetString("username"), id)
+	if err != nil {
+		respondError(c, err, "failed")
+		return
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+// SetMember (workspace admin) adds a user or changes their role
+func (ctr *Controller) SetMember(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req memberReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+		return
+	}
+	m, err := ctr.wsUC.SetMember(c.Request.Context(), c.GetString("username"),
+		Domain.Membership{WorkspaceID: id, Username: c.Param("username"), Role: req.Role})
+	if err != nil {
+		respondError(c, err, "failed to update")
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+// RemoveMember (workspace admin, or the member leaving)
+func (ctr *Controller) RemoveMember(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	if err := ctr.wsUC.RemoveMember(c.Request.Context(), c.GetString("username"), id, c.Param("username")); err != nil {
+		respondError(c, err, "failed to delete")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,78 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// complexity estimates the cost of running a query: every field costs one,
+// and fields below a list taking `first` are counted once per requested item.
+// Syntax errors are left for the executor to report.
+func complexity(query, operation string, variables map[string]interface{}) (int, error) {
+	doc, err := parser.ParseQuery(&ast.Source{Input: query})
+	if err != nil {
+		return 0, nil
+	}
+	op := doc.Operations.ForName(operation)
+	if op == nil {
+		return 0, nil
+	}
+	c := &costCounter{fragments: doc.Fragments, variables: variables, visiting: map[string]bool{}}
+	return c.selectionSet(op.SelectionSet), nil
+}
+
+type costCounter struct {
+	fragments ast.FragmentDefinitionList
+	variables map[string]interface{}
+	visiting  map[string]bool
+}
+
+func (c *costCounter) selectionSet(set ast.SelectionSet) int {
+	total := 0
+	for _, sel := range set {
+		switch s := sel.(type) {
+		case *ast.Field:
+			total += 1 + c.multiplier(s)*c.selectionSet(s.SelectionSet)
+		case *ast.InlineFragment:
+			total += c.selectionSet(s.SelectionSet)
+		case *ast.FragmentSpread:
+			// a fragment that spreads itself is invalid; the executor reports it
+			f := c.fragments.ForName(s.Name)
+			if f == nil || c.visiting[s.Name] {
+				continue
+			}
+			c.visiting[s.Name] = true
+			total += c.selectionSet(f.SelectionSet)
+			delete(c.visiting, s.Name)
+		}
+	}
+	return total
+}
+
+// multiplier is the page size a connection field asks for
+func (c *costCounter) multiplier(f *ast.Field) int {
+	arg := f.Arguments.ForName("first")
+	if arg == nil {
+		if f.Name == "tasks" {
+			return defaultPageSize
+		}
+		return 1
+	}
+	switch arg.Value.Kind {
+	case ast.IntValue:
+		if n, err := strconv.Atoi(arg.Value.Raw); err == nil && n > 0 {
+			return n
+		}
+	case ast.Variable:
+		if v, ok := c.variables[arg.Value.Raw]; ok {
+			if n, err := strconv.Atoi(fmt.Sprint(v)); err == nil && n > 0 {
+				return n
+			}
+		}
+		return defaultPageSize
+	}
+	return 1
+}
@@ -0,0 +1,62 @@
+package graphql
+
+import (
+	"context"
+	"log/slog"
+
+	"task_manager/Delivery/apierror"
+	"task_manager/Domain"
+)
+
+// Error codes sent in extensions.code
+const (
+	CodeBadUserInput = "BAD_USER_INPUT"
+	CodeNotFound     = "NOT_FOUND"
+	CodeConflict     = "CONFLICT"
+	CodeForbidden    = "FORBIDDEN"
+	CodeTooComplex   = "QUERY_TOO_COMPLEX"
+	CodeInternal     = "INTERNAL"
+)
+
+// Error is a resolver error carrying a machine-readable code and, for bad
+// input, the per-field messages
+type Error struct {
+	Message string
+	Code    string
+	Fields  []Domain.FieldError
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Extensions is picked up by graphql-go and sent with the error
+func (e *Error) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": e.Code}
+	if len(e.Fields) > 0 {
+		ext["fields"] = e.Fields
+	}
+	return ext
+}
+
+func inputError(field, msg string) *Error {
+	return &Error{Message: field + ": " + msg, Code: CodeBadUserInput, Fields: []Domain.FieldError{{Field: field, Message: msg}}}
+}
+
+// wrap maps usecase errors the same way the REST controllers do; nil stays nil
+func wrap(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch kind := apierror.Classify(err); kind {
+	case apierror.Invalid:
+		return &Error{Message: err.Error(), Code: CodeBadUserInput, Fields: apierror.Fields(err)}
+	case apierror.NotFound:
+		return &Error{Message: kind.Message(), Code: CodeNotFound}
+	case apierror.Conflict:
+		return &Error{Message: kind.Message(), Code: CodeConflict}
+	case apierror.Forbidden:
+		return &Error{Message: err.Error(), Code: CodeForbidden}
+	default:
+		slog.ErrorContext(ctx, "graphql resolver failed", "error", err)
+		return &Error{Message: kind.Message(), Code: CodeInternal}
+	}
+}
@@ -0,0 +1,89 @@
+// Package graphql serves a GraphQL API over the same usecases as the REST
+// controllers, at POST /graphql.
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	gql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/errors"
+
+	"task_manager/Usecases"
+)
+
+//go:embed schema.graphql
+var Schema string
+
+// Options limits what a single query may ask for
+type Options struct {
+	// MaxDepth rejects queries nested deeper than this
+	MaxDepth int
+	// MaxComplexity rejects queries whose estimated cost is higher
+	MaxComplexity int
+}
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Handler executes GraphQL requests. It must run after authentication:
+// the username and role set by the auth middleware decide what the
+// query may do.
+func Handler(tasks Usecases.TaskUsecase, users Usecases.UserUsecase, labels Usecases.LabelUsecase, opts Options) gin.HandlerFunc {
+	schema := newSchema(&resolver{tasks: tasks, users: users, labels: labels}, opts)
+
+	return func(c *gin.Context) {
+		var req request
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
+			return
+		}
+		if opts.MaxComplexity > 0 {
+			cost, err := complexity(req.Query, req.OperationName, req.Variables)
+			if err == nil && cost > opts.MaxComplexity {
+				c.JSON(http.StatusOK, gin.H{"errors": []*errors.QueryError{{
+					Message:    fmt.Sprintf("query complexity %d exceeds the limit of %d", cost, opts.MaxComplexity),
+					Extensions: map[string]interface{}{"code": CodeTooComplex, "complexity": cost, "limit": opts.MaxComplexity},
+				}}})
+				return
+			}
+		}
+		ctx := withViewer(c.Request.Context(), viewer{username: c.GetString("username"), role: c.GetString("role"), instanceAdmin: c.GetBool("instance_admin")})
+		ctx = withLoaders(ctx, newLoaders(ctx, tasks))
+		c.JSON(http.StatusOK, schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+	}
+}
+
+func newSchema(r *resolver, opts Options) *gql.Schema {
+	// a field waiting on a loader holds one of the executor's slots, so
+	// there must be enough of them for a full batch to wait together
+	schemaOpts := []gql.SchemaOpt{gql.MaxParallelism(loaderMaxBatch)}
+	if opts.MaxDepth > 0 {
+		schemaOpts = append(schemaOpts, gql.MaxDepth(opts.MaxDepth))
+	}
+	return gql.MustParseSchema(Schema, r, schemaOpts...)
+}
+
+// viewer is the authenticated caller
+type viewer struct {
+	username      string
+	role          string // in the active workspace
+	instanceAdmin bool
+}
+
+type viewerKey struct{}
+
+func withViewer(ctx context.Context, v viewer) context.Context {
+	return context.WithValue(ctx, viewerKey{}, v)
+}
+
+func viewerFrom(ctx context.Context) viewer {
+	v, _ := ctx.Value(viewerKey{}).(viewer)
+	return v
+}
@@ -0,0 +1,175 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"task_manager/Domain"
+	"task_manager/Usecases"
+)
+
+const (
+	// loaderWait is how long the first lookup waits for siblings to join its batch
+	loaderWait     = 2 * time.Millisecond
+	loaderMaxBatch = 100
+)
+
+// loader batches and caches lookups by id for one request, so a query
+// asking for many tasks, at the root or nested under other tasks, makes one
+// repository call per level instead of one per task
+type loader[V any] struct {
+	ctx   context.Context
+	fetch func(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]V, error)
+
+	mu      sync.Mutex
+	results map[primitive.ObjectID]*loadResult[V]
+	pending []primitive.ObjectID
+	timer   *time.Timer
+}
+
+type loadResult[V any] struct {
+	done  chan struct{}
+	value V
+	ok    bool
+	err   error
+}
+
+func newLoader[V any](ctx context.Context, fetch func(context.Context, []primitive.ObjectID) (map[primitive.ObjectID]V, error)) *loader[V] {
+	return &loader[V]{ctx: ctx, fetch: fetch, results: map[primitive.ObjectID]*loadResult[V]{}}
+}
+
+// Load returns the value for id; ok is false when there is none
+func (l *loader[V]) Load(ctx context.Context, id primitive.ObjectID) (V, bool, error) {
+	l.mu.Lock()
+	r := l.enqueueLocked(id)
+	l.mu.Unlock()
+	return r.wait(ctx)
+}
+
+// LoadMany returns the values for ids in their order, leaving out the ones
+// that have none. All of ids join the same batch.
+func (l *loader[V]) LoadMany(ctx context.Context, ids []primitive.ObjectID) ([]V, error) {
+	l.mu.Lock()
+	rs := make([]*loadResult[V], len(ids))
+	for i, id := range ids {
+		rs[i] = l.enqueueLocked(id)
+	}
+	l.mu.Unlock()
+	out := make([]V, 0, len(ids))
+	for _, r := range rs {
+		v, ok, err := r.wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+// enqueueLocked returns the result for id, queueing it if it's new; l.mu
+// must be held
+func (l *loader[V]) enqueueLocked(id primitive.ObjectID) *loadResult[V] {
+	if r, seen := l.results[id]; seen {
+		return r
+	}
+	r := &loadResult[V]{done: make(chan struct{})}
+	l.results[id] = r
+	l.pending = append(l.pending, id)
+	switch {
+	case len(l.pending) >= loaderMaxBatch:
+		l.dispatchLocked()
+	case l.timer == nil:
+		l.timer = time.AfterFunc(loaderWait, l.dispatch)
+	}
+	return r
+}
+
+func (r *loadResult[V]) wait(ctx context.Context) (V, bool, error) {
+	select {
+	case <-r.done:
+		return r.value, r.ok, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, false, ctx.Err()
+	}
+}
+
+func (l *loader[V]) dispatch() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.dispatchLocked()
+}
+
+// dispatchLocked sends the pending ids as one batch; l.mu must be held
+func (l *loader[V]) dispatchLocked() {
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	if len(l.pending) == 0 {
+		return
+	}
+	ids := l.pending
+	l.pending = nil
+	batch := make(map[primitive.ObjectID]*loadResult[V], len(ids))
+	for _, id := range ids {
+		batch[id] = l.results[id]
+	}
+	go func() {
+		values, err := l.fetch(l.ctx, ids)
+		for id, r := range batch {
+			r.value, r.ok = values[id]
+			r.err = err
+			close(r.done)
+		}
+	}()
+}
+
+// loaders are the per-request loaders: tasks by id, and the direct
+// subtasks of tasks by parent id
+type loaders struct {
+	tasks    *loader[Domain.Task]
+	subtasks *loader[[]Domain.Task]
+}
+
+func newLoaders(ctx context.Context, tasks Usecases.TaskUsecase) loaders {
+	return loaders{
+		tasks: newLoader(ctx, func(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]Domain.Task, error) {
+			found, err := tasks.GetTasksByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			out := make(map[primitive.ObjectID]Domain.Task, len(found))
+			for _, t := range found {
+				out[t.ID] = t
+			}
+			return out, nil
+		}),
+		subtasks: newLoader(ctx, func(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID][]Domain.Task, error) {
+			children, err := tasks.ListChildren(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			out := make(map[primitive.ObjectID][]Domain.Task, len(ids))
+			for _, t := range children {
+				out[*t.ParentID] = append(out[*t.ParentID], t)
+			}
+			return out, nil
+		}),
+	}
+}
+
+type loaderKey struct{}
+
+func withLoaders(ctx context.Context, l loaders) context.Context {
+	return context.WithValue(ctx, loaderKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) loaders {
+	return ctx.Value(loaderKey{}).(loaders)
+}
@@ -0,0 +1,139 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"task_manager/Domain"
+	"task_manager/Usecases"
+)
+
+// taskStore answers the task lookups the loaders make and counts them; the
+// rest of TaskUsecase is left nil
+type taskStore struct {
+	Usecases.TaskUsecase
+	tasks []Domain.Task
+
+	mu       sync.Mutex
+	byIDs    int
+	children int
+}
+
+func (s *taskStore) ListTasks(ctx context.Context, filter Domain.TaskFilter) ([]Domain.Task, error) {
+	return s.tasks, nil
+}
+
+func (s *taskStore) GetTasksByIDs(ctx context.Context, ids []primitive.ObjectID) ([]Domain.Task, error) {
+	s.mu.Lock()
+	s.byIDs++
+	s.mu.Unlock()
+	want := map[primitive.ObjectID]bool{}
+	for _, id := range ids {
+		want[id] = true
+	}
+	var out []Domain.Task
+	for _, t := range s.tasks {
+		if want[t.ID] {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (s *taskStore) ListChildren(ctx context.Context, parents []primitive.ObjectID) ([]Domain.Task, error) {
+	s.mu.Lock()
+	s.children++
+	s.mu.Unlock()
+	want := map[primitive.ObjectID]bool{}
+	for _, id := range parents {
+		want[id] = true
+	}
+	var out []Domain.Task
+	for _, t := range s.tasks {
+		if t.ParentID != nil && want[*t.ParentID] {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func TestNestedTaskLookupsAreBatched(t *testing.T) {
+	// 10 top-level tasks, each with 2 subtasks blocked by the next top-level task
+	store := &taskStore{}
+	var roots []Domain.Task
+	for i := 0; i < 10; i++ {
+		roots = append(roots, Domain.Task{ID: primitive.NewObjectID(), Title: fmt.Sprintf("root %d", i), Status: "pending"})
+	}
+	store.tasks = append(store.tasks, roots...)
+	for i, root := range roots {
+		parent := root.ID
+		blocker := roots[(i+1)%len(roots)].ID
+		for j := 0; j < 2; j++ {
+			store.tasks = append(store.tasks, Domain.Task{
+				ID: primitive.NewObjectID(), Title: fmt.Sprintf("sub %d.%d", i, j), Status: "pending",
+				ParentID: &parent, BlockedBy: []primitive.ObjectID{blocker},
+			})
+		}
+	}
+
+	schema := newSchema(&resolver{tasks: store}, Options{})
+	ctx := withLoaders(context.Background(), newLoaders(context.Background(), store))
+	resp := schema.Exec(ctx, `{
+	  tasks(first: 100) {
+	    nodes {
+	      id
+	      subtasks { id parent { id } blockers { id subtasks { id } } }
+	    }
+	  }
+	}`, "", nil)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("Exec() errors = %v", resp.Errors)
+	}
+
+	var data struct {
+		Tasks struct {
+			Nodes []struct {
+				ID       string
+				Subtasks []struct {
+					ID       string
+					Parent   struct{ ID string }
+					Blockers []struct {
+						ID       string
+						Subtasks []struct{ ID string }
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	subtasks := 0
+	for _, n := range data.Tasks.Nodes {
+		for _, s := range n.Subtasks {
+			subtasks++
+			if s.Parent.ID != n.ID {
+				t.Errorf("parent of %s = %s, want %s", s.ID, s.Parent.ID, n.ID)
+			}
+			if len(s.Blockers) != 1 || len(s.Blockers[0].Subtasks) != 2 {
+				t.Errorf("blockers of %s = %+v, want one with two subtasks", s.ID, s.Blockers)
+			}
+		}
+	}
+	if len(data.Tasks.Nodes) != len(store.tasks) || subtasks != 20 {
+		t.Fatalf("got %d tasks and %d subtasks, want %d and 20", len(data.Tasks.Nodes), subtasks, len(store.tasks))
+	}
+
+	// parents and blockers are both top-level tasks, and the blockers'
+	// subtasks were loaded with everyone else's, so each loader should run
+	// once; a slow scheduler may split a level, but one call per task (20
+	// and 30 here) means nothing is batched
+	if store.byIDs > 3 || store.children > 3 {
+		t.Errorf("made %d lookups by id and %d subtask lookups, want about 1 of each", store.byIDs, store.children)
+	}
+}
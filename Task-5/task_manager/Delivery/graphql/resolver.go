@@ -0,0 +1,720 @@
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"sort"
+	"strings"
+
+	gql "github.com/graph-gophers/graphql-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"task_manager/Domain"
+	"task_manager/Usecases"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// resolver is the root for both queries and mutations
+type resolver struct {
+	tasks  Usecases.TaskUsecase
+	users  Usecases.UserUsecase
+	labels Usecases.LabelUsecase
+}
+
+// --- queries ---
+
+func (r *resolver) Me(ctx context.Context) (*userResolver, error) {
+	v := viewerFrom(ctx)
+	return &userResolver{Domain.User{Username: v.username, Role: v.role}}, nil
+}
+
+func (r *resolver) Task(ctx context.Context, args struct{ ID gql.ID }) (*taskResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	t, ok, err := loadersFrom(ctx).tasks.Load(ctx, id)
+	if err != nil || !ok {
+		return nil, wrap(ctx, err)
+	}
+	return &taskResolver{t}, nil
+}
+
+type taskFilter struct {
+	IDs           *[]gql.ID
+	Status        *string
+	TitleContains *string
+	DueBefore     *string
+	DueAfter      *string
+	Labels        *[]string
+	LabelMatch    string
+}
+
+// labels is the part of the filter the usecase applies
+func (f *taskFilter) labels() Domain.TaskFilter {
+	if f == nil || f.Labels == nil {
+		return Domain.TaskFilter{}
+	}
+	return Domain.TaskFilter{Labels: *f.Labels, MatchAll: f.LabelMatch != "ANY"}
+}
+
+func (r *resolver) Tasks(ctx context.Context, args struct {
+	Filter *taskFilter
+	First  int32
+	After  *string
+}) (*taskConnection, error) {
+	if args.First < 0 || args.First > maxPageSize {
+		return nil, inputError("first", "must be between 0 and 100")
+	}
+	var tasks []Domain.Task
+	var err error
+	byLabel := args.Filter.labels()
+	if args.Filter != nil && args.Filter.IDs != nil && len(byLabel.Labels) == 0 {
+		ids := make([]primitive.ObjectID, 0, len(*args.Filter.IDs))
+		for _, raw := range *args.Filter.IDs {
+			id, err := parseID(raw)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		tasks, err = r.tasks.GetTasksByIDs(ctx, ids)
+	} else {
+		tasks, err = r.tasks.ListTasks(ctx, byLabel)
+	}
+	if err != nil {
+		return nil, wrap(ctx, err)
+	}
+	if tasks, err = filterTasks(tasks, args.Filter); err != nil {
+		return nil, err
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID.Hex() < tasks[j].ID.Hex() })
+
+	start := 0
+	if args.After != nil {
+		after, err := decodeCursor(*args.After)
+		if err != nil {
+			return nil, err
+		}
+		start = sort.Search(len(tasks), func(i int) bool { return tasks[i].ID.Hex() > after })
+	}
+	end := start + int(args.First)
+	if end > len(tasks) {
+		end = len(tasks)
+	}
+	return &taskConnection{page: tasks[start:end], total: len(tasks), more: end < len(tasks)}, nil
+}
+
+func (r *resolver) Subtasks(ctx context.Context, args struct{ ID gql.ID }) ([]*taskResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	l := loadersFrom(ctx)
+	_, ok, err := l.tasks.Load(ctx, id)
+	if err == nil && !ok {
+		err = Domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, wrap(ctx, err)
+	}
+	tasks, _, err := l.subtasks.Load(ctx, id)
+	if err != nil {
+		return nil, wrap(ctx, err)
+	}
+	return taskResolvers(tasks), nil
+}
+
+func (r *resolver) Dependencies(ctx context.Context, args struct{ ID gql.ID }) (*dependenciesResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := r.tasks.ListDependencies(ctx, id)
+	if err != nil {
+		return nil, wrap(ctx, err)
+	}
+	return &dependenciesResolver{deps}, nil
+}
+
+func (r *resolver) Labels(ctx context.Context) ([]*labelResolver, error) {
+	labels, err := r.labels.List(ctx)
+	if err != nil {
+		return nil, wrap(ctx, err)
+	}
+	out := make([]*labelResolver, len(labels))
+	for i, l := range labels {
+		out[i] = &labelResolver{l}
+	}
+	return out, nil
+}
+
+func filterTasks(tasks []Domain.Task, f *taskFilter) ([]Domain.Task, error) {
+	if f == nil {
+		return tasks, nil
+	}
+	var before, after primitive.DateTime
+	var hasBefore, hasAfter bool
+	if f.DueBefore != nil {
+		t, ok := Domain.ParseDueDate(*f.DueBefore)
+		if !ok {
+			return nil, inputError("filter.dueBefore", "must be RFC3339 or YYYY-MM-DD")
+		}
+		before, hasBefore = primitive.NewDateTimeFromTime(t), true
+	}
+	if f.DueAfter != nil {
+		t, ok := Domain.ParseDueDate(*f.DueAfter)
+		if !ok {
+			return nil, inputError("filter.dueAfter", "must be RFC3339 or YYYY-MM-DD")
+		}
+		after, hasAfter = primitive.NewDateTimeFromTime(t), true
+	}
+	var ids map[string]bool
+	if f.IDs != nil {
+		ids = make(map[string]bool, len(*f.IDs))
+		for _, id := range *f.IDs {
+			ids[string(id)] = true
+		}
+	}
+	out := tasks[:0:0]
+	for _, t := range tasks {
+		if ids != nil && !ids[t.ID.Hex()] {
+			continue
+		}
+		if f.Status != nil && t.Status != fromEnum(*f.Status) {
+			continue
+		}
+		if f.TitleContains != nil && !strings.Contains(strings.ToLower(t.Title), strings.ToLower(*f.TitleContains)) {
+			continue
+		}
+		if hasBefore || hasAfter {
+			due, ok := Domain.ParseDueDate(t.DueDate)
+			if !ok {
+				continue
+			}
+			d := primitive.NewDateTimeFromTime(due)
+			if hasBefore && d >= before || hasAfter && d < after {
+				continue
+			}
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// --- mutations ---
+
+type taskInput struct {
+	Title       string
+	Description *string
+	DueDate     *string
+	Status      string
+	Estimate    *string
+}
+
+func (in taskInput) toTask() Domain.Task {
+	return Domain.Task{Title: in.Title, Description: deref(in.Description), DueDate: deref(in.DueDate), Status: fromEnum(in.Status), Estimate: deref(in.Estimate)}
+}
+
+type taskPatch struct {
+	Title       *string
+	Description *string
+	DueDate     *string
+	Status      *string
+	Estimate    *string
+	Remove      *[]string
+}
+
+func (p taskPatch) toChange() Domain.TaskChange {
+	ch := Domain.TaskChange{Set: map[string]interface{}{}}
+	set := func(field string, v *string) {
+		if v != nil {
+			ch.Set[field] = *v
+		}
+	}
+	set("title", p.Title)
+	set("description", p.Description)
+	set("due_date", p.DueDate)
+	set("estimate", p.Estimate)
+	if p.Status != nil {
+		ch.Set["status"] = fromEnum(*p.Status)
+	}
+	if p.Remove != nil {
+		for _, f := range *p.Remove {
+			ch.Unset = append(ch.Unset, fromEnum(f))
+		}
+	}
+	return ch
+}
+
+func (r *resolver) CreateTask(ctx context.Context, args struct {
+	Input    taskInput
+	ParentID *gql.ID
+}) (*taskResolver, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	task := args.Input.toTask()
+	if args.ParentID != nil {
+		parent, err := parseID(*args.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		task.ParentID = &parent
+	}
+	t, err := r.tasks.CreateTask(ctx, task)
+	if err != nil {
+		return nil, wrap(ctx, err)
+	}
+	return &taskResolver{t}, nil
+}
+
+func (r *resolver) UpdateTask(ctx context.Context, args struct {
+	ID    gql.ID
+	Input taskPatch
+	Force bool
+}) (*taskResolver, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	change := args.Input.toChange()
+	change.Force = args.Force
+	t, err := r.tasks.UpdateTask(ctx, id, change)
+	if err != nil {
+		return nil, wrap(ctx, err)
+	}
+	return &taskResolver{t}, nil
+}
+
+func (r *resolver) ReplaceTask(ctx context.Context, args struct {
+	ID    gql.ID
+	Input taskInput
+	Force bool
+}) (*taskResolver, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	t, err := r.tasks.ReplaceTask(ctx, id, args.Input.toTask(), args.Force)
+	if err != nil {
+		return nil, wrap(ctx, err)
+	}
+	return &taskResolver{t}, nil
+}
+
+func (r *resolver) DeleteTask(ctx context.Context, args struct{ ID gql.ID }) (bool, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return false, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return false, err
+	}
+	if err := r.tasks.DeleteTask(ctx, id); err != nil {
+		return false, wrap(ctx, err)
+	}
+	return true, nil
+}
+
+func (r *resolver) MoveTask(ctx context.Context, args struct {
+	ID       gql.ID
+	ParentID *gql.ID
+}) (*taskResolver, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	var parent primitive.ObjectID
+	if args.ParentID != nil {
+		if parent, err = parseID(*args.ParentID); err != nil {
+			return nil, err
+		}
+	}
+	t, err := r.tasks.MoveTask(ctx, id, parent)
+	if err != nil {
+		return nil, wrap(ctx, err)
+	}
+	return &taskResolver{t}, nil
+}
+
+func (r *resolver) AddChecklistItem(ctx context.Context, args struct {
+	TaskID gql.ID
+	Text   string
+}) (*taskResolver, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	t, err := r.tasks.AddChecklistItem(ctx, id, args.Text)
+	if err != nil {
+		return nil, wrap(ctx, err)
+	}
+	return &taskResolver{t}, nil
+}
+
+func (r *resolver) UpdateChecklistItem(ctx context.Context, args struct {
+	TaskID gql.ID
+	ItemID gql.ID
+	Text   *string
+	Done   *bool
+}) (*taskResolver, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	itemID, err := parseID(args.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	t, err := r.tasks.UpdateChecklistItem(ctx, id, itemID, Domain.ChecklistItemPatch{Text: args.Text, Done: args.Done})
+	if err != nil {
+		return nil, wrap(ctx, err)
+	}
+	return &taskResolver{t}, nil
+}
+
+func (r *resolver) DeleteChecklistItem(ctx context.Context, args struct {
+	TaskID gql.ID
+	ItemID gql.ID
+}) (*taskResolver, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	itemID, err := parseID(args.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	t, err := r.tasks.DeleteChecklistItem(ctx, id, itemID)
+	if err != nil {
+		return nil, wrap(ctx, err)
+	}
+	return &taskResolver{t}, nil
+}
+
+func (r *resolver) AddBlocker(ctx context.Context, args struct {
+	TaskID    gql.ID
+	BlockerID gql.ID
+}) (*taskResolver, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	blocker, err := parseID(args.BlockerID)
+	if err != nil {
+		return nil, err
+	}
+	t, err := r.tasks.AddBlocker(ctx, id, blocker)
+	if err != nil {
+		return nil, wrap(ctx, err)
+	}
+	return &taskResolver{t}, nil
+}
+
+func (r *resolver) RemoveBlocker(ctx context.Context, args struct {
+	TaskID    gql.ID
+	BlockerID gql.ID
+}) (*taskResolver, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	blocker, err := parseID(args.BlockerID)
+	if err != nil {
+		return nil, err
+	}
+	t, err := r.tasks.RemoveBlocker(ctx, id, blocker)
+	if err != nil {
+		return nil, wrap(ctx, err)
+	}
+	return &taskResolver{t}, nil
+}
+
+func (r *resolver) AddLabel(ctx context.Context, args struct {
+	TaskID gql.ID
+	Label  string
+}) (*taskResolver, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	t, err := r.tasks.AddLabel(ctx, id, args.Label)
+	if err != nil {
+		return nil, wrap(ctx, err)
+	}
+	return &taskResolver{t}, nil
+}
+
+func (r *resolver) RemoveLabel(ctx context.Context, args struct {
+	TaskID gql.ID
+	Label  string
+}) (*taskResolver, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	id, err := parseID(args.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	t, err := r.tasks.RemoveLabel(ctx, id, args.Label)
+	if err != nil {
+		return nil, wrap(ctx, err)
+	}
+	return &taskResolver{t}, nil
+}
+
+func (r *resolver) PromoteUser(ctx context.Context, args struct{ Username string }) (*userResolver, error) {
+	if err := requireInstanceAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if err := r.users.Promote(ctx, args.Username); err != nil {
+		return nil, wrap(ctx, err)
+	}
+	return &userResolver{Domain.User{Username: Domain.NormalizeUsername(args.Username), Role: "admin"}}, nil
+}
+
+// --- object resolvers ---
+
+type userResolver struct{ u Domain.User }
+
+func (r *userResolver) Username() string { return r.u.Username }
+func (r *userResolver) Role() string     { return r.u.Role }
+
+type taskResolver struct{ t Domain.Task }
+
+func (r *taskResolver) ID() gql.ID           { return gql.ID(r.t.ID.Hex()) }
+func (r *taskResolver) Title() string        { return r.t.Title }
+func (r *taskResolver) Description() *string { return optional(r.t.Description) }
+func (r *taskResolver) DueDate() *string     { return optional(r.t.DueDate) }
+func (r *taskResolver) Status() string       { return toEnum(r.t.Status) }
+func (r *taskResolver) Estimate() *string    { return optional(r.t.Estimate) }
+
+func (r *taskResolver) BlockedBy() []gql.ID {
+	out := make([]gql.ID, len(r.t.BlockedBy))
+	for i, id := range r.t.BlockedBy {
+		out[i] = gql.ID(id.Hex())
+	}
+	return out
+}
+
+// Labels is never null, so a task without labels gets an empty list
+func (r *taskResolver) Labels() []string {
+	return append([]string{}, r.t.Labels...)
+}
+
+func (r *taskResolver) ParentID() *gql.ID {
+	if r.t.ParentID == nil {
+		return nil
+	}
+	id := gql.ID(r.t.ParentID.Hex())
+	return &id
+}
+
+// Parent, Blockers and Subtasks go through the request's loaders, so the
+// tasks of a whole list are fetched together
+func (r *taskResolver) Parent(ctx context.Context) (*taskResolver, error) {
+	if r.t.ParentID == nil {
+		return nil, nil
+	}
+	t, ok, err := loadersFrom(ctx).tasks.Load(ctx, *r.t.ParentID)
+	if err != nil || !ok {
+		return nil, wrap(ctx, err)
+	}
+	return &taskResolver{t}, nil
+}
+
+func (r *taskResolver) Blockers(ctx context.Context) ([]*taskResolver, error) {
+	tasks, err := loadersFrom(ctx).tasks.LoadMany(ctx, r.t.BlockedBy)
+	if err != nil {
+		return nil, wrap(ctx, err)
+	}
+	return taskResolvers(tasks), nil
+}
+
+func (r *taskResolver) Subtasks(ctx context.Context) ([]*taskResolver, error) {
+	tasks, _, err := loadersFrom(ctx).subtasks.Load(ctx, r.t.ID)
+	if err != nil {
+		return nil, wrap(ctx, err)
+	}
+	return taskResolvers(tasks), nil
+}
+
+func (r *taskResolver) Checklist() []*checklistItemResolver {
+	out := make([]*checklistItemResolver, len(r.t.Checklist))
+	for i, item := range r.t.Checklist {
+		out[i] = &checklistItemResolver{item}
+	}
+	return out
+}
+
+func (r *taskResolver) Progress() *progressResolver {
+	if r.t.Progress == nil {
+		return nil
+	}
+	return &progressResolver{*r.t.Progress}
+}
+
+type dependenciesResolver struct{ d Domain.Dependencies }
+
+func (r *dependenciesResolver) BlockedBy() []*taskResolver { return taskResolvers(r.d.BlockedBy) }
+func (r *dependenciesResolver) Blocking() []*taskResolver  { return taskResolvers(r.d.Blocking) }
+
+func taskResolvers(tasks []Domain.Task) []*taskResolver {
+	out := make([]*taskResolver, len(tasks))
+	for i, t := range tasks {
+		out[i] = &taskResolver{t}
+	}
+	return out
+}
+
+type labelResolver struct{ l Domain.Label }
+
+func (r *labelResolver) ID() gql.ID           { return gql.ID(r.l.ID.Hex()) }
+func (r *labelResolver) Name() string         { return r.l.Name }
+func (r *labelResolver) Color() string        { return r.l.Color }
+func (r *labelResolver) Description() *string { return optional(r.l.Description) }
+func (r *labelResolver) Count() int32         { return int32(r.l.Count) }
+
+type checklistItemResolver struct{ item Domain.ChecklistItem }
+
+func (r *checklistItemResolver) ID() gql.ID   { return gql.ID(r.item.ID.Hex()) }
+func (r *checklistItemResolver) Text() string { return r.item.Text }
+func (r *checklistItemResolver) Done() bool   { return r.item.Done }
+
+type progressResolver struct{ p Domain.Progress }
+
+func (r *progressResolver) Done() int32     { return int32(r.p.Done) }
+func (r *progressResolver) Total() int32    { return int32(r.p.Total) }
+func (r *progressResolver) Subtasks() int32 { return int32(r.p.Subtasks) }
+
+type taskConnection struct {
+	page  []Domain.Task
+	total int
+	more  bool
+}
+
+func (c *taskConnection) Edges() []*taskEdge {
+	out := make([]*taskEdge, len(c.page))
+	for i, t := range c.page {
+		out[i] = &taskEdge{t}
+	}
+	return out
+}
+
+func (c *taskConnection) Nodes() []*taskResolver {
+	out := make([]*taskResolver, len(c.page))
+	for i, t := range c.page {
+		out[i] = &taskResolver{t}
+	}
+	return out
+}
+
+func (c *taskConnection) PageInfo() *pageInfo {
+	p := &pageInfo{HasNext: c.more}
+	if n := len(c.page); n > 0 {
+		cur := encodeCursor(c.page[n-1].ID.Hex())
+		p.End = &cur
+	}
+	return p
+}
+
+func (c *taskConnection) TotalCount() int32 { return int32(c.total) }
+
+type taskEdge struct{ t Domain.Task }
+
+func (e *taskEdge) Cursor() string      { return encodeCursor(e.t.ID.Hex()) }
+func (e *taskEdge) Node() *taskResolver { return &taskResolver{e.t} }
+
+type pageInfo struct {
+	HasNext bool
+	End     *string
+}
+
+func (p *pageInfo) HasNextPage() bool  { return p.HasNext }
+func (p *pageInfo) EndCursor() *string { return p.End }
+
+// --- helpers ---
+
+func parseID(id gql.ID) (primitive.ObjectID, error) {
+	oid, err := primitive.ObjectIDFromHex(string(id))
+	if err != nil {
+		return primitive.NilObjectID, inputError("id", "invalid id")
+	}
+	return oid, nil
+}
+
+func encodeCursor(hex string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("task:" + hex))
+}
+
+func decodeCursor(c string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil || !strings.HasPrefix(string(b), "task:") {
+		return "", inputError("after", "invalid cursor")
+	}
+	return strings.TrimPrefix(string(b), "task:"), nil
+}
+
+// toEnum and fromEnum convert between stored values and GraphQL enum names
+func toEnum(s string) string   { return strings.ToUpper(s) }
+func fromEnum(s string) string { return strings.ToLower(s) }
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func requireAdmin(ctx context.Context) error {
+	if viewerFrom(ctx).role != "admin" {
+		return &Error{Message: "admin required", Code: CodeForbidden}
+	}
+	return nil
+}
+
+func requireInstanceAdmin(ctx context.Context) error {
+	if !viewerFrom(ctx).instanceAdmin {
+		return &Error{Message: "instance admin required", Code: CodeForbidden}
+	}
+	return nil
+}
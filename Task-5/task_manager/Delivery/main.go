@@ -1,50 +1,325 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 	"task_manager/Delivery/controllers"
+	"task_manager/Delivery/graphql"
+	"task_manager/Delivery/rpc"
+	"task_manager/config"
 	"task_manager/Delivery/routers"
+	"task_manager/Repositories"
 	"task_manager/Repositories/mongoimpl"
 	"task_manager/Usecases"
 	"task_manager/Infrastructure"
 )
 
 func main() {
-	// Config via env
-	mongoURI := os.Getenv("MONGO_URI")
-	mongoDB := os.Getenv("MONGO_DB")
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "change_this_secret"
+	// config: defaults < file < env < flags
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fatal("invalid config", err)
+	}
+
+	// structured logging; the standard log package is routed through it too
+	logger, err := Infrastructure.NewLogger(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fatal("logger", err)
+	}
+	slog.SetDefault(logger)
+	for _, w := range cfg.Warnings() {
+		slog.Warn("config warning", "warning", w)
+	}
+	slog.Info("effective config", "config", cfg.Redacted())
+
+	tracing, err := Infrastructure.NewTracing(context.Background(), Infrastructure.TracingOptions{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		Stdout:      os.Stdout,
+	})
+	if err != nil {
+		fatal("tracing", err)
 	}
 
 	// connect repositories (Mongo)
-	mongoClient, err := mongoimpl.NewMongoClient(mongoURI, mongoDB)
+	mongoClient, err := mongoimpl.NewMongoClient(cfg.Mongo.URI, cfg.Mongo.DB)
 	if err != nil {
-		log.Fatalf("failed to connect mongo: %v", err)
+		fatal("failed to connect mongo", err)
 	}
-	defer mongoClient.Close()
 
-	taskRepo := mongoimpl.NewTaskRepository(mongoClient)
-	userRepo := mongoimpl.NewUserRepository(mongoClient)
+	// usernames stored before they were normalized can't log in until lowercased
+	renamed, err := mongoimpl.NormalizeUsernames(context.Background(), mongoClient)
+	if err != nil {
+		fatal("normalizing usernames", err)
+	}
+	for _, c := range renamed {
+		slog.Info("renamed user to its normalized username", "from", c.From, "to", c.To, "collision", c.Collision)
+	}
+
+	// data from before workspaces existed moves into a "Default" workspace
+	adopted, err := mongoimpl.AdoptUnscopedData(context.Background(), mongoClient)
+	if err != nil {
+		fatal("adopting data into the default workspace", err)
+	}
+	if adopted > 0 {
+		slog.Info("moved existing data into the default workspace", "documents", adopted)
+	}
+
+	var taskRepo Repositories.TaskRepository = mongoimpl.NewTaskRepository(mongoClient)
+	var userRepo Repositories.UserRepository = mongoimpl.NewUserRepository(mongoClient)
+
+	// metrics and spans decorate the repositories here and the usecases below
+	var metrics *Infrastructure.Metrics
+	if cfg.Metrics.Enabled {
+		metrics = Infrastructure.NewMetrics()
+		taskRepo = Repositories.InstrumentTaskRepository(taskRepo, metrics.ObserveRepository)
+		userRepo = Repositories.InstrumentUserRepository(userRepo, metrics.ObserveRepository)
+	}
+	taskRepo = Repositories.InstrumentTaskRepository(taskRepo, tracing.ObserveRepository)
+	userRepo = Repositories.InstrumentUserRepository(userRepo, tracing.ObserveRepository)
+
+	// the cache sits outside the instrumentation, so repository metrics and
+	// spans only count calls that reach Mongo
+	var transactor Repositories.Transactor = mongoimpl.NewTransactor(mongoClient)
+	if cfg.Cache.Enabled {
+		var stats Repositories.CacheStats
+		if metrics != nil {
+			stats = metrics.ObserveCache
+		}
+		cache := Repositories.NewTaskCache(cfg.Cache.Size, cfg.Cache.TTL.D(), stats)
+		taskRepo = cache.Tasks(taskRepo)
+		transactor = cache.Transactor(transactor)
+	}
 
 	// usecases
-	userUC := Usecases.NewUserUsecase(userRepo)
-	taskUC := Usecases.NewTaskUsecase(taskRepo)
+	userUC := Usecases.NewUserUsecase(userRepo, Infrastructure.NewPasswordService(cfg.Auth.BcryptCost))
+	var webhookDrops Usecases.WebhookDropStats
+	if metrics != nil {
+		webhookDrops = metrics.ObserveWebhookDrop
+	}
+	webhookUC := Usecases.NewWebhookUsecase(
+		mongoimpl.NewWebhookRepository(mongoClient),
+		mongoimpl.NewWebhookDeliveryRepository(mongoClient),
+		Infrastructure.NewWebhookSender(cfg.Webhooks.Timeout.D()),
+		webhookDrops,
+	)
+	membershipRepo := mongoimpl.NewMembershipRepository(mongoClient)
+	workspaceUC := Usecases.NewWorkspaceUsecase(
+		mongoimpl.NewWorkspaceRepository(mongoClient),
+		membershipRepo,
+		userRepo,
+	)
+	labelRepo := mongoimpl.NewLabelRepository(mongoClient)
+	labelUC := Usecases.NewLabelUsecase(labelRepo, taskRepo, transactor)
+	events := Infrastructure.NewEventHub(cfg.Events.ReplayBuffer)
+	commentUC := Usecases.NewCommentUsecase(mongoimpl.NewCommentRepository(mongoClient), taskRepo, membershipRepo,
+		Usecases.TaskEventPublishers{events, webhookUC})
+	blobs := mongoimpl.NewGridFSBlobStore(mongoClient)
+	if cfg.Attachments.Store == "local" {
+		if blobs, err = Infrastructure.NewLocalBlobStore(cfg.Attachments.Dir); err != nil {
+			fatal("attachment store", err)
+		}
+	}
+	signingKey := cfg.Attachments.SigningKey
+	if signingKey == "" {
+		signingKey = cfg.Auth.JWTSecret
+	}
+	attachmentUC := Usecases.NewAttachmentUsecase(mongoimpl.NewAttachmentRepository(mongoClient), taskRepo, blobs,
+		Infrastructure.NewURLSigner(signingKey.Value()),
+		Usecases.AttachmentOptions{
+			MaxSize:      int64(cfg.Attachments.MaxSize),
+			AllowedTypes: cfg.Attachments.AllowedTypes,
+			LinkTTL:      cfg.Attachments.LinkTTL.D(),
+		})
+	taskUC := Usecases.NewTaskUsecase(taskRepo, labelRepo, transactor,
+		Usecases.TaskEventPublishers{events, webhookUC, commentUC, attachmentUC},
+		Usecases.TaskOptions{MaxDepth: cfg.Tasks.MaxDepth})
+	if metrics != nil {
+		taskUC = Usecases.InstrumentTaskUsecase(taskUC, metrics.ObserveUsecase)
+		userUC = Usecases.InstrumentUserUsecase(userUC, metrics.ObserveUsecase)
+	}
+	taskUC = Usecases.InstrumentTaskUsecase(taskUC, tracing.ObserveUsecase)
+	userUC = Usecases.InstrumentUserUsecase(userUC, tracing.ObserveUsecase)
+	recurrenceUC := Usecases.NewRecurrenceUsecase(mongoimpl.NewRecurrenceRepository(mongoClient), taskRepo, labelRepo, taskUC,
+		Usecases.RecurrenceOptions{Horizon: cfg.Recurrence.Horizon.D(), Interval: cfg.Recurrence.Interval.D()})
+
+	// background workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		webhookUC.Run(workerCtx)
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		recurrenceUC.Run(workerCtx)
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		attachmentUC.Run(workerCtx)
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		commentUC.Run(workerCtx)
+	}()
+
+	health := Infrastructure.NewHealth()
+	health.Register("mongo", mongoClient.Ping)
+	health.Register("webhooks", webhookUC.Healthy)
+	health.Register("recurrence", recurrenceUC.Healthy)
 
 	// infrastructure (jwt service)
-	infraJwt := Infrastructure.NewJWTService(jwtSecret)
+	infraJwt := Infrastructure.NewJWTService(cfg.Auth.JWTSecret.Value(), cfg.Auth.TokenTTL.D())
 
 	// controller
-	ctrl := controllers.NewController(userUC, taskUC, infraJwt)
+	// closed when shutdown starts, so event streams end instead of holding it
+	// open until the timeout; other requests keep their contexts and finish
+	streamsDone := make(chan struct{})
+	ctrl := controllers.NewController(userUC, taskUC, infraJwt, events, webhookUC, workspaceUC, labelUC, commentUC, attachmentUC, recurrenceUC, streamsDone)
+
+	// rate limits
+	var limits routers.RateLimits
+	if cfg.RateLimit.Enabled {
+		limits = routers.RateLimits{
+			Store:         Infrastructure.NewMemoryRateLimitStore(),
+			Public:        ratePolicy("public", cfg.RateLimit.Public),
+			Authenticated: ratePolicy("authenticated", cfg.RateLimit.Authenticated),
+			Admin:         ratePolicy("admin", cfg.RateLimit.Admin),
+		}
+		if cfg.RateLimit.Store == "mongo" {
+			limits.Store = mongoimpl.NewRateLimitStore(mongoClient)
+		}
+	}
+
+	// graphql
+	var gqlHandler gin.HandlerFunc
+	if cfg.GraphQL.Enabled {
+		gqlHandler = graphql.Handler(taskUC, userUC, labelUC, graphql.Options{MaxDepth: cfg.GraphQL.MaxDepth, MaxComplexity: cfg.GraphQL.MaxComplexity})
+	}
+
+	// unversioned paths stay up for old clients until the sunset
+	var legacy *Infrastructure.Deprecation
+	if cfg.API.LegacyRoutes {
+		legacy = &Infrastructure.Deprecation{
+			Since:  cfg.API.LegacyDeprecated.T(),
+			Sunset: cfg.API.LegacySunset.T(),
+			Link:   cfg.API.DeprecationLink,
+		}
+	}
 
 	// router
-	r := routers.SetupRouter(ctrl, infraJwt)
+	r := routers.SetupRouter(ctrl, infraJwt, workspaceUC.Refresh, routers.Options{
+		ValidateRequests: cfg.OpenAPI.Validate,
+		Health:           health,
+		Metrics:          metrics,
+		Logger:           logger,
+		Tracing:          tracing,
+		Idempotency:      Usecases.NewIdempotencyUsecase(mongoimpl.NewIdempotencyRepository(mongoClient), cfg.Idempotency.TTL.D()),
+		RateLimits:       limits,
+		GraphQL:          gqlHandler,
+		Legacy:           legacy,
+		TrustedProxies:   cfg.HTTP.TrustedProxies,
+	})
+
+	srv := &http.Server{
+		Addr:         cfg.HTTP.Addr,
+		Handler:      r,
+		ReadTimeout:  cfg.HTTP.ReadTimeout.D(),
+		WriteTimeout: cfg.HTTP.WriteTimeout.D(),
+		IdleTimeout:  cfg.HTTP.IdleTimeout.D(),
+	}
+	srv.RegisterOnShutdown(func() { close(streamsDone) })
+
+	sigCtx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+	serveErr := make(chan error, 2)
+	go func() {
+		slog.Info("server running", "addr", cfg.HTTP.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	// gRPC on its own port, over the same usecases
+	var grpcSrv *grpc.Server
+	if cfg.GRPC.Enabled {
+		lis, err := net.Listen("tcp", cfg.GRPC.Addr)
+		if err != nil {
+			fatal("grpc listen", err)
+		}
+		grpcSrv = rpc.NewServer(rpc.Deps{Tasks: taskUC, Users: userUC, Workspaces: workspaceUC, JWT: infraJwt, Events: events, Logger: logger, Shutdown: streamsDone})
+		go func() {
+			slog.Info("grpc server running", "addr", cfg.GRPC.Addr)
+			serveErr <- grpcSrv.Serve(lis)
+		}()
+	}
 
-	log.Println("Server running on :8080")
-	if err := r.Run(":8080"); err != nil {
-		log.Fatalf("server failed: %v", err)
+	select {
+	case err := <-serveErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("server failed", "error", err)
+		}
+	case <-sigCtx.Done():
+		slog.Info("shutting down: draining requests")
 	}
+
+	// stop advertising readiness, drain in-flight requests, then stop the
+	// workers and close the repositories they use
+	health.SetShuttingDown()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout.D())
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("shutdown", "error", err)
+	}
+	if grpcSrv != nil {
+		stopGRPC(shutdownCtx, grpcSrv)
+	}
+	stopWorkers()
+	workers.Wait()
+	if err := mongoClient.Close(); err != nil {
+		slog.Error("closing mongo", "error", err)
+	}
+	if err := tracing.Shutdown(shutdownCtx); err != nil {
+		slog.Error("flushing spans", "error", err)
+	}
+	slog.Info("shutdown complete")
+}
+
+func ratePolicy(name string, r config.Rate) Infrastructure.RateLimitPolicy {
+	return Infrastructure.RateLimitPolicy{Name: name, Limit: r.Limit, Period: r.Period.D()}
+}
+
+// stopGRPC drains in-flight calls, cutting them off when ctx expires
+func stopGRPC(ctx context.Context, s *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		slog.Error("shutdown", "error", "grpc calls still running; stopping them")
+		s.Stop()
+	}
+}
+
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
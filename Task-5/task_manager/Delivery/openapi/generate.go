@@ -0,0 +1,276 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Operation documents one route; Request and Response are zero values whose
+// types are reflected into schemas
+type Operation struct {
+	Summary  string
+	Tags     []string
+	Auth     bool
+	Request  interface{}
+	Bodies   map[string]interface{} // request samples per content type, instead of Request
+	Response interface{}
+	Status   int                // success status, defaults to 200
+	Errors   []int              // documented error statuses
+	Params   map[string]*Schema // overrides for path parameter schemas
+	Query    map[string]*Schema
+	// Deprecated marks routes kept only for old clients
+	Deprecated bool
+	// Conditional routes send ETag and answer 304 to a matching If-None-Match
+	Conditional bool
+}
+
+// ErrorResponse is the body every handler writes on failure
+type ErrorResponse struct {
+	Error   string        `json:"error"`
+	Details string        `json:"details,omitempty"`
+	Fields  []fieldDetail `json:"fields,omitempty"`
+}
+
+type fieldDetail struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ObjectIDSchema describes a hex-encoded Mongo ObjectID
+func ObjectIDSchema() *Schema {
+	return &Schema{Type: "string", Pattern: "^[0-9a-fA-F]{24}$"}
+}
+
+// Generate builds the document from the engine's registered routes; routes
+// without an entry in ops are left out
+func Generate(routes gin.RoutesInfo, ops map[string]Operation, info Info) *Document {
+	g := &generator{schemas: map[string]*Schema{}}
+	doc := &Document{
+		OpenAPI: "3.1.0",
+		Info:    info,
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+	errSchema := g.schemaFor(reflect.TypeOf(ErrorResponse{}))
+	for _, rt := range routes {
+		op, ok := ops[rt.Method+" "+rt.Path]
+		if !ok {
+			continue
+		}
+		path, params := convertPath(rt.Path)
+		item := doc.Paths[path]
+		if item == nil {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		obj := &OperationObject{
+			Summary:     op.Summary,
+			Tags:        op.Tags,
+			OperationID: operationID(rt.Method, rt.Path),
+			Deprecated:  op.Deprecated,
+			Responses:   map[string]*Response{},
+		}
+		for _, name := range params {
+			s := op.Params[name]
+			if s == nil {
+				s = &Schema{Type: "string"}
+			}
+			obj.Parameters = append(obj.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: s})
+		}
+		for _, name := range sortedKeys(op.Query) {
+			obj.Parameters = append(obj.Parameters, Parameter{Name: name, In: "query", Schema: op.Query[name]})
+		}
+		if op.Request != nil {
+			obj.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]*MediaType{"application/json": {Schema: g.schemaFor(reflect.TypeOf(op.Request))}},
+			}
+		}
+		if len(op.Bodies) > 0 {
+			obj.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{}}
+			for ct, sample := range op.Bodies {
+				obj.RequestBody.Content[ct] = &MediaType{Schema: g.schemaFor(reflect.TypeOf(sample))}
+			}
+		}
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		ok200 := &Response{Description: http.StatusText(status)}
+		if op.Response != nil {
+			ok200.Content = map[string]*MediaType{"application/json": {Schema: g.schemaFor(reflect.TypeOf(op.Response))}}
+		}
+		obj.Responses[strconv.Itoa(status)] = ok200
+		if op.Conditional {
+			obj.Responses[strconv.Itoa(http.StatusNotModified)] = &Response{Description: http.StatusText(http.StatusNotModified)}
+		}
+		for _, code := range op.Errors {
+			obj.Responses[strconv.Itoa(code)] = &Response{
+				Description: http.StatusText(code),
+				Content:     map[string]*MediaType{"application/json": {Schema: errSchema}},
+			}
+		}
+		if op.Auth {
+			obj.Security = []map[string][]string{{"bearerAuth": {}}}
+		}
+		(*item)[strings.ToLower(rt.Method)] = obj
+	}
+	doc.Components.Schemas = g.schemas
+	return doc
+}
+
+// convertPath turns gin's /tasks/:id into /tasks/{id} and lists the parameters
+func convertPath(p string) (string, []string) {
+	var params []string
+	segs := strings.Split(p, "/")
+	for i, s := range segs {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			params = append(params, s[1:])
+			segs[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segs, "/"), params
+}
+
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, s := range strings.Split(path, "/") {
+		s = strings.TrimLeft(s, ":*")
+		if s == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(s[:1]) + s[1:])
+	}
+	return b.String()
+}
+
+type generator struct {
+	schemas map[string]*Schema
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+	rawJSONType  = reflect.TypeOf(json.RawMessage{})
+	anyType      = reflect.TypeOf((*interface{})(nil)).Elem()
+	bytesType    = reflect.TypeOf([]byte(nil))
+)
+
+func (g *generator) schemaFor(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == objectIDType:
+		return ObjectIDSchema()
+	case t == rawJSONType, t == anyType:
+		return &Schema{}
+	case t == bytesType:
+		// file contents in multipart bodies
+		return &Schema{Type: "string", Format: "binary"}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		inner := g.schemaFor(t.Elem())
+		if inner.Ref != "" {
+			return &Schema{OneOf: []*Schema{inner, {Type: "null"}}}
+		}
+		if s, ok := inner.Type.(string); ok {
+			inner.Type = []string{s, "null"}
+		}
+		return inner
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		name := schemaName(t)
+		if name == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.schemas[name]; !ok {
+			g.schemas[name] = &Schema{} // placeholder breaks recursion
+			g.schemas[name] = g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.addFields(s, t)
+	sort.Strings(s.Required)
+	return s
+}
+
+func (g *generator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addFields(s, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = g.schemaFor(f.Type)
+		if strings.Contains(f.Tag.Get("binding"), "required") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// schemaName capitalizes the Go type name so unexported request types read
+// naturally in the document
+func schemaName(t reflect.Type) string {
+	n := t.Name()
+	if n == "" {
+		return ""
+	}
+	return strings.ToUpper(n[:1]) + n[1:]
+}
+
+func sortedKeys(m map[string]*Schema) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
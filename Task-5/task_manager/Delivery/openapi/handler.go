@@ -0,0 +1,46 @@
+package openapi
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:generate sh ui/fetch.sh
+
+//go:embed ui/index.html
+var swaggerPage []byte
+
+// swaggerAssets is swagger-ui-dist at the version in ui/swagger-ui-dist/VERSION,
+// vendored so /docs works offline and can't change under us
+//
+//go:embed ui/swagger-ui-dist
+var swaggerAssets embed.FS
+
+// SpecHandler serves the generated document as JSON
+func SpecHandler(doc *Document) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, doc)
+	}
+}
+
+// SwaggerUIHandler serves the Swagger UI page pointed at /openapi.json
+func SwaggerUIHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", swaggerPage)
+	}
+}
+
+// SwaggerAssetsHandler serves the page's scripts and styles; the route needs
+// a *file parameter
+func SwaggerAssetsHandler() gin.HandlerFunc {
+	assets, err := fs.Sub(swaggerAssets, "ui/swagger-ui-dist")
+	if err != nil {
+		panic(err)
+	}
+	return func(c *gin.Context) {
+		c.FileFromFS(c.Param("file"), http.FS(assets))
+	}
+}
@@ -0,0 +1,15 @@
+#!/bin/sh
+# Vendors the Swagger UI assets served under /docs/assets, at the version
+# pinned in swagger-ui-dist/VERSION. Run through `go generate ./Delivery/openapi`
+# after changing the pin, and commit the result.
+set -eu
+
+dir=$(dirname "$0")/swagger-ui-dist
+version=$(cat "$dir/VERSION")
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+
+curl -fsSL "https://registry.npmjs.org/swagger-ui-dist/-/swagger-ui-dist-$version.tgz" | tar -xz -C "$tmp"
+for f in swagger-ui.css swagger-ui-bundle.js LICENSE; do
+	cp "$tmp/package/$f" "$dir/$f"
+done
@@ -0,0 +1,256 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Validator checks incoming requests against the generated document. The
+// document is set after routes are registered, so the middleware can be
+// installed first.
+type Validator struct {
+	mu  sync.RWMutex
+	doc *Document
+	res sync.Map // pattern -> *regexp.Regexp
+}
+
+func NewValidator() *Validator {
+	return &Validator{}
+}
+
+func (v *Validator) SetDocument(doc *Document) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.doc = doc
+}
+
+// Middleware rejects requests whose path parameters or JSON body do not match
+// the operation's schema
+func (v *Validator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v.mu.RLock()
+		doc := v.doc
+		v.mu.RUnlock()
+		if doc == nil || c.FullPath() == "" {
+			c.Next()
+			return
+		}
+		path, _ := convertPath(c.FullPath())
+		item := doc.Paths[path]
+		if item == nil {
+			c.Next()
+			return
+		}
+		op := (*item)[strings.ToLower(c.Request.Method)]
+		if op == nil {
+			c.Next()
+			return
+		}
+		var errs []fieldDetail
+		for _, p := range op.Parameters {
+			var val string
+			var present bool
+			switch p.In {
+			case "path":
+				val, present = c.Param(p.Name), true
+			case "query":
+				val, present = c.GetQuery(p.Name)
+			}
+			if present {
+				v.checkString(doc, p.Schema, val, p.Name, &errs)
+			}
+		}
+		if op.RequestBody != nil && c.Request.Body != nil {
+			ct := c.ContentType()
+			mt := op.RequestBody.Content[ct]
+			if mt == nil && len(op.RequestBody.Content) > 1 {
+				c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported content type"})
+				return
+			}
+			if mt == nil {
+				ct = "application/json"
+				mt = op.RequestBody.Content[ct]
+			}
+			// only JSON bodies are checked; uploads are left to the handler
+			// rather than read into memory here
+			if mt != nil && strings.HasSuffix(ct, "json") {
+				body, err := io.ReadAll(c.Request.Body)
+				if err != nil {
+					c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+					return
+				}
+				c.Request.Body = io.NopCloser(bytes.NewReader(body))
+				dec := json.NewDecoder(bytes.NewReader(body))
+				dec.UseNumber()
+				var val interface{}
+				if err := dec.Decode(&val); err != nil {
+					errs = append(errs, fieldDetail{Field: "body", Message: "must be valid JSON"})
+				} else {
+					v.check(doc, mt.Schema, val, "", &errs)
+				}
+			}
+		}
+		if len(errs) > 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "request does not match schema", "fields": errs})
+			return
+		}
+		c.Next()
+	}
+}
+
+func (v *Validator) resolve(doc *Document, s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = doc.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// checkString validates a raw path or query value; only string constraints apply
+func (v *Validator) checkString(doc *Document, s *Schema, val, field string, errs *[]fieldDetail) {
+	s = v.resolve(doc, s)
+	if s == nil {
+		return
+	}
+	if typeAllows(s.Type, "string") {
+		v.check(doc, s, val, field, errs)
+	}
+}
+
+func (v *Validator) check(doc *Document, s *Schema, val interface{}, field string, errs *[]fieldDetail) {
+	s = v.resolve(doc, s)
+	if s == nil {
+		return
+	}
+	if len(s.OneOf) > 0 {
+		var first []fieldDetail
+		for i, alt := range s.OneOf {
+			var tmp []fieldDetail
+			v.check(doc, alt, val, field, &tmp)
+			if len(tmp) == 0 {
+				return
+			}
+			if i == 0 {
+				first = tmp
+			}
+		}
+		*errs = append(*errs, first...)
+		return
+	}
+	name := field
+	if name == "" {
+		name = "body"
+	}
+	if s.Type != nil {
+		jt := jsonType(val)
+		if !typeAllows(s.Type, jt) && !(jt == "integer" && typeAllows(s.Type, "number")) {
+			*errs = append(*errs, fieldDetail{Field: name, Message: "must be of type " + typeString(s.Type)})
+			return
+		}
+	}
+	switch x := val.(type) {
+	case string:
+		if s.MaxLength != nil && utf8.RuneCountInString(x) > *s.MaxLength {
+			*errs = append(*errs, fieldDetail{Field: name, Message: "is too long"})
+		}
+		if s.Pattern != "" && !v.compile(s.Pattern).MatchString(x) {
+			*errs = append(*errs, fieldDetail{Field: name, Message: "does not match pattern " + s.Pattern})
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, x) {
+			*errs = append(*errs, fieldDetail{Field: name, Message: "must be one of " + strings.Join(s.Enum, ", ")})
+		}
+	case map[string]interface{}:
+		for _, req := range s.Required {
+			if _, ok := x[req]; !ok {
+				*errs = append(*errs, fieldDetail{Field: join(field, req), Message: "is required"})
+			}
+		}
+		for k, item := range x {
+			if ps, ok := s.Properties[k]; ok {
+				v.check(doc, ps, item, join(field, k), errs)
+			} else if s.AdditionalProperties != nil {
+				v.check(doc, s.AdditionalProperties, item, join(field, k), errs)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range x {
+				v.check(doc, s.Items, item, name+"["+strconv.Itoa(i)+"]", errs)
+			}
+		}
+	}
+}
+
+func (v *Validator) compile(p string) *regexp.Regexp {
+	if re, ok := v.res.Load(p); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(p)
+	v.res.Store(p, re)
+	return re
+}
+
+func jsonType(val interface{}) string {
+	switch x := val.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if strings.ContainsAny(x.String(), ".eE") {
+			return "number"
+		}
+		return "integer"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return ""
+}
+
+func typeAllows(t interface{}, jt string) bool {
+	switch x := t.(type) {
+	case nil:
+		return true
+	case string:
+		return x == jt
+	case []string:
+		return contains(x, jt)
+	}
+	return false
+}
+
+func typeString(t interface{}) string {
+	if x, ok := t.([]string); ok {
+		return strings.Join(x, " or ")
+	}
+	s, _ := t.(string)
+	return s
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func join(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
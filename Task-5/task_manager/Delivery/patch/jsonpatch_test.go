@@ -0,0 +1,157 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	const doc = `{"title": "t", "labels": ["a", "b"], "a/b": 1, "m~n": 2, "nested": {"x": 1}}`
+	tests := []struct {
+		name    string
+		ops     string
+		want    string // the whole resulting document; ignored when wantErr is set
+		wantErr bool
+	}{
+		{
+			name: "test matches",
+			ops:  `[{"op": "test", "path": "/labels", "value": ["a", "b"]}, {"op": "replace", "path": "/title", "value": "u"}]`,
+			want: `{"title": "u", "labels": ["a", "b"], "a/b": 1, "m~n": 2, "nested": {"x": 1}}`,
+		},
+		{
+			name:    "test mismatch",
+			ops:     `[{"op": "test", "path": "/title", "value": "other"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "test missing path",
+			ops:     `[{"op": "test", "path": "/missing", "value": null}]`,
+			wantErr: true,
+		},
+		{
+			name: "move between objects",
+			ops:  `[{"op": "move", "from": "/nested/x", "path": "/x"}]`,
+			want: `{"title": "t", "labels": ["a", "b"], "a/b": 1, "m~n": 2, "nested": {}, "x": 1}`,
+		},
+		{
+			name: "move within an array",
+			ops:  `[{"op": "move", "from": "/labels/0", "path": "/labels/1"}]`,
+			want: `{"title": "t", "labels": ["b", "a"], "a/b": 1, "m~n": 2, "nested": {"x": 1}}`,
+		},
+		{
+			name:    "move into own child",
+			ops:     `[{"op": "move", "from": "/nested", "path": "/nested/inner"}]`,
+			wantErr: true,
+		},
+		{
+			name: "copy is independent of its source",
+			ops:  `[{"op": "copy", "from": "/nested", "path": "/copy"}, {"op": "replace", "path": "/copy/x", "value": 2}]`,
+			want: `{"title": "t", "labels": ["a", "b"], "a/b": 1, "m~n": 2, "nested": {"x": 1}, "copy": {"x": 2}}`,
+		},
+		{
+			name: "add with - appends",
+			ops:  `[{"op": "add", "path": "/labels/-", "value": "c"}]`,
+			want: `{"title": "t", "labels": ["a", "b", "c"], "a/b": 1, "m~n": 2, "nested": {"x": 1}}`,
+		},
+		{
+			name: "copy to -",
+			ops:  `[{"op": "copy", "from": "/title", "path": "/labels/-"}]`,
+			want: `{"title": "t", "labels": ["a", "b", "t"], "a/b": 1, "m~n": 2, "nested": {"x": 1}}`,
+		},
+		{
+			name:    "remove - is not an index",
+			ops:     `[{"op": "remove", "path": "/labels/-"}]`,
+			wantErr: true,
+		},
+		{
+			name: "add at the array end index",
+			ops:  `[{"op": "add", "path": "/labels/2", "value": "c"}]`,
+			want: `{"title": "t", "labels": ["a", "b", "c"], "a/b": 1, "m~n": 2, "nested": {"x": 1}}`,
+		},
+		{
+			name:    "leading zero index",
+			ops:     `[{"op": "remove", "path": "/labels/01"}]`,
+			wantErr: true,
+		},
+		{
+			name: "~1 escapes a slash",
+			ops:  `[{"op": "replace", "path": "/a~1b", "value": 3}]`,
+			want: `{"title": "t", "labels": ["a", "b"], "a/b": 3, "m~n": 2, "nested": {"x": 1}}`,
+		},
+		{
+			name: "~0 escapes a tilde",
+			ops:  `[{"op": "remove", "path": "/m~0n"}]`,
+			want: `{"title": "t", "labels": ["a", "b"], "a/b": 1, "nested": {"x": 1}}`,
+		},
+		{
+			name: "~01 is a literal ~1",
+			ops:  `[{"op": "add", "path": "/~01", "value": true}]`,
+			want: `{"title": "t", "labels": ["a", "b"], "a/b": 1, "m~n": 2, "nested": {"x": 1}, "~1": true}`,
+		},
+		{
+			name:    "remove a missing key",
+			ops:     `[{"op": "remove", "path": "/missing"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "remove under a missing key",
+			ops:     `[{"op": "remove", "path": "/missing/x"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "remove past the array end",
+			ops:     `[{"op": "remove", "path": "/labels/2"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "replace a missing key",
+			ops:     `[{"op": "replace", "path": "/missing", "value": 1}]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in interface{}
+			if err := json.Unmarshal([]byte(doc), &in); err != nil {
+				t.Fatal(err)
+			}
+			var ops []Operation
+			if err := json.Unmarshal([]byte(tt.ops), &ops); err != nil {
+				t.Fatal(err)
+			}
+			got, err := Apply(in, ops)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Apply() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			var want interface{}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Apply() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyLeavesDocUntouched(t *testing.T) {
+	in := map[string]interface{}{"labels": []interface{}{"a"}}
+	ops := []Operation{
+		{Op: "add", Path: "/labels/-", Value: json.RawMessage(`"b"`)},
+		{Op: "test", Path: "/labels/0", Value: json.RawMessage(`"z"`)},
+	}
+	if _, err := Apply(in, ops); !errors.Is(err, ErrTestFailed) {
+		t.Fatalf("Apply() error = %v, want ErrTestFailed", err)
+	}
+	if want := map[string]interface{}{"labels": []interface{}{"a"}}; !reflect.DeepEqual(in, want) {
+		t.Errorf("doc = %v, want %v", in, want)
+	}
+}
@@ -1,34 +1,245 @@
 package routers
 
 import (
+	"log/slog"
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	"task_manager/Delivery/controllers"
+	"task_manager/Delivery/openapi"
 	"task_manager/Infrastructure"
+	"task_manager/Usecases"
 )
 
+// Options toggles optional router behaviour
+type Options struct {
+	// ValidateRequests rejects requests that don't match the OpenAPI document
+	ValidateRequests bool
+	// Health serves /healthz and /readyz when set
+	Health *Infrastructure.Health
+	// Metrics instruments every request and serves /metrics when set
+	Metrics *Infrastructure.Metrics
+	// Logger receives one record per request; defaults to slog.Default()
+	Logger *slog.Logger
+	// Tracing starts a server span per request when set
+	Tracing *Infrastructure.Tracing
+	// Idempotency replays responses for retried Idempotency-Key requests when set
+	Idempotency Usecases.IdempotencyUsecase
+	// RateLimits throttles each route group when its Store is set
+	RateLimits RateLimits
+	// Legacy also serves the /v1 routes at their old unversioned paths, with
+	// these deprecation headers, when set
+	Legacy *Infrastructure.Deprecation
+	// GraphQL serves POST /graphql to authenticated users when set
+	GraphQL gin.HandlerFunc
+	// TrustedProxies may set X-Forwarded-For; none means the remote address is the client IP
+	TrustedProxies []string
+}
+
+// RateLimits are per-group budgets. Admin routes also count against the
+// authenticated budget. A policy with no limit is skipped.
+type RateLimits struct {
+	Store         Infrastructure.RateLimitStore
+	Public        Infrastructure.RateLimitPolicy // per IP
+	Authenticated Infrastructure.RateLimitPolicy // per user
+	Admin         Infrastructure.RateLimitPolicy // per user
+}
+
+func (l RateLimits) apply(g *gin.RouterGroup, p Infrastructure.RateLimitPolicy) {
+	if l.Store != nil && p.Enabled() {
+		g.Use(Infrastructure.RateLimit(l.Store, p))
+	}
+}
+
 // ctrl is passed so routes call usecases through controller
-func SetupRouter(ctrl *controllers.Controller, jwtSvc Infrastructure.JWTService) *gin.Engine {
-	r := gin.Default()
+func SetupRouter(ctrl *controllers.Controller, jwtSvc Infrastructure.JWTService, sessions Infrastructure.SessionCheck, opts Options) *gin.Engine {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	r := gin.New()
+	if err := r.SetTrustedProxies(opts.TrustedProxies); err != nil {
+		logger.Error("ignoring trusted proxies", "error", err)
+		_ = r.SetTrustedProxies(nil)
+	}
+	r.Use(Infrastructure.RequestID())
+	if opts.Tracing != nil {
+		r.Use(opts.Tracing.Middleware())
+	}
+	r.Use(Infrastructure.RequestLogger(logger), Infrastructure.Recovery(logger))
+
+	if opts.Metrics != nil {
+		r.Use(opts.Metrics.Middleware())
+		r.GET("/metrics", opts.Metrics.Handler())
+	}
+
+	// probes
+	if opts.Health != nil {
+		r.GET("/healthz", opts.Health.LivenessHandler())
+		r.GET("/readyz", opts.Health.ReadinessHandler())
+	}
+
+	// requests are checked against the document generated below
+	validator := openapi.NewValidator()
+	var validate []gin.HandlerFunc
+	if opts.ValidateRequests {
+		validate = append(validate, validator.Middleware())
+	}
+
+	// API versions share the usecases behind ctrl; a /v2 gets its own mount
+	// function, with new controllers only where request or response shapes change
+	mountV1(r.Group("/v1", validate...), ctrl, jwtSvc, sessions, opts)
+	if opts.Legacy != nil {
+		legacy := *opts.Legacy
+		if legacy.Successor == nil {
+			legacy.Successor = func(path string) string { return "/v1" + path }
+		}
+		// deprecation headers go on every response, rejected ones included
+		mountV1(r.Group("/", append([]gin.HandlerFunc{Infrastructure.Deprecated(legacy)}, validate...)...), ctrl, jwtSvc, sessions, opts)
+	}
 
+	// API docs, generated from the routes registered above
+	doc := openapi.Generate(r.Routes(), versionDocs(r.Routes(), controllers.APIDocs()), openapi.Info{Title: "Task Manager API", Version: "1.0.0"})
+	validator.SetDocument(doc)
+	r.GET("/openapi.json", openapi.SpecHandler(doc))
+	r.GET("/docs", openapi.SwaggerUIHandler())
+	r.GET("/docs/assets/*file", openapi.SwaggerAssetsHandler())
+
+	return r
+}
+
+// mountV1 registers the /v1 routes on g
+func mountV1(g *gin.RouterGroup, ctrl *controllers.Controller, jwtSvc Infrastructure.JWTService, sessions Infrastructure.SessionCheck, opts Options) {
 	// public
-	r.POST("/register", ctrl.Register)
-	r.POST("/login", ctrl.Login)
+	public := g.Group("/")
+	opts.RateLimits.apply(public, opts.RateLimits.Public)
+	public.POST("/register", ctrl.Register)
+	public.POST("/login", ctrl.Login)
+	// signed links are the credential, so browsers can follow them
+	public.GET("/attachments/download", ctrl.DownloadAttachment)
 
 	// protected
-	auth := r.Group("/")
-	auth.Use(Infrastructure.AuthMiddleware(jwtSvc))
+	auth := g.Group("/")
+	auth.Use(Infrastructure.AuthMiddleware(jwtSvc, sessions))
+	opts.RateLimits.apply(auth, opts.RateLimits.Authenticated)
+
+	// workspaces; these work without an active workspace, and member changes
+	// check the caller's role in the workspace named in the path
+	auth.GET("/workspaces", ctrl.ListWorkspaces)
+	auth.POST("/workspaces/:id/switch", ctrl.SwitchWorkspace)
+	auth.GET("/workspaces/:id/members", ctrl.ListMembers)
+	auth.PUT("/workspaces/:id/members/:username", ctrl.SetMember)
+	auth.DELETE("/workspaces/:id/members/:username", ctrl.RemoveMember)
 
-	// read endpoints for all authenticated users
-	auth.GET("/tasks", ctrl.GetTasks)
-	auth.GET("/tasks/:id", ctrl.GetTaskByID)
+	// instance admins
+	instance := auth.Group("/")
+	instance.Use(Infrastructure.InstanceAdminMiddleware())
+	opts.RateLimits.apply(instance, opts.RateLimits.Admin)
+	if opts.Idempotency != nil {
+		instance.Use(controllers.Idempotency(opts.Idempotency))
+	}
+	instance.POST("/workspaces", ctrl.CreateWorkspace)
+	instance.POST("/users/:username/promote", ctrl.Promote)
 
-	// admin-only
-	admin := auth.Group("/")
+	// read endpoints for every member of the active workspace
+	member := auth.Group("/")
+	member.Use(Infrastructure.WorkspaceRequired())
+	member.GET("/tasks", ctrl.GetTasks)
+	member.GET("/tasks/:id", ctrl.GetTaskByID)
+	member.GET("/tasks/:id/subtasks", ctrl.ListSubtasks)
+	member.GET("/tasks/:id/dependencies", ctrl.ListDependencies)
+	member.GET("/tasks/schedule", ctrl.Schedule)
+	member.GET("/labels", ctrl.ListLabels)
+	member.GET("/labels/:id", ctrl.GetLabel)
+	member.GET("/recurring-tasks", ctrl.ListRecurringTasks)
+	member.GET("/recurring-tasks/:id", ctrl.GetRecurringTask)
+	member.GET("/recurring-tasks/:id/tasks", ctrl.ListRecurringOccurrences)
+	member.GET("/tasks/:id/comments", ctrl.ListComments)
+	member.GET("/tasks/:id/comments/:commentId", ctrl.GetComment)
+	member.GET("/tasks/:id/attachments", ctrl.ListAttachments)
+	member.GET("/tasks/:id/attachments/:attachmentId", ctrl.GetAttachment)
+	member.GET("/tasks/:id/attachments/:attachmentId/link", ctrl.AttachmentLink)
+	// uploads skip the idempotency store, which would buffer the whole file;
+	// uploading the same content again returns the existing attachment
+	member.POST("/tasks/:id/attachments", ctrl.UploadAttachment)
+	if opts.GraphQL != nil {
+		// mutations check the admin role in the resolvers
+		member.POST("/graphql", opts.GraphQL)
+	}
+
+	// every member may comment; edits and deletions check in the usecase
+	// that the caller wrote the comment or is an admin
+	discuss := member.Group("/")
+	if opts.Idempotency != nil {
+		discuss.Use(controllers.Idempotency(opts.Idempotency))
+	}
+	discuss.POST("/tasks/:id/comments", ctrl.CreateComment)
+	discuss.PATCH("/tasks/:id/comments/:commentId", ctrl.UpdateComment)
+	discuss.DELETE("/tasks/:id/comments/:commentId", ctrl.DeleteComment)
+	discuss.DELETE("/tasks/:id/attachments/:attachmentId", ctrl.DeleteAttachment)
+
+	// change stream; browsers can't set headers on EventSource/WebSocket
+	stream := g.Group("/")
+	stream.Use(Infrastructure.TokenFromQuery("access_token"), Infrastructure.AuthMiddleware(jwtSvc, sessions), Infrastructure.WorkspaceRequired())
+	opts.RateLimits.apply(stream, opts.RateLimits.Authenticated)
+	stream.GET("/tasks/events", ctrl.TaskEvents)
+
+	// admins of the active workspace
+	admin := member.Group("/")
 	admin.Use(Infrastructure.AdminOnlyMiddleware())
+	opts.RateLimits.apply(admin, opts.RateLimits.Admin)
+	if opts.Idempotency != nil {
+		admin.Use(controllers.Idempotency(opts.Idempotency))
+	}
 	admin.POST("/tasks", ctrl.CreateTask)
+	admin.POST("/tasks/bulk", ctrl.BulkTasks)
 	admin.PUT("/tasks/:id", ctrl.UpdateTask)
+	admin.PATCH("/tasks/:id", ctrl.PatchTask)
 	admin.DELETE("/tasks/:id", ctrl.DeleteTask)
-	admin.POST("/users/:username/promote", ctrl.Promote)
+	admin.POST("/tasks/:id/move", ctrl.MoveTask)
+	admin.POST("/tasks/:id/checklist", ctrl.AddChecklistItem)
+	admin.PATCH("/tasks/:id/checklist/:itemId", ctrl.UpdateChecklistItem)
+	admin.DELETE("/tasks/:id/checklist/:itemId", ctrl.DeleteChecklistItem)
+	admin.POST("/tasks/:id/dependencies", ctrl.AddBlocker)
+	admin.DELETE("/tasks/:id/dependencies/:blockerId", ctrl.RemoveBlocker)
+	admin.POST("/tasks/:id/labels", ctrl.AddTaskLabel)
+	admin.DELETE("/tasks/:id/labels/:name", ctrl.RemoveTaskLabel)
+	admin.POST("/labels", ctrl.CreateLabel)
+	admin.PATCH("/labels/:id", ctrl.UpdateLabel)
+	admin.DELETE("/labels/:id", ctrl.DeleteLabel)
+	admin.POST("/labels/:id/merge", ctrl.MergeLabel)
+	admin.POST("/recurring-tasks", ctrl.CreateRecurringTask)
+	admin.PATCH("/recurring-tasks/:id", ctrl.UpdateRecurringTask)
+	admin.DELETE("/recurring-tasks/:id", ctrl.DeleteRecurringTask)
+	admin.POST("/webhooks", ctrl.CreateWebhook)
+	admin.GET("/webhooks", ctrl.ListWebhooks)
+	admin.GET("/webhooks/:id", ctrl.GetWebhook)
+	admin.PATCH("/webhooks/:id", ctrl.UpdateWebhook)
+	admin.DELETE("/webhooks/:id", ctrl.DeleteWebhook)
+	admin.GET("/webhooks/:id/deliveries", ctrl.ListWebhookDeliveries)
+	admin.POST("/webhooks/:id/deliveries/:deliveryId/redeliver", ctrl.RedeliverWebhook)
+}
 
-	return r
+// versionDocs keys each operation by the paths it is mounted at: /v1 routes
+// share the unversioned description, and the unversioned twins of /v1 routes
+// are marked deprecated
+func versionDocs(routes gin.RoutesInfo, docs map[string]openapi.Operation) map[string]openapi.Operation {
+	out := make(map[string]openapi.Operation, len(docs))
+	for k, op := range docs {
+		out[k] = op
+	}
+	for _, rt := range routes {
+		base, ok := strings.CutPrefix(rt.Path, "/v1")
+		if !ok || !strings.HasPrefix(base, "/") {
+			continue
+		}
+		op, ok := docs[rt.Method+" "+base]
+		if !ok {
+			continue
+		}
+		out[rt.Method+" "+rt.Path] = op
+		op.Deprecated = true
+		out[rt.Method+" "+base] = op
+	}
+	return out
 }
@@ -0,0 +1,114 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"task_manager/Domain"
+	"task_manager/Infrastructure"
+	pb "task_manager/api/taskmanager/v1"
+)
+
+// publicMethods need no token, like POST /register and POST /login
+var publicMethods = map[string]bool{
+	pb.UserService_Register_FullMethodName: true,
+	pb.UserService_Login_FullMethodName:    true,
+}
+
+// adminMethods need the admin role in the active workspace, like the
+// admin-only REST routes
+var adminMethods = map[string]bool{
+	pb.TaskService_CreateTask_FullMethodName:  true,
+	pb.TaskService_UpdateTask_FullMethodName:  true,
+	pb.TaskService_ReplaceTask_FullMethodName: true,
+	pb.TaskService_DeleteTask_FullMethodName:  true,
+	pb.TaskService_BulkTasks_FullMethodName:   true,
+}
+
+// instanceAdminMethods need an instance admin
+var instanceAdminMethods = map[string]bool{
+	pb.UserService_PromoteUser_FullMethodName: true,
+}
+
+// caller is the authenticated user behind a call
+type caller struct {
+	username string
+	role     string
+}
+
+type callerKey struct{}
+
+// authenticate checks the bearer token in the "authorization" metadata, that
+// its session is still current and the role the method needs, and stores the
+// caller and the active workspace in the returned context
+func authenticate(ctx context.Context, jwtSvc Infrastructure.JWTService, sessions Infrastructure.SessionCheck, method string) (context.Context, error) {
+	if publicMethods[method] {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata required")
+	}
+	parts := strings.Fields(values[0])
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata")
+	}
+	claims, err := jwtSvc.ValidateToken(parts[1])
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	s, err := sessions(ctx, claims.Session())
+	if errors.Is(err, Domain.ErrNotFound) {
+		return nil, status.Error(codes.Unauthenticated, "session revoked")
+	}
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	if adminMethods[method] && s.Role != Domain.RoleAdmin {
+		return nil, status.Error(codes.PermissionDenied, "admin required")
+	}
+	if instanceAdminMethods[method] && !s.InstanceAdmin {
+		return nil, status.Error(codes.PermissionDenied, "instance admin required")
+	}
+	if !s.Workspace.IsZero() {
+		ctx = Domain.WithWorkspace(ctx, s.Workspace)
+	}
+	return context.WithValue(ctx, callerKey{}, caller{username: s.Username, role: s.Role}), nil
+}
+
+// AuthUnaryInterceptor authenticates unary calls
+func AuthUnaryInterceptor(jwtSvc Infrastructure.JWTService, sessions Infrastructure.SessionCheck) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, jwtSvc, sessions, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamInterceptor authenticates streaming calls
+func AuthStreamInterceptor(jwtSvc Infrastructure.JWTService, sessions Infrastructure.SessionCheck) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), jwtSvc, sessions, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// contextStream replaces a stream's context
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context { return s.ctx }
@@ -0,0 +1,155 @@
+// Package rpc serves the gRPC API defined in api/taskmanager/v1 over the
+// same usecases as the REST controllers.
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"task_manager/Delivery/apierror"
+	"task_manager/Infrastructure"
+	"task_manager/Usecases"
+	pb "task_manager/api/taskmanager/v1"
+)
+
+// Deps are what the gRPC services call into
+type Deps struct {
+	Tasks      Usecases.TaskUsecase
+	Users      Usecases.UserUsecase
+	Workspaces Usecases.WorkspaceUsecase
+	JWT        Infrastructure.JWTService
+	Events     *Infrastructure.EventHub
+	// Logger receives one record per call; defaults to slog.Default()
+	Logger *slog.Logger
+	// Shutdown ends WatchTasks streams when closed, so they don't hold
+	// GracefulStop open
+	Shutdown <-chan struct{}
+}
+
+// NewServer registers the task and user services behind recovery, logging
+// and auth interceptors
+func NewServer(d Deps) *grpc.Server {
+	logger := d.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(recoveryUnary(logger), loggingUnary(logger), AuthUnaryInterceptor(d.JWT, d.Workspaces.Refresh)),
+		grpc.ChainStreamInterceptor(recoveryStream(logger), loggingStream(logger), AuthStreamInterceptor(d.JWT, d.Workspaces.Refresh)),
+	)
+	pb.RegisterTaskServiceServer(srv, &taskServer{tasks: d.Tasks, events: d.Events, shutdown: d.Shutdown})
+	pb.RegisterUserServiceServer(srv, &userServer{users: d.Users, workspaces: d.Workspaces, jwt: d.JWT})
+	return srv
+}
+
+// grpcError maps usecase errors with the same rules as the REST API; validation
+// errors carry their field violations as BadRequest details
+func grpcError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	switch kind := apierror.Classify(err); kind {
+	case apierror.Invalid:
+		st := status.New(kind.GRPCCode(), err.Error())
+		br := &errdetails.BadRequest{}
+		for _, f := range apierror.Fields(err) {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Message})
+		}
+		if withDetails, derr := st.WithDetails(br); derr == nil {
+			st = withDetails
+		}
+		return st.Err()
+	case apierror.Internal:
+		slog.ErrorContext(ctx, "grpc call failed", "error", err)
+		return status.Error(kind.GRPCCode(), kind.Message())
+	default:
+		return status.Error(kind.GRPCCode(), kind.Message())
+	}
+}
+
+func invalidArgument(field, msg string) error {
+	st, err := status.New(codes.InvalidArgument, field+": "+msg).WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: msg}},
+	})
+	if err != nil {
+		return status.Error(codes.InvalidArgument, field+": "+msg)
+	}
+	return st.Err()
+}
+
+// --- interceptors ---
+
+func recoveryUnary(l *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				l.ErrorContext(ctx, "panic serving grpc call", "panic", fmt.Sprint(p), "method", info.FullMethod)
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func recoveryStream(l *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				l.ErrorContext(ss.Context(), "panic serving grpc call", "panic", fmt.Sprint(p), "method", info.FullMethod)
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+// loggingUnary writes one record per call, like Infrastructure.RequestLogger.
+// It runs before auth, so the caller isn't known here.
+func loggingUnary(l *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, l, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func loggingStream(l *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(ss.Context(), l, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logCall(ctx context.Context, l *slog.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK, codes.Canceled:
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+	l.LogAttrs(ctx, level, "grpc call",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(start)),
+	)
+}
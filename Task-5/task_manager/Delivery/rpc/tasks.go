@@ -0,0 +1,276 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"task_manager/Domain"
+	"task_manager/Infrastructure"
+	"task_manager/Usecases"
+	pb "task_manager/api/taskmanager/v1"
+)
+
+type taskServer struct {
+	pb.UnimplementedTaskServiceServer
+	tasks    Usecases.TaskUsecase
+	events   *Infrastructure.EventHub
+	shutdown <-chan struct{}
+}
+
+func (s *taskServer) CreateTask(ctx context.Context, req *pb.CreateTaskRequest) (*pb.Task, error) {
+	t, err := s.tasks.CreateTask(ctx, fromTask(req.GetTask()))
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return toTask(t), nil
+}
+
+func (s *taskServer) ListTasks(ctx context.Context, _ *pb.ListTasksRequest) (*pb.ListTasksResponse, error) {
+	tasks, err := s.tasks.ListTasks(ctx, Domain.TaskFilter{})
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return &pb.ListTasksResponse{Tasks: toTasks(tasks)}, nil
+}
+
+func (s *taskServer) GetTask(ctx context.Context, req *pb.GetTaskRequest) (*pb.Task, error) {
+	id, err := parseID("id", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	t, err := s.tasks.GetTaskByID(ctx, id)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return toTask(t), nil
+}
+
+func (s *taskServer) BatchGetTasks(ctx context.Context, req *pb.BatchGetTasksRequest) (*pb.BatchGetTasksResponse, error) {
+	ids := make([]primitive.ObjectID, len(req.GetIds()))
+	for i, raw := range req.GetIds() {
+		id, err := parseID(fmt.Sprintf("ids[%d]", i), raw)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	tasks, err := s.tasks.GetTasksByIDs(ctx, ids)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return &pb.BatchGetTasksResponse{Tasks: toTasks(tasks)}, nil
+}
+
+func (s *taskServer) UpdateTask(ctx context.Context, req *pb.UpdateTaskRequest) (*pb.Task, error) {
+	id, err := parseID("id", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	change, err := maskChange("update_mask", req.GetTask(), req.GetUpdateMask())
+	if err != nil {
+		return nil, err
+	}
+	t, err := s.tasks.UpdateTask(ctx, id, change)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return toTask(t), nil
+}
+
+func (s *taskServer) ReplaceTask(ctx context.Context, req *pb.ReplaceTaskRequest) (*pb.Task, error) {
+	id, err := parseID("id", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	// the message has no estimate, so the stored one is kept
+	current, err := s.tasks.GetTaskByID(ctx, id)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	replacement := fromTask(req.GetTask())
+	replacement.Estimate = current.Estimate
+	t, err := s.tasks.ReplaceTask(ctx, id, replacement, false)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return toTask(t), nil
+}
+
+func (s *taskServer) DeleteTask(ctx context.Context, req *pb.DeleteTaskRequest) (*pb.DeleteTaskResponse, error) {
+	id, err := parseID("id", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tasks.DeleteTask(ctx, id); err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return &pb.DeleteTaskResponse{}, nil
+}
+
+func (s *taskServer) BulkTasks(ctx context.Context, req *pb.BulkTasksRequest) (*pb.BulkTasksResponse, error) {
+	// an op that can't be read fails on its own, like one the usecase rejects
+	ops := make([]Usecases.BulkTaskOp, len(req.GetOperations()))
+	for i, o := range req.GetOperations() {
+		op := Usecases.BulkTaskOp{Op: o.GetOp()}
+		var err error
+		if o.GetOp() != "create" {
+			op.ID, err = parseID("id", o.GetId())
+		}
+		switch {
+		case err != nil:
+		case o.GetOp() == "create":
+			op.Task = fromTask(o.GetTask())
+		case o.GetOp() == "update":
+			op.Change, err = maskChange("update_mask", o.GetTask(), o.GetUpdateMask())
+		}
+		if err != nil {
+			op.Invalid = errors.New(status.Convert(err).Message())
+		}
+		ops[i] = op
+	}
+	report, err := s.tasks.BulkTasks(ctx, ops, req.GetStopOnError())
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	resp := &pb.BulkTasksResponse{Transactional: report.Transactional, Results: make([]*pb.BulkResult, len(report.Results))}
+	for i, r := range report.Results {
+		item := &pb.BulkResult{Index: int32(i), Op: r.Op, Status: r.Status}
+		if !r.ID.IsZero() {
+			item.Id = r.ID.Hex()
+		}
+		if r.Task != nil {
+			item.Task = toTask(*r.Task)
+		}
+		if r.Err != nil {
+			item.Error = r.Err.Error()
+		}
+		resp.Results[i] = item
+	}
+	return resp, nil
+}
+
+// WatchTasks streams task events until the client goes away. If the client
+// falls behind, the stream ends with Unavailable so it reconnects with its
+// last event ID.
+func (s *taskServer) WatchTasks(req *pb.WatchTasksRequest, stream pb.TaskService_WatchTasksServer) error {
+	// members see every task in their workspace, as with ListTasks
+	ws, ok := Domain.WorkspaceFrom(stream.Context())
+	if !ok {
+		return grpcError(stream.Context(), Domain.ErrNoWorkspace)
+	}
+	// TaskEvent has no comment field, so comment events are left out
+	visible := func(e Domain.TaskEvent) bool { return e.WorkspaceID == ws && e.Comment == nil }
+	sub, missed, complete := s.events.Subscribe(req.GetLastEventId(), visible)
+	defer s.events.Unsubscribe(sub)
+
+	if !complete {
+		if err := stream.Send(&pb.TaskEvent{Type: "reset"}); err != nil {
+			return err
+		}
+	}
+	for _, e := range missed {
+		if err := stream.Send(toEvent(e)); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.shutdown:
+			return status.Error(codes.Unavailable, "server shutting down")
+		case e, ok := <-sub.C:
+			if !ok {
+				// events may be lost by the time the client reconnects
+				if err := stream.Send(&pb.TaskEvent{Type: "reset"}); err != nil {
+					return err
+				}
+				return status.Error(codes.Unavailable, "stream fell behind; reconnect with last_event_id")
+			}
+			if err := stream.Send(toEvent(e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// --- conversions ---
+
+// maskChange turns the masked fields of t into a change; optional fields in
+// the mask that are empty are removed, like null in a merge patch
+func maskChange(field string, t *pb.Task, mask *fieldmaskpb.FieldMask) (Domain.TaskChange, error) {
+	change := Domain.TaskChange{Set: map[string]interface{}{}}
+	values := map[string]string{
+		"title":       t.GetTitle(),
+		"description": t.GetDescription(),
+		"due_date":    t.GetDueDate(),
+		"status":      fromStatus(t.GetStatus()),
+	}
+	for _, path := range mask.GetPaths() {
+		v, ok := values[path]
+		switch {
+		case !ok:
+			return Domain.TaskChange{}, invalidArgument(field, "unknown field "+path)
+		case v == "" && (path == "description" || path == "due_date"):
+			change.Unset = append(change.Unset, path)
+		default:
+			change.Set[path] = v
+		}
+	}
+	return change, nil
+}
+
+func parseID(field, raw string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(raw)
+	if err != nil {
+		return primitive.NilObjectID, invalidArgument(field, "invalid id")
+	}
+	return id, nil
+}
+
+func toTask(t Domain.Task) *pb.Task {
+	return &pb.Task{Id: t.ID.Hex(), Title: t.Title, Description: t.Description, DueDate: t.DueDate, Status: toStatus(t.Status)}
+}
+
+func toTasks(tasks []Domain.Task) []*pb.Task {
+	out := make([]*pb.Task, len(tasks))
+	for i, t := range tasks {
+		out[i] = toTask(t)
+	}
+	return out
+}
+
+func fromTask(t *pb.Task) Domain.Task {
+	return Domain.Task{Title: t.GetTitle(), Description: t.GetDescription(), DueDate: t.GetDueDate(), Status: fromStatus(t.GetStatus())}
+}
+
+func toEvent(e Domain.TaskEvent) *pb.TaskEvent {
+	out := &pb.TaskEvent{Id: e.ID, Type: e.Type, TaskId: e.TaskID, At: timestamppb.New(e.At)}
+	if e.Task != nil {
+		out.Task = toTask(*e.Task)
+	}
+	return out
+}
+
+const statusPrefix = "TASK_STATUS_"
+
+// fromStatus maps TASK_STATUS_IN_PROGRESS to "in_progress"; unspecified is
+// left empty so validation reports it
+func fromStatus(s pb.TaskStatus) string {
+	if s == pb.TaskStatus_TASK_STATUS_UNSPECIFIED {
+		return ""
+	}
+	return strings.ToLower(strings.TrimPrefix(s.String(), statusPrefix))
+}
+
+func toStatus(s string) pb.TaskStatus {
+	return pb.TaskStatus(pb.TaskStatus_value[statusPrefix+strings.ToUpper(s)])
+}
@@ -0,0 +1,76 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"task_manager/Domain"
+	"task_manager/Infrastructure"
+	"task_manager/Usecases"
+	pb "task_manager/api/taskmanager/v1"
+)
+
+type userServer struct {
+	pb.UnimplementedUserServiceServer
+	users      Usecases.UserUsecase
+	workspaces Usecases.WorkspaceUsecase
+	jwt        Infrastructure.JWTService
+}
+
+func (s *userServer) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.User, error) {
+	u, err := s.users.Register(ctx, req.GetUsername(), req.GetPassword())
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return toUser(u), nil
+}
+
+func (s *userServer) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	var wsID primitive.ObjectID
+	if req.GetWorkspaceId() != "" {
+		id, err := parseID("workspace_id", req.GetWorkspaceId())
+		if err != nil {
+			return nil, err
+		}
+		wsID = id
+	}
+	u, err := s.users.Login(ctx, req.GetUsername(), req.GetPassword())
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+	session, err := s.workspaces.Session(ctx, u.Username, wsID)
+	if errors.Is(err, Domain.ErrNotFound) {
+		return nil, status.Error(codes.PermissionDenied, "not a member of that workspace")
+	}
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	token, err := s.jwt.GenerateToken(session)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	resp := &pb.LoginResponse{Token: token, User: toUser(u), WorkspaceRole: session.Role}
+	if !session.Workspace.IsZero() {
+		resp.WorkspaceId = session.Workspace.Hex()
+	}
+	return resp, nil
+}
+
+func (s *userServer) PromoteUser(ctx context.Context, req *pb.PromoteUserRequest) (*pb.User, error) {
+	if req.GetUsername() == "" {
+		return nil, invalidArgument("username", "required")
+	}
+	if err := s.users.Promote(ctx, req.GetUsername()); err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return &pb.User{Username: Domain.NormalizeUsername(req.GetUsername()), Role: "admin"}, nil
+}
+
+func toUser(u Domain.User) *pb.User {
+	return &pb.User{Username: u.Username, Role: u.Role}
+}
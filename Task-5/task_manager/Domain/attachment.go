@@ -0,0 +1,99 @@
+package Domain
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxAttachmentNameLength bounds stored file names, in characters
+const MaxAttachmentNameLength = 255
+
+// ErrLinkInvalid is returned for download links that are malformed, forged or
+// expired
+var ErrLinkInvalid = fmt.Errorf("%w: download link is invalid or expired", ErrForbidden)
+
+// ErrAttachmentExists is returned when the task already has an attachment
+// with the same content
+var ErrAttachmentExists = errors.New("content already attached to the task")
+
+// Attachment is a file on a task. Its content is kept once per workspace
+// under the SHA-256 of the bytes, so uploading the same file again stores
+// nothing new.
+type Attachment struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID `bson:"workspace_id" json:"workspace_id"` // set by the repository
+	TaskID      primitive.ObjectID `bson:"task_id" json:"task_id"`
+	Filename    string             `bson:"filename" json:"filename"`
+	// ContentType is sniffed from the content, not taken from the client
+	ContentType string    `bson:"content_type" json:"content_type"`
+	Size        int64     `bson:"size" json:"size"`
+	SHA256      string    `bson:"sha256" json:"sha256"`
+	UploadedBy  string    `bson:"uploaded_by" json:"uploaded_by"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+}
+
+// BlobKey is where the attachment's content is stored
+func (a Attachment) BlobKey() string {
+	return a.WorkspaceID.Hex() + "/" + a.SHA256
+}
+
+// AttachmentLink is a short-lived signed URL for downloading an attachment.
+// Token is what the URL carries; the delivery layer builds URL around it.
+type AttachmentLink struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Token     string    `json:"-"`
+}
+
+// CleanFilename keeps the last path element of a client-supplied name,
+// without control characters, at most MaxAttachmentNameLength long.
+// Names that end up empty become "file".
+func CleanFilename(name string) string {
+	name = strings.ReplaceAll(name, `\`, "/")
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, path.Base(name))
+	name = strings.TrimSpace(name)
+	if name == "." || name == "/" || name == ".." {
+		name = ""
+	}
+	if utf8.RuneCountInString(name) > MaxAttachmentNameLength {
+		name = string([]rune(name)[:MaxAttachmentNameLength])
+	}
+	if name == "" {
+		return "file"
+	}
+	return name
+}
+
+// MediaTypeAllowed reports whether t matches one of allowed, where "image/*"
+// matches any image type and "*/*" anything
+func MediaTypeAllowed(allowed []string, t string) bool {
+	major, _, _ := strings.Cut(t, "/")
+	for _, a := range allowed {
+		switch {
+		case a == "*/*", a == t:
+			return true
+		case strings.HasSuffix(a, "/*") && strings.TrimSuffix(a, "/*") == major:
+			return true
+		}
+	}
+	return false
+}
+
+// AttachmentError reports an upload that can't be accepted
+func AttachmentError(msg string) error {
+	v := &ValidationError{}
+	v.add("file", msg)
+	return v
+}
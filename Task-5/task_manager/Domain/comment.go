@@ -0,0 +1,107 @@
+package Domain
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Comment rules
+const (
+	MaxCommentLength = 5000
+	// MaxCommentEdits is how many earlier versions a comment keeps; older
+	// ones are dropped
+	MaxCommentEdits = 20
+	// MaxCommentMentions bounds the users one comment can notify
+	MaxCommentMentions = 20
+	DefaultCommentPage = 20
+	MaxCommentPage     = 100
+)
+
+// Comment event types, published alongside the task events so a task's
+// history shows its discussion
+const (
+	EventCommentCreated = "comment.created"
+	EventCommentUpdated = "comment.updated"
+	EventCommentDeleted = "comment.deleted"
+)
+
+// mentionPattern finds @username, not preceded by a word character so email
+// addresses aren't taken for mentions
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([A-Za-z0-9._-]{3,32})`)
+
+// Comment is a message on a task. Replies point at a top-level comment;
+// replies can't be replied to.
+type Comment struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID  `bson:"workspace_id" json:"workspace_id"` // set by the repository
+	TaskID      primitive.ObjectID  `bson:"task_id" json:"task_id"`
+	ParentID    *primitive.ObjectID `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	Author      string              `bson:"author" json:"author"`
+	Body        string              `bson:"body" json:"body"`
+	// Mentions are the users named with @username that exist
+	Mentions  []string   `bson:"mentions,omitempty" json:"mentions,omitempty"`
+	CreatedAt time.Time  `bson:"created_at" json:"created_at"`
+	EditedAt  *time.Time `bson:"edited_at,omitempty" json:"edited_at,omitempty"`
+	// Edits are the earlier versions of the body, oldest first
+	Edits []CommentEdit `bson:"edits,omitempty" json:"edits,omitempty"`
+	// Replies are filled in on listings of top-level comments
+	Replies []Comment `bson:"-" json:"replies,omitempty"`
+}
+
+// CommentEdit is a replaced version of a comment body, with who replaced it
+// and when
+type CommentEdit struct {
+	Body     string    `bson:"body" json:"body"`
+	EditedBy string    `bson:"edited_by" json:"edited_by"`
+	EditedAt time.Time `bson:"edited_at" json:"edited_at"`
+}
+
+// CommentPage is one page of a task's top-level comments, oldest first. Next
+// is the cursor for the following page, empty on the last one.
+type CommentPage struct {
+	Comments []Comment `json:"comments"`
+	Next     string    `json:"next,omitempty"`
+}
+
+// ValidateCommentBody trims a comment body and checks its length
+func ValidateCommentBody(body string) (string, error) {
+	v := &ValidationError{}
+	body = strings.TrimSpace(body)
+	switch n := utf8.RuneCountInString(body); {
+	case n == 0:
+		v.add("body", "must not be empty")
+	case n > MaxCommentLength:
+		v.add("body", "must be at most 5000 characters")
+	}
+	return body, v.err()
+}
+
+// ParseMentions returns the usernames mentioned in body, normalized, in order
+// of first mention and without repeats. At most MaxCommentMentions are kept.
+func ParseMentions(body string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		name := NormalizeUsername(strings.TrimRight(m[1], "."))
+		if utf8.RuneCountInString(name) < MinUsernameLength || seen[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, name)
+		if len(out) == MaxCommentMentions {
+			break
+		}
+	}
+	return out
+}
+
+// CommentError reports a comment that can't be written as asked
+func CommentError(field, msg string) error {
+	v := &ValidationError{}
+	v.add(field, msg)
+	return v
+}
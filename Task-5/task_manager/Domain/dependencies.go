@@ -0,0 +1,258 @@
+package Domain
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxBlockers caps how many tasks may block one task
+const MaxBlockers = 50
+
+// Dependencies are the tasks on either side of a task's blocked-by edges
+type Dependencies struct {
+	// BlockedBy must be done before the task can start
+	BlockedBy []Task `json:"blocked_by"`
+	// Blocking are the tasks waiting on this one
+	Blocking []Task `json:"blocking"`
+}
+
+// StartsWork reports whether moving to status needs every blocker done
+func StartsWork(status string) bool {
+	return status == StatusInProgress || status == StatusDone
+}
+
+// OpenBlockers counts the blockers that aren't done
+func OpenBlockers(blockers []Task) int {
+	return OpenSubtasks(blockers)
+}
+
+// DependencyError reports an unusable blocker, e.g. a missing task or a cycle
+func DependencyError(msg string) error {
+	v := &ValidationError{}
+	v.add("blocked_by", msg)
+	return v
+}
+
+// BlockedError is returned when a task is started or completed while n of
+// its blockers are open and the change isn't forced
+func BlockedError(n int) error {
+	v := &ValidationError{}
+	v.add("status", fmt.Sprintf("blocked by %d open task(s); finish them first or force the change", n))
+	return v
+}
+
+// ScheduledTask is one open task placed on the schedule. Durations are Go
+// duration strings; a negative slack means the task can't meet its due date.
+type ScheduledTask struct {
+	ID             primitive.ObjectID   `json:"id"`
+	Title          string               `json:"title"`
+	Estimate       string               `json:"estimate,omitempty"`
+	DueDate        string               `json:"due_date,omitempty"`
+	BlockedBy      []primitive.ObjectID `json:"blocked_by,omitempty"`
+	EarliestStart  time.Time            `json:"earliest_start"`
+	EarliestFinish time.Time            `json:"earliest_finish"`
+	LatestFinish   time.Time            `json:"latest_finish"`
+	Slack          string               `json:"slack"`
+	Critical       bool                 `json:"critical"`
+	// Late is set when the earliest finish is after the due date
+	Late bool `json:"late"`
+}
+
+// Schedule orders the open tasks so every task comes after its blockers
+type Schedule struct {
+	Start  time.Time `json:"start"`
+	Finish time.Time `json:"finish"`
+	// Tasks is in dependency order, ties broken by creation
+	Tasks []ScheduledTask `json:"tasks"`
+	// CriticalPath is the chain of tasks with the least slack, first to last
+	CriticalPath []primitive.ObjectID `json:"critical_path"`
+	// Unscheduled tasks are in, or wait on, a dependency cycle
+	Unscheduled []primitive.ObjectID `json:"unscheduled,omitempty"`
+}
+
+// estimateOf is t's estimate; tasks without one take no time
+func estimateOf(t Task) time.Duration {
+	d, err := time.ParseDuration(t.Estimate)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// dueBy is when t is due. A date without a time means the end of that day.
+func dueBy(t Task) (time.Time, bool) {
+	due, ok := ParseDueDate(t.DueDate)
+	if ok && len(t.DueDate) == len("2006-01-02") {
+		due = due.Add(24 * time.Hour)
+	}
+	return due, ok
+}
+
+// indexHeap pops the smallest index first
+type indexHeap []int
+
+func (h indexHeap) Len() int            { return len(h) }
+func (h indexHeap) Less(i, j int) bool  { return h[i] < h[j] }
+func (h indexHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *indexHeap) Push(x interface{}) { *h = append(*h, x.(int)) }
+func (h *indexHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// PlanSchedule runs the critical path method over the open tasks, starting
+// now. Each task takes its estimate and starts once its open blockers are
+// done; done or missing blockers are ignored. A task must finish by its due
+// date and before the tasks it blocks start, or the whole plan finishes.
+func PlanSchedule(tasks []Task, now time.Time) Schedule {
+	var open []Task
+	for _, t := range tasks {
+		if t.Status != StatusDone {
+			open = append(open, t)
+		}
+	}
+	sort.Slice(open, func(i, j int) bool { return open[i].ID.Hex() < open[j].ID.Hex() })
+	index := make(map[primitive.ObjectID]int, len(open))
+	for i, t := range open {
+		index[t.ID] = i
+	}
+	preds := make([][]int, len(open))
+	succs := make([][]int, len(open))
+	waiting := make([]int, len(open))
+	for i, t := range open {
+		for _, b := range t.BlockedBy {
+			if j, ok := index[b]; ok {
+				preds[i] = append(preds[i], j)
+				succs[j] = append(succs[j], i)
+				waiting[i]++
+			}
+		}
+	}
+
+	// Kahn's algorithm, always taking the oldest ready task
+	ready := &indexHeap{}
+	for i := range open {
+		if waiting[i] == 0 {
+			heap.Push(ready, i)
+		}
+	}
+	var order []int
+	for ready.Len() > 0 {
+		i := heap.Pop(ready).(int)
+		order = append(order, i)
+		for _, s := range succs[i] {
+			if waiting[s]--; waiting[s] == 0 {
+				heap.Push(ready, s)
+			}
+		}
+	}
+
+	// times are offsets from now
+	es := make([]time.Duration, len(open))
+	ef := make([]time.Duration, len(open))
+	var finish time.Duration
+	for _, i := range order {
+		for _, p := range preds[i] {
+			if ef[p] > es[i] {
+				es[i] = ef[p]
+			}
+		}
+		ef[i] = es[i] + estimateOf(open[i])
+		if ef[i] > finish {
+			finish = ef[i]
+		}
+	}
+	lf := make([]time.Duration, len(open))
+	for k := len(order) - 1; k >= 0; k-- {
+		i := order[k]
+		lf[i] = finish
+		for _, s := range succs[i] {
+			if ls := lf[s] - estimateOf(open[s]); ls < lf[i] {
+				lf[i] = ls
+			}
+		}
+		if due, ok := dueBy(open[i]); ok && due.Sub(now) < lf[i] {
+			lf[i] = due.Sub(now)
+		}
+	}
+
+	sched := Schedule{Start: now, Finish: now.Add(finish), Tasks: []ScheduledTask{}, CriticalPath: []primitive.ObjectID{}}
+	if len(order) > 0 {
+		sched.CriticalPath = criticalPath(order, preds, es, ef, lf, open)
+	}
+	critical := make(map[primitive.ObjectID]bool, len(sched.CriticalPath))
+	for _, id := range sched.CriticalPath {
+		critical[id] = true
+	}
+	for _, i := range order {
+		t := open[i]
+		st := ScheduledTask{
+			ID:             t.ID,
+			Title:          t.Title,
+			Estimate:       t.Estimate,
+			DueDate:        t.DueDate,
+			EarliestStart:  now.Add(es[i]),
+			EarliestFinish: now.Add(ef[i]),
+			LatestFinish:   now.Add(lf[i]),
+			Slack:          (lf[i] - ef[i]).String(),
+			Critical:       critical[t.ID],
+		}
+		for _, p := range preds[i] {
+			st.BlockedBy = append(st.BlockedBy, open[p].ID)
+		}
+		if due, ok := dueBy(t); ok && now.Add(ef[i]).After(due) {
+			st.Late = true
+		}
+		sched.Tasks = append(sched.Tasks, st)
+	}
+	if len(order) < len(open) {
+		placed := make([]bool, len(open))
+		for _, i := range order {
+			placed[i] = true
+		}
+		for i, t := range open {
+			if !placed[i] {
+				sched.Unscheduled = append(sched.Unscheduled, t.ID)
+			}
+		}
+	}
+	return sched
+}
+
+// criticalPath ends at the last-finishing task with the least slack and
+// walks back through blockers that finish exactly when it starts. Those
+// blockers have the same slack, so the whole chain is equally tight.
+func criticalPath(order []int, preds [][]int, es, ef, lf []time.Duration, open []Task) []primitive.ObjectID {
+	end := order[0]
+	for _, i := range order {
+		slack, best := lf[i]-ef[i], lf[end]-ef[end]
+		if slack < best || (slack == best && ef[i] >= ef[end]) {
+			end = i
+		}
+	}
+	least := lf[end] - ef[end]
+	path := []primitive.ObjectID{open[end].ID}
+	for cur := end; ; {
+		next := -1
+		for _, p := range preds[cur] {
+			if ef[p] == es[cur] && lf[p]-ef[p] == least && (next < 0 || p < next) {
+				next = p
+			}
+		}
+		if next < 0 {
+			break
+		}
+		path = append(path, open[next].ID)
+		cur = next
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
@@ -0,0 +1,182 @@
+package Domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// planTask describes a task by name; IDs follow the order tasks are listed,
+// so earlier tasks are older
+type planTask struct {
+	name      string
+	estimate  string
+	due       string
+	done      bool
+	blockedBy []string
+}
+
+func TestPlanSchedule(t *testing.T) {
+	now := time.Date(2026, 1, 1, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		tasks       []planTask
+		order       []string // Schedule.Tasks
+		unscheduled []string
+		critical    []string
+		finish      time.Duration
+		slack       map[string]time.Duration // checked for the tasks listed
+		late        []string
+	}{
+		{
+			name: "self-cycle",
+			tasks: []planTask{
+				{name: "a", estimate: "1h", blockedBy: []string{"a"}},
+				{name: "b", estimate: "1h"},
+			},
+			order:       []string{"b"},
+			unscheduled: []string{"a"},
+			critical:    []string{"b"},
+			finish:      time.Hour,
+		},
+		{
+			name: "indirect cycle and a task waiting on it",
+			tasks: []planTask{
+				{name: "a", estimate: "1h", blockedBy: []string{"c"}},
+				{name: "b", estimate: "1h", blockedBy: []string{"a"}},
+				{name: "c", estimate: "1h", blockedBy: []string{"b"}},
+				{name: "d", estimate: "1h", blockedBy: []string{"c"}},
+				{name: "e", estimate: "2h"},
+			},
+			order:       []string{"e"},
+			unscheduled: []string{"a", "b", "c", "d"},
+			critical:    []string{"e"},
+			finish:      2 * time.Hour,
+		},
+		{
+			name: "diamond",
+			tasks: []planTask{
+				{name: "a", estimate: "1h"},
+				{name: "b", estimate: "2h", blockedBy: []string{"a"}},
+				{name: "c", estimate: "30m", blockedBy: []string{"a"}},
+				{name: "d", estimate: "1h", blockedBy: []string{"b", "c"}},
+			},
+			order:    []string{"a", "b", "c", "d"},
+			critical: []string{"a", "b", "d"},
+			finish:   4 * time.Hour,
+			slack:    map[string]time.Duration{"a": 0, "b": 0, "c": 90 * time.Minute, "d": 0},
+		},
+		{
+			name: "tasks without an estimate take no time",
+			tasks: []planTask{
+				{name: "a"},
+				{name: "b", estimate: "1h", blockedBy: []string{"a"}},
+				{name: "c", blockedBy: []string{"b"}},
+				{name: "d", estimate: "soon"},
+			},
+			order:    []string{"a", "b", "c", "d"},
+			critical: []string{"a", "b", "c"},
+			finish:   time.Hour,
+			slack:    map[string]time.Duration{"a": 0, "c": 0, "d": time.Hour},
+		},
+		{
+			name: "done and missing blockers are ignored",
+			tasks: []planTask{
+				{name: "a", estimate: "1h", done: true},
+				{name: "b", estimate: "1h", blockedBy: []string{"a", "gone"}},
+			},
+			order:    []string{"b"},
+			critical: []string{"b"},
+			finish:   time.Hour,
+		},
+		{
+			name: "a due date tightens slack",
+			tasks: []planTask{
+				{name: "a", estimate: "5h", due: "2026-01-01"},
+				{name: "b", estimate: "1h", due: "2026-01-02T00:00:00Z"},
+				{name: "c", estimate: "6h"},
+			},
+			order:    []string{"a", "b", "c"},
+			critical: []string{"a"},
+			finish:   6 * time.Hour,
+			slack:    map[string]time.Duration{"a": -time.Hour, "b": 3 * time.Hour, "c": 0},
+			late:     []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := map[string]primitive.ObjectID{}
+			names := map[primitive.ObjectID]string{}
+			id := func(name string) primitive.ObjectID {
+				if _, ok := ids[name]; !ok {
+					var oid primitive.ObjectID
+					oid[11] = byte(len(ids) + 1)
+					ids[name], names[oid] = oid, name
+				}
+				return ids[name]
+			}
+			for _, pt := range tt.tasks {
+				id(pt.name)
+			}
+			var tasks []Task
+			for _, pt := range tt.tasks {
+				task := Task{ID: id(pt.name), Title: pt.name, Estimate: pt.estimate, DueDate: pt.due, Status: StatusPending}
+				if pt.done {
+					task.Status = StatusDone
+				}
+				for _, b := range pt.blockedBy {
+					task.BlockedBy = append(task.BlockedBy, id(b))
+				}
+				tasks = append(tasks, task)
+			}
+			nameAll := func(ids []primitive.ObjectID) []string {
+				var out []string
+				for _, id := range ids {
+					out = append(out, names[id])
+				}
+				return out
+			}
+
+			got := PlanSchedule(tasks, now)
+
+			var order, late []string
+			for _, st := range got.Tasks {
+				order = append(order, names[st.ID])
+				if st.Late {
+					late = append(late, names[st.ID])
+				}
+				if want, ok := tt.slack[names[st.ID]]; ok && st.Slack != want.String() {
+					t.Errorf("slack of %s = %s, want %s", names[st.ID], st.Slack, want)
+				}
+			}
+			if !reflect.DeepEqual(order, tt.order) {
+				t.Errorf("order = %v, want %v", order, tt.order)
+			}
+			if u := nameAll(got.Unscheduled); !reflect.DeepEqual(u, tt.unscheduled) {
+				t.Errorf("unscheduled = %v, want %v", u, tt.unscheduled)
+			}
+			if c := nameAll(got.CriticalPath); !reflect.DeepEqual(c, tt.critical) {
+				t.Errorf("critical path = %v, want %v", c, tt.critical)
+			}
+			if f := got.Finish.Sub(now); f != tt.finish {
+				t.Errorf("finish = %s after start, want %s", f, tt.finish)
+			}
+			if !reflect.DeepEqual(late, tt.late) {
+				t.Errorf("late = %v, want %v", late, tt.late)
+			}
+		})
+	}
+}
+
+func TestPlanScheduleEmpty(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	got := PlanSchedule(nil, now)
+	if len(got.Tasks) != 0 || len(got.CriticalPath) != 0 || len(got.Unscheduled) != 0 || !got.Finish.Equal(now) {
+		t.Errorf("PlanSchedule(nil) = %+v, want an empty schedule finishing now", got)
+	}
+	if got.Tasks == nil || got.CriticalPath == nil {
+		t.Error("Tasks and CriticalPath must be empty slices so they encode as []")
+	}
+}
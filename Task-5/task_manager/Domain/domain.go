@@ -1,20 +1,92 @@
 package Domain
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 // Domain entities — independent of frameworks
 
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrPreconditionFailed means a conditional change no longer matches the stored task
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
 type User struct {
 	ID       primitive.ObjectID `bson:"_id,omitempty" json:"-"`
 	Username string             `bson:"username" json:"username"`
 	Password string             `bson:"password,omitempty" json:"-"`
-	Role     string             `bson:"role" json:"role"` // "admin" or "user"
+	// Role is the instance-wide role: "admin" may create workspaces and
+	// promote users; task permissions come from Membership roles
+	Role string `bson:"role" json:"role"` // "admin" or "user"
 }
 
 type Task struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID `bson:"workspace_id" json:"workspace_id"` // set by the repository
 	Title       string             `bson:"title" json:"title"`
 	Description string             `bson:"description,omitempty" json:"description,omitempty"`
 	DueDate     string             `bson:"due_date,omitempty" json:"due_date,omitempty"`
 	Status      string             `bson:"status" json:"status"`
+	// Estimate is how long the task should take, as a duration such as "4h30m"
+	Estimate string `bson:"estimate,omitempty" json:"estimate,omitempty"`
+	// ParentID makes this a subtask; it only changes through a move
+	ParentID  *primitive.ObjectID `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	Checklist []ChecklistItem     `bson:"checklist,omitempty" json:"checklist,omitempty"`
+	// BlockedBy lists the tasks that must be done before this one can start
+	BlockedBy []primitive.ObjectID `bson:"blocked_by,omitempty" json:"blocked_by,omitempty"`
+	// Labels are names from the workspace's label catalog
+	Labels []string `bson:"labels,omitempty" json:"labels,omitempty"`
+	// RecurrenceID is the recurring task this one was generated from, and
+	// Occurrence the occurrence of its rule it stands for
+	RecurrenceID *primitive.ObjectID `bson:"recurrence_id,omitempty" json:"recurrence_id,omitempty"`
+	Occurrence   *time.Time          `bson:"occurrence,omitempty" json:"occurrence,omitempty"`
+	// Progress is filled in by the usecase on reads
+	Progress *Progress `bson:"-" json:"progress,omitempty"`
+	// UpdatedAt is set by the server on every write; nil for tasks written
+	// before it was tracked
+	UpdatedAt *time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// LastModified is when t was last written, falling back to its creation
+// time from the ObjectID
+func (t Task) LastModified() time.Time {
+	if t.UpdatedAt != nil {
+		return *t.UpdatedAt
+	}
+	return t.ID.Timestamp()
+}
+
+// TaskChange is a partial update: fields to set, fields to remove and, when
+// Expect is set, the values the stored task must still hold for it to apply.
+// Keys are bson field names.
+type TaskChange struct {
+	Set    map[string]interface{}
+	Unset  []string
+	Expect map[string]interface{}
+	// Force starts or completes a task even though some of its subtasks or
+	// blockers are open
+	Force bool
+}
+
+// Task event types
+const (
+	EventTaskCreated = "task.created"
+	EventTaskUpdated = "task.updated"
+	EventTaskDeleted = "task.deleted"
+)
+
+// TaskEvent records one change to a task or its comments. Task is empty for
+// deletions and comment events, Comment is set only by comment events.
+type TaskEvent struct {
+	ID          uint64             `json:"id"`
+	Type        string             `json:"type"`
+	WorkspaceID primitive.ObjectID `json:"workspace_id"`
+	TaskID      string             `json:"task_id"`
+	Task        *Task              `json:"task,omitempty"`
+	Comment     *Comment           `json:"comment,omitempty"`
+	At          time.Time          `json:"at"`
 }
@@ -0,0 +1,29 @@
+package Domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrIdempotencyKeyReused means a key was sent again with a different request
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+	// ErrIdempotencyInFlight means the first request with this key hasn't finished yet
+	ErrIdempotencyInFlight = errors.New("a request with this idempotency key is still in progress")
+)
+
+// IdempotencyRecord remembers the first request made with an Idempotency-Key
+// and, once it finished, the response to replay for retries. Keys are scoped
+// per user.
+type IdempotencyRecord struct {
+	User        string            `bson:"user"`
+	Key         string            `bson:"key"`
+	Fingerprint string            `bson:"fingerprint"` // hash of method, path and body
+	Completed   bool              `bson:"completed"`
+	LockedUntil time.Time         `bson:"locked_until"` // an unfinished request past this is presumed dead
+	Status      int               `bson:"status,omitempty"`
+	Header      map[string]string `bson:"header,omitempty"`
+	Body        []byte            `bson:"body,omitempty"`
+	CreatedAt   time.Time         `bson:"created_at"`
+	ExpiresAt   time.Time         `bson:"expires_at"`
+}
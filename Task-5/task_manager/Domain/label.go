@@ -0,0 +1,132 @@
+package Domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Label rules
+const (
+	MaxLabelNameLength        = 50
+	MaxLabelDescriptionLength = 200
+	MaxTaskLabels             = 20
+	// DefaultLabelColor is used when a label is created without a color
+	DefaultLabelColor = "#808080"
+)
+
+// Label filter modes for task listings
+const (
+	LabelMatchAll = "all"
+	LabelMatchAny = "any"
+)
+
+var labelColor = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// Label is an entry in a workspace's label catalog. Tasks carry label names,
+// which are unique per workspace regardless of case.
+type Label struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID `bson:"workspace_id" json:"workspace_id"` // set by the repository
+	Name        string             `bson:"name" json:"name"`
+	// Key is the lowercased name the uniqueness index is on
+	Key         string    `bson:"key" json:"-"`
+	Color       string    `bson:"color" json:"color"`
+	Description string    `bson:"description,omitempty" json:"description,omitempty"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+	// Count is how many tasks carry the label, filled in by the usecase
+	Count int64 `bson:"-" json:"count"`
+}
+
+// LabelKey is the case-insensitive form of a label name
+func LabelKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// ValidateLabel trims the label, lowercases its color, defaulting it, and
+// checks every field
+func ValidateLabel(l Label) (Label, error) {
+	v := &ValidationError{}
+	l.Name = strings.TrimSpace(l.Name)
+	l.Key = LabelKey(l.Name)
+	l.Color = strings.ToLower(strings.TrimSpace(l.Color))
+	if l.Color == "" {
+		l.Color = DefaultLabelColor
+	}
+	l.Description = strings.TrimSpace(l.Description)
+	checkLabelName(v, "name", l.Name)
+	if !labelColor.MatchString(l.Color) {
+		v.add("color", "must be a hex color such as #1f77b4")
+	}
+	if utf8.RuneCountInString(l.Description) > MaxLabelDescriptionLength {
+		v.add("description", "must be at most 200 characters")
+	}
+	return l, v.err()
+}
+
+func checkLabelName(v *ValidationError, field, name string) {
+	switch {
+	case name == "":
+		v.add(field, "must not be empty")
+	case utf8.RuneCountInString(name) > MaxLabelNameLength:
+		v.add(field, "must be at most 50 characters")
+	case strings.ContainsAny(name, ",/"):
+		// label filters are comma-separated and names go in URL paths
+		v.add(field, "must not contain commas or slashes")
+	}
+}
+
+// NormalizeLabelNames trims the names a task is tagged with and drops
+// repeats, which differ from an earlier name only in case
+func NormalizeLabelNames(names []string) ([]string, error) {
+	v := &ValidationError{}
+	names = normalizeLabelNames(v, names)
+	return names, v.err()
+}
+
+func normalizeLabelNames(v *ValidationError, names []string) []string {
+	seen := make(map[string]bool, len(names))
+	var out []string
+	for _, n := range names {
+		n = strings.TrimSpace(n)
+		checkLabelName(v, "labels", n)
+		if k := LabelKey(n); !seen[k] {
+			seen[k] = true
+			out = append(out, n)
+		}
+	}
+	if len(out) > MaxTaskLabels {
+		v.add("labels", "a task may have at most 20 labels")
+	}
+	return out
+}
+
+// LabelError reports a label that can't be used, e.g. one missing from the
+// catalog
+func LabelError(field, msg string) error {
+	v := &ValidationError{}
+	v.add(field, msg)
+	return v
+}
+
+// UnknownLabelError names a label that isn't in the catalog
+func UnknownLabelError(field, name string) error {
+	return LabelError(field, fmt.Sprintf("no label named %q", name))
+}
+
+// LabelNameTaken is returned when another label already has the name
+func LabelNameTaken() error {
+	return LabelError("name", "another label already has this name")
+}
+
+// TaskFilter narrows a task listing; the zero filter keeps every task
+type TaskFilter struct {
+	// Labels are catalog names; a task matches if it carries any of them,
+	// or all of them with MatchAll
+	Labels   []string
+	MatchAll bool
+}
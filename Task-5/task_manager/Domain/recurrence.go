@@ -0,0 +1,478 @@
+package Domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrOccurrenceExists is returned when a recurring task's occurrence was
+// already generated, e.g. by another replica
+var ErrOccurrenceExists = errors.New("occurrence already generated")
+
+// Recurrence frequencies
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+)
+
+const (
+	// MaxRecurrenceInterval bounds INTERVAL
+	MaxRecurrenceInterval = 1000
+	// maxEmptyPeriods stops the expansion of a rule that no longer matches
+	// any day, such as BYMONTHDAY=31 every 12 months from February
+	maxEmptyPeriods = 1000
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+var weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+var byDayPattern = regexp.MustCompile(`^([+-]?[0-9]{1,2})?(SU|MO|TU|WE|TH|FR|SA)$`)
+
+// RecurringTask is a template that the recurrence worker turns into tasks,
+// one per occurrence of its rule, ahead of time. The task fields are copied
+// to every occurrence.
+type RecurringTask struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID `bson:"workspace_id" json:"workspace_id"` // set by the repository
+	Title       string             `bson:"title" json:"title"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	Estimate    string             `bson:"estimate,omitempty" json:"estimate,omitempty"`
+	Labels      []string           `bson:"labels,omitempty" json:"labels,omitempty"`
+	// RRule is an iCalendar RRULE such as "FREQ=WEEKLY;BYDAY=MO,TH", stored
+	// in canonical form
+	RRule string `bson:"rrule" json:"rrule"`
+	// Start is the first possible occurrence, a date or an RFC3339 time. Its
+	// time of day and UTC offset carry over to every occurrence.
+	Start string `bson:"start" json:"start"`
+	// GeneratedThrough is the latest occurrence turned into a task
+	GeneratedThrough *time.Time `bson:"generated_through,omitempty" json:"generated_through,omitempty"`
+	// NextOccurrence is the first occurrence not generated yet; nil once the
+	// rule has ended
+	NextOccurrence *time.Time `bson:"next_occurrence,omitempty" json:"next_occurrence,omitempty"`
+	// LastError is why the last generation stopped early; it is retried
+	LastError string `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	// SyncAfter asks the worker to bring the pending occurrences after it in
+	// line with an edited template
+	SyncAfter *time.Time `bson:"sync_after,omitempty" json:"-"`
+	// NextRunAt is when the worker looks at the template again; nil when
+	// there is nothing left to generate
+	NextRunAt  *time.Time `bson:"next_run_at,omitempty" json:"-"`
+	LeaseUntil time.Time  `bson:"lease_until" json:"-"`
+	// Version changes on every edit, so a generation run that started
+	// before an edit doesn't overwrite it
+	Version   int64     `bson:"version" json:"-"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+	// Upcoming previews the next occurrences, filled in on reads
+	Upcoming []time.Time `bson:"-" json:"upcoming,omitempty"`
+}
+
+// Occurrence is the task generated for at. Due dates keep the form of the
+// template's start: a date for a date, a time otherwise.
+func (r RecurringTask) Occurrence(at time.Time) Task {
+	due := at.Format(time.RFC3339)
+	if _, err := time.Parse("2006-01-02", r.Start); err == nil {
+		due = at.Format("2006-01-02")
+	}
+	id := r.ID
+	return Task{
+		Title:        r.Title,
+		Description:  r.Description,
+		Estimate:     r.Estimate,
+		Labels:       append([]string(nil), r.Labels...),
+		DueDate:      due,
+		Status:       StatusPending,
+		RecurrenceID: &id,
+		Occurrence:   &at,
+	}
+}
+
+// ValidateRecurringTask trims the template, puts its rule in canonical form
+// and checks every field
+func ValidateRecurringTask(r RecurringTask) (RecurringTask, error) {
+	v := &ValidationError{}
+	r.Title = strings.TrimSpace(r.Title)
+	r.Description = strings.TrimSpace(r.Description)
+	r.Estimate = strings.TrimSpace(r.Estimate)
+	r.Start = strings.TrimSpace(r.Start)
+	checkTitle(v, r.Title)
+	checkDescription(v, r.Description)
+	checkEstimate(v, r.Estimate)
+	if len(r.Labels) > 0 {
+		r.Labels = normalizeLabelNames(v, r.Labels)
+	}
+	if _, ok := ParseRecurrenceStart(r.Start); !ok {
+		v.add("start", "must be RFC3339 or YYYY-MM-DD")
+	}
+	rule, err := ParseRRule(r.RRule)
+	if err != nil {
+		v.add("rrule", err.Error())
+	} else {
+		r.RRule = rule.String()
+	}
+	return r, v.err()
+}
+
+// ParseRecurrenceStart parses a template's start. A time keeps its UTC
+// offset but not the server's zone, so expansion doesn't depend on where the
+// server runs, and whole seconds, which survive storage unchanged.
+func ParseRecurrenceStart(s string) (time.Time, bool) {
+	t, ok := ParseDueDate(s)
+	if !ok {
+		return time.Time{}, false
+	}
+	_, offset := t.Zone()
+	return t.In(time.FixedZone("", offset)).Truncate(time.Second), true
+}
+
+// RecurrenceError reports a template that can't be saved as asked
+func RecurrenceError(field, msg string) error {
+	v := &ValidationError{}
+	v.add(field, msg)
+	return v
+}
+
+// WeekdayNum is a BYDAY entry: a weekday and, for monthly rules, which one
+// in the month (1 is the first, -1 the last, 0 every one)
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// Recurrence is a parsed RRULE. It supports the DAILY, WEEKLY and MONTHLY
+// frequencies with INTERVAL, BYDAY, BYMONTHDAY, WKST and COUNT or UNTIL.
+type Recurrence struct {
+	Freq       string
+	Interval   int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	WeekStart  time.Weekday
+	Count      int
+	// Until is the last possible occurrence, inclusive; with UntilDate only
+	// its date counts
+	Until     time.Time
+	UntilDate bool
+}
+
+// ParseRRule parses an RRULE value, with or without the "RRULE:" prefix
+func ParseRRule(s string) (Recurrence, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 6 && strings.EqualFold(s[:6], "RRULE:") {
+		s = s[6:]
+	}
+	if s == "" {
+		return Recurrence{}, errors.New("must not be empty")
+	}
+	r := Recurrence{Interval: 1, WeekStart: time.Monday}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		key, val = strings.ToUpper(strings.TrimSpace(key)), strings.ToUpper(strings.TrimSpace(val))
+		if !ok || key == "" || val == "" {
+			return Recurrence{}, fmt.Errorf("%q is not a KEY=VALUE part", part)
+		}
+		if seen[key] {
+			return Recurrence{}, fmt.Errorf("%s is given twice", key)
+		}
+		seen[key] = true
+		var err error
+		switch key {
+		case "FREQ":
+			switch val {
+			case FreqDaily, FreqWeekly, FreqMonthly:
+				r.Freq = val
+			default:
+				err = errors.New("FREQ must be DAILY, WEEKLY or MONTHLY")
+			}
+		case "INTERVAL":
+			r.Interval, err = ruleInt(key, val, 1, MaxRecurrenceInterval)
+		case "COUNT":
+			r.Count, err = ruleInt(key, val, 1, 1<<20)
+		case "UNTIL":
+			err = r.parseUntil(val)
+		case "BYDAY":
+			for _, d := range strings.Split(val, ",") {
+				m := byDayPattern.FindStringSubmatch(d)
+				if m == nil {
+					return Recurrence{}, fmt.Errorf("BYDAY has an invalid day %q", d)
+				}
+				wd := WeekdayNum{Weekday: weekdayCodes[m[2]]}
+				if m[1] != "" {
+					if wd.N, err = strconv.Atoi(m[1]); err != nil || wd.N == 0 || wd.N < -5 || wd.N > 5 {
+						return Recurrence{}, fmt.Errorf("BYDAY has an invalid day %q", d)
+					}
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(val, ",") {
+				n, err := ruleInt(key, d, -31, 31)
+				if err != nil || n == 0 {
+					return Recurrence{}, fmt.Errorf("BYMONTHDAY has an invalid day %q", d)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "WKST":
+			wd, ok := weekdayCodes[val]
+			if !ok {
+				err = errors.New("WKST must be a weekday such as MO")
+			}
+			r.WeekStart = wd
+		default:
+			err = fmt.Errorf("%s is not supported", key)
+		}
+		if err != nil {
+			return Recurrence{}, err
+		}
+	}
+	switch {
+	case r.Freq == "":
+		return Recurrence{}, errors.New("FREQ is required")
+	case r.Count > 0 && !r.Until.IsZero():
+		return Recurrence{}, errors.New("COUNT and UNTIL can't both be given")
+	case r.Freq == FreqWeekly && len(r.ByMonthDay) > 0:
+		return Recurrence{}, errors.New("BYMONTHDAY can't be used with FREQ=WEEKLY")
+	}
+	for _, d := range r.ByDay {
+		if d.N != 0 && r.Freq != FreqMonthly {
+			return Recurrence{}, errors.New("numbered BYDAY days such as 1MO need FREQ=MONTHLY")
+		}
+	}
+	return r, nil
+}
+
+func ruleInt(key, val string, min, max int) (int, error) {
+	n, err := strconv.Atoi(val)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%s must be a number from %d to %d", key, min, max)
+	}
+	return n, nil
+}
+
+func (r *Recurrence) parseUntil(val string) error {
+	if t, err := time.Parse("20060102", val); err == nil {
+		r.Until, r.UntilDate = t, true
+		return nil
+	}
+	if t, err := time.Parse("20060102T150405Z", val); err == nil {
+		r.Until = t
+		return nil
+	}
+	return errors.New("UNTIL must be a date such as 20261231 or a UTC time such as 20261231T170000Z")
+}
+
+// String is the canonical RRULE, without the prefix
+func (r Recurrence) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = weekdayNames[d.Weekday]
+			if d.N != 0 {
+				days[i] = strconv.Itoa(d.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayNames[r.WeekStart])
+	}
+	switch {
+	case r.Count > 0:
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	case r.UntilDate:
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	case !r.Until.IsZero():
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Each calls fn with every occurrence of the rule from start, in order,
+// until fn returns false or the rule ends. Every occurrence has start's time
+// of day. start itself is an occurrence only if it matches the rule; COUNT
+// counts from it.
+func (r Recurrence) Each(start time.Time, fn func(at time.Time) bool) {
+	n, empty := 0, 0
+	for period := 0; empty < maxEmptyPeriods; period++ {
+		days := r.period(start, period)
+		if len(days) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+		for _, at := range days {
+			if at.Before(start) {
+				continue
+			}
+			if r.past(at) {
+				return
+			}
+			n++
+			if !fn(at) || (r.Count > 0 && n == r.Count) {
+				return
+			}
+		}
+		if last := days[len(days)-1]; !r.Until.IsZero() && r.past(last) {
+			return
+		}
+	}
+}
+
+// past reports whether at is after UNTIL
+func (r Recurrence) past(at time.Time) bool {
+	switch {
+	case r.Until.IsZero():
+		return false
+	case r.UntilDate:
+		y, m, d := at.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).After(r.Until)
+	default:
+		return at.After(r.Until)
+	}
+}
+
+// period lists the candidate occurrences of the period'th day, week or
+// month counted from start's, in order
+func (r Recurrence) period(start time.Time, period int) []time.Time {
+	y, m, d := start.Date()
+	h, mi, s := start.Clock()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, h, mi, s, start.Nanosecond(), start.Location())
+	}
+	switch r.Freq {
+	case FreqDaily:
+		day := at(y, m, d+period*r.Interval)
+		if r.matchesWeekday(day) && r.matchesMonthDay(day) {
+			return []time.Time{day}
+		}
+		return nil
+	case FreqWeekly:
+		weekStart := d - (int(start.Weekday())-int(r.WeekStart)+7)%7 + period*r.Interval*7
+		if len(r.ByDay) == 0 {
+			return []time.Time{at(y, m, d+period*r.Interval*7)}
+		}
+		var out []time.Time
+		for i := 0; i < 7; i++ {
+			if day := at(y, m, weekStart+i); r.matchesWeekday(day) {
+				out = append(out, day)
+			}
+		}
+		return out
+	default:
+		first := time.Date(y, m+time.Month(period*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+		var out []time.Time
+		for _, day := range r.monthDays(first, d) {
+			out = append(out, at(first.Year(), first.Month(), day))
+		}
+		return out
+	}
+}
+
+func (r Recurrence) matchesWeekday(day time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Weekday == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Recurrence) matchesMonthDay(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	dim := daysIn(day.Year(), day.Month())
+	for _, md := range r.ByMonthDay {
+		if md == day.Day() || md < 0 && dim+md+1 == day.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// monthDays lists the days of first's month that match, in order. Without
+// BYDAY or BYMONTHDAY that is startDay, if the month has it.
+func (r Recurrence) monthDays(first time.Time, startDay int) []int {
+	dim := daysIn(first.Year(), first.Month())
+	byMonthDay := map[int]bool{}
+	for _, md := range r.ByMonthDay {
+		if md < 0 {
+			md = dim + md + 1
+		}
+		if md >= 1 && md <= dim {
+			byMonthDay[md] = true
+		}
+	}
+	byDay := map[int]bool{}
+	for _, wd := range r.ByDay {
+		firstOf := 1 + (int(wd.Weekday)-int(first.Weekday())+7)%7
+		switch {
+		case wd.N == 0:
+			for day := firstOf; day <= dim; day += 7 {
+				byDay[day] = true
+			}
+		case wd.N > 0:
+			byDay[firstOf+(wd.N-1)*7] = true
+		default:
+			lastOf := firstOf + (dim-firstOf)/7*7
+			byDay[lastOf+(wd.N+1)*7] = true
+		}
+	}
+	var days []int
+	switch {
+	case len(r.ByDay) > 0 && len(r.ByMonthDay) > 0:
+		for day := range byMonthDay {
+			if byDay[day] {
+				days = append(days, day)
+			}
+		}
+	case len(r.ByDay) > 0:
+		for day := range byDay {
+			days = append(days, day)
+		}
+	case len(r.ByMonthDay) > 0:
+		for day := range byMonthDay {
+			days = append(days, day)
+		}
+	case startDay <= dim:
+		days = append(days, startDay)
+	}
+	kept := days[:0]
+	for _, day := range days {
+		if day >= 1 && day <= dim {
+			kept = append(kept, day)
+		}
+	}
+	sort.Ints(kept)
+	return kept
+}
+
+func daysIn(y int, m time.Month) int {
+	return time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
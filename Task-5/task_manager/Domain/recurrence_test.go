@@ -0,0 +1,179 @@
+package Domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecurrenceEach(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start string // a date, or an RFC3339 time
+		max   int    // stop after this many; 0 means 10
+		want  []string
+	}{
+		{
+			name:  "COUNT counts from start and keeps its time and offset",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: "2026-01-05T09:30:00+02:00",
+			want:  []string{"2026-01-05T09:30:00+02:00", "2026-01-06T09:30:00+02:00", "2026-01-07T09:30:00+02:00"},
+		},
+		{
+			name:  "COUNT skips a start that doesn't match",
+			rule:  "FREQ=WEEKLY;BYDAY=MO;COUNT=2",
+			start: "2026-01-07",
+			want:  []string{"2026-01-12", "2026-01-19"},
+		},
+		{
+			name:  "UNTIL as a date includes that whole day",
+			rule:  "FREQ=DAILY;UNTIL=20260107",
+			start: "2026-01-05T23:00:00+02:00",
+			want:  []string{"2026-01-05T23:00:00+02:00", "2026-01-06T23:00:00+02:00", "2026-01-07T23:00:00+02:00"},
+		},
+		{
+			name:  "UNTIL as a time is compared in UTC",
+			rule:  "FREQ=DAILY;UNTIL=20260107T200000Z",
+			start: "2026-01-05T23:00:00+02:00",
+			want:  []string{"2026-01-05T23:00:00+02:00", "2026-01-06T23:00:00+02:00"},
+		},
+		{
+			name:  "UNTIL as a time is inclusive",
+			rule:  "FREQ=DAILY;UNTIL=20260107T210000Z",
+			start: "2026-01-05T23:00:00+02:00",
+			want:  []string{"2026-01-05T23:00:00+02:00", "2026-01-06T23:00:00+02:00", "2026-01-07T23:00:00+02:00"},
+		},
+		{
+			name:  "UNTIL before start",
+			rule:  "FREQ=DAILY;UNTIL=20260101",
+			start: "2026-01-05",
+			want:  nil,
+		},
+		{
+			name:  "-1FR is the last Friday of each month",
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR;COUNT=4",
+			start: "2026-01-01",
+			want:  []string{"2026-01-30", "2026-02-27", "2026-03-27", "2026-04-24"},
+		},
+		{
+			name:  "2TU is the second Tuesday",
+			rule:  "FREQ=MONTHLY;BYDAY=2TU;COUNT=2",
+			start: "2026-01-01",
+			want:  []string{"2026-01-13", "2026-02-10"},
+		},
+		{
+			name:  "BYMONTHDAY=31 skips short months",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=31;COUNT=4",
+			start: "2026-01-01",
+			want:  []string{"2026-01-31", "2026-03-31", "2026-05-31", "2026-07-31"},
+		},
+		{
+			name:  "BYMONTHDAY=-1 is the last day, February included",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3",
+			start: "2028-01-01",
+			want:  []string{"2028-01-31", "2028-02-29", "2028-03-31"},
+		},
+		{
+			name:  "monthly from the 31st skips short months",
+			rule:  "FREQ=MONTHLY;COUNT=3",
+			start: "2026-01-31",
+			want:  []string{"2026-01-31", "2026-03-31", "2026-05-31"},
+		},
+		{
+			name:  "BYMONTHDAY=31 every 12 months from February never matches",
+			rule:  "FREQ=MONTHLY;INTERVAL=12;BYMONTHDAY=31",
+			start: "2026-02-01",
+			want:  nil,
+		},
+		{
+			// RFC 5545 section 3.8.5.3, the WKST example
+			name:  "INTERVAL with WKST=MO",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO",
+			start: "1997-08-05T09:00:00-04:00",
+			want:  []string{"1997-08-05T09:00:00-04:00", "1997-08-10T09:00:00-04:00", "1997-08-19T09:00:00-04:00", "1997-08-24T09:00:00-04:00"},
+		},
+		{
+			name:  "INTERVAL with WKST=SU",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU",
+			start: "1997-08-05T09:00:00-04:00",
+			want:  []string{"1997-08-05T09:00:00-04:00", "1997-08-17T09:00:00-04:00", "1997-08-19T09:00:00-04:00", "1997-08-31T09:00:00-04:00"},
+		},
+		{
+			name:  "DAILY with INTERVAL and BYDAY",
+			rule:  "FREQ=DAILY;INTERVAL=2;BYDAY=MO,WE,FR",
+			start: "2026-01-05",
+			max:   4,
+			want:  []string{"2026-01-05", "2026-01-07", "2026-01-09", "2026-01-19"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRRule(%q) error = %v", tt.rule, err)
+			}
+			start, ok := ParseRecurrenceStart(tt.start)
+			if !ok {
+				t.Fatalf("ParseRecurrenceStart(%q) failed", tt.start)
+			}
+			layout := "2006-01-02T15:04:05Z07:00"
+			if len(tt.start) == len("2006-01-02") {
+				layout = "2006-01-02"
+			}
+			max := tt.max
+			if max == 0 {
+				max = 10
+			}
+			var got []string
+			rule.Each(start, func(at time.Time) bool {
+				got = append(got, at.Format(layout))
+				return len(got) < max
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("occurrences = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    string // canonical form
+		wantErr bool
+	}{
+		{rule: "RRULE:freq=weekly;wkst=su;interval=2", want: "FREQ=WEEKLY;INTERVAL=2;WKST=SU"},
+		{rule: "FREQ=MONTHLY;BYDAY=-1FR;INTERVAL=1", want: "FREQ=MONTHLY;BYDAY=-1FR"},
+		{rule: "FREQ=DAILY;UNTIL=20261231", want: "FREQ=DAILY;UNTIL=20261231"},
+		{rule: "FREQ=DAILY;UNTIL=20261231T170000Z", want: "FREQ=DAILY;UNTIL=20261231T170000Z"},
+		{rule: "", wantErr: true},
+		{rule: "INTERVAL=2", wantErr: true},
+		{rule: "FREQ=YEARLY", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=2;UNTIL=20261231", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYDAY=1MO", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYMONTHDAY=1", wantErr: true},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=0", wantErr: true},
+		{rule: "FREQ=MONTHLY;BYDAY=6MO", wantErr: true},
+		{rule: "FREQ=DAILY;FREQ=WEEKLY", wantErr: true},
+		{rule: "FREQ=DAILY;UNTIL=2026-12-31", wantErr: true},
+		{rule: "FREQ=DAILY;BYHOUR=9", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			r, err := ParseRRule(tt.rule)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseRRule(%q) = %s, want an error", tt.rule, r)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRRule(%q) error = %v", tt.rule, err)
+			}
+			if got := r.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
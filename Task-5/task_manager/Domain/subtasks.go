@@ -0,0 +1,115 @@
+package Domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MaxChecklistItems      = 100
+	MaxChecklistTextLength = 200
+)
+
+// ChecklistItem is a lightweight step inside a task; unlike a subtask it has
+// no status, due date or children of its own
+type ChecklistItem struct {
+	ID   primitive.ObjectID `bson:"id" json:"id"`
+	Text string             `bson:"text" json:"text"`
+	Done bool               `bson:"done" json:"done"`
+}
+
+// ChecklistItemPatch changes the fields that are set
+type ChecklistItemPatch struct {
+	Text *string
+	Done *bool
+}
+
+// Progress is computed on read from a task's direct subtasks and its
+// checklist items; it is never stored
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+	// Subtasks is how many of Total are subtasks; the rest are checklist items
+	Subtasks int `json:"subtasks"`
+}
+
+// ComputeProgress counts t's checklist items and the given children; nil
+// when there is nothing to count
+func ComputeProgress(t Task, children []Task) *Progress {
+	if len(children) == 0 && len(t.Checklist) == 0 {
+		return nil
+	}
+	p := &Progress{Total: len(children) + len(t.Checklist), Subtasks: len(children)}
+	for _, c := range children {
+		if c.Status == StatusDone {
+			p.Done++
+		}
+	}
+	for _, item := range t.Checklist {
+		if item.Done {
+			p.Done++
+		}
+	}
+	return p
+}
+
+// OpenSubtasks counts the children that aren't done
+func OpenSubtasks(children []Task) int {
+	n := 0
+	for _, c := range children {
+		if c.Status != StatusDone {
+			n++
+		}
+	}
+	return n
+}
+
+// ValidateChecklistText trims an item's text and checks it
+func ValidateChecklistText(s string) (string, error) {
+	v := &ValidationError{}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		v.add("text", "must not be empty")
+	}
+	if utf8.RuneCountInString(s) > MaxChecklistTextLength {
+		v.add("text", "must be at most 200 characters")
+	}
+	if strings.IndexFunc(s, unicode.IsControl) >= 0 {
+		v.add("text", "must not contain control characters")
+	}
+	return s, v.err()
+}
+
+// ChecklistFullError is returned when a task already has MaxChecklistItems
+func ChecklistFullError() error {
+	v := &ValidationError{}
+	v.add("checklist", "may hold at most 100 items")
+	return v
+}
+
+// OpenSubtasksError is returned when a task is completed while n of its
+// subtasks are still open and the change isn't forced
+func OpenSubtasksError(n int) error {
+	v := &ValidationError{}
+	v.add("status", fmt.Sprintf("%d subtask(s) are still open; complete them first or force the change", n))
+	return v
+}
+
+// HasSubtasksError is returned when deleting a task that still has subtasks
+func HasSubtasksError(n int) error {
+	v := &ValidationError{}
+	v.add("subtasks", fmt.Sprintf("task has %d subtask(s); delete or move them first", n))
+	return v
+}
+
+// ParentError reports an unusable parent_id, e.g. a missing task, a cycle or
+// nesting past the depth limit
+func ParentError(msg string) error {
+	v := &ValidationError{}
+	v.add("parent_id", msg)
+	return v
+}
@@ -0,0 +1,290 @@
+package Domain
+
+import (
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Validation rules — applied by the usecases so they hold for every delivery layer
+
+const (
+	StatusPending    = "pending"
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+
+	MaxTitleLength       = 200
+	MaxDescriptionLength = 5000
+	MaxEstimate          = 10000 * time.Hour
+	MinUsernameLength    = 3
+	MaxUsernameLength    = 32
+	MinPasswordLength    = 8
+	MaxPasswordLength    = 72 // bcrypt ignores anything past 72 bytes
+)
+
+// DueDateLayouts are the accepted formats for Task.DueDate
+var DueDateLayouts = []string{time.RFC3339, "2006-01-02"}
+
+var statusAliases = map[string]string{
+	"pending":     StatusPending,
+	"todo":        StatusPending,
+	"in_progress": StatusInProgress,
+	"in-progress": StatusInProgress,
+	"in progress": StatusInProgress,
+	"done":        StatusDone,
+	"completed":   StatusDone,
+}
+
+// FieldError describes a single rule violation
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects every violation found in one input
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		parts = append(parts, fe.Field+": "+fe.Message)
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) add(field, msg string) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Message: msg})
+}
+
+// err returns nil when nothing was collected so callers can return it directly
+func (e *ValidationError) err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// NormalizeStatus maps accepted spellings to the canonical status value
+func NormalizeStatus(s string) (string, bool) {
+	v, ok := statusAliases[strings.ToLower(strings.TrimSpace(s))]
+	return v, ok
+}
+
+// ParseDueDate parses a due date in any of DueDateLayouts
+func ParseDueDate(s string) (time.Time, bool) {
+	for _, layout := range DueDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// NormalizeUsername trims and lowercases a username
+func NormalizeUsername(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// ValidateCredentials normalizes the username and checks both fields
+func ValidateCredentials(username, password string) (string, error) {
+	v := &ValidationError{}
+	username = NormalizeUsername(username)
+	checkUsername(v, username)
+	switch {
+	case len(password) < MinPasswordLength:
+		v.add("password", "must be at least 8 characters")
+	case len(password) > MaxPasswordLength:
+		v.add("password", "must be at most 72 bytes")
+	}
+	return username, v.err()
+}
+
+func checkUsername(v *ValidationError, username string) {
+	n := utf8.RuneCountInString(username)
+	if n < MinUsernameLength || n > MaxUsernameLength {
+		v.add("username", "must be between 3 and 32 characters")
+	}
+	for _, r := range username {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-') {
+			v.add("username", "may only contain letters, digits, '.', '_' and '-'")
+			break
+		}
+	}
+}
+
+// NormalizeTask trims text fields and canonicalizes the status
+func NormalizeTask(t Task) Task {
+	t.Title = strings.TrimSpace(t.Title)
+	t.Description = strings.TrimSpace(t.Description)
+	t.DueDate = strings.TrimSpace(t.DueDate)
+	t.Estimate = strings.TrimSpace(t.Estimate)
+	if s, ok := NormalizeStatus(t.Status); ok {
+		t.Status = s
+	}
+	return t
+}
+
+// ValidateTask normalizes a full task and checks every field
+func ValidateTask(t Task) (Task, error) {
+	t = NormalizeTask(t)
+	v := &ValidationError{}
+	checkTitle(v, t.Title)
+	checkDescription(v, t.Description)
+	checkDueDate(v, t.DueDate)
+	checkEstimate(v, t.Estimate)
+	checkStatus(v, t.Status)
+	if len(t.Labels) > 0 {
+		t.Labels = normalizeLabelNames(v, t.Labels)
+	}
+	return t, v.err()
+}
+
+// ValidateTaskPatch normalizes and checks a partial update keyed by bson field name
+func ValidateTaskPatch(patch map[string]interface{}) (map[string]interface{}, error) {
+	v := &ValidationError{}
+	out := make(map[string]interface{}, len(patch))
+	for k, raw := range patch {
+		s, ok := raw.(string)
+		if !ok {
+			v.add(k, "must be a string")
+			continue
+		}
+		s = strings.TrimSpace(s)
+		switch k {
+		case "title":
+			checkTitle(v, s)
+		case "description":
+			checkDescription(v, s)
+		case "due_date":
+			checkDueDate(v, s)
+		case "estimate":
+			checkEstimate(v, s)
+		case "status":
+			if norm, ok := NormalizeStatus(s); ok {
+				s = norm
+			}
+			checkStatus(v, s)
+		default:
+			v.add(k, "unknown field")
+		}
+		out[k] = s
+	}
+	return out, v.err()
+}
+
+// optionalTaskFields may be removed from a task
+var optionalTaskFields = map[string]bool{"description": true, "due_date": true, "estimate": true}
+
+// ValidateTaskChange checks the fields being set and that only optional
+// fields are removed
+func ValidateTaskChange(ch TaskChange) (TaskChange, error) {
+	set, err := ValidateTaskPatch(ch.Set)
+	v, _ := err.(*ValidationError)
+	if v == nil {
+		v = &ValidationError{}
+	}
+	for _, k := range ch.Unset {
+		if _, dup := set[k]; dup {
+			v.add(k, "cannot be both set and removed")
+		} else if !optionalTaskFields[k] {
+			v.add(k, "cannot be removed")
+		}
+	}
+	ch.Set = set
+	return ch, v.err()
+}
+
+// ReplacementChange turns a validated full task into a change that sets the
+// given fields and removes the optional ones left empty
+func ReplacementChange(t Task) TaskChange {
+	ch := TaskChange{Set: map[string]interface{}{"title": t.Title, "status": t.Status}}
+	optional := []struct{ field, val string }{{"description", t.Description}, {"due_date", t.DueDate}, {"estimate", t.Estimate}}
+	for _, o := range optional {
+		if o.val == "" {
+			ch.Unset = append(ch.Unset, o.field)
+		} else {
+			ch.Set[o.field] = o.val
+		}
+	}
+	return ch
+}
+
+func checkTitle(v *ValidationError, s string) {
+	if s == "" {
+		v.add("title", "must not be empty")
+	}
+	if utf8.RuneCountInString(s) > MaxTitleLength {
+		v.add("title", "must be at most 200 characters")
+	}
+	if strings.IndexFunc(s, unicode.IsControl) >= 0 {
+		v.add("title", "must not contain control characters")
+	}
+}
+
+func checkDescription(v *ValidationError, s string) {
+	if utf8.RuneCountInString(s) > MaxDescriptionLength {
+		v.add("description", "must be at most 5000 characters")
+	}
+}
+
+func checkDueDate(v *ValidationError, s string) {
+	if s == "" {
+		return
+	}
+	if _, ok := ParseDueDate(s); !ok {
+		v.add("due_date", "must be RFC3339 or YYYY-MM-DD")
+	}
+}
+
+func checkEstimate(v *ValidationError, s string) {
+	if s == "" {
+		return
+	}
+	if d, err := time.ParseDuration(s); err != nil || d <= 0 || d > MaxEstimate {
+		v.add("estimate", "must be a positive duration of at most 10000h, such as 90m or 4h30m")
+	}
+}
+
+func checkStatus(v *ValidationError, s string) {
+	if _, ok := statusAliases[s]; !ok || s != statusAliases[s] {
+		v.add("status", "must be one of pending, in_progress, done")
+	}
+}
+
+// webhookEvents are the event types a webhook may subscribe to
+var webhookEvents = map[string]bool{
+	"*":              true,
+	EventTaskCreated: true,
+	EventTaskUpdated: true,
+	EventTaskDeleted: true,
+
+	EventCommentCreated: true,
+	EventCommentUpdated: true,
+	EventCommentDeleted: true,
+}
+
+// ValidateWebhook trims the URL and checks it is an absolute http(s) URL
+// subscribed to known events
+func ValidateWebhook(w Webhook) (Webhook, error) {
+	v := &ValidationError{}
+	w.URL = strings.TrimSpace(w.URL)
+	if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add("url", "must be an absolute http or https URL")
+	}
+	if len(w.Events) == 0 {
+		v.add("events", "must list at least one event")
+	}
+	for _, e := range w.Events {
+		if !webhookEvents[e] {
+			v.add("events", "unknown event "+e)
+		}
+	}
+	if w.Secret != "" && len(w.Secret) < 16 {
+		v.add("secret", "must be at least 16 characters")
+	}
+	return w, v.err()
+}
@@ -0,0 +1,45 @@
+package Domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Webhook is an external endpoint notified about task events. Events holds
+// event types or "*" for all of them.
+type Webhook struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID `bson:"workspace_id" json:"workspace_id"` // set by the repository
+	URL         string             `bson:"url" json:"url"`
+	Events      []string           `bson:"events" json:"events"`
+	Secret      string             `bson:"secret" json:"secret,omitempty"`
+	Active      bool               `bson:"active" json:"active"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Delivery statuses
+const (
+	DeliveryPending    = "pending"
+	DeliveryInFlight   = "delivering"
+	DeliverySucceeded  = "succeeded"
+	DeliveryDeadLetter = "dead"
+)
+
+// WebhookDelivery is one event queued for one webhook, with its attempt log
+type WebhookDelivery struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WorkspaceID   primitive.ObjectID `bson:"workspace_id" json:"-"` // set by the repository
+	WebhookID     primitive.ObjectID `bson:"webhook_id" json:"webhook_id"`
+	EventID       string             `bson:"event_id" json:"event_id"`
+	EventType     string             `bson:"event_type" json:"event_type"`
+	Payload       string             `bson:"payload" json:"payload"`
+	Status        string             `bson:"status" json:"status"`
+	Attempts      int                `bson:"attempts" json:"attempts"`
+	NextAttemptAt time.Time          `bson:"next_attempt_at" json:"next_attempt_at"`
+	LeaseUntil    time.Time          `bson:"lease_until,omitempty" json:"-"`
+	LastError     string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	LastStatus    int                `bson:"last_status,omitempty" json:"last_status,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+}
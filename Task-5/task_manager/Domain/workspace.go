@@ -0,0 +1,93 @@
+package Domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrNoWorkspace means a tenant-scoped operation ran without an active workspace
+	ErrNoWorkspace = errors.New("no active workspace")
+	// ErrForbidden means the caller's role doesn't allow the operation
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Workspace roles; admins manage the workspace's tasks, webhooks and members
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "user"
+
+	MaxWorkspaceNameLength = 100
+)
+
+// Workspace is a tenant: tasks and webhooks belong to exactly one
+type Workspace struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Membership gives a user a role in a workspace
+type Membership struct {
+	WorkspaceID primitive.ObjectID `bson:"workspace_id" json:"workspace_id"`
+	Username    string             `bson:"username" json:"username"`
+	Role        string             `bson:"role" json:"role"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Session is who a token speaks for: the user, the active workspace (zero
+// when the user belongs to none) and the role held there
+type Session struct {
+	Username      string
+	Workspace     primitive.ObjectID
+	Role          string
+	InstanceAdmin bool
+}
+
+type workspaceKey struct{}
+
+// WithWorkspace makes id the active workspace for repository calls made with
+// the returned context
+func WithWorkspace(ctx context.Context, id primitive.ObjectID) context.Context {
+	return context.WithValue(ctx, workspaceKey{}, id)
+}
+
+// WorkspaceFrom returns the active workspace, if any
+func WorkspaceFrom(ctx context.Context) (primitive.ObjectID, bool) {
+	id, ok := ctx.Value(workspaceKey{}).(primitive.ObjectID)
+	return id, ok && !id.IsZero()
+}
+
+// ValidateWorkspace trims the name and checks it
+func ValidateWorkspace(w Workspace) (Workspace, error) {
+	v := &ValidationError{}
+	w.Name = strings.TrimSpace(w.Name)
+	if w.Name == "" {
+		v.add("name", "must not be empty")
+	} else if utf8.RuneCountInString(w.Name) > MaxWorkspaceNameLength {
+		v.add("name", "must be at most 100 characters")
+	}
+	return w, v.err()
+}
+
+// ValidateRole checks a workspace role
+func ValidateRole(role string) error {
+	if role != RoleAdmin && role != RoleMember {
+		v := &ValidationError{}
+		v.add("role", "must be admin or user")
+		return v
+	}
+	return nil
+}
+
+// LastAdminError is returned when a change would leave a workspace without an admin
+func LastAdminError() error {
+	v := &ValidationError{}
+	v.add("role", "workspace must keep at least one admin")
+	return v
+}
@@ -1,51 +1,126 @@
 package Infrastructure
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strings"
 
+	"task_manager/Domain"
+
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 )
 
-// AuthMiddleware validates JWT token and stores username+role in context
-func AuthMiddleware(jwtSvc JWTService) gin.HandlerFunc {
+// SessionCheck reloads the session a token claims, so a removed member or a
+// demoted admin loses access on their next request instead of when the token
+// expires. It returns Domain.ErrNotFound once the session is gone.
+type SessionCheck func(ctx context.Context, claimed Domain.Session) (Domain.Session, error)
+
+// AuthMiddleware validates the JWT, checks its session is still current and
+// stores username+role in context
+func AuthMiddleware(jwtSvc JWTService, sessions SessionCheck) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		h := c.GetHeader("Authorization")
 		if h == "" {
+			AuthFailed(c, AuthMissingHeader)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization header required"})
 			return
 		}
 		parts := strings.Fields(h)
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+			AuthFailed(c, AuthMalformed)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header"})
 			return
 		}
 		token := parts[1]
 		claims, err := jwtSvc.ValidateToken(token)
 		if err != nil {
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				AuthFailed(c, AuthExpiredToken)
+			} else {
+				AuthFailed(c, AuthInvalidToken)
+			}
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token", "details": err.Error()})
 			return
 		}
-		// store user info
-		c.Set("username", claims.Username)
-		c.Set("role", claims.Role)
+		s, err := sessions(c.Request.Context(), claims.Session())
+		if errors.Is(err, Domain.ErrNotFound) {
+			AuthFailed(c, AuthRevoked)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked", "details": "the user or their membership no longer exists; log in again"})
+			return
+		}
+		if err != nil {
+			_ = c.Error(err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check session"})
+			return
+		}
+		// store user info; repositories read the workspace from the request context
+		c.Set("username", s.Username)
+		c.Set("role", s.Role)
+		c.Set("instance_admin", s.InstanceAdmin)
+		if !s.Workspace.IsZero() {
+			c.Set("workspace", s.Workspace.Hex())
+			c.Request = c.Request.WithContext(Domain.WithWorkspace(c.Request.Context(), s.Workspace))
+		}
+		c.Next()
+	}
+}
+
+// TokenFromQuery lets clients that cannot set headers (EventSource, browser
+// WebSockets) pass the JWT as a query parameter; use it only on streaming routes
+func TokenFromQuery(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if tok := c.Query(param); tok != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+tok)
+			}
+		}
 		c.Next()
 	}
 }
 
-// AdminOnly middleware reads role from context
+// AdminOnly middleware reads role from context: the role in the active workspace
 func AdminOnlyMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		rv, exists := c.Get("role")
 		if !exists {
+			AuthFailed(c, AuthForbidden)
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "role not found"})
 			return
 		}
 		role := rv.(string)
 		if role != "admin" {
+			AuthFailed(c, AuthForbidden)
 			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin required"})
 			return
 		}
 		c.Next()
 	}
 }
+
+// WorkspaceRequired rejects tokens without an active workspace; put it in
+// front of routes that touch tenant data
+func WorkspaceRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("workspace") == "" {
+			AuthFailed(c, AuthNoWorkspace)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "no active workspace", "details": "create or join a workspace, then log in or switch to it"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// InstanceAdminMiddleware admits instance admins only, as opposed to admins
+// of the active workspace
+func InstanceAdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("instance_admin") {
+			AuthFailed(c, AuthForbidden)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "instance admin required"})
+			return
+		}
+		c.Next()
+	}
+}
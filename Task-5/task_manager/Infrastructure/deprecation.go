@@ -0,0 +1,50 @@
+package Infrastructure
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecation describes routes that are going away
+type Deprecation struct {
+	// Since is when the routes were deprecated
+	Since time.Time
+	// Sunset is when they stop working; zero if not decided yet
+	Sunset time.Time
+	// Link points at a human-readable migration notice; optional
+	Link string
+	// Successor maps a request path to the path that replaces it; optional
+	Successor func(path string) string
+}
+
+// Deprecated announces d on every response: Deprecation (RFC 9745), Sunset
+// (RFC 8594) and Link with rel="successor-version" and rel="deprecation"
+func Deprecated(d Deprecation) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(d.Since.Unix(), 10)
+	var sunset string
+	if !d.Sunset.IsZero() {
+		sunset = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("Deprecation", deprecation)
+		if sunset != "" {
+			h.Set("Sunset", sunset)
+		}
+		var links []string
+		if d.Successor != nil {
+			links = append(links, "<"+d.Successor(c.Request.URL.Path)+`>; rel="successor-version"`)
+		}
+		if d.Link != "" {
+			links = append(links, "<"+d.Link+`>; rel="deprecation"; type="text/html"`)
+		}
+		if len(links) > 0 {
+			h.Add("Link", strings.Join(links, ", "))
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,107 @@
+package Infrastructure
+
+import (
+	"sync"
+	"time"
+
+	"task_manager/Domain"
+)
+
+// EventHub fans task events out to subscribers and keeps the most recent
+// ones so reconnecting clients can resume from an event ID
+type EventHub struct {
+	mu     sync.Mutex
+	nextID uint64
+	replay []Domain.TaskEvent // ring buffer, oldest at start
+	size   int
+	start  int
+	subs   map[*Subscription]struct{}
+}
+
+// Subscription delivers events matching its filter on C. C is closed when the
+// subscriber falls too far behind or unsubscribes.
+type Subscription struct {
+	C      <-chan Domain.TaskEvent
+	ch     chan Domain.TaskEvent
+	filter func(Domain.TaskEvent) bool
+}
+
+// NewEventHub keeps up to replaySize events for resumption. IDs start from
+// the current time so they keep increasing across restarts.
+func NewEventHub(replaySize int) *EventHub {
+	if replaySize < 1 {
+		replaySize = 1
+	}
+	return &EventHub{
+		nextID: uint64(time.Now().UnixNano()),
+		replay: make([]Domain.TaskEvent, replaySize),
+		subs:   map[*Subscription]struct{}{},
+	}
+}
+
+// Publish stamps the event with an ID and time and delivers it without
+// blocking; slow subscribers are dropped
+func (h *EventHub) Publish(e Domain.TaskEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nextID++
+	e.ID = h.nextID
+	if e.At.IsZero() {
+		e.At = time.Now().UTC()
+	}
+	if h.size < len(h.replay) {
+		h.replay[(h.start+h.size)%len(h.replay)] = e
+		h.size++
+	} else {
+		h.replay[h.start] = e
+		h.start = (h.start + 1) % len(h.replay)
+	}
+	for s := range h.subs {
+		if s.filter != nil && !s.filter(e) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			delete(h.subs, s)
+			close(s.ch)
+		}
+	}
+}
+
+// Subscribe registers a subscriber and returns the buffered events after
+// lastID. complete is false when events after lastID have already been
+// evicted, meaning the client missed changes and should reload.
+func (h *EventHub) Subscribe(lastID uint64, filter func(Domain.TaskEvent) bool) (sub *Subscription, missed []Domain.TaskEvent, complete bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan Domain.TaskEvent, 64)
+	sub = &Subscription{C: ch, ch: ch, filter: filter}
+	h.subs[sub] = struct{}{}
+	complete = true
+	if lastID == 0 {
+		return sub, nil, complete
+	}
+	if h.size > 0 {
+		oldest := h.replay[h.start].ID
+		complete = lastID >= oldest-1 && lastID <= h.nextID
+	} else {
+		complete = lastID == h.nextID
+	}
+	for i := 0; i < h.size; i++ {
+		e := h.replay[(h.start+i)%len(h.replay)]
+		if e.ID > lastID && (filter == nil || filter(e)) {
+			missed = append(missed, e)
+		}
+	}
+	return sub, missed, complete
+}
+
+func (h *EventHub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
@@ -0,0 +1,101 @@
+package Infrastructure
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthCheck reports whether one dependency is usable
+type HealthCheck func(ctx context.Context) error
+
+// Health backs the liveness and readiness probes. Readiness fails once
+// shutdown starts so load balancers stop routing new requests here.
+type Health struct {
+	mu           sync.RWMutex
+	checks       map[string]HealthCheck
+	shuttingDown atomic.Bool
+	timeout      time.Duration
+}
+
+func NewHealth() *Health {
+	return &Health{checks: map[string]HealthCheck{}, timeout: 2 * time.Second}
+}
+
+// Register adds a named readiness check
+func (h *Health) Register(name string, check HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check
+}
+
+func (h *Health) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Check runs every check concurrently and returns each one's status
+func (h *Health) Check(ctx context.Context) (map[string]string, bool) {
+	h.mu.RLock()
+	names := make([]string, 0, len(h.checks))
+	for name := range h.checks {
+		names = append(names, name)
+	}
+	h.mu.RUnlock()
+	sort.Strings(names)
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	results := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		h.mu.RLock()
+		check := h.checks[name]
+		h.mu.RUnlock()
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			results[i] = check(ctx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	status := make(map[string]string, len(names))
+	ok := true
+	for i, name := range names {
+		if results[i] != nil {
+			status[name] = results[i].Error()
+			ok = false
+		} else {
+			status[name] = "ok"
+		}
+	}
+	if h.shuttingDown.Load() {
+		status["server"] = "shutting down"
+		ok = false
+	}
+	return status, ok
+}
+
+// LivenessHandler answers 200 while the process can serve requests at all
+func (h *Health) LivenessHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	}
+}
+
+// ReadinessHandler answers 200 only when every check passes, 503 otherwise
+func (h *Health) ReadinessHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		checks, ok := h.Check(c.Request.Context())
+		if !ok {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": checks})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ok", "checks": checks})
+	}
+}
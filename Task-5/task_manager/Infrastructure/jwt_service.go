@@ -4,38 +4,60 @@ import (
 	"errors"
 	"time"
 
+	"task_manager/Domain"
+
 	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // JWTService interface for generating/validating tokens
 type JWTService interface {
-	GenerateToken(username, role string) (string, error)
+	GenerateToken(s Domain.Session) (string, error)
 	ValidateToken(tokenStr string) (*TokenClaims, error)
 }
 
 type jwtService struct {
 	secret []byte
+	ttl    time.Duration
 }
 
+// TokenClaims carry a Domain.Session. Role is the role in Workspace, the
+// active workspace's hex ID; both are empty when the user belongs to none.
 type TokenClaims struct {
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	Username      string `json:"username"`
+	Role          string `json:"role"`
+	Workspace     string `json:"workspace,omitempty"`
+	InstanceAdmin bool   `json:"instance_admin,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func NewJWTService(secret string) JWTService {
-	return &jwtService{secret: []byte(secret)}
+// Session is the session the token was issued for
+func (c *TokenClaims) Session() Domain.Session {
+	s := Domain.Session{Username: c.Username, Role: c.Role, InstanceAdmin: c.InstanceAdmin}
+	if ws, err := primitive.ObjectIDFromHex(c.Workspace); err == nil {
+		s.Workspace = ws
+	}
+	return s
 }
 
-func (j *jwtService) GenerateToken(username, role string) (string, error) {
+// NewJWTService signs tokens with secret; each token is valid for ttl
+func NewJWTService(secret string, ttl time.Duration) JWTService {
+	return &jwtService{secret: []byte(secret), ttl: ttl}
+}
+
+func (j *jwtService) GenerateToken(s Domain.Session) (string, error) {
 	claims := &TokenClaims{
-		Username: username,
-		Role:     role,
+		Username:      s.Username,
+		Role:          s.Role,
+		InstanceAdmin: s.InstanceAdmin,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
+	if !s.Workspace.IsZero() {
+		claims.Workspace = s.Workspace.Hex()
+	}
 	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return t.SignedString(j.secret)
 }
@@ -0,0 +1,170 @@
+package Infrastructure
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"task_manager/Domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// authFailureKey is where auth middleware and handlers leave the reason a
+// request was rejected, for Metrics.Middleware to count
+const authFailureKey = "auth_failure"
+
+// Auth failure reasons; a fixed set keeps the label bounded
+const (
+	AuthMissingHeader  = "missing_header"
+	AuthMalformed      = "malformed_header"
+	AuthInvalidToken   = "invalid_token"
+	AuthExpiredToken   = "expired_token"
+	AuthForbidden      = "forbidden"
+	AuthBadCredentials = "bad_credentials"
+	AuthNoWorkspace    = "no_workspace"
+	AuthRevoked        = "revoked_session"
+)
+
+// AuthFailed records why the current request failed authentication
+func AuthFailed(c *gin.Context, reason string) {
+	c.Set(authFailureKey, reason)
+}
+
+// Metrics owns a Prometheus registry and the collectors for every layer
+type Metrics struct {
+	registry     *prometheus.Registry
+	requests     *prometheus.CounterVec
+	latency      *prometheus.HistogramVec
+	inFlight     prometheus.Gauge
+	authFailures *prometheus.CounterVec
+	usecases     *prometheus.HistogramVec
+	repositories *prometheus.HistogramVec
+	cache        *prometheus.CounterVec
+	webhookDrops *prometheus.CounterVec
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by route template, method and status.",
+		}, []string{"method", "route", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by route template, method and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "HTTP requests currently being served.",
+		}),
+		authFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_failures_total",
+			Help: "Rejected authentication or authorization attempts by reason.",
+		}, []string{"reason"}),
+		usecases: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "usecase_operation_duration_seconds",
+			Help:    "Usecase call duration by operation and outcome.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"usecase", "operation", "outcome"}),
+		repositories: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "repository_operation_duration_seconds",
+			Help:    "Repository (Mongo) call duration by operation and outcome.",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"repository", "operation", "outcome"}),
+		cache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_lookups_total",
+			Help: "In-process cache lookups by cache and result (hit or miss).",
+		}, []string{"cache", "result"}),
+		webhookDrops: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "webhook_events_dropped_total",
+			Help: "Task events dropped because the webhook queue stayed full, by event type.",
+		}, []string{"event"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.latency, m.inFlight, m.authFailures, m.usecases, m.repositories, m.cache, m.webhookDrops,
+	)
+	return m
+}
+
+// Middleware records every request. Routes are labelled with their template
+// (/tasks/:id), never the raw path, so cardinality stays bounded.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		m.inFlight.Inc()
+		c.Next()
+		m.inFlight.Dec()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		m.requests.WithLabelValues(c.Request.Method, route, status).Inc()
+		m.latency.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+		if reason := c.GetString(authFailureKey); reason != "" {
+			m.authFailures.WithLabelValues(reason).Inc()
+		}
+	}
+}
+
+// Handler serves the registry in the Prometheus text format
+func (m *Metrics) Handler() gin.HandlerFunc {
+	h := promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+	return gin.WrapH(h)
+}
+
+// ObserveUsecase times one usecase call; call the returned func with its result
+func (m *Metrics) ObserveUsecase(ctx context.Context, usecase, op string) (context.Context, func(error)) {
+	start := time.Now()
+	return ctx, func(err error) {
+		m.usecases.WithLabelValues(usecase, op, Outcome(err)).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveRepository times one repository call; call the returned func with its result
+func (m *Metrics) ObserveRepository(ctx context.Context, repo, op string) (context.Context, func(error)) {
+	start := time.Now()
+	return ctx, func(err error) {
+		m.repositories.WithLabelValues(repo, op, Outcome(err)).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveCache counts one cache lookup
+func (m *Metrics) ObserveCache(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cache.WithLabelValues(cache, result).Inc()
+}
+
+// ObserveWebhookDrop counts one event the webhook queue had no room for
+func (m *Metrics) ObserveWebhookDrop(eventType string) {
+	m.webhookDrops.WithLabelValues(eventType).Inc()
+}
+
+// Outcome classifies err into a small fixed set of label values
+func Outcome(err error) string {
+	var verr *Domain.ValidationError
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, Domain.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, Domain.ErrPreconditionFailed):
+		return "conflict"
+	case errors.As(err, &verr):
+		return "invalid"
+	}
+	return "error"
+}
@@ -4,13 +4,28 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// PasswordService hashes and checks user passwords
+type PasswordService interface {
+	HashPassword(pw string) (string, error)
+	ComparePassword(hash, pw string) error
+}
+
+type bcryptService struct {
+	cost int
+}
+
+// NewPasswordService returns a bcrypt-backed service using the given cost
+func NewPasswordService(cost int) PasswordService {
+	return &bcryptService{cost: cost}
+}
+
 // HashPassword returns bcrypt hash
-func HashPassword(pw string) (string, error) {
-	b, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.DefaultCost)
-	return string(b), err
+func (b *bcryptService) HashPassword(pw string) (string, error) {
+	h, err := bcrypt.GenerateFromPassword([]byte(pw), b.cost)
+	return string(h), err
 }
 
 // ComparePassword compares hash with plaintext
-func ComparePassword(hash, pw string) error {
+func (b *bcryptService) ComparePassword(hash, pw string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pw))
 }
@@ -0,0 +1,150 @@
+package Infrastructure
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitPolicy is a token bucket holding Limit tokens that refills
+// completely over Period; each request takes one token
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// Enabled reports whether the policy limits anything
+func (p RateLimitPolicy) Enabled() bool { return p.Limit > 0 && p.Period > 0 }
+
+// refill returns tokens added per second
+func (p RateLimitPolicy) refill() float64 { return float64(p.Limit) / p.Period.Seconds() }
+
+// RateLimitResult is the state of one bucket after a request
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next token when not allowed
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps token buckets; implementations must be safe for
+// concurrent use (and, for shared stores, across replicas). now is the
+// caller's clock; a shared store should use its own instead, so replicas
+// whose clocks differ refill a bucket alike.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, p RateLimitPolicy, now time.Time) (RateLimitResult, error)
+}
+
+// BucketResult derives the reported state from the tokens left after a take
+func BucketResult(p RateLimitPolicy, tokens float64, allowed bool) RateLimitResult {
+	rate := p.refill()
+	res := RateLimitResult{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(p.Limit) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return res
+}
+
+// memoryRateLimitStore keeps buckets in process memory; limits are per replica
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	period  time.Duration
+}
+
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{buckets: map[string]*memoryBucket{}}
+}
+
+func (s *memoryRateLimitStore) Take(_ context.Context, key string, p RateLimitPolicy, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: float64(p.Limit), updated: now, period: p.Period}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(p.Limit), b.tokens+now.Sub(b.updated).Seconds()*p.refill())
+	b.updated = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return BucketResult(p, b.tokens, allowed), nil
+}
+
+// sweep drops buckets that have been idle long enough to be full again, so
+// one-off clients don't accumulate
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for k, b := range s.buckets {
+		if now.Sub(b.updated) > b.period {
+			delete(s.buckets, k)
+		}
+	}
+}
+
+// rateLimitKey is where the tightest result seen so far for a request is kept,
+// so nested group limits report the one closest to running out
+const rateLimitKey = "rate_limit"
+
+// RateLimit enforces p per user (when authenticated) or per client IP, and
+// sets RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy. Requests over the limit get 429 with Retry-After. If the
+// store fails the request is let through.
+func RateLimit(store RateLimitStore, p RateLimitPolicy) gin.HandlerFunc {
+	policyHeader := fmt.Sprintf("%d;w=%d", p.Limit, int(p.Period.Seconds()))
+	return func(c *gin.Context) {
+		key := p.Name + ":ip:" + c.ClientIP()
+		if u := c.GetString("username"); u != "" {
+			key = p.Name + ":user:" + u
+		}
+		res, err := store.Take(c.Request.Context(), key, p, time.Now())
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "rate limit store failed; allowing request", "policy", p.Name, "error", err)
+			c.Next()
+			return
+		}
+		if prev, ok := c.Get(rateLimitKey); !ok || res.Remaining < prev.(RateLimitResult).Remaining || !res.Allowed {
+			c.Set(rateLimitKey, res)
+			c.Header("RateLimit-Limit", strconv.Itoa(p.Limit))
+			c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+			c.Header("RateLimit-Policy", policyHeader)
+		}
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded", "details": "retry after " + res.RetryAfter.Round(time.Second).String()})
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
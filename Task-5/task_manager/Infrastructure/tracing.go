@@ -0,0 +1,144 @@
+package Infrastructure
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Trace exporters
+const (
+	TraceExporterNone   = "none"
+	TraceExporterStdout = "stdout"
+	TraceExporterOTLP   = "otlp"
+)
+
+// TracingOptions selects where spans go
+type TracingOptions struct {
+	Exporter    string // none, stdout or otlp
+	Endpoint    string // OTLP/HTTP endpoint URL; empty uses OTEL_EXPORTER_OTLP_* env
+	ServiceName string
+	SampleRatio float64 // fraction of new traces recorded; parents' decisions are kept
+	Stdout      io.Writer
+}
+
+// Tracing creates spans for the HTTP, usecase and repository layers and
+// propagates W3C trace context
+type Tracing struct {
+	provider *sdktrace.TracerProvider // nil when disabled
+	tracer   trace.Tracer
+}
+
+// NewTracing installs the global tracer provider and propagator
+func NewTracing(ctx context.Context, opts TracingOptions) (*Tracing, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exp sdktrace.SpanExporter
+	var err error
+	switch opts.Exporter {
+	case TraceExporterNone, "":
+		return &Tracing{tracer: noop.NewTracerProvider().Tracer("task_manager")}, nil
+	case TraceExporterStdout:
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(opts.Stdout))
+	case TraceExporterOTLP:
+		var o []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			o = append(o, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		}
+		exp, err = otlptracehttp.New(ctx, o...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", opts.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(opts.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return &Tracing{provider: tp, tracer: tp.Tracer("task_manager")}, nil
+}
+
+// Shutdown flushes buffered spans
+func (t *Tracing) Shutdown(ctx context.Context) error {
+	if t.provider == nil {
+		return nil
+	}
+	return t.provider.Shutdown(ctx)
+}
+
+// Middleware starts a server span per request, continuing the caller's
+// trace when a traceparent header is present
+func (t *Tracing) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		name := c.Request.Method + " " + route
+		if route == "" {
+			name = c.Request.Method
+		}
+		ctx, span := t.tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+			))
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(c.Writer.Header()))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if u := c.GetString("username"); u != "" {
+			span.SetAttributes(semconv.EnduserID(u))
+		}
+		if status >= 500 {
+			span.SetStatus(codes.Error, c.Errors.String())
+		}
+	}
+}
+
+// ObserveUsecase starts a span for one usecase call
+func (t *Tracing) ObserveUsecase(ctx context.Context, usecase, op string) (context.Context, func(error)) {
+	return t.start(ctx, "usecase "+usecase+"."+op, trace.SpanKindInternal, attribute.String("usecase", usecase))
+}
+
+// ObserveRepository starts a client span for one repository call
+func (t *Tracing) ObserveRepository(ctx context.Context, repo, op string) (context.Context, func(error)) {
+	return t.start(ctx, "repository "+repo+"."+op, trace.SpanKindClient, semconv.DBSystemMongoDB, semconv.DBOperation(op))
+}
+
+func (t *Tracing) start(ctx context.Context, name string, kind trace.SpanKind, attrs ...attribute.KeyValue) (context.Context, func(error)) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+	return ctx, func(err error) {
+		outcome := Outcome(err)
+		span.SetAttributes(attribute.String("outcome", outcome))
+		if outcome == "error" {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,34 @@
+package Infrastructure
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// URLSigner signs the payload of short-lived links, such as attachment
+// downloads, so they can be checked without a lookup
+type URLSigner interface {
+	Sign(payload string) string
+	Verify(payload, signature string) bool
+}
+
+type urlSigner struct {
+	key []byte
+}
+
+// NewURLSigner signs with HMAC-SHA256 under key
+func NewURLSigner(key string) URLSigner {
+	return &urlSigner{key: []byte(key)}
+}
+
+// Sign returns the unpadded base64url MAC of payload
+func (s *urlSigner) Sign(payload string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *urlSigner) Verify(payload, signature string) bool {
+	return hmac.Equal([]byte(s.Sign(payload)), []byte(signature))
+}
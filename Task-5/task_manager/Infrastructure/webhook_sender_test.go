@@ -0,0 +1,79 @@
+package Infrastructure
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWebhookSenderSigns(t *testing.T) {
+	const secret = "0123456789abcdef0123456789abcdef"
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	got := make(chan received, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{r.Header.Clone(), body}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	body := []byte(`{"type":"task.created"}`)
+	status, err := NewWebhookSender(time.Second).Send(context.Background(), srv.URL, secret, map[string]string{"X-Webhook-Event": "task.created"}, body)
+	if err != nil || status != http.StatusAccepted {
+		t.Fatalf("Send() = %d, %v, want 202", status, err)
+	}
+	r := <-got
+	if ct := r.header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if ev := r.header.Get("X-Webhook-Event"); ev != "task.created" {
+		t.Errorf("X-Webhook-Event = %q", ev)
+	}
+	ts, sig := r.header.Get(WebhookTimestampHeader), r.header.Get(WebhookSignatureHeader)
+	if n, err := strconv.ParseInt(ts, 10, 64); err != nil || time.Since(time.Unix(n, 0)).Abs() > time.Minute {
+		t.Errorf("%s = %q, want the current unix time", WebhookTimestampHeader, ts)
+	}
+	if !VerifyWebhook(secret, ts, sig, r.body, 5*time.Minute) {
+		t.Errorf("VerifyWebhook rejected the signature %q", sig)
+	}
+	if VerifyWebhook("another-secret-of-16", ts, sig, r.body, 5*time.Minute) {
+		t.Error("VerifyWebhook accepted the wrong secret")
+	}
+	if VerifyWebhook(secret, ts, sig, []byte(`{"type":"task.deleted"}`), 5*time.Minute) {
+		t.Error("VerifyWebhook accepted a changed body")
+	}
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	const secret = "0123456789abcdef"
+	body := []byte(`{}`)
+	now := time.Now().Unix()
+	tests := []struct {
+		name      string
+		timestamp string
+		signature string
+		want      bool
+	}{
+		{name: "fresh", timestamp: strconv.FormatInt(now, 10), signature: SignWebhook(secret, now, body), want: true},
+		{name: "within tolerance", timestamp: strconv.FormatInt(now-240, 10), signature: SignWebhook(secret, now-240, body), want: true},
+		{name: "stale", timestamp: strconv.FormatInt(now-600, 10), signature: SignWebhook(secret, now-600, body)},
+		{name: "from the future", timestamp: strconv.FormatInt(now+600, 10), signature: SignWebhook(secret, now+600, body)},
+		{name: "timestamp swapped after signing", timestamp: strconv.FormatInt(now, 10), signature: SignWebhook(secret, now-60, body)},
+		{name: "not a number", timestamp: "yesterday", signature: SignWebhook(secret, now, body)},
+		{name: "missing sha256= prefix", timestamp: strconv.FormatInt(now, 10), signature: SignWebhook(secret, now, body)[len("sha256="):]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyWebhook(secret, tt.timestamp, tt.signature, body, 5*time.Minute); got != tt.want {
+				t.Errorf("VerifyWebhook() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
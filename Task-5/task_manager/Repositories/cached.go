@@ -0,0 +1,359 @@
+package Repositories
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"task_manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CacheStats is told whether each cache lookup was a hit
+type CacheStats func(cache string, hit bool)
+
+// TaskCache keeps recent task reads in memory: an LRU of at most size
+// entries, each dropped after ttl. Any write through Tasks or a transaction
+// through Transactor empties it. Writes made by other processes are only
+// seen once entries expire.
+type TaskCache struct {
+	size  int
+	ttl   time.Duration
+	stats CacheStats
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // front is most recently used
+	// gen changes on every flush; a read only stores its result if gen is
+	// unchanged, so a read racing a write can't cache what it replaced
+	gen uint64
+}
+
+type cacheEntry struct {
+	key     string
+	value   interface{} // Domain.Task or []Domain.Task
+	expires time.Time
+}
+
+// NewTaskCache makes an empty cache; stats may be nil
+func NewTaskCache(size int, ttl time.Duration, stats CacheStats) *TaskCache {
+	return &TaskCache{size: size, ttl: ttl, stats: stats, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+func (c *TaskCache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if ok && time.Now().After(el.Value.(*cacheEntry).expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		ok = false
+	}
+	if c.stats != nil {
+		c.stats("task", ok)
+	}
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cacheEntry).value, true
+}
+
+func (c *TaskCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// put stores value unless the cache was flushed since gen was read
+func (c *TaskCache) put(gen uint64, key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	expires := time.Now().Add(c.ttl)
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*cacheEntry)
+		e.value, e.expires = value, expires
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Flush empties the cache
+func (c *TaskCache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+type inTransactionKey struct{}
+
+// keyPrefix scopes cache keys to the workspace in ctx. ok is false when reads
+// must go to the store: without a workspace, which the store rejects, or
+// inside a transaction, whose uncommitted writes mustn't leak into the cache.
+func keyPrefix(ctx context.Context) (prefix string, ok bool) {
+	ws, ok := Domain.WorkspaceFrom(ctx)
+	if !ok || ctx.Value(inTransactionKey{}) != nil {
+		return "", false
+	}
+	return ws.Hex() + ":", true
+}
+
+// Tasks wraps r so reads are served from the cache and writes empty it
+func (c *TaskCache) Tasks(r TaskRepository) TaskRepository {
+	return &cachedTaskRepo{next: r, cache: c}
+}
+
+// Transactor wraps tx so reads inside a transaction skip the cache and the
+// cache is emptied once the transaction ends
+func (c *TaskCache) Transactor(tx Transactor) Transactor {
+	return &cachedTransactor{next: tx, cache: c}
+}
+
+type cachedTransactor struct {
+	next  Transactor
+	cache *TaskCache
+}
+
+func (t *cachedTransactor) SupportsTransactions() bool { return t.next.SupportsTransactions() }
+
+func (t *cachedTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	defer t.cache.Flush()
+	return t.next.WithTransaction(context.WithValue(ctx, inTransactionKey{}, true), fn)
+}
+
+type cachedTaskRepo struct {
+	next  TaskRepository
+	cache *TaskCache
+}
+
+// copyTasks keeps callers that sort or edit a result from changing the cache
+func copyTasks(tasks []Domain.Task) []Domain.Task {
+	return append([]Domain.Task(nil), tasks...)
+}
+
+func (r *cachedTaskRepo) FindAll(ctx context.Context) ([]Domain.Task, error) {
+	prefix, ok := keyPrefix(ctx)
+	if !ok {
+		return r.next.FindAll(ctx)
+	}
+	key := prefix + "all"
+	if v, ok := r.cache.get(key); ok {
+		return copyTasks(v.([]Domain.Task)), nil
+	}
+	gen := r.cache.generation()
+	out, err := r.next.FindAll(ctx)
+	if err == nil {
+		r.cache.put(gen, key, copyTasks(out))
+	}
+	return out, err
+}
+
+func (r *cachedTaskRepo) FindByID(ctx context.Context, id primitive.ObjectID) (Domain.Task, error) {
+	prefix, ok := keyPrefix(ctx)
+	if !ok {
+		return r.next.FindByID(ctx, id)
+	}
+	key := prefix + id.Hex()
+	if v, ok := r.cache.get(key); ok {
+		return v.(Domain.Task), nil
+	}
+	gen := r.cache.generation()
+	out, err := r.next.FindByID(ctx, id)
+	if err == nil {
+		r.cache.put(gen, key, out)
+	}
+	return out, err
+}
+
+// FindByIDs answers what it can from the cache and fetches the rest in one call
+func (r *cachedTaskRepo) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]Domain.Task, error) {
+	prefix, ok := keyPrefix(ctx)
+	if !ok {
+		return r.next.FindByIDs(ctx, ids)
+	}
+	var out []Domain.Task
+	var missing []primitive.ObjectID
+	for _, id := range ids {
+		if v, ok := r.cache.get(prefix + id.Hex()); ok {
+			out = append(out, v.(Domain.Task))
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return out, nil
+	}
+	gen := r.cache.generation()
+	fetched, err := r.next.FindByIDs(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range fetched {
+		r.cache.put(gen, prefix+t.ID.Hex(), t)
+	}
+	return append(out, fetched...), nil
+}
+
+// FindChildren caches each parent's subtasks
+func (r *cachedTaskRepo) FindChildren(ctx context.Context, parentIDs []primitive.ObjectID) ([]Domain.Task, error) {
+	return r.findRelated(ctx, "children:", parentIDs, r.next.FindChildren, func(t Domain.Task) []primitive.ObjectID {
+		return []primitive.ObjectID{*t.ParentID}
+	})
+}
+
+// FindDependents caches the tasks each blocker blocks
+func (r *cachedTaskRepo) FindDependents(ctx context.Context, blockerIDs []primitive.ObjectID) ([]Domain.Task, error) {
+	return r.findRelated(ctx, "dependents:", blockerIDs, r.next.FindDependents, func(t Domain.Task) []primitive.ObjectID {
+		return t.BlockedBy
+	})
+}
+
+// findRelated caches the tasks related to each id under kind, empty lists
+// included, and fetches the ids it doesn't have in one call. relatedTo lists
+// the ids a fetched task was found through.
+func (r *cachedTaskRepo) findRelated(ctx context.Context, kind string, ids []primitive.ObjectID,
+	fetch func(context.Context, []primitive.ObjectID) ([]Domain.Task, error), relatedTo func(Domain.Task) []primitive.ObjectID) ([]Domain.Task, error) {
+	prefix, ok := keyPrefix(ctx)
+	if !ok {
+		return fetch(ctx, ids)
+	}
+	var out []Domain.Task
+	var missing []primitive.ObjectID
+	seen := make(map[primitive.ObjectID]bool)
+	for _, id := range ids {
+		v, ok := r.cache.get(prefix + kind + id.Hex())
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		// a task related to several ids is listed once, as the store does
+		for _, t := range v.([]Domain.Task) {
+			if !seen[t.ID] {
+				seen[t.ID] = true
+				out = append(out, t)
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return out, nil
+	}
+	gen := r.cache.generation()
+	fetched, err := fetch(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID][]Domain.Task, len(missing))
+	for _, t := range fetched {
+		for _, id := range relatedTo(t) {
+			byID[id] = append(byID[id], t)
+		}
+		if !seen[t.ID] {
+			seen[t.ID] = true
+			out = append(out, t)
+		}
+	}
+	for _, id := range missing {
+		r.cache.put(gen, prefix+kind+id.Hex(), copyTasks(byID[id]))
+	}
+	return out, nil
+}
+
+// FindOccurrences is only used when generating and editing recurring
+// tasks, so it isn't cached
+func (r *cachedTaskRepo) FindOccurrences(ctx context.Context, recurrenceID primitive.ObjectID, after time.Time) ([]Domain.Task, error) {
+	return r.next.FindOccurrences(ctx, recurrenceID, after)
+}
+
+func (r *cachedTaskRepo) Create(ctx context.Context, t Domain.Task) (Domain.Task, error) {
+	defer r.cache.Flush()
+	return r.next.Create(ctx, t)
+}
+
+func (r *cachedTaskRepo) Update(ctx context.Context, id primitive.ObjectID, change Domain.TaskChange) (Domain.Task, error) {
+	defer r.cache.Flush()
+	return r.next.Update(ctx, id, change)
+}
+
+func (r *cachedTaskRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	defer r.cache.Flush()
+	return r.next.Delete(ctx, id)
+}
+
+func (r *cachedTaskRepo) RemoveBlocker(ctx context.Context, id primitive.ObjectID) error {
+	defer r.cache.Flush()
+	return r.next.RemoveBlocker(ctx, id)
+}
+
+func (r *cachedTaskRepo) ReplaceLabel(ctx context.Context, from, to string) error {
+	defer r.cache.Flush()
+	return r.next.ReplaceLabel(ctx, from, to)
+}
+
+func (r *cachedTaskRepo) DropLabel(ctx context.Context, name string) error {
+	defer r.cache.Flush()
+	return r.next.DropLabel(ctx, name)
+}
+
+// FindByLabels caches each filter, keyed by its labels and mode
+func (r *cachedTaskRepo) FindByLabels(ctx context.Context, labels []string, all bool) ([]Domain.Task, error) {
+	prefix, ok := keyPrefix(ctx)
+	if !ok {
+		return r.next.FindByLabels(ctx, labels, all)
+	}
+	key := prefix + "labels:" + strconv.FormatBool(all) + ":" + strings.Join(labels, "\x00")
+	if v, ok := r.cache.get(key); ok {
+		return copyTasks(v.([]Domain.Task)), nil
+	}
+	gen := r.cache.generation()
+	out, err := r.next.FindByLabels(ctx, labels, all)
+	if err == nil {
+		r.cache.put(gen, key, copyTasks(out))
+	}
+	return out, err
+}
+
+func copyCounts(counts map[string]int64) map[string]int64 {
+	out := make(map[string]int64, len(counts))
+	for k, v := range counts {
+		out[k] = v
+	}
+	return out
+}
+
+func (r *cachedTaskRepo) CountLabels(ctx context.Context) (map[string]int64, error) {
+	prefix, ok := keyPrefix(ctx)
+	if !ok {
+		return r.next.CountLabels(ctx)
+	}
+	key := prefix + "label_counts"
+	if v, ok := r.cache.get(key); ok {
+		return copyCounts(v.(map[string]int64)), nil
+	}
+	gen := r.cache.generation()
+	out, err := r.next.CountLabels(ctx)
+	if err == nil {
+		r.cache.put(gen, key, copyCounts(out))
+	}
+	return out, err
+}
+
+// BulkWrite flushes even on error, since some operations may have applied
+func (r *cachedTaskRepo) BulkWrite(ctx context.Context, ops []TaskWriteOp, ordered bool) ([]TaskWriteResult, error) {
+	defer r.cache.Flush()
+	return r.next.BulkWrite(ctx, ops, ordered)
+}
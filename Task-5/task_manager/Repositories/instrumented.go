@@ -0,0 +1,181 @@
+package Repositories
+
+import (
+	"context"
+	"time"
+
+	"task_manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Observer is called as a repository call starts. It may return a derived
+// context (e.g. carrying a span) and returns a func to call with the result.
+type Observer func(ctx context.Context, repo, op string) (context.Context, func(error))
+
+// InstrumentTaskRepository wraps r so every call is reported to observe
+func InstrumentTaskRepository(r TaskRepository, observe Observer) TaskRepository {
+	return &instrumentedTaskRepo{next: r, observe: observe}
+}
+
+type instrumentedTaskRepo struct {
+	next    TaskRepository
+	observe Observer
+}
+
+func (r *instrumentedTaskRepo) Create(ctx context.Context, t Domain.Task) (Domain.Task, error) {
+	ctx, done := r.observe(ctx, "task", "create")
+	out, err := r.next.Create(ctx, t)
+	done(err)
+	return out, err
+}
+
+func (r *instrumentedTaskRepo) FindAll(ctx context.Context) ([]Domain.Task, error) {
+	ctx, done := r.observe(ctx, "task", "find_all")
+	out, err := r.next.FindAll(ctx)
+	done(err)
+	return out, err
+}
+
+func (r *instrumentedTaskRepo) FindByID(ctx context.Context, id primitive.ObjectID) (Domain.Task, error) {
+	ctx, done := r.observe(ctx, "task", "find_by_id")
+	out, err := r.next.FindByID(ctx, id)
+	done(err)
+	return out, err
+}
+
+func (r *instrumentedTaskRepo) Update(ctx context.Context, id primitive.ObjectID, change Domain.TaskChange) (Domain.Task, error) {
+	ctx, done := r.observe(ctx, "task", "update")
+	out, err := r.next.Update(ctx, id, change)
+	done(err)
+	return out, err
+}
+
+func (r *instrumentedTaskRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, done := r.observe(ctx, "task", "delete")
+	err := r.next.Delete(ctx, id)
+	done(err)
+	return err
+}
+
+func (r *instrumentedTaskRepo) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]Domain.Task, error) {
+	ctx, done := r.observe(ctx, "task", "find_by_ids")
+	out, err := r.next.FindByIDs(ctx, ids)
+	done(err)
+	return out, err
+}
+
+func (r *instrumentedTaskRepo) FindChildren(ctx context.Context, parentIDs []primitive.ObjectID) ([]Domain.Task, error) {
+	ctx, done := r.observe(ctx, "task", "find_children")
+	out, err := r.next.FindChildren(ctx, parentIDs)
+	done(err)
+	return out, err
+}
+
+func (r *instrumentedTaskRepo) FindByLabels(ctx context.Context, labels []string, all bool) ([]Domain.Task, error) {
+	ctx, done := r.observe(ctx, "task", "find_by_labels")
+	out, err := r.next.FindByLabels(ctx, labels, all)
+	done(err)
+	return out, err
+}
+
+func (r *instrumentedTaskRepo) FindDependents(ctx context.Context, blockerIDs []primitive.ObjectID) ([]Domain.Task, error) {
+	ctx, done := r.observe(ctx, "task", "find_dependents")
+	out, err := r.next.FindDependents(ctx, blockerIDs)
+	done(err)
+	return out, err
+}
+
+func (r *instrumentedTaskRepo) RemoveBlocker(ctx context.Context, id primitive.ObjectID) error {
+	ctx, done := r.observe(ctx, "task", "remove_blocker")
+	err := r.next.RemoveBlocker(ctx, id)
+	done(err)
+	return err
+}
+
+func (r *instrumentedTaskRepo) ReplaceLabel(ctx context.Context, from, to string) error {
+	ctx, done := r.observe(ctx, "task", "replace_label")
+	err := r.next.ReplaceLabel(ctx, from, to)
+	done(err)
+	return err
+}
+
+func (r *instrumentedTaskRepo) DropLabel(ctx context.Context, name string) error {
+	ctx, done := r.observe(ctx, "task", "drop_label")
+	err := r.next.DropLabel(ctx, name)
+	done(err)
+	return err
+}
+
+func (r *instrumentedTaskRepo) CountLabels(ctx context.Context) (map[string]int64, error) {
+	ctx, done := r.observe(ctx, "task", "count_labels")
+	out, err := r.next.CountLabels(ctx)
+	done(err)
+	return out, err
+}
+
+func (r *instrumentedTaskRepo) BulkWrite(ctx context.Context, ops []TaskWriteOp, ordered bool) ([]TaskWriteResult, error) {
+	ctx, done := r.observe(ctx, "task", "bulk_write")
+	out, err := r.next.BulkWrite(ctx, ops, ordered)
+	done(err)
+	return out, err
+}
+
+func (r *instrumentedTaskRepo) FindOccurrences(ctx context.Context, recurrenceID primitive.ObjectID, after time.Time) ([]Domain.Task, error) {
+	ctx, done := r.observe(ctx, "task", "find_occurrences")
+	out, err := r.next.FindOccurrences(ctx, recurrenceID, after)
+	done(err)
+	return out, err
+}
+
+// InstrumentUserRepository wraps r so every call is reported to observe
+func InstrumentUserRepository(r UserRepository, observe Observer) UserRepository {
+	return &instrumentedUserRepo{next: r, observe: observe}
+}
+
+type instrumentedUserRepo struct {
+	next    UserRepository
+	observe Observer
+}
+
+func (r *instrumentedUserRepo) Create(ctx context.Context, u Domain.User) (Domain.User, error) {
+	ctx, done := r.observe(ctx, "user", "create")
+	out, err := r.next.Create(ctx, u)
+	done(err)
+	return out, err
+}
+
+func (r *instrumentedUserRepo) FindByUsername(ctx context.Context, username string) (Domain.User, error) {
+	ctx, done := r.observe(ctx, "user", "find_by_username")
+	out, err := r.next.FindByUsername(ctx, username)
+	done(err)
+	return out, err
+}
+
+func (r *instrumentedUserRepo) UpdateRole(ctx context.Context, username, role string) error {
+	ctx, done := r.observe(ctx, "user", "update_role")
+	err := r.next.UpdateRole(ctx, username, role)
+	done(err)
+	return err
+}
+
+func (r *instrumentedUserRepo) UpdatePassword(ctx context.Context, username, hash string) error {
+	ctx, done := r.observe(ctx, "user", "update_password")
+	err := r.next.UpdatePassword(ctx, username, hash)
+	done(err)
+	return err
+}
+
+func (r *instrumentedUserRepo) FindAll(ctx context.Context) ([]Domain.User, error) {
+	ctx, done := r.observe(ctx, "user", "find_all")
+	out, err := r.next.FindAll(ctx)
+	done(err)
+	return out, err
+}
+
+func (r *instrumentedUserRepo) CountUsers(ctx context.Context) (int64, error) {
+	ctx, done := r.observe(ctx, "user", "count")
+	n, err := r.next.CountUsers(ctx)
+	done(err)
+	return n, err
+}
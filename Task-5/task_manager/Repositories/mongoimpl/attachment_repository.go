@@ -0,0 +1,115 @@
+package mongoimpl
+
+import (
+	"context"
+
+	"task_manager/Domain"
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type attachmentRepo struct {
+	coll *mongo.Collection
+}
+
+func NewAttachmentRepository(client *MongoClient) Repositories.AttachmentRepository {
+	coll := client.Client.Database(client.DBName).Collection("attachments")
+	client.ensureIndexes(coll)
+	return &attachmentRepo{coll: coll}
+}
+
+func (r *attachmentRepo) Create(ctx context.Context, a Domain.Attachment) (Domain.Attachment, error) {
+	ws, ok := Domain.WorkspaceFrom(ctx)
+	if !ok {
+		return Domain.Attachment{}, Domain.ErrNoWorkspace
+	}
+	a.ID = primitive.NewObjectID()
+	a.WorkspaceID = ws
+	if _, err := r.coll.InsertOne(ctx, a); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return Domain.Attachment{}, Domain.ErrAttachmentExists
+		}
+		return Domain.Attachment{}, err
+	}
+	return a, nil
+}
+
+func (r *attachmentRepo) FindByID(ctx context.Context, id primitive.ObjectID) (Domain.Attachment, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *attachmentRepo) FindByHash(ctx context.Context, taskID primitive.ObjectID, sha256 string) (Domain.Attachment, error) {
+	return r.findOne(ctx, bson.M{"task_id": taskID, "sha256": sha256})
+}
+
+func (r *attachmentRepo) findOne(ctx context.Context, filter bson.M) (Domain.Attachment, error) {
+	filter, err := scoped(ctx, filter)
+	if err != nil {
+		return Domain.Attachment{}, err
+	}
+	var a Domain.Attachment
+	if err := r.coll.FindOne(ctx, filter).Decode(&a); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.Attachment{}, Domain.ErrNotFound
+		}
+		return Domain.Attachment{}, err
+	}
+	return a, nil
+}
+
+func (r *attachmentRepo) FindByTask(ctx context.Context, taskID primitive.ObjectID) ([]Domain.Attachment, error) {
+	filter, err := scoped(ctx, bson.M{"task_id": taskID})
+	if err != nil {
+		return nil, err
+	}
+	cur, err := r.coll.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []Domain.Attachment
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *attachmentRepo) CountByHash(ctx context.Context, sha256 string) (int64, error) {
+	filter, err := scoped(ctx, bson.M{"sha256": sha256})
+	if err != nil {
+		return 0, err
+	}
+	return r.coll.CountDocuments(ctx, filter)
+}
+
+func (r *attachmentRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	filter, err := scoped(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	res, err := r.coll.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return Domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *attachmentRepo) DeleteByTask(ctx context.Context, taskID primitive.ObjectID) error {
+	filter, err := scoped(ctx, bson.M{"task_id": taskID})
+	if err != nil {
+		return err
+	}
+	_, err = r.coll.DeleteMany(ctx, filter)
+	return err
+}
+
+func (r *attachmentRepo) OrphanedTasks(ctx context.Context, limit int64) ([]Repositories.TaskRef, error) {
+	return orphanedTasks(ctx, r.coll, limit)
+}
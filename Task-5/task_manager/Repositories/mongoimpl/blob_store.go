@@ -0,0 +1,97 @@
+package mongoimpl
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"task_manager/Domain"
+	"task_manager/Infrastructure"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type gridFSBlobStore struct {
+	db *mongo.Database
+}
+
+// NewGridFSBlobStore keeps blobs in the "blobs" GridFS bucket, one file per
+// key named by the key
+func NewGridFSBlobStore(client *MongoClient) Infrastructure.BlobStore {
+	return &gridFSBlobStore{db: client.Client.Database(client.DBName)}
+}
+
+// bucket returns a bucket for one call with ctx's deadline on writes.
+// Buckets carry their deadlines rather than taking a context, so they aren't
+// shared between requests.
+func (s *gridFSBlobStore) bucket(ctx context.Context) (*gridfs.Bucket, error) {
+	b, err := gridfs.NewBucket(s.db, options.GridFSBucket().SetName("blobs"))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := b.SetWriteDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Put uploads under a fresh file ID. Two concurrent puts of one key leave
+// two copies of the same bytes, which Open and Delete both handle; reusing
+// the key as the ID would instead let a failed put delete the other's chunks.
+func (s *gridFSBlobStore) Put(ctx context.Context, key string, r io.Reader) error {
+	b, err := s.bucket(ctx)
+	if err != nil {
+		return err
+	}
+	n, err := b.GetFilesCollection().CountDocuments(ctx, bson.M{"filename": key}, options.Count().SetLimit(1))
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = b.UploadFromStream(key, r)
+	return err
+}
+
+// Open streams the newest copy of the key. The download outlives the call,
+// so reads get no deadline.
+func (s *gridFSBlobStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	b, err := s.bucket(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := b.OpenDownloadStreamByName(key)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, Domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (s *gridFSBlobStore) Delete(ctx context.Context, key string) error {
+	b, err := s.bucket(ctx)
+	if err != nil {
+		return err
+	}
+	cur, err := b.FindContext(ctx, bson.M{"filename": key})
+	if err != nil {
+		return err
+	}
+	var files []struct {
+		ID interface{} `bson:"_id"`
+	}
+	if err := cur.All(ctx, &files); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := b.DeleteContext(ctx, f.ID); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+			return err
+		}
+	}
+	return nil
+}
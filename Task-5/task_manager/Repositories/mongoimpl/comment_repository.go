@@ -0,0 +1,146 @@
+package mongoimpl
+
+import (
+	"context"
+
+	"task_manager/Domain"
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type commentRepo struct {
+	coll *mongo.Collection
+}
+
+func NewCommentRepository(client *MongoClient) Repositories.CommentRepository {
+	coll := client.Client.Database(client.DBName).Collection("comments")
+	client.ensureIndexes(coll)
+	return &commentRepo{coll: coll}
+}
+
+func (r *commentRepo) Create(ctx context.Context, c Domain.Comment) (Domain.Comment, error) {
+	ws, ok := Domain.WorkspaceFrom(ctx)
+	if !ok {
+		return Domain.Comment{}, Domain.ErrNoWorkspace
+	}
+	c.ID = primitive.NewObjectID()
+	c.WorkspaceID = ws
+	if _, err := r.coll.InsertOne(ctx, c); err != nil {
+		return Domain.Comment{}, err
+	}
+	return c, nil
+}
+
+func (r *commentRepo) FindByID(ctx context.Context, id primitive.ObjectID) (Domain.Comment, error) {
+	filter, err := scoped(ctx, bson.M{"_id": id})
+	if err != nil {
+		return Domain.Comment{}, err
+	}
+	var c Domain.Comment
+	if err := r.coll.FindOne(ctx, filter).Decode(&c); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.Comment{}, Domain.ErrNotFound
+		}
+		return Domain.Comment{}, err
+	}
+	return c, nil
+}
+
+func (r *commentRepo) FindTopLevel(ctx context.Context, taskID, after primitive.ObjectID, limit int64) ([]Domain.Comment, error) {
+	// a null parent_id also matches comments without one, and uses the index
+	filter := bson.M{"task_id": taskID, "parent_id": nil}
+	if !after.IsZero() {
+		filter["_id"] = bson.M{"$gt": after}
+	}
+	return r.find(ctx, filter, options.Find().SetSort(bson.M{"_id": 1}).SetLimit(limit))
+}
+
+func (r *commentRepo) FindReplies(ctx context.Context, taskID primitive.ObjectID, parents []primitive.ObjectID) ([]Domain.Comment, error) {
+	if len(parents) == 0 {
+		return nil, nil
+	}
+	filter := bson.M{"task_id": taskID, "parent_id": bson.M{"$in": parents}}
+	return r.find(ctx, filter, options.Find().SetSort(bson.M{"_id": 1}))
+}
+
+func (r *commentRepo) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]Domain.Comment, error) {
+	filter, err := scoped(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := r.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []Domain.Comment
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *commentRepo) UpdateBody(ctx context.Context, id primitive.ObjectID, expectBody, body string, mentions []string, edit Domain.CommentEdit) (Domain.Comment, error) {
+	filter, err := scoped(ctx, bson.M{"_id": id, "body": expectBody})
+	if err != nil {
+		return Domain.Comment{}, err
+	}
+	set := bson.M{"body": body, "edited_at": edit.EditedAt}
+	update := bson.M{
+		"$set": set,
+		"$push": bson.M{"edits": bson.M{
+			"$each":  []Domain.CommentEdit{edit},
+			"$slice": -Domain.MaxCommentEdits,
+		}},
+	}
+	if len(mentions) > 0 {
+		set["mentions"] = mentions
+	} else {
+		update["$unset"] = bson.M{"mentions": ""}
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var c Domain.Comment
+	if err := r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&c); err != nil {
+		if err == mongo.ErrNoDocuments {
+			delete(filter, "body")
+			if n, cerr := r.coll.CountDocuments(ctx, filter); cerr == nil && n > 0 {
+				return Domain.Comment{}, Domain.ErrPreconditionFailed
+			}
+			return Domain.Comment{}, Domain.ErrNotFound
+		}
+		return Domain.Comment{}, err
+	}
+	return c, nil
+}
+
+func (r *commentRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	filter, err := scoped(ctx, bson.M{"$or": bson.A{bson.M{"_id": id}, bson.M{"parent_id": id}}})
+	if err != nil {
+		return err
+	}
+	res, err := r.coll.DeleteMany(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return Domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *commentRepo) DeleteByTask(ctx context.Context, taskID primitive.ObjectID) error {
+	filter, err := scoped(ctx, bson.M{"task_id": taskID})
+	if err != nil {
+		return err
+	}
+	_, err = r.coll.DeleteMany(ctx, filter)
+	return err
+}
+
+func (r *commentRepo) OrphanedTasks(ctx context.Context, limit int64) ([]Repositories.TaskRef, error) {
+	return orphanedTasks(ctx, r.coll, limit)
+}
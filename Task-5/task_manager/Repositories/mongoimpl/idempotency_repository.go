@@ -0,0 +1,71 @@
+package mongoimpl
+
+import (
+	"context"
+	"time"
+
+	"task_manager/Domain"
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type idempotencyRepo struct {
+	coll *mongo.Collection
+}
+
+// NewIdempotencyRepository stores records in "idempotency_keys"; a TTL index
+// removes them once expires_at passes
+func NewIdempotencyRepository(client *MongoClient) Repositories.IdempotencyRepository {
+	coll := client.Client.Database(client.DBName).Collection("idempotency_keys")
+	client.ensureIndexes(coll)
+	return &idempotencyRepo{coll: coll}
+}
+
+func (r *idempotencyRepo) Reserve(ctx context.Context, rec Domain.IdempotencyRecord) (Domain.IdempotencyRecord, bool, error) {
+	_, err := r.coll.InsertOne(ctx, rec)
+	if err == nil {
+		return rec, true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return Domain.IdempotencyRecord{}, false, err
+	}
+	var existing Domain.IdempotencyRecord
+	err = r.coll.FindOne(ctx, bson.M{"user": rec.User, "key": rec.Key}).Decode(&existing)
+	if err == mongo.ErrNoDocuments {
+		// expired and removed between the insert and the read; try once more
+		if _, err = r.coll.InsertOne(ctx, rec); err == nil {
+			return rec, true, nil
+		}
+	}
+	if err != nil {
+		return Domain.IdempotencyRecord{}, false, err
+	}
+	return existing, false, nil
+}
+
+func (r *idempotencyRepo) TakeOver(ctx context.Context, user, key string, now, lockedUntil time.Time) error {
+	res, err := r.coll.UpdateOne(ctx,
+		bson.M{"user": user, "key": key, "completed": false, "locked_until": bson.M{"$lt": now}},
+		bson.M{"$set": bson.M{"locked_until": lockedUntil}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return Domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *idempotencyRepo) Complete(ctx context.Context, rec Domain.IdempotencyRecord) error {
+	_, err := r.coll.UpdateOne(ctx,
+		bson.M{"user": rec.User, "key": rec.Key, "completed": false},
+		bson.M{"$set": bson.M{"completed": true, "status": rec.Status, "header": rec.Header, "body": rec.Body}})
+	return err
+}
+
+func (r *idempotencyRepo) Delete(ctx context.Context, user, key string) error {
+	_, err := r.coll.DeleteOne(ctx, bson.M{"user": user, "key": key, "completed": false})
+	return err
+}
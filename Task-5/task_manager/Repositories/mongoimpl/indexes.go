@@ -0,0 +1,167 @@
+package mongoimpl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// collectionIndexes lists the indexes each collection needs. The repository
+// constructors create their collection's indexes best-effort on startup;
+// EnsureIndexes creates them all and reports what failed.
+var collectionIndexes = []struct {
+	collection string
+	models     []mongo.IndexModel
+}{
+	{"users", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true)},
+	}},
+	{"tasks", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "parent_id", Value: 1}}},
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "blocked_by", Value: 1}}},
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "labels", Value: 1}}},
+		// one task per occurrence of a recurring task, however many workers
+		// generate it
+		{
+			Keys:    bson.D{{Key: "workspace_id", Value: 1}, {Key: "recurrence_id", Value: 1}, {Key: "occurrence", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"recurrence_id": bson.M{"$exists": true}}),
+		},
+	}},
+	{"recurring_tasks", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "next_run_at", Value: 1}}},
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "created_at", Value: 1}}},
+	}},
+	{"labels", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+	}},
+	{"comments", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "task_id", Value: 1}, {Key: "parent_id", Value: 1}, {Key: "_id", Value: 1}}},
+	}},
+	{"attachments", []mongo.IndexModel{
+		// content is attached to a task once, however many uploads race
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "task_id", Value: 1}, {Key: "sha256", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "sha256", Value: 1}}},
+	}},
+	{"memberships", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "username", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "username", Value: 1}, {Key: "created_at", Value: 1}}},
+	}},
+	{"webhooks", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "active", Value: 1}}},
+	}},
+	{"webhook_deliveries", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	}},
+	{"idempotency_keys", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user", Value: 1}, {Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}},
+	{"rate_limits", []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}},
+}
+
+func indexModels(collection string) []mongo.IndexModel {
+	for _, c := range collectionIndexes {
+		if c.collection == collection {
+			return c.models
+		}
+	}
+	return nil
+}
+
+// ensureIndexes creates coll's indexes, ignoring errors so a missing index
+// never stops the server; EnsureIndexes is the way to see failures
+func (m *MongoClient) ensureIndexes(coll *mongo.Collection) {
+	if m.SkipIndexes {
+		return
+	}
+	if models := indexModels(coll.Name()); len(models) > 0 {
+		_, _ = coll.Indexes().CreateMany(context.Background(), models)
+	}
+}
+
+// indexName is the name Mongo gives an index by default, e.g. "user_1_key_1"
+func indexName(keys bson.D) string {
+	parts := make([]string, 0, 2*len(keys))
+	for _, k := range keys {
+		parts = append(parts, k.Key, fmt.Sprint(k.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+// IndexStatus is one index the repositories need
+type IndexStatus struct {
+	Collection string `json:"collection"`
+	Name       string `json:"name"`
+	Exists     bool   `json:"exists"`
+	// Created is set by EnsureIndexes when it built the index
+	Created bool   `json:"created,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Indexes reports whether each index the repositories need exists
+func Indexes(ctx context.Context, client *MongoClient) ([]IndexStatus, error) {
+	db := client.Client.Database(client.DBName)
+	var out []IndexStatus
+	for _, c := range collectionIndexes {
+		if len(c.models) == 0 {
+			continue
+		}
+		specs, err := db.Collection(c.collection).Indexes().ListSpecifications(ctx)
+		if isNamespaceNotFound(err) {
+			// the collection doesn't exist yet, so it has no indexes
+			specs, err = nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.collection, err)
+		}
+		existing := make(map[string]bool, len(specs))
+		for _, s := range specs {
+			existing[s.Name] = true
+		}
+		for _, m := range c.models {
+			name := indexName(m.Keys.(bson.D))
+			out = append(out, IndexStatus{Collection: c.collection, Name: name, Exists: existing[name]})
+		}
+	}
+	return out, nil
+}
+
+// EnsureIndexes creates every missing index, one at a time so a failure is
+// reported against the index that caused it (for example an existing index
+// with the same keys but other options, or duplicates under a unique index)
+func EnsureIndexes(ctx context.Context, client *MongoClient) ([]IndexStatus, error) {
+	status, err := Indexes(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	db := client.Client.Database(client.DBName)
+	for i, s := range status {
+		if s.Exists {
+			continue
+		}
+		for _, m := range indexModels(s.Collection) {
+			if indexName(m.Keys.(bson.D)) != s.Name {
+				continue
+			}
+			if _, err := db.Collection(s.Collection).Indexes().CreateOne(ctx, m); err != nil {
+				status[i].Error = err.Error()
+			} else {
+				status[i].Exists, status[i].Created = true, true
+			}
+		}
+	}
+	return status, nil
+}
+
+func isNamespaceNotFound(err error) bool {
+	var ce mongo.CommandError
+	return errors.As(err, &ce) && ce.Code == 26
+}
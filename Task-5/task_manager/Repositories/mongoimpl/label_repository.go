@@ -0,0 +1,116 @@
+package mongoimpl
+
+import (
+	"context"
+
+	"task_manager/Domain"
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type labelRepo struct {
+	coll *mongo.Collection
+}
+
+func NewLabelRepository(client *MongoClient) Repositories.LabelRepository {
+	coll := client.Client.Database(client.DBName).Collection("labels")
+	client.ensureIndexes(coll)
+	return &labelRepo{coll: coll}
+}
+
+func (r *labelRepo) Create(ctx context.Context, l Domain.Label) (Domain.Label, error) {
+	ws, ok := Domain.WorkspaceFrom(ctx)
+	if !ok {
+		return Domain.Label{}, Domain.ErrNoWorkspace
+	}
+	l.ID = primitive.NewObjectID()
+	l.WorkspaceID = ws
+	if _, err := r.coll.InsertOne(ctx, l); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return Domain.Label{}, Domain.LabelNameTaken()
+		}
+		return Domain.Label{}, err
+	}
+	return l, nil
+}
+
+func (r *labelRepo) FindAll(ctx context.Context) ([]Domain.Label, error) {
+	return r.find(ctx, bson.M{})
+}
+
+func (r *labelRepo) FindByKeys(ctx context.Context, keys []string) ([]Domain.Label, error) {
+	return r.find(ctx, bson.M{"key": bson.M{"$in": keys}})
+}
+
+func (r *labelRepo) find(ctx context.Context, filter bson.M) ([]Domain.Label, error) {
+	filter, err := scoped(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := r.coll.Find(ctx, filter, options.Find().SetSort(bson.M{"key": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []Domain.Label
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *labelRepo) FindByID(ctx context.Context, id primitive.ObjectID) (Domain.Label, error) {
+	filter, err := scoped(ctx, bson.M{"_id": id})
+	if err != nil {
+		return Domain.Label{}, err
+	}
+	var l Domain.Label
+	if err := r.coll.FindOne(ctx, filter).Decode(&l); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.Label{}, Domain.ErrNotFound
+		}
+		return Domain.Label{}, err
+	}
+	return l, nil
+}
+
+func (r *labelRepo) Update(ctx context.Context, id primitive.ObjectID, set map[string]interface{}) (Domain.Label, error) {
+	if len(set) == 0 {
+		return r.FindByID(ctx, id)
+	}
+	filter, err := scoped(ctx, bson.M{"_id": id})
+	if err != nil {
+		return Domain.Label{}, err
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var l Domain.Label
+	if err := r.coll.FindOneAndUpdate(ctx, filter, bson.M{"$set": set}, opts).Decode(&l); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.Label{}, Domain.ErrNotFound
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return Domain.Label{}, Domain.LabelNameTaken()
+		}
+		return Domain.Label{}, err
+	}
+	return l, nil
+}
+
+func (r *labelRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	filter, err := scoped(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	res, err := r.coll.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return Domain.ErrNotFound
+	}
+	return nil
+}
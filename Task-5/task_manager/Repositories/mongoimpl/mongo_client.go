@@ -12,6 +12,9 @@ import (
 type MongoClient struct {
 	Client *mongo.Client
 	DBName string
+	// SkipIndexes stops repository constructors from creating indexes, for
+	// tools that must not write unless asked to
+	SkipIndexes bool
 }
 
 func NewMongoClient(uri, dbName string) (*MongoClient, error) {
@@ -39,3 +42,8 @@ func (m *MongoClient) Close() error {
 	defer cancel()
 	return m.Client.Disconnect(ctx)
 }
+
+// Ping checks the server is reachable; used by the readiness probe
+func (m *MongoClient) Ping(ctx context.Context) error {
+	return m.Client.Ping(ctx, nil)
+}
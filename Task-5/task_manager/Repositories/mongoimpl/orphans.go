@@ -0,0 +1,36 @@
+package mongoimpl
+
+import (
+	"context"
+
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// orphanedTasks lists up to limit task ids, across workspaces, that coll's
+// documents refer to in task_id but the tasks collection lacks
+func orphanedTasks(ctx context.Context, coll *mongo.Collection, limit int64) ([]Repositories.TaskRef, error) {
+	cur, err := coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": bson.M{"workspace_id": "$workspace_id", "task_id": "$task_id"}}}},
+		{{Key: "$lookup", Value: bson.M{"from": "tasks", "localField": "_id.task_id", "foreignField": "_id", "as": "task"}}},
+		{{Key: "$match", Value: bson.M{"task": bson.M{"$size": 0}}}},
+		{{Key: "$limit", Value: limit}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var rows []struct {
+		Ref Repositories.TaskRef `bson:"_id"`
+	}
+	if err := cur.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	out := make([]Repositories.TaskRef, len(rows))
+	for i, row := range rows {
+		out[i] = row.Ref
+	}
+	return out, nil
+}
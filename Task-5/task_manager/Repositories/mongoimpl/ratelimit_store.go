@@ -0,0 +1,63 @@
+package mongoimpl
+
+import (
+	"context"
+	"time"
+
+	"task_manager/Infrastructure"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type rateLimitStore struct {
+	coll *mongo.Collection
+}
+
+// NewRateLimitStore keeps token buckets in "rate_limits" so every replica
+// shares them; idle buckets are removed by a TTL index
+func NewRateLimitStore(client *MongoClient) Infrastructure.RateLimitStore {
+	coll := client.Client.Database(client.DBName).Collection("rate_limits")
+	client.ensureIndexes(coll)
+	return &rateLimitStore{coll: coll}
+}
+
+type rateLimitBucket struct {
+	Tokens  float64 `bson:"tokens"`
+	Allowed bool    `bson:"allowed"`
+}
+
+// Take refills and takes from the bucket in a single pipeline update, so
+// concurrent requests on different replicas can't overspend it. Time is the
+// server's $$NOW rather than the caller's: replicas' clocks drift apart, and a bucket
+// refilled by several of them would gain or lose tokens by the skew.
+func (s *rateLimitStore) Take(ctx context.Context, key string, p Infrastructure.RateLimitPolicy, _ time.Time) (Infrastructure.RateLimitResult, error) {
+	limit := float64(p.Limit)
+	perMilli := limit / float64(p.Period.Milliseconds())
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"tokens": bson.M{"$min": bson.A{limit, bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$tokens", limit}},
+				bson.M{"$multiply": bson.A{bson.M{"$subtract": bson.A{"$$NOW", bson.M{"$ifNull": bson.A{"$updated", "$$NOW"}}}}, perMilli}},
+			}}}},
+			"updated": "$$NOW",
+		}}},
+		{{Key: "$set", Value: bson.M{"allowed": bson.M{"$gte": bson.A{"$tokens", 1}}}}},
+		{{Key: "$set", Value: bson.M{
+			"tokens":     bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+			"expires_at": bson.M{"$add": bson.A{"$$NOW", p.Period.Milliseconds()}},
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var b rateLimitBucket
+	err := s.coll.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&b)
+	if mongo.IsDuplicateKeyError(err) {
+		// two first requests raced to create the bucket; the loser retries
+		err = s.coll.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&b)
+	}
+	if err != nil {
+		return Infrastructure.RateLimitResult{}, err
+	}
+	return Infrastructure.BucketResult(p, b.Tokens, b.Allowed), nil
+}
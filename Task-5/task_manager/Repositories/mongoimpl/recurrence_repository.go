@@ -0,0 +1,201 @@
+package mongoimpl
+
+import (
+	"context"
+	"time"
+
+	"task_manager/Domain"
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type recurrenceRepo struct {
+	coll *mongo.Collection
+}
+
+func NewRecurrenceRepository(client *MongoClient) Repositories.RecurrenceRepository {
+	coll := client.Client.Database(client.DBName).Collection("recurring_tasks")
+	client.ensureIndexes(coll)
+	return &recurrenceRepo{coll: coll}
+}
+
+func (r *recurrenceRepo) Create(ctx context.Context, rt Domain.RecurringTask) (Domain.RecurringTask, error) {
+	ws, ok := Domain.WorkspaceFrom(ctx)
+	if !ok {
+		return Domain.RecurringTask{}, Domain.ErrNoWorkspace
+	}
+	rt.ID = primitive.NewObjectID()
+	rt.WorkspaceID = ws
+	if _, err := r.coll.InsertOne(ctx, rt); err != nil {
+		return Domain.RecurringTask{}, err
+	}
+	return rt, nil
+}
+
+func (r *recurrenceRepo) FindAll(ctx context.Context) ([]Domain.RecurringTask, error) {
+	filter, err := scoped(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	cur, err := r.coll.Find(ctx, filter, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []Domain.RecurringTask
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *recurrenceRepo) FindByID(ctx context.Context, id primitive.ObjectID) (Domain.RecurringTask, error) {
+	filter, err := scoped(ctx, bson.M{"_id": id})
+	if err != nil {
+		return Domain.RecurringTask{}, err
+	}
+	var rt Domain.RecurringTask
+	if err := r.coll.FindOne(ctx, filter).Decode(&rt); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.RecurringTask{}, Domain.ErrNotFound
+		}
+		return Domain.RecurringTask{}, err
+	}
+	return rt, nil
+}
+
+// Update leaves the lease alone: a worker holding it finds its progress
+// discarded by the version check and runs again for the edit
+func (r *recurrenceRepo) Update(ctx context.Context, rt Domain.RecurringTask) (Domain.RecurringTask, error) {
+	filter, err := scoped(ctx, bson.M{"_id": rt.ID, "version": rt.Version})
+	if err != nil {
+		return Domain.RecurringTask{}, err
+	}
+	set := bson.M{
+		"title":      rt.Title,
+		"estimate":   rt.Estimate,
+		"rrule":      rt.RRule,
+		"start":      rt.Start,
+		"updated_at": rt.UpdatedAt,
+	}
+	unset := bson.M{}
+	optional := map[string]interface{}{
+		"description":       rt.Description,
+		"labels":            rt.Labels,
+		"generated_through": rt.GeneratedThrough,
+		"next_occurrence":   rt.NextOccurrence,
+		"last_error":        rt.LastError,
+		"next_run_at":       rt.NextRunAt,
+		"sync_after":        rt.SyncAfter,
+	}
+	for field, val := range optional {
+		switch v := val.(type) {
+		case string:
+			if v == "" {
+				unset[field] = ""
+				continue
+			}
+		case []string:
+			if len(v) == 0 {
+				unset[field] = ""
+				continue
+			}
+		case *time.Time:
+			if v == nil {
+				unset[field] = ""
+				continue
+			}
+		}
+		set[field] = val
+	}
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	var updated Domain.RecurringTask
+	err = r.coll.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		if _, err := r.FindByID(ctx, rt.ID); err != nil {
+			return Domain.RecurringTask{}, err
+		}
+		return Domain.RecurringTask{}, Domain.ErrPreconditionFailed
+	}
+	if err != nil {
+		return Domain.RecurringTask{}, err
+	}
+	return updated, nil
+}
+
+func (r *recurrenceRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	filter, err := scoped(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	res, err := r.coll.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return Domain.ErrNotFound
+	}
+	return nil
+}
+
+// ClaimDue spans every workspace; the claimed template says which it
+// belongs to
+func (r *recurrenceRepo) ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (Domain.RecurringTask, error) {
+	filter := bson.M{"next_run_at": bson.M{"$lte": now}, "lease_until": bson.M{"$lte": now}}
+	update := bson.M{"$set": bson.M{"lease_until": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_run_at", Value: 1}}).
+		SetReturnDocument(options.After)
+	var rt Domain.RecurringTask
+	if err := r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&rt); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.RecurringTask{}, Domain.ErrNotFound
+		}
+		return Domain.RecurringTask{}, err
+	}
+	return rt, nil
+}
+
+func (r *recurrenceRepo) SaveProgress(ctx context.Context, rt Domain.RecurringTask) error {
+	progress := bson.M{"$set": bson.M{"lease_until": time.Time{}}}
+	set := progress["$set"].(bson.M)
+	unset := bson.M{}
+	if rt.GeneratedThrough != nil {
+		set["generated_through"] = rt.GeneratedThrough
+	}
+	if rt.NextOccurrence != nil {
+		set["next_occurrence"] = rt.NextOccurrence
+	} else {
+		unset["next_occurrence"] = ""
+	}
+	if rt.NextRunAt != nil {
+		set["next_run_at"] = rt.NextRunAt
+	} else {
+		unset["next_run_at"] = ""
+	}
+	if rt.LastError != "" {
+		set["last_error"] = rt.LastError
+	} else {
+		unset["last_error"] = ""
+	}
+	if rt.SyncAfter == nil {
+		unset["sync_after"] = ""
+	}
+	if len(unset) > 0 {
+		progress["$unset"] = unset
+	}
+	res, err := r.coll.UpdateOne(ctx, bson.M{"_id": rt.ID, "version": rt.Version}, progress)
+	if err != nil || res.MatchedCount > 0 {
+		return err
+	}
+	// edited meanwhile: only release the lease so the edit is picked up
+	_, err = r.coll.UpdateOne(ctx, bson.M{"_id": rt.ID}, bson.M{"$set": bson.M{"lease_until": time.Time{}}})
+	return err
+}
@@ -3,6 +3,7 @@ package mongoimpl
 import (
 	"context"
 	"errors"
+	"time"
 
 	"task_manager/Domain"
 	"task_manager/Repositories"
@@ -19,21 +20,35 @@ type taskRepo struct {
 
 func NewTaskRepository(client *MongoClient) Repositories.TaskRepository {
 	coll := client.Client.Database(client.DBName).Collection("tasks")
+	client.ensureIndexes(coll)
 	return &taskRepo{coll: coll}
 }
 
 func (r *taskRepo) Create(ctx context.Context, t Domain.Task) (Domain.Task, error) {
+	ws, ok := Domain.WorkspaceFrom(ctx)
+	if !ok {
+		return Domain.Task{}, Domain.ErrNoWorkspace
+	}
+	t.WorkspaceID = ws
 	res, err := r.coll.InsertOne(ctx, t)
 	if err != nil {
+		// the only unique index is on generated occurrences
+		if mongo.IsDuplicateKeyError(err) {
+			return Domain.Task{}, Domain.ErrOccurrenceExists
+		}
 		return Domain.Task{}, err
 	}
 	var created Domain.Task
-	err = r.coll.FindOne(ctx, bson.M{"_id": res.InsertedID}).Decode(&created)
+	err = r.coll.FindOne(ctx, bson.M{"_id": res.InsertedID, "workspace_id": ws}).Decode(&created)
 	return created, err
 }
 
 func (r *taskRepo) FindAll(ctx context.Context) ([]Domain.Task, error) {
-	cur, err := r.coll.Find(ctx, bson.M{})
+	filter, err := scoped(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	cur, err := r.coll.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -46,33 +61,379 @@ func (r *taskRepo) FindAll(ctx context.Context) ([]Domain.Task, error) {
 }
 
 func (r *taskRepo) FindByID(ctx context.Context, id primitive.ObjectID) (Domain.Task, error) {
+	filter, err := scoped(ctx, bson.M{"_id": id})
+	if err != nil {
+		return Domain.Task{}, err
+	}
 	var t Domain.Task
-	if err := r.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&t); err != nil {
+	if err := r.coll.FindOne(ctx, filter).Decode(&t); err != nil {
 		if err == mongo.ErrNoDocuments {
-			return Domain.Task{}, errors.New("not found")
+			return Domain.Task{}, Domain.ErrNotFound
 		}
 		return Domain.Task{}, err
 	}
 	return t, nil
 }
 
-func (r *taskRepo) Update(ctx context.Context, id primitive.ObjectID, patch map[string]interface{}) (Domain.Task, error) {
-	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	res := r.coll.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": patch}, opts)
+func (r *taskRepo) Update(ctx context.Context, id primitive.ObjectID, change Domain.TaskChange) (Domain.Task, error) {
+	filter, err := scoped(ctx, changeFilter(id, change))
+	if err != nil {
+		return Domain.Task{}, err
+	}
 	var updated Domain.Task
-	if err := res.Decode(&updated); err != nil {
+	if update := changeUpdate(change); update != nil {
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err = r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated)
+	} else {
+		err = r.coll.FindOne(ctx, filter).Decode(&updated)
+	}
+	if err == mongo.ErrNoDocuments {
+		if len(change.Expect) > 0 {
+			if n, cerr := r.coll.CountDocuments(ctx, bson.M{"_id": id, "workspace_id": filter["workspace_id"]}); cerr == nil && n > 0 {
+				return Domain.Task{}, Domain.ErrPreconditionFailed
+			}
+		}
+		return Domain.Task{}, Domain.ErrNotFound
+	}
+	if err != nil {
 		return Domain.Task{}, err
 	}
 	return updated, nil
 }
 
+// scoped restricts filter to the workspace in ctx. Every query on tenant data
+// goes through it, so a missing workspace fails instead of reading across
+// tenants.
+func scoped(ctx context.Context, filter bson.M) (bson.M, error) {
+	ws, ok := Domain.WorkspaceFrom(ctx)
+	if !ok {
+		return nil, Domain.ErrNoWorkspace
+	}
+	filter["workspace_id"] = ws
+	return filter, nil
+}
+
+// changeFilter matches the task and, for conditional changes, its expected values
+func changeFilter(id primitive.ObjectID, change Domain.TaskChange) bson.M {
+	filter := bson.M{"_id": id}
+	for k, v := range change.Expect {
+		if v == nil {
+			filter[k] = bson.M{"$exists": false}
+		} else {
+			filter[k] = v
+		}
+	}
+	return filter
+}
+
+// changeUpdate builds the $set/$unset document, or nil when there is nothing to write
+func changeUpdate(change Domain.TaskChange) bson.M {
+	update := bson.M{}
+	if len(change.Set) > 0 {
+		update["$set"] = change.Set
+	}
+	if len(change.Unset) > 0 {
+		unset := bson.M{}
+		for _, k := range change.Unset {
+			unset[k] = ""
+		}
+		update["$unset"] = unset
+	}
+	if len(update) == 0 {
+		return nil
+	}
+	return update
+}
+
 func (r *taskRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
-	res, err := r.coll.DeleteOne(ctx, bson.M{"_id": id})
+	filter, err := scoped(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	res, err := r.coll.DeleteOne(ctx, filter)
 	if err != nil {
 		return err
 	}
 	if res.DeletedCount == 0 {
-		return errors.New("not found")
+		return Domain.ErrNotFound
 	}
 	return nil
 }
+
+func (r *taskRepo) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]Domain.Task, error) {
+	filter, err := scoped(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	cur, err := r.coll.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []Domain.Task
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *taskRepo) FindChildren(ctx context.Context, parentIDs []primitive.ObjectID) ([]Domain.Task, error) {
+	filter, err := scoped(ctx, bson.M{"parent_id": bson.M{"$in": parentIDs}})
+	if err != nil {
+		return nil, err
+	}
+	cur, err := r.coll.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []Domain.Task
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FindByLabels uses the {workspace_id, labels} index
+func (r *taskRepo) FindByLabels(ctx context.Context, labels []string, all bool) ([]Domain.Task, error) {
+	op := "$in"
+	if all {
+		op = "$all"
+	}
+	filter, err := scoped(ctx, bson.M{"labels": bson.M{op: labels}})
+	if err != nil {
+		return nil, err
+	}
+	cur, err := r.coll.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []Domain.Task
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *taskRepo) FindDependents(ctx context.Context, blockerIDs []primitive.ObjectID) ([]Domain.Task, error) {
+	filter, err := scoped(ctx, bson.M{"blocked_by": bson.M{"$in": blockerIDs}})
+	if err != nil {
+		return nil, err
+	}
+	cur, err := r.coll.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []Domain.Task
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *taskRepo) FindOccurrences(ctx context.Context, recurrenceID primitive.ObjectID, after time.Time) ([]Domain.Task, error) {
+	filter, err := scoped(ctx, bson.M{"recurrence_id": recurrenceID, "occurrence": bson.M{"$gt": after}})
+	if err != nil {
+		return nil, err
+	}
+	cur, err := r.coll.Find(ctx, filter, options.Find().SetSort(bson.M{"occurrence": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []Domain.Task
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RemoveBlocker also stamps updated_at on every task it changes. A list left
+// empty is removed, as the usecase never stores an empty one.
+func (r *taskRepo) RemoveBlocker(ctx context.Context, id primitive.ObjectID) error {
+	filter, err := scoped(ctx, bson.M{"blocked_by": id})
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	if _, err := r.coll.UpdateMany(ctx, filter, bson.M{"$pull": bson.M{"blocked_by": id}, "$set": bson.M{"updated_at": now}}); err != nil {
+		return err
+	}
+	empty, _ := scoped(ctx, bson.M{"blocked_by": bson.M{"$size": 0}})
+	_, err = r.coll.UpdateMany(ctx, empty, bson.M{"$unset": bson.M{"blocked_by": ""}})
+	return err
+}
+
+// ReplaceLabel rewrites each task's labels in a single update, so a task is
+// never seen with both labels or neither; it stamps updated_at too
+func (r *taskRepo) ReplaceLabel(ctx context.Context, from, to string) error {
+	filter, err := scoped(ctx, bson.M{"labels": from})
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	// swap from for to in place, skipping a to already kept
+	relabel := bson.M{"$reduce": bson.M{
+		"input":        "$labels",
+		"initialValue": bson.A{},
+		"in": bson.M{"$let": bson.M{
+			"vars": bson.M{"l": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$$this", from}}, to, "$$this"}}},
+			"in": bson.M{"$cond": bson.A{
+				bson.M{"$in": bson.A{"$$l", "$$value"}},
+				"$$value",
+				bson.M{"$concatArrays": bson.A{"$$value", bson.A{"$$l"}}},
+			}},
+		}},
+	}}
+	pipeline := mongo.Pipeline{{{Key: "$set", Value: bson.M{"labels": relabel, "updated_at": now}}}}
+	_, err = r.coll.UpdateMany(ctx, filter, pipeline)
+	return err
+}
+
+// DropLabel stamps updated_at on every task it changes and, like
+// RemoveBlocker, removes a list left empty
+func (r *taskRepo) DropLabel(ctx context.Context, name string) error {
+	filter, err := scoped(ctx, bson.M{"labels": name})
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	if _, err := r.coll.UpdateMany(ctx, filter, bson.M{"$pull": bson.M{"labels": name}, "$set": bson.M{"updated_at": now}}); err != nil {
+		return err
+	}
+	empty, _ := scoped(ctx, bson.M{"labels": bson.M{"$size": 0}})
+	_, err = r.coll.UpdateMany(ctx, empty, bson.M{"$unset": bson.M{"labels": ""}})
+	return err
+}
+
+func (r *taskRepo) CountLabels(ctx context.Context) (map[string]int64, error) {
+	filter, err := scoped(ctx, bson.M{"labels": bson.M{"$exists": true}})
+	if err != nil {
+		return nil, err
+	}
+	cur, err := r.coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$unwind", Value: "$labels"}},
+		{{Key: "$group", Value: bson.M{"_id": "$labels", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var rows []struct {
+		Label string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	if err := cur.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Label] = row.Count
+	}
+	return counts, nil
+}
+
+// BulkWrite sends the batch in one round trip. Updates and deletes of missing
+// ids are reported as "not found" up front since the bulk result only carries
+// aggregate counts.
+func (r *taskRepo) BulkWrite(ctx context.Context, ops []Repositories.TaskWriteOp, ordered bool) ([]Repositories.TaskWriteResult, error) {
+	ws, ok := Domain.WorkspaceFrom(ctx)
+	if !ok {
+		return nil, Domain.ErrNoWorkspace
+	}
+	results := make([]Repositories.TaskWriteResult, len(ops))
+	var lookup []primitive.ObjectID
+	for _, op := range ops {
+		if op.Kind != "create" {
+			lookup = append(lookup, op.ID)
+		}
+	}
+	exists := map[primitive.ObjectID]bool{}
+	if len(lookup) > 0 {
+		cur, err := r.coll.Find(ctx, bson.M{"_id": bson.M{"$in": lookup}, "workspace_id": ws}, options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			return nil, err
+		}
+		var docs []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cur.All(ctx, &docs); err != nil {
+			return nil, err
+		}
+		for _, d := range docs {
+			exists[d.ID] = true
+		}
+	}
+
+	var models []mongo.WriteModel
+	var modelOp []int // model index -> op index
+	stopAt := len(ops)
+	for i, op := range ops {
+		switch op.Kind {
+		case "create":
+			t := op.Task
+			t.ID = primitive.NewObjectID()
+			t.WorkspaceID = ws
+			results[i].ID = t.ID
+			models = append(models, mongo.NewInsertOneModel().SetDocument(t))
+		case "update", "delete":
+			results[i].ID = op.ID
+			if !exists[op.ID] {
+				results[i].Err = Domain.ErrNotFound
+				break
+			}
+			if op.Kind == "update" {
+				update := changeUpdate(op.Change)
+				if update == nil {
+					continue // nothing to write; the op still succeeds
+				}
+				filter := changeFilter(op.ID, op.Change)
+				filter["workspace_id"] = ws
+				models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update))
+			} else {
+				delete(exists, op.ID)
+				models = append(models, mongo.NewDeleteOneModel().SetFilter(bson.M{"_id": op.ID, "workspace_id": ws}))
+			}
+		default:
+			results[i].Err = errors.New("unknown operation " + op.Kind)
+		}
+		if results[i].Err != nil {
+			if ordered {
+				stopAt = i
+				break
+			}
+			continue
+		}
+		modelOp = append(modelOp, i)
+	}
+	for i := stopAt + 1; i < len(ops); i++ {
+		results[i].Skipped = true
+	}
+
+	if len(models) == 0 {
+		return results, nil
+	}
+	_, err := r.coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(ordered))
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) {
+		for _, we := range bwe.WriteErrors {
+			results[modelOp[we.Index]].Err = errors.New(we.Message)
+			if ordered {
+				for _, j := range modelOp[we.Index+1:] {
+					results[j].Skipped = true
+				}
+			}
+		}
+		if bwe.WriteConcernError != nil {
+			return results, err
+		}
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,46 @@
+package mongoimpl
+
+import (
+	"context"
+	"time"
+
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type transactor struct {
+	client    *mongo.Client
+	supported bool
+}
+
+// NewTransactor checks once whether the deployment can run transactions
+// (replica set or sharded cluster); standalone servers cannot
+func NewTransactor(client *MongoClient) Repositories.Transactor {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := client.Client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	supported := err == nil && (hello.SetName != "" || hello.Msg == "isdbgrid")
+	return &transactor{client: client.Client, supported: supported}
+}
+
+func (t *transactor) SupportsTransactions() bool {
+	return t.supported
+}
+
+func (t *transactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	sess, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer sess.EndSession(ctx)
+	_, err = sess.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
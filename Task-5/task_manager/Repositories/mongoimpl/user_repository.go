@@ -2,7 +2,6 @@ package mongoimpl
 
 import (
 	"context"
-	"errors"
 
 	"task_manager/Domain"
 	"task_manager/Repositories"
@@ -18,10 +17,7 @@ type userRepo struct {
 
 func NewUserRepository(client *MongoClient) Repositories.UserRepository {
 	coll := client.Client.Database(client.DBName).Collection("users")
-	_, _ = coll.Indexes().CreateOne(context.Background(), mongo.IndexModel{
-		Keys:    bson.D{{Key: "username", Value: 1}},
-		Options: options.Index().SetUnique(true),
-	})
+	client.ensureIndexes(coll)
 	return &userRepo{coll: coll}
 }
 
@@ -38,7 +34,7 @@ func (r *userRepo) FindByUsername(ctx context.Context, username string) (Domain.
 	var u Domain.User
 	if err := r.coll.FindOne(ctx, bson.M{"username": username}).Decode(&u); err != nil {
 		if err == mongo.ErrNoDocuments {
-			return Domain.User{}, errors.New("not found")
+			return Domain.User{}, Domain.ErrNotFound
 		}
 		return Domain.User{}, err
 	}
@@ -51,7 +47,18 @@ func (r *userRepo) UpdateRole(ctx context.Context, username, role string) error
 		return err
 	}
 	if res.MatchedCount == 0 {
-		return errors.New("not found")
+		return Domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *userRepo) UpdatePassword(ctx context.Context, username, hash string) error {
+	res, err := r.coll.UpdateOne(ctx, bson.M{"username": username}, bson.M{"$set": bson.M{"password": hash}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return Domain.ErrNotFound
 	}
 	return nil
 }
@@ -59,3 +66,16 @@ func (r *userRepo) UpdateRole(ctx context.Context, username, role string) error
 func (r *userRepo) CountUsers(ctx context.Context) (int64, error) {
 	return r.coll.CountDocuments(ctx, bson.M{})
 }
+
+func (r *userRepo) FindAll(ctx context.Context) ([]Domain.User, error) {
+	cur, err := r.coll.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "username", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []Domain.User
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
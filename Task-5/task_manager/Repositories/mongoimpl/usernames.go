@@ -0,0 +1,116 @@
+package mongoimpl
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"task_manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UsernameChange is an account renamed by NormalizeUsernames
+type UsernameChange struct {
+	From string
+	To   string
+	// Collision is set when another account already had the normalized name,
+	// so this one gets a numbered suffix instead
+	Collision bool
+}
+
+// PlanUsernameChanges lists the renames NormalizeUsernames would make,
+// without writing anything
+func PlanUsernameChanges(ctx context.Context, client *MongoClient) ([]UsernameChange, error) {
+	cur, err := client.Client.Database(client.DBName).Collection("users").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var users []Domain.User
+	if err := cur.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return planUsernames(users), nil
+}
+
+// planUsernames gives every account whose username isn't normalized its
+// normalized name. When several accounts share one, an account already
+// spelled that way keeps it, otherwise the oldest does; the others become
+// name-2, name-3 and so on.
+func planUsernames(users []Domain.User) []UsernameChange {
+	sort.Slice(users, func(i, j int) bool { return users[i].ID.Hex() < users[j].ID.Hex() })
+	taken := map[string]bool{}
+	for _, u := range users {
+		if u.Username == Domain.NormalizeUsername(u.Username) {
+			taken[u.Username] = true
+		}
+	}
+	var changes []UsernameChange
+	for _, u := range users {
+		n := Domain.NormalizeUsername(u.Username)
+		if u.Username == n {
+			continue
+		}
+		change := UsernameChange{From: u.Username, To: n}
+		for i := 2; taken[change.To]; i++ {
+			change.To, change.Collision = fmt.Sprintf("%s-%d", n, i), true
+		}
+		taken[change.To] = true
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// NormalizeUsernames lowercases usernames stored before login normalized
+// them, which can't log in otherwise, and renames the memberships, comments
+// and attachments that refer to them. It is safe to run again if
+// interrupted: renamed accounts are normalized and left alone.
+func NormalizeUsernames(ctx context.Context, client *MongoClient) ([]UsernameChange, error) {
+	changes, err := PlanUsernameChanges(ctx, client)
+	if err != nil || len(changes) == 0 {
+		return nil, err
+	}
+	db := client.Client.Database(client.DBName)
+	for i, c := range changes {
+		// references first, so an interrupted run finds the account unrenamed
+		// and finishes the job
+		if err := renameMemberships(ctx, db.Collection("memberships"), c.From, c.To); err != nil {
+			return changes[:i], err
+		}
+		refs := []struct{ coll, field string }{{"comments", "author"}, {"attachments", "uploaded_by"}}
+		for _, ref := range refs {
+			if _, err := db.Collection(ref.coll).UpdateMany(ctx, bson.M{ref.field: c.From}, bson.M{"$set": bson.M{ref.field: c.To}}); err != nil {
+				return changes[:i], err
+			}
+		}
+		if _, err := db.Collection("users").UpdateOne(ctx, bson.M{"username": c.From}, bson.M{"$set": bson.M{"username": c.To}}); err != nil {
+			return changes[:i], err
+		}
+	}
+	return changes, nil
+}
+
+// renameMemberships moves from's memberships to to. A workspace where to is
+// already a member keeps that membership.
+func renameMemberships(ctx context.Context, coll *mongo.Collection, from, to string) error {
+	cur, err := coll.Find(ctx, bson.M{"username": from})
+	if err != nil {
+		return err
+	}
+	var ms []Domain.Membership
+	if err := cur.All(ctx, &ms); err != nil {
+		return err
+	}
+	for _, m := range ms {
+		filter := bson.M{"workspace_id": m.WorkspaceID, "username": from}
+		_, err := coll.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"username": to}})
+		if mongo.IsDuplicateKeyError(err) {
+			_, err = coll.DeleteOne(ctx, filter)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
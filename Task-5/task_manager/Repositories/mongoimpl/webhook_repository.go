@@ -0,0 +1,205 @@
+package mongoimpl
+
+import (
+	"context"
+	"time"
+
+	"task_manager/Domain"
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type webhookRepo struct {
+	coll *mongo.Collection
+}
+
+func NewWebhookRepository(client *MongoClient) Repositories.WebhookRepository {
+	coll := client.Client.Database(client.DBName).Collection("webhooks")
+	client.ensureIndexes(coll)
+	return &webhookRepo{coll: coll}
+}
+
+func (r *webhookRepo) Create(ctx context.Context, w Domain.Webhook) (Domain.Webhook, error) {
+	ws, ok := Domain.WorkspaceFrom(ctx)
+	if !ok {
+		return Domain.Webhook{}, Domain.ErrNoWorkspace
+	}
+	w.ID = primitive.NewObjectID()
+	w.WorkspaceID = ws
+	if _, err := r.coll.InsertOne(ctx, w); err != nil {
+		return Domain.Webhook{}, err
+	}
+	return w, nil
+}
+
+func (r *webhookRepo) FindAll(ctx context.Context) ([]Domain.Webhook, error) {
+	return r.find(ctx, bson.M{})
+}
+
+func (r *webhookRepo) FindActiveForEvent(ctx context.Context, eventType string) ([]Domain.Webhook, error) {
+	return r.find(ctx, bson.M{"active": true, "events": bson.M{"$in": []string{eventType, "*"}}})
+}
+
+func (r *webhookRepo) find(ctx context.Context, filter bson.M) ([]Domain.Webhook, error) {
+	filter, err := scoped(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := r.coll.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []Domain.Webhook
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *webhookRepo) FindByID(ctx context.Context, id primitive.ObjectID) (Domain.Webhook, error) {
+	filter, err := scoped(ctx, bson.M{"_id": id})
+	if err != nil {
+		return Domain.Webhook{}, err
+	}
+	var w Domain.Webhook
+	if err := r.coll.FindOne(ctx, filter).Decode(&w); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.Webhook{}, Domain.ErrNotFound
+		}
+		return Domain.Webhook{}, err
+	}
+	return w, nil
+}
+
+func (r *webhookRepo) Update(ctx context.Context, id primitive.ObjectID, set map[string]interface{}) (Domain.Webhook, error) {
+	if len(set) == 0 {
+		return r.FindByID(ctx, id)
+	}
+	filter, err := scoped(ctx, bson.M{"_id": id})
+	if err != nil {
+		return Domain.Webhook{}, err
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var w Domain.Webhook
+	if err := r.coll.FindOneAndUpdate(ctx, filter, bson.M{"$set": set}, opts).Decode(&w); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.Webhook{}, Domain.ErrNotFound
+		}
+		return Domain.Webhook{}, err
+	}
+	return w, nil
+}
+
+func (r *webhookRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	filter, err := scoped(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	res, err := r.coll.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return Domain.ErrNotFound
+	}
+	return nil
+}
+
+type webhookDeliveryRepo struct {
+	coll *mongo.Collection
+}
+
+func NewWebhookDeliveryRepository(client *MongoClient) Repositories.WebhookDeliveryRepository {
+	coll := client.Client.Database(client.DBName).Collection("webhook_deliveries")
+	client.ensureIndexes(coll)
+	return &webhookDeliveryRepo{coll: coll}
+}
+
+func (r *webhookDeliveryRepo) CreateMany(ctx context.Context, ds []Domain.WebhookDelivery) error {
+	if len(ds) == 0 {
+		return nil
+	}
+	ws, ok := Domain.WorkspaceFrom(ctx)
+	if !ok {
+		return Domain.ErrNoWorkspace
+	}
+	docs := make([]interface{}, len(ds))
+	for i, d := range ds {
+		d.WorkspaceID = ws
+		docs[i] = d
+	}
+	_, err := r.coll.InsertMany(ctx, docs)
+	return err
+}
+
+func (r *webhookDeliveryRepo) FindByID(ctx context.Context, id primitive.ObjectID) (Domain.WebhookDelivery, error) {
+	filter, err := scoped(ctx, bson.M{"_id": id})
+	if err != nil {
+		return Domain.WebhookDelivery{}, err
+	}
+	var d Domain.WebhookDelivery
+	if err := r.coll.FindOne(ctx, filter).Decode(&d); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.WebhookDelivery{}, Domain.ErrNotFound
+		}
+		return Domain.WebhookDelivery{}, err
+	}
+	return d, nil
+}
+
+func (r *webhookDeliveryRepo) FindByWebhook(ctx context.Context, webhookID primitive.ObjectID, limit int64) ([]Domain.WebhookDelivery, error) {
+	filter, err := scoped(ctx, bson.M{"webhook_id": webhookID})
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	cur, err := r.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []Domain.WebhookDelivery
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ClaimDue spans every workspace; the claimed delivery says which it belongs to
+func (r *webhookDeliveryRepo) ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (Domain.WebhookDelivery, error) {
+	filter := bson.M{"$or": []bson.M{
+		{"status": Domain.DeliveryPending, "next_attempt_at": bson.M{"$lte": now}},
+		{"status": Domain.DeliveryInFlight, "lease_until": bson.M{"$lte": now}},
+	}}
+	update := bson.M{"$set": bson.M{"status": Domain.DeliveryInFlight, "lease_until": now.Add(lease), "updated_at": now}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+	var d Domain.WebhookDelivery
+	if err := r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&d); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.WebhookDelivery{}, Domain.ErrNotFound
+		}
+		return Domain.WebhookDelivery{}, err
+	}
+	return d, nil
+}
+
+func (r *webhookDeliveryRepo) Update(ctx context.Context, d Domain.WebhookDelivery) error {
+	_, err := r.coll.ReplaceOne(ctx, bson.M{"_id": d.ID}, d)
+	return err
+}
+
+func (r *webhookDeliveryRepo) DeleteByWebhook(ctx context.Context, webhookID primitive.ObjectID) error {
+	filter, err := scoped(ctx, bson.M{"webhook_id": webhookID})
+	if err != nil {
+		return err
+	}
+	_, err = r.coll.DeleteMany(ctx, filter)
+	return err
+}
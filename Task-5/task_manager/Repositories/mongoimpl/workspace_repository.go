@@ -0,0 +1,194 @@
+package mongoimpl
+
+import (
+	"context"
+	"time"
+
+	"task_manager/Domain"
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type workspaceRepo struct {
+	coll *mongo.Collection
+}
+
+func NewWorkspaceRepository(client *MongoClient) Repositories.WorkspaceRepository {
+	coll := client.Client.Database(client.DBName).Collection("workspaces")
+	return &workspaceRepo{coll: coll}
+}
+
+func (r *workspaceRepo) Create(ctx context.Context, w Domain.Workspace) (Domain.Workspace, error) {
+	w.ID = primitive.NewObjectID()
+	if _, err := r.coll.InsertOne(ctx, w); err != nil {
+		return Domain.Workspace{}, err
+	}
+	return w, nil
+}
+
+func (r *workspaceRepo) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]Domain.Workspace, error) {
+	return r.find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+func (r *workspaceRepo) FindAll(ctx context.Context) ([]Domain.Workspace, error) {
+	return r.find(ctx, bson.M{})
+}
+
+func (r *workspaceRepo) find(ctx context.Context, filter bson.M) ([]Domain.Workspace, error) {
+	cur, err := r.coll.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []Domain.Workspace
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+type membershipRepo struct {
+	coll *mongo.Collection
+}
+
+func NewMembershipRepository(client *MongoClient) Repositories.MembershipRepository {
+	coll := client.Client.Database(client.DBName).Collection("memberships")
+	client.ensureIndexes(coll)
+	return &membershipRepo{coll: coll}
+}
+
+func (r *membershipRepo) Upsert(ctx context.Context, m Domain.Membership) (Domain.Membership, error) {
+	filter := bson.M{"workspace_id": m.WorkspaceID, "username": m.Username}
+	update := bson.M{
+		"$set":         bson.M{"role": m.Role},
+		"$setOnInsert": bson.M{"created_at": m.CreatedAt},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var out Domain.Membership
+	if err := r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&out); err != nil {
+		return Domain.Membership{}, err
+	}
+	return out, nil
+}
+
+func (r *membershipRepo) Find(ctx context.Context, workspaceID primitive.ObjectID, username string) (Domain.Membership, error) {
+	var m Domain.Membership
+	if err := r.coll.FindOne(ctx, bson.M{"workspace_id": workspaceID, "username": username}).Decode(&m); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return Domain.Membership{}, Domain.ErrNotFound
+		}
+		return Domain.Membership{}, err
+	}
+	return m, nil
+}
+
+func (r *membershipRepo) FindByUser(ctx context.Context, username string) ([]Domain.Membership, error) {
+	return r.find(ctx, bson.M{"username": username})
+}
+
+func (r *membershipRepo) FindByWorkspace(ctx context.Context, workspaceID primitive.ObjectID) ([]Domain.Membership, error) {
+	return r.find(ctx, bson.M{"workspace_id": workspaceID})
+}
+
+func (r *membershipRepo) find(ctx context.Context, filter bson.M) ([]Domain.Membership, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := r.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+	var out []Domain.Membership
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *membershipRepo) Delete(ctx context.Context, workspaceID primitive.ObjectID, username string) error {
+	res, err := r.coll.DeleteOne(ctx, bson.M{"workspace_id": workspaceID, "username": username})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return Domain.ErrNotFound
+	}
+	return nil
+}
+
+// scopedCollections hold documents that belong to a workspace
+var scopedCollections = []string{"tasks", "webhooks", "webhook_deliveries"}
+
+var unscoped = bson.M{"workspace_id": bson.M{"$exists": false}}
+
+// CountUnscopedData counts the documents AdoptUnscopedData would move
+func CountUnscopedData(ctx context.Context, client *MongoClient) (int64, error) {
+	db := client.Client.Database(client.DBName)
+	var pending int64
+	for _, name := range scopedCollections {
+		n, err := db.Collection(name).CountDocuments(ctx, unscoped)
+		if err != nil {
+			return 0, err
+		}
+		pending += n
+	}
+	return pending, nil
+}
+
+// AdoptUnscopedData moves data written before workspaces existed into a
+// workspace named "Default": tasks, webhooks and deliveries without a
+// workspace_id get its ID, and every user joins it with their instance role
+// so nobody loses access. It does nothing once no such data is left, and is
+// safe to run again if interrupted.
+func AdoptUnscopedData(ctx context.Context, client *MongoClient) (adopted int64, err error) {
+	pending, err := CountUnscopedData(ctx, client)
+	if err != nil || pending == 0 {
+		return 0, err
+	}
+	db := client.Client.Database(client.DBName)
+
+	// the legacy marker lets a rerun find the same workspace
+	now := time.Now().UTC()
+	var ws struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	err = db.Collection("workspaces").FindOneAndUpdate(ctx,
+		bson.M{"legacy": true},
+		bson.M{"$setOnInsert": bson.M{"name": "Default", "created_at": now}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&ws)
+	if err != nil {
+		return 0, err
+	}
+
+	cur, err := db.Collection("users").Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	var users []Domain.User
+	if err := cur.All(ctx, &users); err != nil {
+		return 0, err
+	}
+	members := NewMembershipRepository(client)
+	for _, u := range users {
+		role := Domain.RoleMember
+		if u.Role == Domain.RoleAdmin {
+			role = Domain.RoleAdmin
+		}
+		if _, err := members.Upsert(ctx, Domain.Membership{WorkspaceID: ws.ID, Username: u.Username, Role: role, CreatedAt: now}); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, name := range scopedCollections {
+		res, err := db.Collection(name).UpdateMany(ctx, unscoped, bson.M{"$set": bson.M{"workspace_id": ws.ID}})
+		if err != nil {
+			return adopted, err
+		}
+		adopted += res.ModifiedCount
+	}
+	return adopted, nil
+}
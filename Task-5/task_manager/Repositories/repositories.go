@@ -2,19 +2,188 @@ package Repositories
 
 import (
 	"context"
+	"time"
 
 	"task_manager/Domain"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// TaskRepository defines task persistence operations
+// TaskRepository defines task persistence operations. Every call is scoped
+// to the workspace in ctx (Domain.WithWorkspace) and fails with
+// Domain.ErrNoWorkspace without one.
 type TaskRepository interface {
 	Create(ctx context.Context, t Domain.Task) (Domain.Task, error)
 	FindAll(ctx context.Context) ([]Domain.Task, error)
 	FindByID(ctx context.Context, id primitive.ObjectID) (Domain.Task, error)
-	Update(ctx context.Context, id primitive.ObjectID, patch map[string]interface{}) (Domain.Task, error)
+	Update(ctx context.Context, id primitive.ObjectID, change Domain.TaskChange) (Domain.Task, error)
 	Delete(ctx context.Context, id primitive.ObjectID) error
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]Domain.Task, error)
+	// FindChildren lists the direct subtasks of every given parent
+	FindChildren(ctx context.Context, parentIDs []primitive.ObjectID) ([]Domain.Task, error)
+	// FindDependents lists the tasks blocked by any of the given tasks
+	FindDependents(ctx context.Context, blockerIDs []primitive.ObjectID) ([]Domain.Task, error)
+	// RemoveBlocker drops id from every task's blocked_by, once id is deleted
+	RemoveBlocker(ctx context.Context, id primitive.ObjectID) error
+	// ReplaceLabel puts label to in place of from on every task carrying
+	// from; a task that already has to keeps a single copy
+	ReplaceLabel(ctx context.Context, from, to string) error
+	// DropLabel takes the label off every task carrying it
+	DropLabel(ctx context.Context, name string) error
+	// FindByLabels lists the tasks carrying any of the labels, or all of
+	// them when all is set
+	FindByLabels(ctx context.Context, labels []string, all bool) ([]Domain.Task, error)
+	// CountLabels counts the tasks carrying each label
+	CountLabels(ctx context.Context) (map[string]int64, error)
+	BulkWrite(ctx context.Context, ops []TaskWriteOp, ordered bool) ([]TaskWriteResult, error)
+	// FindOccurrences lists the tasks generated from a recurring task whose
+	// occurrence is after the given time, earliest first
+	FindOccurrences(ctx context.Context, recurrenceID primitive.ObjectID, after time.Time) ([]Domain.Task, error)
+}
+
+// TaskRef names a task in a workspace
+type TaskRef struct {
+	WorkspaceID primitive.ObjectID `bson:"workspace_id"`
+	TaskID      primitive.ObjectID `bson:"task_id"`
+}
+
+// TaskWriteOp is one create, update or delete in a batch
+type TaskWriteOp struct {
+	Kind   string // "create", "update" or "delete"
+	ID     primitive.ObjectID
+	Task   Domain.Task
+	Change Domain.TaskChange
+}
+
+// TaskWriteResult reports the outcome of the op at the same index. Skipped is
+// set when an ordered batch stopped before reaching the op.
+type TaskWriteResult struct {
+	ID      primitive.ObjectID
+	Err     error
+	Skipped bool
+}
+
+// WebhookRepository stores webhook registrations, scoped to the workspace in
+// ctx like TaskRepository
+type WebhookRepository interface {
+	Create(ctx context.Context, w Domain.Webhook) (Domain.Webhook, error)
+	FindAll(ctx context.Context) ([]Domain.Webhook, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (Domain.Webhook, error)
+	FindActiveForEvent(ctx context.Context, eventType string) ([]Domain.Webhook, error)
+	Update(ctx context.Context, id primitive.ObjectID, set map[string]interface{}) (Domain.Webhook, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// LabelRepository stores the label catalog, scoped to the workspace in ctx
+// like TaskRepository. Writes that would give two labels the same name,
+// ignoring case, fail with Domain.LabelNameTaken.
+type LabelRepository interface {
+	Create(ctx context.Context, l Domain.Label) (Domain.Label, error)
+	// FindAll lists the catalog ordered by name, ignoring case
+	FindAll(ctx context.Context) ([]Domain.Label, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (Domain.Label, error)
+	// FindByKeys loads the labels whose Domain.LabelKey is among keys
+	FindByKeys(ctx context.Context, keys []string) ([]Domain.Label, error)
+	Update(ctx context.Context, id primitive.ObjectID, set map[string]interface{}) (Domain.Label, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// RecurrenceRepository stores recurring task templates, scoped to the
+// workspace in ctx like TaskRepository except for the worker's claims
+type RecurrenceRepository interface {
+	Create(ctx context.Context, r Domain.RecurringTask) (Domain.RecurringTask, error)
+	FindAll(ctx context.Context) ([]Domain.RecurringTask, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (Domain.RecurringTask, error)
+	// Update writes r's definition and progress if its version is still
+	// r.Version, bumping the version; Domain.ErrPreconditionFailed otherwise
+	Update(ctx context.Context, r Domain.RecurringTask) (Domain.RecurringTask, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// ClaimDue leases a template, from any workspace, whose next run is due
+	// and that no other worker holds; Domain.ErrNotFound when none is due
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (Domain.RecurringTask, error)
+	// SaveProgress records a generation run and releases the lease. The
+	// progress is only kept if the template wasn't edited since the claim.
+	SaveProgress(ctx context.Context, r Domain.RecurringTask) error
+}
+
+// CommentRepository stores task comments, scoped to the workspace in ctx like
+// TaskRepository
+type CommentRepository interface {
+	Create(ctx context.Context, c Domain.Comment) (Domain.Comment, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (Domain.Comment, error)
+	// FindTopLevel lists up to limit of the task's comments that aren't
+	// replies, oldest first, starting after the comment with id after (zero
+	// for the first page)
+	FindTopLevel(ctx context.Context, taskID, after primitive.ObjectID, limit int64) ([]Domain.Comment, error)
+	// FindReplies lists the task's replies to any of parents, oldest first
+	FindReplies(ctx context.Context, taskID primitive.ObjectID, parents []primitive.ObjectID) ([]Domain.Comment, error)
+	// UpdateBody replaces the body only if it still reads expectBody, keeping
+	// edit as the newest of at most Domain.MaxCommentEdits earlier versions.
+	// A comment whose body changed meanwhile gives
+	// Domain.ErrPreconditionFailed.
+	UpdateBody(ctx context.Context, id primitive.ObjectID, expectBody, body string, mentions []string, edit Domain.CommentEdit) (Domain.Comment, error)
+	// Delete removes the comment and its replies
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// DeleteByTask removes every comment on the task
+	DeleteByTask(ctx context.Context, taskID primitive.ObjectID) error
+	// OrphanedTasks lists up to limit tasks, in any workspace, that have
+	// comments but no longer exist
+	OrphanedTasks(ctx context.Context, limit int64) ([]TaskRef, error)
+}
+
+// AttachmentRepository stores attachment records, scoped to the workspace in
+// ctx like TaskRepository; the content lives in a blob store
+type AttachmentRepository interface {
+	// Create fails with Domain.ErrAttachmentExists when the task already has
+	// an attachment with the same content hash
+	Create(ctx context.Context, a Domain.Attachment) (Domain.Attachment, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (Domain.Attachment, error)
+	// FindByTask lists the task's attachments, oldest first
+	FindByTask(ctx context.Context, taskID primitive.ObjectID) ([]Domain.Attachment, error)
+	// FindByHash returns the task's attachment with the content hash, or
+	// Domain.ErrNotFound
+	FindByHash(ctx context.Context, taskID primitive.ObjectID, sha256 string) (Domain.Attachment, error)
+	// CountByHash counts the workspace's attachments with the content hash
+	CountByHash(ctx context.Context, sha256 string) (int64, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// DeleteByTask removes every attachment record on the task
+	DeleteByTask(ctx context.Context, taskID primitive.ObjectID) error
+	// OrphanedTasks lists up to limit tasks, in any workspace, that have
+	// attachments but no longer exist
+	OrphanedTasks(ctx context.Context, limit int64) ([]TaskRef, error)
+}
+
+// WebhookDeliveryRepository is the delivery queue and per-webhook log. Calls
+// are scoped to the workspace in ctx, except ClaimDue and Update, which serve
+// the delivery worker across all workspaces.
+type WebhookDeliveryRepository interface {
+	CreateMany(ctx context.Context, ds []Domain.WebhookDelivery) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (Domain.WebhookDelivery, error)
+	FindByWebhook(ctx context.Context, webhookID primitive.ObjectID, limit int64) ([]Domain.WebhookDelivery, error)
+	// ClaimDue leases the oldest due delivery (or one whose lease expired) so
+	// only one worker sends it; returns Domain.ErrNotFound when none is due
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (Domain.WebhookDelivery, error)
+	Update(ctx context.Context, d Domain.WebhookDelivery) error
+	DeleteByWebhook(ctx context.Context, webhookID primitive.ObjectID) error
+}
+
+// IdempotencyRepository stores Idempotency-Key records, unique per user and key
+type IdempotencyRepository interface {
+	// Reserve inserts rec unless the user already has a record for the key,
+	// in which case it returns that record and false
+	Reserve(ctx context.Context, rec Domain.IdempotencyRecord) (Domain.IdempotencyRecord, bool, error)
+	// TakeOver relocks an unfinished record whose lock expired before now;
+	// returns Domain.ErrNotFound if it finished or another request holds it
+	TakeOver(ctx context.Context, user, key string, now, lockedUntil time.Time) error
+	// Complete stores the response for an unfinished record
+	Complete(ctx context.Context, rec Domain.IdempotencyRecord) error
+	Delete(ctx context.Context, user, key string) error
+}
+
+// Transactor runs fn inside a transaction when the backend supports one
+type Transactor interface {
+	SupportsTransactions() bool
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 // UserRepository defines user persistence operations
@@ -22,5 +191,28 @@ type UserRepository interface {
 	Create(ctx context.Context, u Domain.User) (Domain.User, error)
 	FindByUsername(ctx context.Context, username string) (Domain.User, error)
 	UpdateRole(ctx context.Context, username, role string) error
+	// UpdatePassword replaces the stored password hash
+	UpdatePassword(ctx context.Context, username, hash string) error
 	CountUsers(ctx context.Context) (int64, error)
+	// FindAll lists every user, password hashes included, by username
+	FindAll(ctx context.Context) ([]Domain.User, error)
+}
+
+// WorkspaceRepository stores workspaces
+type WorkspaceRepository interface {
+	Create(ctx context.Context, w Domain.Workspace) (Domain.Workspace, error)
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]Domain.Workspace, error)
+	FindAll(ctx context.Context) ([]Domain.Workspace, error)
+}
+
+// MembershipRepository stores each user's role per workspace, unique per
+// workspace and username
+type MembershipRepository interface {
+	// Upsert adds the member or changes their role
+	Upsert(ctx context.Context, m Domain.Membership) (Domain.Membership, error)
+	Find(ctx context.Context, workspaceID primitive.ObjectID, username string) (Domain.Membership, error)
+	// FindByUser lists the user's memberships, oldest first
+	FindByUser(ctx context.Context, username string) ([]Domain.Membership, error)
+	FindByWorkspace(ctx context.Context, workspaceID primitive.ObjectID) ([]Domain.Membership, error)
+	Delete(ctx context.Context, workspaceID primitive.ObjectID, username string) error
 }
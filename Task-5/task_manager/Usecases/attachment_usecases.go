@@ -0,0 +1,350 @@
+package Usecases
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"task_manager/Domain"
+	"task_manager/Infrastructure"
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AttachmentUsecase manages files on tasks. Any member may upload; the
+// uploader or an admin may delete. Downloads go through signed links, so
+// browsers can fetch them without the bearer token.
+type AttachmentUsecase interface {
+	// Upload stores content as an attachment on the task. Content already
+	// attached to the task is not attached twice: the existing attachment
+	// is returned with created false.
+	Upload(ctx context.Context, actor Domain.Session, taskID primitive.ObjectID, filename string, content io.ReadSeeker) (a Domain.Attachment, created bool, err error)
+	List(ctx context.Context, taskID primitive.ObjectID) ([]Domain.Attachment, error)
+	Get(ctx context.Context, taskID, id primitive.ObjectID) (Domain.Attachment, error)
+	Delete(ctx context.Context, actor Domain.Session, taskID, id primitive.ObjectID) error
+	// Link signs a short-lived download token for the attachment
+	Link(ctx context.Context, taskID, id primitive.ObjectID) (Domain.AttachmentLink, error)
+	// Open checks a download token and opens the attachment's content; the
+	// caller closes it
+	Open(ctx context.Context, token string) (Domain.Attachment, io.ReadCloser, error)
+	// MaxSize is the largest upload accepted, in bytes
+	MaxSize() int64
+	// Publish removes the attachments of deleted tasks, so the usecase can be
+	// subscribed to the task usecase's events
+	Publish(e Domain.TaskEvent)
+	// Run removes, until ctx is done, attachments of deleted tasks that
+	// Publish missed
+	Run(ctx context.Context)
+}
+
+// AttachmentOptions limit uploads and downloads
+type AttachmentOptions struct {
+	MaxSize int64
+	// AllowedTypes are media types such as "application/pdf" or "image/*"
+	AllowedTypes []string
+	// LinkTTL is how long a download link works
+	LinkTTL time.Duration
+}
+
+type attachmentUsecase struct {
+	attachments Repositories.AttachmentRepository
+	tasks       Repositories.TaskRepository
+	blobs       Infrastructure.BlobStore
+	signer      Infrastructure.URLSigner
+	opts        AttachmentOptions
+	timeout     time.Duration
+}
+
+func NewAttachmentUsecase(a Repositories.AttachmentRepository, t Repositories.TaskRepository, blobs Infrastructure.BlobStore, signer Infrastructure.URLSigner, opts AttachmentOptions) AttachmentUsecase {
+	return &attachmentUsecase{attachments: a, tasks: t, blobs: blobs, signer: signer, opts: opts, timeout: 5 * time.Second}
+}
+
+func (u *attachmentUsecase) MaxSize() int64 { return u.opts.MaxSize }
+
+// sniff hashes content and detects its media type from the first bytes,
+// failing once it is larger than the limit
+func (u *attachmentUsecase) sniff(content io.Reader) (sum, mediaType string, size int64, err error) {
+	h := sha256.New()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", "", 0, err
+	}
+	head = head[:n]
+	h.Write(head)
+	rest, err := io.Copy(h, io.LimitReader(content, u.opts.MaxSize-int64(n)+1))
+	if err != nil {
+		return "", "", 0, err
+	}
+	size = int64(n) + rest
+	switch {
+	case size == 0:
+		return "", "", 0, Domain.AttachmentError("must not be empty")
+	case size > u.opts.MaxSize:
+		return "", "", 0, Domain.AttachmentError(fmt.Sprintf("must be at most %d bytes", u.opts.MaxSize))
+	}
+	mediaType, _, err = mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		mediaType = "application/octet-stream"
+	}
+	return hex.EncodeToString(h.Sum(nil)), mediaType, size, nil
+}
+
+func (u *attachmentUsecase) Upload(ctx context.Context, actor Domain.Session, taskID primitive.ObjectID, filename string, content io.ReadSeeker) (Domain.Attachment, bool, error) {
+	// the repository calls are bounded; the blob write takes as long as the
+	// file needs
+	lookup, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	if _, err := u.tasks.FindByID(lookup, taskID); err != nil {
+		return Domain.Attachment{}, false, err
+	}
+	sum, mediaType, size, err := u.sniff(content)
+	if err != nil {
+		return Domain.Attachment{}, false, err
+	}
+	if !Domain.MediaTypeAllowed(u.opts.AllowedTypes, mediaType) {
+		return Domain.Attachment{}, false, Domain.AttachmentError(mediaType + " files are not accepted")
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return Domain.Attachment{}, false, err
+	}
+	ws, _ := Domain.WorkspaceFrom(ctx)
+	a := Domain.Attachment{
+		WorkspaceID: ws,
+		TaskID:      taskID,
+		Filename:    Domain.CleanFilename(filename),
+		ContentType: mediaType,
+		Size:        size,
+		SHA256:      sum,
+		UploadedBy:  actor.Username,
+		CreatedAt:   time.Now().UTC(),
+	}
+	// the blob is put even when the content is known, which restores one a
+	// concurrent delete removed
+	if err := u.blobs.Put(ctx, a.BlobKey(), content); err != nil {
+		return Domain.Attachment{}, false, err
+	}
+	lookup, cancel = context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	a, created, err := u.record(lookup, a)
+	if err != nil {
+		return Domain.Attachment{}, false, err
+	}
+	// the task may have been deleted, and its attachments with it, since it
+	// was checked above
+	if _, err := u.tasks.FindByID(lookup, taskID); err != nil {
+		if created && errors.Is(err, Domain.ErrNotFound) {
+			if derr := u.attachments.Delete(lookup, a.ID); derr == nil {
+				_ = u.releaseBlob(lookup, a)
+			}
+		}
+		return Domain.Attachment{}, false, err
+	}
+	// releaseBlob may have deleted the content after the put above but
+	// counted before the record existed; once the record exists, putting
+	// again either finds the content or restores it
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return Domain.Attachment{}, false, err
+	}
+	if err := u.blobs.Put(ctx, a.BlobKey(), content); err != nil {
+		return Domain.Attachment{}, false, err
+	}
+	return a, created, nil
+}
+
+// record creates the attachment, or returns the task's existing one with
+// the same content
+func (u *attachmentUsecase) record(ctx context.Context, a Domain.Attachment) (Domain.Attachment, bool, error) {
+	existing, err := u.attachments.FindByHash(ctx, a.TaskID, a.SHA256)
+	if err == nil {
+		return existing, false, nil
+	}
+	if !errors.Is(err, Domain.ErrNotFound) {
+		return Domain.Attachment{}, false, err
+	}
+	created, err := u.attachments.Create(ctx, a)
+	if errors.Is(err, Domain.ErrAttachmentExists) {
+		// a concurrent upload of the same content got there first
+		existing, err := u.attachments.FindByHash(ctx, a.TaskID, a.SHA256)
+		return existing, false, err
+	}
+	if err != nil {
+		return Domain.Attachment{}, false, err
+	}
+	return created, true, nil
+}
+
+func (u *attachmentUsecase) List(ctx context.Context, taskID primitive.ObjectID) ([]Domain.Attachment, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	if _, err := u.tasks.FindByID(ctx, taskID); err != nil {
+		return nil, err
+	}
+	return u.attachments.FindByTask(ctx, taskID)
+}
+
+func (u *attachmentUsecase) find(ctx context.Context, taskID, id primitive.ObjectID) (Domain.Attachment, error) {
+	a, err := u.attachments.FindByID(ctx, id)
+	if err != nil {
+		return Domain.Attachment{}, err
+	}
+	if a.TaskID != taskID {
+		return Domain.Attachment{}, Domain.ErrNotFound
+	}
+	return a, nil
+}
+
+func (u *attachmentUsecase) Get(ctx context.Context, taskID, id primitive.ObjectID) (Domain.Attachment, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	return u.find(ctx, taskID, id)
+}
+
+func (u *attachmentUsecase) Delete(ctx context.Context, actor Domain.Session, taskID, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	a, err := u.find(ctx, taskID, id)
+	if err != nil {
+		return err
+	}
+	if a.UploadedBy != actor.Username && actor.Role != Domain.RoleAdmin {
+		return Domain.ErrForbidden
+	}
+	if err := u.attachments.Delete(ctx, id); err != nil {
+		return err
+	}
+	return u.releaseBlob(ctx, a)
+}
+
+// releaseBlob deletes the attachment's content once no attachment in the
+// workspace refers to it. An upload can attach the same content between the
+// count and the delete, so it counts again afterwards and puts the content,
+// read before deleting, back if it is in use after all.
+func (u *attachmentUsecase) releaseBlob(ctx context.Context, a Domain.Attachment) error {
+	n, err := u.attachments.CountByHash(ctx, a.SHA256)
+	if err != nil || n > 0 {
+		return err
+	}
+	r, err := u.blobs.Open(ctx, a.BlobKey())
+	if errors.Is(err, Domain.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	content, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return err
+	}
+	if err := u.blobs.Delete(ctx, a.BlobKey()); err != nil {
+		return err
+	}
+	// restore on a failed count too: a stray blob only costs space
+	if n, err = u.attachments.CountByHash(ctx, a.SHA256); err != nil || n > 0 {
+		if perr := u.blobs.Put(ctx, a.BlobKey(), bytes.NewReader(content)); perr != nil {
+			return perr
+		}
+	}
+	return err
+}
+
+// Link signs "<workspace>.<attachment>.<expiry unix>"; the token is that
+// payload followed by "." and the signature
+func (u *attachmentUsecase) Link(ctx context.Context, taskID, id primitive.ObjectID) (Domain.AttachmentLink, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	a, err := u.find(ctx, taskID, id)
+	if err != nil {
+		return Domain.AttachmentLink{}, err
+	}
+	expires := time.Now().Add(u.opts.LinkTTL).UTC().Truncate(time.Second)
+	payload := a.WorkspaceID.Hex() + "." + a.ID.Hex() + "." + strconv.FormatInt(expires.Unix(), 10)
+	return Domain.AttachmentLink{ExpiresAt: expires, Token: payload + "." + u.signer.Sign(payload)}, nil
+}
+
+func (u *attachmentUsecase) Open(ctx context.Context, token string) (Domain.Attachment, io.ReadCloser, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 || !u.signer.Verify(strings.Join(parts[:3], "."), parts[3]) {
+		return Domain.Attachment{}, nil, Domain.ErrLinkInvalid
+	}
+	ws, err1 := primitive.ObjectIDFromHex(parts[0])
+	id, err2 := primitive.ObjectIDFromHex(parts[1])
+	expires, err3 := strconv.ParseInt(parts[2], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil || time.Now().Unix() > expires {
+		return Domain.Attachment{}, nil, Domain.ErrLinkInvalid
+	}
+	ctx = Domain.WithWorkspace(ctx, ws)
+	lookup, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	a, err := u.attachments.FindByID(lookup, id)
+	if err != nil {
+		return Domain.Attachment{}, nil, err
+	}
+	// the stream is read after this returns, so it gets the caller's context
+	body, err := u.blobs.Open(ctx, a.BlobKey())
+	if err != nil {
+		return Domain.Attachment{}, nil, err
+	}
+	return a, body, nil
+}
+
+// Publish runs the cleanup in the background; events are published after
+// the task is already gone, and publishers must not block. Run retries
+// what fails here.
+func (u *attachmentUsecase) Publish(e Domain.TaskEvent) {
+	if e.Type != Domain.EventTaskDeleted {
+		return
+	}
+	taskID, err := primitive.ObjectIDFromHex(e.TaskID)
+	if err != nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(Domain.WithWorkspace(context.Background(), e.WorkspaceID), u.timeout)
+		defer cancel()
+		if err := u.deleteForTask(ctx, taskID); err != nil {
+			slog.Error("attachments: delete for deleted task", "task_id", e.TaskID, "error", err)
+		}
+	}()
+}
+
+func (u *attachmentUsecase) Run(ctx context.Context) {
+	u.sweep().run(ctx)
+}
+
+func (u *attachmentUsecase) sweep() orphanSweep {
+	return orphanSweep{what: "attachments", find: u.attachments.OrphanedTasks, clean: u.deleteForTask, timeout: u.timeout}
+}
+
+func (u *attachmentUsecase) deleteForTask(ctx context.Context, taskID primitive.ObjectID) error {
+	as, err := u.attachments.FindByTask(ctx, taskID)
+	if err != nil || len(as) == 0 {
+		return err
+	}
+	if err := u.attachments.DeleteByTask(ctx, taskID); err != nil {
+		return err
+	}
+	released := map[string]bool{}
+	for _, a := range as {
+		if released[a.SHA256] {
+			continue
+		}
+		released[a.SHA256] = true
+		if err := u.releaseBlob(ctx, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,328 @@
+package Usecases
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"task_manager/Domain"
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// attachmentRepo keeps attachment records in memory. afterCount and
+// beforeFind, when set, run once after the next CountByHash or ahead of
+// the next FindByHash, to interleave a concurrent request there.
+type attachmentRepo struct {
+	mu         sync.Mutex
+	records    map[primitive.ObjectID]Domain.Attachment
+	afterCount func()
+	beforeFind func()
+	tasks      *taskSet // for OrphanedTasks
+}
+
+func (r *attachmentRepo) hook(f *func()) {
+	r.mu.Lock()
+	h := *f
+	*f = nil
+	r.mu.Unlock()
+	if h != nil {
+		h()
+	}
+}
+
+func (r *attachmentRepo) Create(ctx context.Context, a Domain.Attachment) (Domain.Attachment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.records {
+		if e.TaskID == a.TaskID && e.SHA256 == a.SHA256 {
+			return Domain.Attachment{}, Domain.ErrAttachmentExists
+		}
+	}
+	a.ID = primitive.NewObjectID()
+	a.WorkspaceID, _ = Domain.WorkspaceFrom(ctx)
+	r.records[a.ID] = a
+	return a, nil
+}
+
+func (r *attachmentRepo) FindByID(ctx context.Context, id primitive.ObjectID) (Domain.Attachment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.records[id]
+	if !ok {
+		return Domain.Attachment{}, Domain.ErrNotFound
+	}
+	return a, nil
+}
+
+func (r *attachmentRepo) FindByTask(ctx context.Context, taskID primitive.ObjectID) ([]Domain.Attachment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []Domain.Attachment
+	for _, a := range r.records {
+		if a.TaskID == taskID {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func (r *attachmentRepo) FindByHash(ctx context.Context, taskID primitive.ObjectID, sha256 string) (Domain.Attachment, error) {
+	r.hook(&r.beforeFind)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, a := range r.records {
+		if a.TaskID == taskID && a.SHA256 == sha256 {
+			return a, nil
+		}
+	}
+	return Domain.Attachment{}, Domain.ErrNotFound
+}
+
+func (r *attachmentRepo) CountByHash(ctx context.Context, sha256 string) (int64, error) {
+	defer r.hook(&r.afterCount)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for _, a := range r.records {
+		if a.SHA256 == sha256 {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *attachmentRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.records[id]; !ok {
+		return Domain.ErrNotFound
+	}
+	delete(r.records, id)
+	return nil
+}
+
+func (r *attachmentRepo) DeleteByTask(ctx context.Context, taskID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, a := range r.records {
+		if a.TaskID == taskID {
+			delete(r.records, id)
+		}
+	}
+	return nil
+}
+
+func (r *attachmentRepo) OrphanedTasks(ctx context.Context, limit int64) ([]Repositories.TaskRef, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := map[primitive.ObjectID]bool{}
+	var out []Repositories.TaskRef
+	for _, a := range r.records {
+		if !seen[a.TaskID] && !r.tasks.has(a.TaskID) && int64(len(out)) < limit {
+			seen[a.TaskID] = true
+			out = append(out, Repositories.TaskRef{WorkspaceID: a.WorkspaceID, TaskID: a.TaskID})
+		}
+	}
+	return out, nil
+}
+
+// memBlobs is a BlobStore in memory
+type memBlobs struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func (s *memBlobs) Put(ctx context.Context, key string, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.blobs[key]; !ok {
+		s.blobs[key] = b
+	}
+	return nil
+}
+
+func (s *memBlobs) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.blobs[key]
+	if !ok {
+		return nil, Domain.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (s *memBlobs) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, key)
+	return nil
+}
+
+// taskSet answers FindByID for the tasks it holds; the rest of
+// TaskRepository is left nil
+type taskSet struct {
+	Repositories.TaskRepository
+	mu  sync.Mutex
+	ids map[primitive.ObjectID]bool
+}
+
+func (r *taskSet) FindByID(ctx context.Context, id primitive.ObjectID) (Domain.Task, error) {
+	if !r.has(id) {
+		return Domain.Task{}, Domain.ErrNotFound
+	}
+	return Domain.Task{ID: id}, nil
+}
+
+func (r *taskSet) has(id primitive.ObjectID) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ids[id]
+}
+
+func (r *taskSet) remove(id primitive.ObjectID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.ids, id)
+}
+
+type attachmentTest struct {
+	u     *attachmentUsecase
+	repo  *attachmentRepo
+	blobs *memBlobs
+	tasks *taskSet
+	ctx   context.Context
+	admin Domain.Session
+}
+
+func newAttachmentTest(tasks ...primitive.ObjectID) *attachmentTest {
+	at := &attachmentTest{
+		repo:  &attachmentRepo{records: map[primitive.ObjectID]Domain.Attachment{}},
+		blobs: &memBlobs{blobs: map[string][]byte{}},
+		tasks: &taskSet{ids: map[primitive.ObjectID]bool{}},
+		ctx:   Domain.WithWorkspace(context.Background(), primitive.NewObjectID()),
+		admin: Domain.Session{Username: "root", Role: Domain.RoleAdmin},
+	}
+	for _, id := range tasks {
+		at.tasks.ids[id] = true
+	}
+	at.repo.tasks = at.tasks
+	at.u = NewAttachmentUsecase(at.repo, at.tasks, at.blobs, nil, AttachmentOptions{MaxSize: 1 << 20, AllowedTypes: []string{"text/plain"}}).(*attachmentUsecase)
+	return at
+}
+
+func (at *attachmentTest) upload(t *testing.T, taskID primitive.ObjectID, content string) Domain.Attachment {
+	t.Helper()
+	a, _, err := at.u.Upload(at.ctx, at.admin, taskID, "notes.txt", bytes.NewReader([]byte(content)))
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	return a
+}
+
+// readable fails the test unless a's content can be downloaded
+func (at *attachmentTest) readable(t *testing.T, a Domain.Attachment, want string) {
+	t.Helper()
+	r, err := at.blobs.Open(at.ctx, a.BlobKey())
+	if err != nil {
+		t.Fatalf("content of %s: %v", a.ID.Hex(), err)
+	}
+	b, _ := io.ReadAll(r)
+	if string(b) != want {
+		t.Errorf("content = %q, want %q", b, want)
+	}
+}
+
+func TestAttachmentUploadBetweenCountAndDelete(t *testing.T) {
+	task1, task2 := primitive.NewObjectID(), primitive.NewObjectID()
+	at := newAttachmentTest(task1, task2)
+	first := at.upload(t, task1, "same bytes")
+
+	// the upload's record exists by the time the release counts again
+	var second Domain.Attachment
+	at.repo.afterCount = func() { second = at.upload(t, task2, "same bytes") }
+	if err := at.u.Delete(at.ctx, at.admin, task1, first.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	at.readable(t, second, "same bytes")
+}
+
+func TestAttachmentReleaseBetweenPutAndRecord(t *testing.T) {
+	task1, task2 := primitive.NewObjectID(), primitive.NewObjectID()
+	at := newAttachmentTest(task1, task2)
+	first := at.upload(t, task1, "same bytes")
+
+	// the upload puts before the release deletes, but records after it counts
+	at.repo.beforeFind = func() {
+		if err := at.u.Delete(at.ctx, at.admin, task1, first.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+	}
+	second := at.upload(t, task2, "same bytes")
+	at.readable(t, second, "same bytes")
+}
+
+func TestAttachmentReleaseDeletesUnusedContent(t *testing.T) {
+	task := primitive.NewObjectID()
+	at := newAttachmentTest(task)
+	a := at.upload(t, task, "only copy")
+	if err := at.u.Delete(at.ctx, at.admin, task, a.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := at.blobs.Open(at.ctx, a.BlobKey()); !errors.Is(err, Domain.ErrNotFound) {
+		t.Errorf("content still stored after its last attachment was deleted (err = %v)", err)
+	}
+}
+
+func TestAttachmentUploadDeduplicates(t *testing.T) {
+	task := primitive.NewObjectID()
+	at := newAttachmentTest(task)
+	first := at.upload(t, task, "twice")
+	again, created, err := at.u.Upload(at.ctx, at.admin, task, "other.txt", bytes.NewReader([]byte("twice")))
+	if err != nil || created || again.ID != first.ID {
+		t.Errorf("Upload() of known content = %s, created %v, %v; want the existing %s", again.ID.Hex(), created, err, first.ID.Hex())
+	}
+}
+
+func TestAttachmentUploadToDeletedTask(t *testing.T) {
+	task := primitive.NewObjectID()
+	at := newAttachmentTest(task)
+	// the task is deleted after the upload checked it, before the record
+	at.repo.beforeFind = func() { at.tasks.remove(task) }
+	_, _, err := at.u.Upload(at.ctx, at.admin, task, "notes.txt", bytes.NewReader([]byte("late")))
+	if !errors.Is(err, Domain.ErrNotFound) {
+		t.Fatalf("Upload() error = %v, want ErrNotFound", err)
+	}
+	if len(at.repo.records) != 0 || len(at.blobs.blobs) != 0 {
+		t.Errorf("left %d records and %d blobs behind", len(at.repo.records), len(at.blobs.blobs))
+	}
+}
+
+func TestAttachmentSweep(t *testing.T) {
+	kept, deleted := primitive.NewObjectID(), primitive.NewObjectID()
+	at := newAttachmentTest(kept, deleted)
+	keep := at.upload(t, kept, "shared")
+	at.upload(t, deleted, "shared")
+	gone := at.upload(t, deleted, "only on the deleted task")
+	// the task goes without its event reaching the usecase
+	at.tasks.remove(deleted)
+
+	at.u.sweep().once(at.ctx)
+
+	left, _ := at.repo.FindByTask(at.ctx, deleted)
+	if len(left) != 0 {
+		t.Errorf("deleted task still has %d attachments", len(left))
+	}
+	at.readable(t, keep, "shared")
+	if _, err := at.blobs.Open(at.ctx, gone.BlobKey()); !errors.Is(err, Domain.ErrNotFound) {
+		t.Errorf("content only the deleted task used is still stored (err = %v)", err)
+	}
+}
@@ -0,0 +1,262 @@
+package Usecases
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"task_manager/Domain"
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CommentUsecase manages the discussion on tasks. Any member may comment;
+// authors edit and delete their own comments and admins may moderate anyone's.
+type CommentUsecase interface {
+	// Create comments on the task as actor; a reply names a top-level
+	// comment on the same task as parentID
+	Create(ctx context.Context, actor Domain.Session, taskID primitive.ObjectID, parentID *primitive.ObjectID, body string) (Domain.Comment, error)
+	// List pages through the task's top-level comments, each with its replies
+	List(ctx context.Context, taskID, after primitive.ObjectID, limit int64) (Domain.CommentPage, error)
+	Get(ctx context.Context, taskID, id primitive.ObjectID) (Domain.Comment, error)
+	// Edit replaces the body, keeping the old one in the edit history
+	Edit(ctx context.Context, actor Domain.Session, taskID, id primitive.ObjectID, body string) (Domain.Comment, error)
+	// Delete removes the comment together with its replies
+	Delete(ctx context.Context, actor Domain.Session, taskID, id primitive.ObjectID) error
+	// Publish removes the comments of deleted tasks, so the usecase can be
+	// subscribed to the task usecase's events
+	Publish(e Domain.TaskEvent)
+	// Run removes, until ctx is done, comments of deleted tasks that Publish
+	// missed
+	Run(ctx context.Context)
+}
+
+type commentUsecase struct {
+	comments    Repositories.CommentRepository
+	tasks       Repositories.TaskRepository
+	memberships Repositories.MembershipRepository
+	events      TaskEventPublisher
+	timeout     time.Duration
+}
+
+// NewCommentUsecase wires the repositories; events may be nil
+func NewCommentUsecase(c Repositories.CommentRepository, t Repositories.TaskRepository, m Repositories.MembershipRepository, events TaskEventPublisher) CommentUsecase {
+	return &commentUsecase{comments: c, tasks: t, memberships: m, events: events, timeout: 5 * time.Second}
+}
+
+func (u *commentUsecase) publish(ctx context.Context, typ string, c Domain.Comment) {
+	if u.events == nil {
+		return
+	}
+	ws, _ := Domain.WorkspaceFrom(ctx)
+	u.events.Publish(Domain.TaskEvent{Type: typ, WorkspaceID: ws, TaskID: c.TaskID.Hex(), Comment: &c, At: time.Now().UTC()})
+}
+
+// mentions keeps the users mentioned in body that are members of the
+// workspace; anyone else stays plain text, so comments don't reveal who has
+// an account elsewhere
+func (u *commentUsecase) mentions(ctx context.Context, body string) ([]string, error) {
+	ws, ok := Domain.WorkspaceFrom(ctx)
+	if !ok {
+		return nil, Domain.ErrNoWorkspace
+	}
+	var out []string
+	for _, name := range Domain.ParseMentions(body) {
+		_, err := u.memberships.Find(ctx, ws, name)
+		if errors.Is(err, Domain.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, name)
+	}
+	return out, nil
+}
+
+// find loads a comment, which must be on taskID
+func (u *commentUsecase) find(ctx context.Context, taskID, id primitive.ObjectID) (Domain.Comment, error) {
+	c, err := u.comments.FindByID(ctx, id)
+	if err != nil {
+		return Domain.Comment{}, err
+	}
+	if c.TaskID != taskID {
+		return Domain.Comment{}, Domain.ErrNotFound
+	}
+	return c, nil
+}
+
+// canModify reports whether actor may edit or delete c
+func canModify(actor Domain.Session, c Domain.Comment) bool {
+	return c.Author == actor.Username || actor.Role == Domain.RoleAdmin
+}
+
+func (u *commentUsecase) Create(ctx context.Context, actor Domain.Session, taskID primitive.ObjectID, parentID *primitive.ObjectID, body string) (Domain.Comment, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	body, err := Domain.ValidateCommentBody(body)
+	if err != nil {
+		return Domain.Comment{}, err
+	}
+	if _, err := u.tasks.FindByID(ctx, taskID); err != nil {
+		return Domain.Comment{}, err
+	}
+	if parentID != nil {
+		parent, err := u.find(ctx, taskID, *parentID)
+		if errors.Is(err, Domain.ErrNotFound) {
+			return Domain.Comment{}, Domain.CommentError("parent_id", "no such comment on this task")
+		}
+		if err != nil {
+			return Domain.Comment{}, err
+		}
+		if parent.ParentID != nil {
+			return Domain.Comment{}, Domain.CommentError("parent_id", "replies can't be replied to")
+		}
+	}
+	mentions, err := u.mentions(ctx, body)
+	if err != nil {
+		return Domain.Comment{}, err
+	}
+	created, err := u.comments.Create(ctx, Domain.Comment{
+		TaskID:    taskID,
+		ParentID:  parentID,
+		Author:    actor.Username,
+		Body:      body,
+		Mentions:  mentions,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return Domain.Comment{}, err
+	}
+	u.publish(ctx, Domain.EventCommentCreated, created)
+	return created, nil
+}
+
+func (u *commentUsecase) List(ctx context.Context, taskID, after primitive.ObjectID, limit int64) (Domain.CommentPage, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	if _, err := u.tasks.FindByID(ctx, taskID); err != nil {
+		return Domain.CommentPage{}, err
+	}
+	// one extra tells whether there is a next page
+	top, err := u.comments.FindTopLevel(ctx, taskID, after, limit+1)
+	if err != nil {
+		return Domain.CommentPage{}, err
+	}
+	page := Domain.CommentPage{Comments: []Domain.Comment{}}
+	if int64(len(top)) > limit {
+		top = top[:limit]
+		page.Next = top[limit-1].ID.Hex()
+	}
+	ids := make([]primitive.ObjectID, len(top))
+	for i, c := range top {
+		ids[i] = c.ID
+	}
+	replies, err := u.comments.FindReplies(ctx, taskID, ids)
+	if err != nil {
+		return Domain.CommentPage{}, err
+	}
+	byParent := make(map[primitive.ObjectID][]Domain.Comment, len(top))
+	for _, r := range replies {
+		byParent[*r.ParentID] = append(byParent[*r.ParentID], r)
+	}
+	for i := range top {
+		top[i].Replies = byParent[top[i].ID]
+	}
+	page.Comments = append(page.Comments, top...)
+	return page, nil
+}
+
+func (u *commentUsecase) Get(ctx context.Context, taskID, id primitive.ObjectID) (Domain.Comment, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	c, err := u.find(ctx, taskID, id)
+	if err != nil {
+		return Domain.Comment{}, err
+	}
+	if c.ParentID == nil {
+		if c.Replies, err = u.comments.FindReplies(ctx, taskID, []primitive.ObjectID{c.ID}); err != nil {
+			return Domain.Comment{}, err
+		}
+	}
+	return c, nil
+}
+
+// Edit writes only if the body is still the one read, so a concurrent edit
+// can't slip out of the history; that case is reported as a conflict
+func (u *commentUsecase) Edit(ctx context.Context, actor Domain.Session, taskID, id primitive.ObjectID, body string) (Domain.Comment, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	body, err := Domain.ValidateCommentBody(body)
+	if err != nil {
+		return Domain.Comment{}, err
+	}
+	c, err := u.find(ctx, taskID, id)
+	if err != nil {
+		return Domain.Comment{}, err
+	}
+	if !canModify(actor, c) {
+		return Domain.Comment{}, Domain.ErrForbidden
+	}
+	if c.Body == body {
+		return c, nil
+	}
+	mentions, err := u.mentions(ctx, body)
+	if err != nil {
+		return Domain.Comment{}, err
+	}
+	edit := Domain.CommentEdit{Body: c.Body, EditedBy: actor.Username, EditedAt: time.Now().UTC()}
+	updated, err := u.comments.UpdateBody(ctx, id, c.Body, body, mentions, edit)
+	if err != nil {
+		return Domain.Comment{}, err
+	}
+	u.publish(ctx, Domain.EventCommentUpdated, updated)
+	return updated, nil
+}
+
+func (u *commentUsecase) Delete(ctx context.Context, actor Domain.Session, taskID, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	c, err := u.find(ctx, taskID, id)
+	if err != nil {
+		return err
+	}
+	if !canModify(actor, c) {
+		return Domain.ErrForbidden
+	}
+	if err := u.comments.Delete(ctx, id); err != nil {
+		return err
+	}
+	u.publish(ctx, Domain.EventCommentDeleted, c)
+	return nil
+}
+
+// Publish runs the cleanup in the background; events are published after
+// the task is already gone, and publishers must not block. Run retries
+// what fails here.
+func (u *commentUsecase) Publish(e Domain.TaskEvent) {
+	if e.Type != Domain.EventTaskDeleted {
+		return
+	}
+	taskID, err := primitive.ObjectIDFromHex(e.TaskID)
+	if err != nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(Domain.WithWorkspace(context.Background(), e.WorkspaceID), u.timeout)
+		defer cancel()
+		if err := u.comments.DeleteByTask(ctx, taskID); err != nil {
+			slog.Error("comments: delete for deleted task", "task_id", e.TaskID, "error", err)
+		}
+	}()
+}
+
+func (u *commentUsecase) Run(ctx context.Context) {
+	u.sweep().run(ctx)
+}
+
+func (u *commentUsecase) sweep() orphanSweep {
+	return orphanSweep{what: "comments", find: u.comments.OrphanedTasks, clean: u.comments.DeleteByTask, timeout: u.timeout}
+}
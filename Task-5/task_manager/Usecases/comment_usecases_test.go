@@ -0,0 +1,107 @@
+package Usecases
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"task_manager/Domain"
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memberRepo answers Find from a fixed set; the rest of
+// MembershipRepository is left nil
+type memberRepo struct {
+	Repositories.MembershipRepository
+	members map[primitive.ObjectID][]string
+}
+
+func (r *memberRepo) Find(ctx context.Context, workspaceID primitive.ObjectID, username string) (Domain.Membership, error) {
+	for _, name := range r.members[workspaceID] {
+		if name == username {
+			return Domain.Membership{WorkspaceID: workspaceID, Username: name, Role: Domain.RoleMember}, nil
+		}
+	}
+	return Domain.Membership{}, Domain.ErrNotFound
+}
+
+func TestCommentMentions(t *testing.T) {
+	ws, other := primitive.NewObjectID(), primitive.NewObjectID()
+	u := &commentUsecase{memberships: &memberRepo{members: map[primitive.ObjectID][]string{
+		ws:    {"alice", "bob"},
+		other: {"mallory"},
+	}}}
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{name: "members", body: "@alice and @bob, please look", want: []string{"alice", "bob"}},
+		{name: "case is ignored", body: "thanks @Alice", want: []string{"alice"}},
+		{name: "member of another workspace", body: "@mallory @alice", want: []string{"alice"}},
+		{name: "no such user", body: "@nobody here", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := u.mentions(Domain.WithWorkspace(context.Background(), ws), tt.body)
+			if err != nil {
+				t.Fatalf("mentions() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mentions(%q) = %v, want %v", tt.body, got, tt.want)
+			}
+		})
+	}
+	if _, err := u.mentions(context.Background(), "@alice"); !errors.Is(err, Domain.ErrNoWorkspace) {
+		t.Errorf("mentions() without a workspace: error = %v, want ErrNoWorkspace", err)
+	}
+}
+
+// orphanComments has comments on deleted tasks; DeleteByTask fails while
+// failures is above zero
+type orphanComments struct {
+	Repositories.CommentRepository
+	orphans  map[primitive.ObjectID]primitive.ObjectID // task -> workspace
+	failures int
+	deleted  []primitive.ObjectID
+}
+
+func (r *orphanComments) OrphanedTasks(ctx context.Context, limit int64) ([]Repositories.TaskRef, error) {
+	var out []Repositories.TaskRef
+	for task, ws := range r.orphans {
+		out = append(out, Repositories.TaskRef{WorkspaceID: ws, TaskID: task})
+	}
+	return out, nil
+}
+
+func (r *orphanComments) DeleteByTask(ctx context.Context, taskID primitive.ObjectID) error {
+	if ws, _ := Domain.WorkspaceFrom(ctx); ws != r.orphans[taskID] {
+		return errors.New("wrong workspace")
+	}
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("unavailable")
+	}
+	delete(r.orphans, taskID)
+	r.deleted = append(r.deleted, taskID)
+	return nil
+}
+
+func TestCommentSweepRetries(t *testing.T) {
+	task := primitive.NewObjectID()
+	repo := &orphanComments{orphans: map[primitive.ObjectID]primitive.ObjectID{task: primitive.NewObjectID()}, failures: 1}
+	u := &commentUsecase{comments: repo, timeout: time.Second}
+
+	u.sweep().once(context.Background())
+	if len(repo.deleted) != 0 || len(repo.orphans) != 1 {
+		t.Fatalf("first sweep: deleted %v, want the failure left for later", repo.deleted)
+	}
+	u.sweep().once(context.Background())
+	if len(repo.deleted) != 1 || repo.deleted[0] != task || len(repo.orphans) != 0 {
+		t.Errorf("second sweep: deleted %v, want %v", repo.deleted, task)
+	}
+}
@@ -0,0 +1,286 @@
+package Usecases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"task_manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// checkBlockers refuses to start or complete a task while any of its
+// blockers is open, unless the change is forced or the task already has
+// that status
+func (u *taskUsecase) checkBlockers(ctx context.Context, id primitive.ObjectID, change Domain.TaskChange) error {
+	status, _ := change.Set["status"].(string)
+	if change.Force || !Domain.StartsWork(status) {
+		return nil
+	}
+	t, err := u.repo.FindByID(ctx, id)
+	if err != nil || t.Status == status || len(t.BlockedBy) == 0 {
+		// a missing task is reported by the write itself
+		return nil
+	}
+	blockers, err := u.repo.FindByIDs(ctx, t.BlockedBy)
+	if err != nil {
+		return err
+	}
+	if open := Domain.OpenBlockers(blockers); open > 0 {
+		return Domain.BlockedError(open)
+	}
+	return nil
+}
+
+// bulkBlockerRules adds to errs the batch updates that start or complete a
+// task with open blockers. A blocker completed or deleted in the same batch
+// no longer counts as open. Ops that already broke a rule are left alone.
+func (u *taskUsecase) bulkBlockerRules(ctx context.Context, ops []BulkTaskOp, errs map[int]error) error {
+	completing := map[primitive.ObjectID]bool{}
+	released := map[primitive.ObjectID]bool{}
+	starting := map[int]string{}
+	var ids []primitive.ObjectID
+	for i, op := range ops {
+		if op.Invalid != nil {
+			continue
+		}
+		switch op.Op {
+		case "update":
+			s, ok := op.Change.Set["status"].(string)
+			if !ok {
+				continue
+			}
+			norm, _ := Domain.NormalizeStatus(s)
+			if norm == Domain.StatusDone {
+				completing[op.ID] = true
+			}
+			if Domain.StartsWork(norm) && !op.Change.Force && errs[i] == nil {
+				starting[i] = norm
+				ids = append(ids, op.ID)
+			}
+		case "delete":
+			released[op.ID] = true
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	current, err := u.repo.FindByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byID := make(map[primitive.ObjectID]Domain.Task, len(current))
+	var blockerIDs []primitive.ObjectID
+	for _, t := range current {
+		byID[t.ID] = t
+		blockerIDs = append(blockerIDs, t.BlockedBy...)
+	}
+	if len(blockerIDs) == 0 {
+		return nil
+	}
+	blockers, err := u.repo.FindByIDs(ctx, blockerIDs)
+	if err != nil {
+		return err
+	}
+	open := map[primitive.ObjectID]bool{}
+	for _, b := range blockers {
+		open[b.ID] = b.Status != Domain.StatusDone && !completing[b.ID] && !released[b.ID]
+	}
+	for i, status := range starting {
+		t, ok := byID[ops[i].ID]
+		if !ok || t.Status == status {
+			continue
+		}
+		n := 0
+		for _, b := range t.BlockedBy {
+			if open[b] {
+				n++
+			}
+		}
+		if n > 0 {
+			errs[i] = Domain.BlockedError(n)
+		}
+	}
+	return nil
+}
+
+// releaseBlocked removes a deleted task from the blockers of the tasks it
+// blocked
+func (u *taskUsecase) releaseBlocked(ctx context.Context, ids []primitive.ObjectID) error {
+	for _, id := range ids {
+		if err := u.repo.RemoveBlocker(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitsOn reports whether from is blocked, directly or through other
+// tasks, by target
+func (u *taskUsecase) waitsOn(ctx context.Context, from Domain.Task, target primitive.ObjectID) (bool, error) {
+	seen := map[primitive.ObjectID]bool{from.ID: true}
+	frontier := from.BlockedBy
+	for len(frontier) > 0 {
+		var next []primitive.ObjectID
+		for _, id := range frontier {
+			if id == target {
+				return true, nil
+			}
+			if !seen[id] {
+				seen[id] = true
+				next = append(next, id)
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+		tasks, err := u.repo.FindByIDs(ctx, next)
+		if err != nil {
+			return false, err
+		}
+		frontier = nil
+		for _, t := range tasks {
+			frontier = append(frontier, t.BlockedBy...)
+		}
+	}
+	return false, nil
+}
+
+func (u *taskUsecase) ListDependencies(ctx context.Context, id primitive.ObjectID) (Domain.Dependencies, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	t, err := u.repo.FindByID(ctx, id)
+	if err != nil {
+		return Domain.Dependencies{}, err
+	}
+	deps := Domain.Dependencies{BlockedBy: []Domain.Task{}, Blocking: []Domain.Task{}}
+	if len(t.BlockedBy) > 0 {
+		blockers, err := u.repo.FindByIDs(ctx, t.BlockedBy)
+		if err != nil {
+			return Domain.Dependencies{}, err
+		}
+		// keep the order the blockers were added in
+		byID := make(map[primitive.ObjectID]Domain.Task, len(blockers))
+		for _, b := range blockers {
+			byID[b.ID] = b
+		}
+		for _, b := range t.BlockedBy {
+			if bt, ok := byID[b]; ok {
+				deps.BlockedBy = append(deps.BlockedBy, bt)
+			}
+		}
+	}
+	blocking, err := u.repo.FindDependents(ctx, []primitive.ObjectID{id})
+	if err != nil {
+		return Domain.Dependencies{}, err
+	}
+	deps.Blocking = append(deps.Blocking, blocking...)
+	if deps.BlockedBy, err = u.withProgress(ctx, deps.BlockedBy); err != nil {
+		return Domain.Dependencies{}, err
+	}
+	if deps.Blocking, err = u.withProgress(ctx, deps.Blocking); err != nil {
+		return Domain.Dependencies{}, err
+	}
+	return deps, nil
+}
+
+// maxBlockerAttempts bounds retries when the blockers change between reading
+// and writing them
+const maxBlockerAttempts = 3
+
+// errUnchanged tells editBlockers there is nothing to write
+var errUnchanged = errors.New("unchanged")
+
+// editBlockers writes edit's result only if blocked_by still holds what was
+// read, reading again when it doesn't. edit gets the task as read and must
+// not change it.
+func (u *taskUsecase) editBlockers(ctx context.Context, id primitive.ObjectID, edit func(ctx context.Context, t Domain.Task) ([]primitive.ObjectID, error)) (Domain.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	for attempt := 1; ; attempt++ {
+		t, err := u.repo.FindByID(ctx, id)
+		if err != nil {
+			return Domain.Task{}, err
+		}
+		blockers, err := edit(ctx, t)
+		if errors.Is(err, errUnchanged) {
+			tasks, err := u.withProgress(ctx, []Domain.Task{t})
+			if err != nil {
+				return Domain.Task{}, err
+			}
+			return tasks[0], nil
+		}
+		if err != nil {
+			return Domain.Task{}, err
+		}
+		change := Domain.TaskChange{Set: map[string]interface{}{}, Expect: map[string]interface{}{"blocked_by": nil}}
+		if len(t.BlockedBy) > 0 {
+			change.Expect["blocked_by"] = t.BlockedBy
+		}
+		if len(blockers) > 0 {
+			change.Set["blocked_by"] = blockers
+		} else {
+			change.Unset = []string{"blocked_by"}
+		}
+		updated, err := u.update(ctx, id, change)
+		if errors.Is(err, Domain.ErrPreconditionFailed) && attempt < maxBlockerAttempts {
+			continue
+		}
+		return updated, err
+	}
+}
+
+// AddBlocker makes id wait on blocker. Adding a blocker that is already there
+// changes nothing.
+func (u *taskUsecase) AddBlocker(ctx context.Context, id, blocker primitive.ObjectID) (Domain.Task, error) {
+	if id == blocker {
+		return Domain.Task{}, Domain.DependencyError("a task cannot block itself")
+	}
+	return u.editBlockers(ctx, id, func(ctx context.Context, t Domain.Task) ([]primitive.ObjectID, error) {
+		for _, b := range t.BlockedBy {
+			if b == blocker {
+				return nil, errUnchanged
+			}
+		}
+		if len(t.BlockedBy) >= Domain.MaxBlockers {
+			return nil, Domain.DependencyError("a task may have at most 50 blockers")
+		}
+		b, err := u.repo.FindByID(ctx, blocker)
+		if errors.Is(err, Domain.ErrNotFound) {
+			return nil, Domain.DependencyError("no such task")
+		}
+		if err != nil {
+			return nil, err
+		}
+		cycle, err := u.waitsOn(ctx, b, id)
+		if err != nil {
+			return nil, err
+		}
+		if cycle {
+			return nil, Domain.DependencyError("would create a cycle: the blocker already waits on this task")
+		}
+		return append(append([]primitive.ObjectID(nil), t.BlockedBy...), blocker), nil
+	})
+}
+
+func (u *taskUsecase) RemoveBlocker(ctx context.Context, id, blocker primitive.ObjectID) (Domain.Task, error) {
+	return u.editBlockers(ctx, id, func(ctx context.Context, t Domain.Task) ([]primitive.ObjectID, error) {
+		for i, b := range t.BlockedBy {
+			if b == blocker {
+				return append(append([]primitive.ObjectID(nil), t.BlockedBy[:i]...), t.BlockedBy[i+1:]...), nil
+			}
+		}
+		return nil, Domain.ErrNotFound
+	})
+}
+
+func (u *taskUsecase) Schedule(ctx context.Context) (Domain.Schedule, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	tasks, err := u.repo.FindAll(ctx)
+	if err != nil {
+		return Domain.Schedule{}, err
+	}
+	return Domain.PlanSchedule(tasks, time.Now().UTC().Truncate(time.Second)), nil
+}
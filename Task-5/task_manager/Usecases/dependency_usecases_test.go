@@ -0,0 +1,106 @@
+package Usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"task_manager/Domain"
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// blockerRepo serves FindByIDs from a fixed graph and counts the lookups;
+// the rest of TaskRepository is left nil
+type blockerRepo struct {
+	Repositories.TaskRepository
+	tasks   map[primitive.ObjectID]Domain.Task
+	lookups int
+}
+
+func (r *blockerRepo) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]Domain.Task, error) {
+	r.lookups++
+	var out []Domain.Task
+	for _, id := range ids {
+		if t, ok := r.tasks[id]; ok {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func TestWaitsOn(t *testing.T) {
+	tests := []struct {
+		name  string
+		edges map[string][]string // task -> its blockers
+		from  string
+		to    string
+		want  bool
+	}{
+		{name: "direct", edges: map[string][]string{"b": {"a"}}, from: "b", to: "a", want: true},
+		{name: "indirect", edges: map[string][]string{"c": {"b"}, "b": {"a"}}, from: "c", to: "a", want: true},
+		{name: "not blocked", edges: map[string][]string{"c": {"b"}}, from: "c", to: "a", want: false},
+		{name: "reverse direction", edges: map[string][]string{"b": {"a"}}, from: "a", to: "b", want: false},
+		{
+			name:  "diamond",
+			edges: map[string][]string{"d": {"b", "c"}, "b": {"a"}, "c": {"a"}},
+			from:  "d", to: "a", want: true,
+		},
+		{
+			name:  "existing cycle elsewhere ends the walk",
+			edges: map[string][]string{"c": {"b"}, "b": {"c"}},
+			from:  "c", to: "a", want: false,
+		},
+		{
+			name:  "missing task",
+			edges: map[string][]string{"c": {"gone"}},
+			from:  "c", to: "a", want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := map[string]primitive.ObjectID{}
+			id := func(name string) primitive.ObjectID {
+				if _, ok := ids[name]; !ok {
+					ids[name] = primitive.NewObjectID()
+				}
+				return ids[name]
+			}
+			repo := &blockerRepo{tasks: map[primitive.ObjectID]Domain.Task{}}
+			for name, blockers := range tt.edges {
+				task := Domain.Task{ID: id(name)}
+				for _, b := range blockers {
+					task.BlockedBy = append(task.BlockedBy, id(b))
+				}
+				repo.tasks[task.ID] = task
+			}
+			from, ok := repo.tasks[id(tt.from)]
+			if !ok {
+				from = Domain.Task{ID: id(tt.from)}
+			}
+			u := &taskUsecase{repo: repo}
+
+			got, err := u.waitsOn(context.Background(), from, id(tt.to))
+			if err != nil {
+				t.Fatalf("waitsOn() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("waitsOn(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+			if repo.lookups > len(tt.edges)+1 {
+				t.Errorf("waitsOn made %d lookups for %d tasks", repo.lookups, len(tt.edges))
+			}
+		})
+	}
+}
+
+func TestAddBlockerRefusesSelf(t *testing.T) {
+	u := &taskUsecase{}
+	id := primitive.NewObjectID()
+	_, err := u.AddBlocker(context.Background(), id, id)
+	var verr *Domain.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("AddBlocker(id, id) error = %v, want a validation error", err)
+	}
+}
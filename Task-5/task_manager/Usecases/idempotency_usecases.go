@@ -0,0 +1,80 @@
+package Usecases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"task_manager/Domain"
+	"task_manager/Repositories"
+)
+
+// IdempotencyLease is how long a request may run before a retry with the
+// same key assumes it died and runs it again
+const IdempotencyLease = time.Minute
+
+type IdempotencyUsecase interface {
+	// Begin claims key for the request identified by fingerprint. It returns
+	// the stored record when there is a finished response to replay, or nil
+	// when the caller should run the request and then Complete or Abandon it.
+	Begin(ctx context.Context, user, key, fingerprint string) (*Domain.IdempotencyRecord, error)
+	// Complete stores the response that retries will receive
+	Complete(ctx context.Context, user, key string, status int, header map[string]string, body []byte) error
+	// Abandon releases the key so a retry runs the request again
+	Abandon(ctx context.Context, user, key string) error
+}
+
+type idempotencyUsecase struct {
+	repo    Repositories.IdempotencyRepository
+	ttl     time.Duration
+	timeout time.Duration
+}
+
+// NewIdempotencyUsecase keeps each key for ttl after its first use
+func NewIdempotencyUsecase(r Repositories.IdempotencyRepository, ttl time.Duration) IdempotencyUsecase {
+	return &idempotencyUsecase{repo: r, ttl: ttl, timeout: 5 * time.Second}
+}
+
+func (u *idempotencyUsecase) Begin(ctx context.Context, user, key, fingerprint string) (*Domain.IdempotencyRecord, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	now := time.Now().UTC()
+	existing, created, err := u.repo.Reserve(ctx, Domain.IdempotencyRecord{
+		User:        user,
+		Key:         key,
+		Fingerprint: fingerprint,
+		LockedUntil: now.Add(IdempotencyLease),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(u.ttl),
+	})
+	switch {
+	case err != nil:
+		return nil, err
+	case created:
+		return nil, nil
+	case existing.Fingerprint != fingerprint:
+		return nil, Domain.ErrIdempotencyKeyReused
+	case existing.Completed:
+		return &existing, nil
+	}
+	// another request holds the key; take it over only if that one is stale
+	if err := u.repo.TakeOver(ctx, user, key, now, now.Add(IdempotencyLease)); err != nil {
+		if errors.Is(err, Domain.ErrNotFound) {
+			return nil, Domain.ErrIdempotencyInFlight
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (u *idempotencyUsecase) Complete(ctx context.Context, user, key string, status int, header map[string]string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	return u.repo.Complete(ctx, Domain.IdempotencyRecord{User: user, Key: key, Status: status, Header: header, Body: body})
+}
+
+func (u *idempotencyUsecase) Abandon(ctx context.Context, user, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	return u.repo.Delete(ctx, user, key)
+}
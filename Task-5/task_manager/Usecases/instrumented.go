@@ -0,0 +1,194 @@
+package Usecases
+
+import (
+	"context"
+
+	"task_manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Observer is called as a usecase call starts. It may return a derived
+// context (e.g. carrying a span) and returns a func to call with the result.
+type Observer func(ctx context.Context, usecase, op string) (context.Context, func(error))
+
+// InstrumentTaskUsecase wraps u so every call is reported to observe
+func InstrumentTaskUsecase(u TaskUsecase, observe Observer) TaskUsecase {
+	return &instrumentedTaskUsecase{next: u, observe: observe}
+}
+
+type instrumentedTaskUsecase struct {
+	next    TaskUsecase
+	observe Observer
+}
+
+func (u *instrumentedTaskUsecase) CreateTask(ctx context.Context, t Domain.Task) (Domain.Task, error) {
+	ctx, done := u.observe(ctx, "task", "create")
+	out, err := u.next.CreateTask(ctx, t)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedTaskUsecase) ListTasks(ctx context.Context, filter Domain.TaskFilter) ([]Domain.Task, error) {
+	ctx, done := u.observe(ctx, "task", "list")
+	out, err := u.next.ListTasks(ctx, filter)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedTaskUsecase) GetTaskByID(ctx context.Context, id primitive.ObjectID) (Domain.Task, error) {
+	ctx, done := u.observe(ctx, "task", "get")
+	out, err := u.next.GetTaskByID(ctx, id)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedTaskUsecase) GetTasksByIDs(ctx context.Context, ids []primitive.ObjectID) ([]Domain.Task, error) {
+	ctx, done := u.observe(ctx, "task", "get_many")
+	out, err := u.next.GetTasksByIDs(ctx, ids)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedTaskUsecase) UpdateTask(ctx context.Context, id primitive.ObjectID, change Domain.TaskChange) (Domain.Task, error) {
+	ctx, done := u.observe(ctx, "task", "update")
+	out, err := u.next.UpdateTask(ctx, id, change)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedTaskUsecase) ReplaceTask(ctx context.Context, id primitive.ObjectID, t Domain.Task, force bool) (Domain.Task, error) {
+	ctx, done := u.observe(ctx, "task", "replace")
+	out, err := u.next.ReplaceTask(ctx, id, t, force)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedTaskUsecase) DeleteTask(ctx context.Context, id primitive.ObjectID) error {
+	ctx, done := u.observe(ctx, "task", "delete")
+	err := u.next.DeleteTask(ctx, id)
+	done(err)
+	return err
+}
+
+func (u *instrumentedTaskUsecase) BulkTasks(ctx context.Context, ops []BulkTaskOp, stopOnError bool) (BulkTaskReport, error) {
+	ctx, done := u.observe(ctx, "task", "bulk")
+	out, err := u.next.BulkTasks(ctx, ops, stopOnError)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedTaskUsecase) ListSubtasks(ctx context.Context, id primitive.ObjectID) ([]Domain.Task, error) {
+	ctx, done := u.observe(ctx, "task", "list_subtasks")
+	out, err := u.next.ListSubtasks(ctx, id)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedTaskUsecase) ListChildren(ctx context.Context, parents []primitive.ObjectID) ([]Domain.Task, error) {
+	ctx, done := u.observe(ctx, "task", "list_children")
+	out, err := u.next.ListChildren(ctx, parents)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedTaskUsecase) MoveTask(ctx context.Context, id, parent primitive.ObjectID) (Domain.Task, error) {
+	ctx, done := u.observe(ctx, "task", "move")
+	out, err := u.next.MoveTask(ctx, id, parent)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedTaskUsecase) AddChecklistItem(ctx context.Context, id primitive.ObjectID, text string) (Domain.Task, error) {
+	ctx, done := u.observe(ctx, "task", "add_checklist_item")
+	out, err := u.next.AddChecklistItem(ctx, id, text)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedTaskUsecase) UpdateChecklistItem(ctx context.Context, id, itemID primitive.ObjectID, patch Domain.ChecklistItemPatch) (Domain.Task, error) {
+	ctx, done := u.observe(ctx, "task", "update_checklist_item")
+	out, err := u.next.UpdateChecklistItem(ctx, id, itemID, patch)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedTaskUsecase) DeleteChecklistItem(ctx context.Context, id, itemID primitive.ObjectID) (Domain.Task, error) {
+	ctx, done := u.observe(ctx, "task", "delete_checklist_item")
+	out, err := u.next.DeleteChecklistItem(ctx, id, itemID)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedTaskUsecase) ListDependencies(ctx context.Context, id primitive.ObjectID) (Domain.Dependencies, error) {
+	ctx, done := u.observe(ctx, "task", "list_dependencies")
+	out, err := u.next.ListDependencies(ctx, id)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedTaskUsecase) AddBlocker(ctx context.Context, id, blocker primitive.ObjectID) (Domain.Task, error) {
+	ctx, done := u.observe(ctx, "task", "add_blocker")
+	out, err := u.next.AddBlocker(ctx, id, blocker)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedTaskUsecase) RemoveBlocker(ctx context.Context, id, blocker primitive.ObjectID) (Domain.Task, error) {
+	ctx, done := u.observe(ctx, "task", "remove_blocker")
+	out, err := u.next.RemoveBlocker(ctx, id, blocker)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedTaskUsecase) Schedule(ctx context.Context) (Domain.Schedule, error) {
+	ctx, done := u.observe(ctx, "task", "schedule")
+	out, err := u.next.Schedule(ctx)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedTaskUsecase) AddLabel(ctx context.Context, id primitive.ObjectID, name string) (Domain.Task, error) {
+	ctx, done := u.observe(ctx, "task", "add_label")
+	out, err := u.next.AddLabel(ctx, id, name)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedTaskUsecase) RemoveLabel(ctx context.Context, id primitive.ObjectID, name string) (Domain.Task, error) {
+	ctx, done := u.observe(ctx, "task", "remove_label")
+	out, err := u.next.RemoveLabel(ctx, id, name)
+	done(err)
+	return out, err
+}
+
+// InstrumentUserUsecase wraps u so every call is reported to observe
+func InstrumentUserUsecase(u UserUsecase, observe Observer) UserUsecase {
+	return &instrumentedUserUsecase{next: u, observe: observe}
+}
+
+type instrumentedUserUsecase struct {
+	next    UserUsecase
+	observe Observer
+}
+
+func (u *instrumentedUserUsecase) Register(ctx context.Context, username, password string) (Domain.User, error) {
+	ctx, done := u.observe(ctx, "user", "register")
+	out, err := u.next.Register(ctx, username, password)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedUserUsecase) Login(ctx context.Context, username, password string) (Domain.User, error) {
+	ctx, done := u.observe(ctx, "user", "login")
+	out, err := u.next.Login(ctx, username, password)
+	done(err)
+	return out, err
+}
+
+func (u *instrumentedUserUsecase) Promote(ctx context.Context, username string) error {
+	ctx, done := u.observe(ctx, "user", "promote")
+	err := u.next.Promote(ctx, username)
+	done(err)
+	return err
+}
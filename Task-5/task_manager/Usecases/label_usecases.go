@@ -0,0 +1,360 @@
+package Usecases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"task_manager/Domain"
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LabelUsecase interface {
+	Create(ctx context.Context, l Domain.Label) (Domain.Label, error)
+	// List returns the catalog by name, each label with its task count
+	List(ctx context.Context) ([]Domain.Label, error)
+	Get(ctx context.Context, id primitive.ObjectID) (Domain.Label, error)
+	// Update changes the fields in patch; a new name is also written to
+	// every task carrying the label
+	Update(ctx context.Context, id primitive.ObjectID, patch LabelPatch) (Domain.Label, error)
+	// Delete removes the label from the catalog and from every task
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// Merge moves every task tagged with id over to into, then deletes id
+	Merge(ctx context.Context, id, into primitive.ObjectID) (Domain.Label, error)
+}
+
+// LabelPatch holds the fields an update changes; nil means unchanged
+type LabelPatch struct {
+	Name        *string
+	Color       *string
+	Description *string
+}
+
+type labelUsecase struct {
+	labels  Repositories.LabelRepository
+	tasks   Repositories.TaskRepository
+	tx      Repositories.Transactor
+	timeout time.Duration
+}
+
+// NewLabelUsecase wires the repositories; tx may be nil
+func NewLabelUsecase(l Repositories.LabelRepository, t Repositories.TaskRepository, tx Repositories.Transactor) LabelUsecase {
+	return &labelUsecase{labels: l, tasks: t, tx: tx, timeout: 5 * time.Second}
+}
+
+// atomically runs fn in a transaction when the store has them. Without one,
+// callers write the tasks before the catalog, so a failed call can simply be
+// repeated.
+func (u *labelUsecase) atomically(ctx context.Context, fn func(ctx context.Context) error) error {
+	if u.tx != nil && u.tx.SupportsTransactions() {
+		return u.tx.WithTransaction(ctx, fn)
+	}
+	return fn(ctx)
+}
+
+func (u *labelUsecase) withCount(ctx context.Context, l Domain.Label) (Domain.Label, error) {
+	counts, err := u.tasks.CountLabels(ctx)
+	if err != nil {
+		return Domain.Label{}, err
+	}
+	l.Count = counts[l.Name]
+	return l, nil
+}
+
+// checkNameFree fails if a label other than id has name, ignoring case
+func (u *labelUsecase) checkNameFree(ctx context.Context, id primitive.ObjectID, name string) error {
+	clash, err := u.labels.FindByKeys(ctx, []string{Domain.LabelKey(name)})
+	if err != nil {
+		return err
+	}
+	for _, l := range clash {
+		if l.ID != id {
+			return Domain.LabelNameTaken()
+		}
+	}
+	return nil
+}
+
+func (u *labelUsecase) Create(ctx context.Context, l Domain.Label) (Domain.Label, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	l, err := Domain.ValidateLabel(l)
+	if err != nil {
+		return Domain.Label{}, err
+	}
+	l.CreatedAt = time.Now().UTC()
+	return u.labels.Create(ctx, l)
+}
+
+func (u *labelUsecase) List(ctx context.Context) ([]Domain.Label, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	labels, err := u.labels.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := u.tasks.CountLabels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range labels {
+		labels[i].Count = counts[labels[i].Name]
+	}
+	return labels, nil
+}
+
+func (u *labelUsecase) Get(ctx context.Context, id primitive.ObjectID) (Domain.Label, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	l, err := u.labels.FindByID(ctx, id)
+	if err != nil {
+		return Domain.Label{}, err
+	}
+	return u.withCount(ctx, l)
+}
+
+func (u *labelUsecase) Update(ctx context.Context, id primitive.ObjectID, patch LabelPatch) (Domain.Label, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	current, err := u.labels.FindByID(ctx, id)
+	if err != nil {
+		return Domain.Label{}, err
+	}
+	oldName := current.Name
+	if patch.Name != nil {
+		current.Name = *patch.Name
+	}
+	if patch.Color != nil {
+		current.Color = *patch.Color
+	}
+	if patch.Description != nil {
+		current.Description = *patch.Description
+	}
+	current, err = Domain.ValidateLabel(current)
+	if err != nil {
+		return Domain.Label{}, err
+	}
+	set := map[string]interface{}{
+		"name":        current.Name,
+		"key":         current.Key,
+		"color":       current.Color,
+		"description": current.Description,
+	}
+	if current.Name == oldName {
+		updated, err := u.labels.Update(ctx, id, set)
+		if err != nil {
+			return Domain.Label{}, err
+		}
+		return u.withCount(ctx, updated)
+	}
+	// the unique index would catch a clash, but only after the tasks were
+	// renamed when there is no transaction to undo that
+	if err := u.checkNameFree(ctx, id, current.Name); err != nil {
+		return Domain.Label{}, err
+	}
+	var updated Domain.Label
+	err = u.atomically(ctx, func(ctx context.Context) error {
+		if err := u.tasks.ReplaceLabel(ctx, oldName, current.Name); err != nil {
+			return err
+		}
+		updated, err = u.labels.Update(ctx, id, set)
+		return err
+	})
+	if err != nil {
+		return Domain.Label{}, err
+	}
+	return u.withCount(ctx, updated)
+}
+
+func (u *labelUsecase) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	l, err := u.labels.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return u.atomically(ctx, func(ctx context.Context) error {
+		if err := u.tasks.DropLabel(ctx, l.Name); err != nil {
+			return err
+		}
+		return u.labels.Delete(ctx, id)
+	})
+}
+
+func (u *labelUsecase) Merge(ctx context.Context, id, into primitive.ObjectID) (Domain.Label, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	if id == into {
+		return Domain.Label{}, Domain.LabelError("into", "cannot merge a label into itself")
+	}
+	from, err := u.labels.FindByID(ctx, id)
+	if err != nil {
+		return Domain.Label{}, err
+	}
+	target, err := u.labels.FindByID(ctx, into)
+	if errors.Is(err, Domain.ErrNotFound) {
+		return Domain.Label{}, Domain.LabelError("into", "no such label")
+	}
+	if err != nil {
+		return Domain.Label{}, err
+	}
+	err = u.atomically(ctx, func(ctx context.Context) error {
+		if err := u.tasks.ReplaceLabel(ctx, from.Name, target.Name); err != nil {
+			return err
+		}
+		return u.labels.Delete(ctx, id)
+	})
+	if err != nil {
+		return Domain.Label{}, err
+	}
+	return u.withCount(ctx, target)
+}
+
+// --- Tagging tasks ---
+
+// resolveLabels maps names to the catalog's spelling, in the order given,
+// failing on the first name the catalog lacks
+func (u *taskUsecase) resolveLabels(ctx context.Context, field string, names []string) ([]string, error) {
+	return resolveLabelNames(ctx, u.labels, field, names)
+}
+
+func resolveLabelNames(ctx context.Context, labels Repositories.LabelRepository, field string, names []string) ([]string, error) {
+	catalog, err := findLabelNames(ctx, labels, names)
+	if err != nil {
+		return nil, err
+	}
+	return catalog.resolve(field, names)
+}
+
+// labelNames maps label keys to the catalog's spelling
+type labelNames map[string]string
+
+// findLabelNames looks up the catalog entries for names in one query
+func findLabelNames(ctx context.Context, labels Repositories.LabelRepository, names []string) (labelNames, error) {
+	keys := make([]string, len(names))
+	for i, n := range names {
+		keys[i] = Domain.LabelKey(n)
+	}
+	found, err := labels.FindByKeys(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(labelNames, len(found))
+	for _, l := range found {
+		byKey[l.Key] = l.Name
+	}
+	return byKey, nil
+}
+
+// resolve maps names to the catalog's spelling, in the order given, failing
+// on the first name the catalog lacks
+func (c labelNames) resolve(field string, names []string) ([]string, error) {
+	out := make([]string, len(names))
+	for i, n := range names {
+		name, ok := c[Domain.LabelKey(n)]
+		if !ok {
+			return nil, Domain.UnknownLabelError(field, n)
+		}
+		out[i] = name
+	}
+	return out, nil
+}
+
+// bulkLabels looks up the labels of every create in the batch at once
+func (u *taskUsecase) bulkLabels(ctx context.Context, ops []BulkTaskOp) (labelNames, error) {
+	var names []string
+	for _, op := range ops {
+		if op.Op == "create" && op.Invalid == nil {
+			names = append(names, op.Task.Labels...)
+		}
+	}
+	if len(names) == 0 {
+		return labelNames{}, nil
+	}
+	return findLabelNames(ctx, u.labels, names)
+}
+
+// maxLabelAttempts bounds retries when the labels change between reading and
+// writing them
+const maxLabelAttempts = 3
+
+// editLabels writes edit's result only if labels still holds what was read,
+// reading again when it doesn't. edit gets the task as read and must not
+// change it.
+func (u *taskUsecase) editLabels(ctx context.Context, id primitive.ObjectID, edit func(t Domain.Task) ([]string, error)) (Domain.Task, error) {
+	for attempt := 1; ; attempt++ {
+		t, err := u.repo.FindByID(ctx, id)
+		if err != nil {
+			return Domain.Task{}, err
+		}
+		labels, err := edit(t)
+		if errors.Is(err, errUnchanged) {
+			tasks, err := u.withProgress(ctx, []Domain.Task{t})
+			if err != nil {
+				return Domain.Task{}, err
+			}
+			return tasks[0], nil
+		}
+		if err != nil {
+			return Domain.Task{}, err
+		}
+		change := Domain.TaskChange{Set: map[string]interface{}{}, Expect: map[string]interface{}{"labels": nil}}
+		if len(t.Labels) > 0 {
+			change.Expect["labels"] = t.Labels
+		}
+		if len(labels) > 0 {
+			change.Set["labels"] = labels
+		} else {
+			change.Unset = []string{"labels"}
+		}
+		updated, err := u.update(ctx, id, change)
+		if errors.Is(err, Domain.ErrPreconditionFailed) && attempt < maxLabelAttempts {
+			continue
+		}
+		return updated, err
+	}
+}
+
+// AddLabel tags the task with a catalog label. Adding a label the task
+// already has changes nothing.
+func (u *taskUsecase) AddLabel(ctx context.Context, id primitive.ObjectID, name string) (Domain.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	names, err := Domain.NormalizeLabelNames([]string{name})
+	if err != nil {
+		return Domain.Task{}, err
+	}
+	names, err = u.resolveLabels(ctx, "label", names)
+	if err != nil {
+		return Domain.Task{}, err
+	}
+	return u.editLabels(ctx, id, func(t Domain.Task) ([]string, error) {
+		for _, l := range t.Labels {
+			if l == names[0] {
+				return nil, errUnchanged
+			}
+		}
+		if len(t.Labels) >= Domain.MaxTaskLabels {
+			return nil, Domain.LabelError("label", "a task may have at most 20 labels")
+		}
+		return append(append([]string(nil), t.Labels...), names[0]), nil
+	})
+}
+
+// RemoveLabel untags the task; the name is matched ignoring case and needn't
+// be in the catalog any more
+func (u *taskUsecase) RemoveLabel(ctx context.Context, id primitive.ObjectID, name string) (Domain.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	key := Domain.LabelKey(name)
+	return u.editLabels(ctx, id, func(t Domain.Task) ([]string, error) {
+		for i, l := range t.Labels {
+			if Domain.LabelKey(l) == key {
+				return append(append([]string(nil), t.Labels[:i]...), t.Labels[i+1:]...), nil
+			}
+		}
+		return nil, Domain.ErrNotFound
+	})
+}
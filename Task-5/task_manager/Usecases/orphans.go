@@ -0,0 +1,73 @@
+package Usecases
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"task_manager/Domain"
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Orphan sweep policy
+const (
+	orphanSweepInterval = 10 * time.Minute
+	orphanSweepBatch    = 100
+)
+
+// orphanSweep removes what deleted tasks left behind. Deleting a task
+// cleans up right away, but that can fail, and a write that checked the
+// task just before it was deleted can land afterwards; the sweep retries
+// both until they are gone.
+type orphanSweep struct {
+	what    string // for logs: "attachments" or "comments"
+	find    func(ctx context.Context, limit int64) ([]Repositories.TaskRef, error)
+	clean   func(ctx context.Context, taskID primitive.ObjectID) error
+	timeout time.Duration
+}
+
+// run sweeps at once and then every orphanSweepInterval until ctx is done
+func (s orphanSweep) run(ctx context.Context) {
+	ticker := time.NewTicker(orphanSweepInterval)
+	defer ticker.Stop()
+	for {
+		s.once(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// once cleans up after every deleted task found, a batch at a time. A task
+// that fails is left for the next sweep.
+func (s orphanSweep) once(ctx context.Context) {
+	for ctx.Err() == nil {
+		fctx, cancel := context.WithTimeout(ctx, s.timeout)
+		refs, err := s.find(fctx, orphanSweepBatch)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error(s.what+": orphan sweep failed", "error", err)
+			}
+			return
+		}
+		failed := false
+		for _, ref := range refs {
+			cctx, cancel := context.WithTimeout(Domain.WithWorkspace(ctx, ref.WorkspaceID), s.timeout)
+			err := s.clean(cctx, ref.TaskID)
+			cancel()
+			if err != nil {
+				slog.Error(s.what+": delete for deleted task", "task_id", ref.TaskID.Hex(), "error", err)
+				failed = true
+			}
+		}
+		// a failed task would come back in the next batch
+		if failed || len(refs) < orphanSweepBatch {
+			return
+		}
+	}
+}
@@ -0,0 +1,458 @@
+package Usecases
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"task_manager/Domain"
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RecurrenceUsecase manages recurring task templates and the worker that
+// turns them into tasks. Every member may read them; admins edit them.
+type RecurrenceUsecase interface {
+	Create(ctx context.Context, r Domain.RecurringTask) (Domain.RecurringTask, error)
+	List(ctx context.Context) ([]Domain.RecurringTask, error)
+	Get(ctx context.Context, id primitive.ObjectID) (Domain.RecurringTask, error)
+	// Update edits the template. Occurrences generated from then on follow
+	// it; with future the pending occurrences still ahead are brought in
+	// line too, and those the new rule no longer has are deleted.
+	Update(ctx context.Context, id primitive.ObjectID, patch RecurrencePatch, future bool) (Domain.RecurringTask, error)
+	// Delete removes the template; with future its pending occurrences still
+	// ahead are deleted too
+	Delete(ctx context.Context, id primitive.ObjectID, future bool) error
+	// ListOccurrences lists the tasks generated from the template, in
+	// occurrence order
+	ListOccurrences(ctx context.Context, id primitive.ObjectID) ([]Domain.Task, error)
+	// Run generates occurrences until ctx is done
+	Run(ctx context.Context)
+	Healthy(ctx context.Context) error
+}
+
+// RecurrencePatch is a partial template edit; nil fields are left alone
+type RecurrencePatch struct {
+	Title       *string
+	Description *string
+	Estimate    *string
+	Labels      *[]string
+	RRule       *string
+	Start       *string
+}
+
+// RecurrenceOptions tune the generation worker
+type RecurrenceOptions struct {
+	// Horizon is how far ahead occurrences are turned into tasks
+	Horizon time.Duration
+	// Interval is how often the worker looks for templates due a run
+	Interval time.Duration
+}
+
+const (
+	recurrenceLease = time.Minute
+	// recurrenceBatch caps the tasks created per template and run; the
+	// template is run again straight away when there are more
+	recurrenceBatch = 100
+	recurrenceRetry = 5 * time.Minute
+	// recurrenceUpcoming is how many occurrences reads preview
+	recurrenceUpcoming = 5
+)
+
+type recurrenceUsecase struct {
+	recurrences Repositories.RecurrenceRepository
+	taskRepo    Repositories.TaskRepository
+	labels      Repositories.LabelRepository
+	tasks       TaskUsecase
+	opts        RecurrenceOptions
+	kick        chan struct{}
+	running     atomic.Bool
+	timeout     time.Duration
+}
+
+// NewRecurrenceUsecase wires the repositories. Occurrences are created,
+// edited and deleted through tasks, so they publish events like any task.
+func NewRecurrenceUsecase(r Repositories.RecurrenceRepository, t Repositories.TaskRepository, labels Repositories.LabelRepository, tasks TaskUsecase, opts RecurrenceOptions) RecurrenceUsecase {
+	return &recurrenceUsecase{
+		recurrences: r,
+		taskRepo:    t,
+		labels:      labels,
+		tasks:       tasks,
+		opts:        opts,
+		kick:        make(chan struct{}, 1),
+		timeout:     5 * time.Second,
+	}
+}
+
+func (u *recurrenceUsecase) wake() {
+	select {
+	case u.kick <- struct{}{}:
+	default:
+	}
+}
+
+// preview fills in the template's next occurrences after now
+func preview(r *Domain.RecurringTask, now time.Time) {
+	rule, err := Domain.ParseRRule(r.RRule)
+	start, ok := Domain.ParseRecurrenceStart(r.Start)
+	if err != nil || !ok {
+		return
+	}
+	r.Upcoming = nil
+	rule.Each(start, func(at time.Time) bool {
+		if at.After(now) {
+			r.Upcoming = append(r.Upcoming, at)
+		}
+		return len(r.Upcoming) < recurrenceUpcoming
+	})
+}
+
+// prepare validates a template and resolves its labels against the catalog
+func (u *recurrenceUsecase) prepare(ctx context.Context, r Domain.RecurringTask) (Domain.RecurringTask, error) {
+	r, err := Domain.ValidateRecurringTask(r)
+	if err != nil {
+		return Domain.RecurringTask{}, err
+	}
+	if len(r.Labels) > 0 {
+		if r.Labels, err = resolveLabelNames(ctx, u.labels, "labels", r.Labels); err != nil {
+			return Domain.RecurringTask{}, err
+		}
+	}
+	return r, nil
+}
+
+func (u *recurrenceUsecase) Create(ctx context.Context, r Domain.RecurringTask) (Domain.RecurringTask, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	r, err := u.prepare(ctx, r)
+	if err != nil {
+		return Domain.RecurringTask{}, err
+	}
+	now := *writeTime()
+	r.CreatedAt, r.UpdatedAt = now, now
+	r.NextRunAt = &now
+	r.Version = 1
+	created, err := u.recurrences.Create(ctx, r)
+	if err != nil {
+		return Domain.RecurringTask{}, err
+	}
+	u.wake()
+	preview(&created, now)
+	return created, nil
+}
+
+func (u *recurrenceUsecase) List(ctx context.Context) ([]Domain.RecurringTask, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	rs, err := u.recurrences.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range rs {
+		preview(&rs[i], now)
+	}
+	return rs, nil
+}
+
+func (u *recurrenceUsecase) Get(ctx context.Context, id primitive.ObjectID) (Domain.RecurringTask, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	r, err := u.recurrences.FindByID(ctx, id)
+	if err != nil {
+		return Domain.RecurringTask{}, err
+	}
+	preview(&r, time.Now())
+	return r, nil
+}
+
+func (u *recurrenceUsecase) Update(ctx context.Context, id primitive.ObjectID, patch RecurrencePatch, future bool) (Domain.RecurringTask, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	r, err := u.recurrences.FindByID(ctx, id)
+	if err != nil {
+		return Domain.RecurringTask{}, err
+	}
+	old := r
+	for _, f := range []struct {
+		val *string
+		dst *string
+	}{{patch.Title, &r.Title}, {patch.Description, &r.Description}, {patch.Estimate, &r.Estimate}, {patch.RRule, &r.RRule}, {patch.Start, &r.Start}} {
+		if f.val != nil {
+			*f.dst = *f.val
+		}
+	}
+	if patch.Labels != nil {
+		r.Labels = *patch.Labels
+	}
+	if r, err = u.prepare(ctx, r); err != nil {
+		return Domain.RecurringTask{}, err
+	}
+	now := *writeTime()
+	if r.RRule != old.RRule || r.Start != old.Start {
+		// the new schedule starts after what is kept of the old one: now
+		// when occurrences ahead are synced, otherwise after those already
+		// generated
+		switch {
+		case r.GeneratedThrough == nil,
+			future && r.GeneratedThrough.After(now),
+			!future && r.GeneratedThrough.Before(now):
+			r.GeneratedThrough = &now
+		}
+		r.NextOccurrence = nil
+	}
+	if future && r.SyncAfter == nil {
+		r.SyncAfter = &now
+	}
+	r.UpdatedAt = now
+	r.NextRunAt = &now
+	r.LastError = ""
+	updated, err := u.recurrences.Update(ctx, r)
+	if err != nil {
+		return Domain.RecurringTask{}, err
+	}
+	u.wake()
+	preview(&updated, now)
+	return updated, nil
+}
+
+func (u *recurrenceUsecase) Delete(ctx context.Context, id primitive.ObjectID, future bool) error {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	if err := u.recurrences.Delete(ctx, id); err != nil {
+		return err
+	}
+	if !future {
+		return nil
+	}
+	pending, err := u.taskRepo.FindOccurrences(ctx, id, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, t := range pending {
+		if t.Status != Domain.StatusPending {
+			continue
+		}
+		if err := u.tasks.DeleteTask(ctx, t.ID); err != nil && !skippable(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *recurrenceUsecase) ListOccurrences(ctx context.Context, id primitive.ObjectID) ([]Domain.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	if _, err := u.recurrences.FindByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return u.taskRepo.FindOccurrences(ctx, id, time.Time{})
+}
+
+// skippable reports errors that leave one occurrence as it is without
+// failing the whole run: it is gone, changed meanwhile or can't take the
+// change, e.g. because it has subtasks or a label left the catalog
+func skippable(err error) bool {
+	var v *Domain.ValidationError
+	return errors.Is(err, Domain.ErrNotFound) || errors.Is(err, Domain.ErrPreconditionFailed) || errors.As(err, &v)
+}
+
+func (u *recurrenceUsecase) Healthy(ctx context.Context) error {
+	if !u.running.Load() {
+		return errors.New("recurrence worker not running")
+	}
+	return nil
+}
+
+func (u *recurrenceUsecase) Run(ctx context.Context) {
+	u.running.Store(true)
+	defer u.running.Store(false)
+	ticker := time.NewTicker(u.opts.Interval)
+	defer ticker.Stop()
+	for {
+		r, err := u.recurrences.ClaimDue(ctx, time.Now().UTC(), recurrenceLease)
+		if err == nil {
+			u.process(ctx, r)
+			continue
+		}
+		if !errors.Is(err, Domain.ErrNotFound) && ctx.Err() == nil {
+			slog.Error("recurrence: claim failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-u.kick:
+		}
+	}
+}
+
+// process runs one claimed template and records how far it got. Progress is
+// saved only if the template wasn't edited meanwhile; an edit runs it again.
+func (u *recurrenceUsecase) process(ctx context.Context, r Domain.RecurringTask) {
+	wctx, cancel := context.WithTimeout(Domain.WithWorkspace(ctx, r.WorkspaceID), recurrenceLease/2)
+	defer cancel()
+	r.LastError = ""
+	if r.SyncAfter != nil {
+		if err := u.sync(wctx, r); err != nil {
+			r.LastError = err.Error()
+		} else {
+			r.SyncAfter = nil
+		}
+	}
+	if r.LastError == "" {
+		u.generate(wctx, &r)
+	} else {
+		retry := time.Now().UTC().Add(recurrenceRetry)
+		r.NextRunAt = &retry
+	}
+	if r.LastError != "" {
+		slog.Warn("recurrence: run failed", "recurrence_id", r.ID.Hex(), "error", r.LastError)
+	}
+	// saved even when ctx is done, so the next run doesn't redo the work
+	save, cancel := context.WithTimeout(context.Background(), u.timeout)
+	defer cancel()
+	if err := u.recurrences.SaveProgress(save, r); err != nil {
+		slog.Error("recurrence: save progress", "recurrence_id", r.ID.Hex(), "error", err)
+	}
+}
+
+// generate creates the occurrences within the horizon that aren't tasks
+// yet and works out when the template is next due. An occurrence created
+// by another replica, or before a restart, is left as it is.
+func (u *recurrenceUsecase) generate(ctx context.Context, r *Domain.RecurringTask) {
+	rule, err := Domain.ParseRRule(r.RRule)
+	start, ok := Domain.ParseRecurrenceStart(r.Start)
+	if err != nil || !ok {
+		r.LastError, r.NextRunAt, r.NextOccurrence = "template is invalid", nil, nil
+		return
+	}
+	now := time.Now().UTC()
+	horizon := now.Add(u.opts.Horizon)
+	// nothing from before the day the template was made
+	y, m, d := r.CreatedAt.In(start.Location()).Date()
+	floor := time.Date(y, m, d, 0, 0, 0, 0, start.Location())
+	var next *time.Time
+	created := 0
+	rule.Each(start, func(at time.Time) bool {
+		if at.Before(floor) || r.GeneratedThrough != nil && !at.After(*r.GeneratedThrough) {
+			return true
+		}
+		if at.After(horizon) || created == recurrenceBatch {
+			next = &at
+			return false
+		}
+		if _, err := u.tasks.CreateTask(ctx, r.Occurrence(at)); err != nil && !errors.Is(err, Domain.ErrOccurrenceExists) {
+			r.LastError = err.Error()
+			next = &at
+			return false
+		}
+		created++
+		r.GeneratedThrough = &at
+		return true
+	})
+	r.NextOccurrence = next
+	switch {
+	case r.LastError != "":
+		retry := now.Add(recurrenceRetry)
+		r.NextRunAt = &retry
+	case next == nil:
+		// the rule has ended
+		r.NextRunAt = nil
+	case !next.After(horizon):
+		r.NextRunAt = &now
+	default:
+		due := next.Add(-u.opts.Horizon).UTC()
+		r.NextRunAt = &due
+	}
+}
+
+// sync brings the template's pending occurrences after SyncAfter in line
+// with it. Tasks someone started or finished are theirs and stay as they are.
+func (u *recurrenceUsecase) sync(ctx context.Context, r Domain.RecurringTask) error {
+	rule, err := Domain.ParseRRule(r.RRule)
+	start, ok := Domain.ParseRecurrenceStart(r.Start)
+	if err != nil || !ok {
+		return errors.New("template is invalid")
+	}
+	occurrences, err := u.taskRepo.FindOccurrences(ctx, r.ID, *r.SyncAfter)
+	if err != nil || len(occurrences) == 0 {
+		return err
+	}
+	last := *occurrences[len(occurrences)-1].Occurrence
+	scheduled := map[int64]bool{}
+	rule.Each(start, func(at time.Time) bool {
+		if at.After(last) {
+			return false
+		}
+		scheduled[at.Unix()] = true
+		return true
+	})
+	for _, t := range occurrences {
+		if t.Status != Domain.StatusPending {
+			continue
+		}
+		if !scheduled[t.Occurrence.Unix()] {
+			err = u.tasks.DeleteTask(ctx, t.ID)
+		} else {
+			// stored in UTC; due times are written in start's offset
+			err = u.syncTask(ctx, t, r.Occurrence(t.Occurrence.In(start.Location())))
+		}
+		if err != nil && !skippable(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncTask makes t look like want. The fields are written only while t is
+// still pending; labels follow one at a time.
+func (u *recurrenceUsecase) syncTask(ctx context.Context, t, want Domain.Task) error {
+	change := Domain.TaskChange{
+		Set:    map[string]interface{}{},
+		Expect: map[string]interface{}{"status": Domain.StatusPending},
+	}
+	for _, f := range []struct{ field, have, want string }{
+		{"title", t.Title, want.Title},
+		{"description", t.Description, want.Description},
+		{"estimate", t.Estimate, want.Estimate},
+		{"due_date", t.DueDate, want.DueDate},
+	} {
+		switch {
+		case f.have == f.want:
+		case f.want == "":
+			change.Unset = append(change.Unset, f.field)
+		default:
+			change.Set[f.field] = f.want
+		}
+	}
+	if len(change.Set) > 0 || len(change.Unset) > 0 {
+		if _, err := u.tasks.UpdateTask(ctx, t.ID, change); err != nil {
+			return err
+		}
+	}
+	have := map[string]bool{}
+	for _, l := range t.Labels {
+		have[Domain.LabelKey(l)] = true
+	}
+	wanted := map[string]bool{}
+	for _, l := range want.Labels {
+		wanted[Domain.LabelKey(l)] = true
+		if !have[Domain.LabelKey(l)] {
+			if _, err := u.tasks.AddLabel(ctx, t.ID, l); err != nil && !skippable(err) {
+				return err
+			}
+		}
+	}
+	for _, l := range t.Labels {
+		if !wanted[Domain.LabelKey(l)] {
+			if _, err := u.tasks.RemoveLabel(ctx, t.ID, l); err != nil && !skippable(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,362 @@
+package Usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"task_manager/Domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TaskOptions tunes the task rules
+type TaskOptions struct {
+	// MaxDepth is how many levels of subtasks may nest, top-level tasks included
+	MaxDepth int
+}
+
+func childrenByParent(children []Domain.Task) map[primitive.ObjectID][]Domain.Task {
+	out := make(map[primitive.ObjectID][]Domain.Task)
+	for _, c := range children {
+		if c.ParentID != nil {
+			out[*c.ParentID] = append(out[*c.ParentID], c)
+		}
+	}
+	return out
+}
+
+// attachProgress sets Progress on each task from the subtasks in children
+func attachProgress(tasks, children []Domain.Task) {
+	byParent := childrenByParent(children)
+	for i := range tasks {
+		tasks[i].Progress = Domain.ComputeProgress(tasks[i], byParent[tasks[i].ID])
+	}
+}
+
+// withProgress fills in Progress, looking up the subtasks of every task in one query
+func (u *taskUsecase) withProgress(ctx context.Context, tasks []Domain.Task) ([]Domain.Task, error) {
+	if len(tasks) == 0 {
+		return tasks, nil
+	}
+	ids := make([]primitive.ObjectID, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+	children, err := u.repo.FindChildren(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	attachProgress(tasks, children)
+	return tasks, nil
+}
+
+// ancestors returns id followed by its parent, grandparent and so on, at
+// most MaxDepth+1 of them. A missing ancestor ends the chain, as if its
+// subtree were top-level; a missing id is Domain.ErrNotFound.
+func (u *taskUsecase) ancestors(ctx context.Context, id primitive.ObjectID) ([]primitive.ObjectID, error) {
+	var chain []primitive.ObjectID
+	for cur := &id; cur != nil && len(chain) <= u.opts.MaxDepth; {
+		t, err := u.repo.FindByID(ctx, *cur)
+		if errors.Is(err, Domain.ErrNotFound) && len(chain) > 0 {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, t.ID)
+		cur = t.ParentID
+	}
+	return chain, nil
+}
+
+// height counts the levels of id's subtree, id included, stopping once it
+// passes MaxDepth
+func (u *taskUsecase) height(ctx context.Context, id primitive.ObjectID) (int, error) {
+	levels, frontier := 1, []primitive.ObjectID{id}
+	for levels <= u.opts.MaxDepth {
+		children, err := u.repo.FindChildren(ctx, frontier)
+		if err != nil {
+			return 0, err
+		}
+		if len(children) == 0 {
+			break
+		}
+		levels++
+		frontier = make([]primitive.ObjectID, len(children))
+		for i, c := range children {
+			frontier[i] = c.ID
+		}
+	}
+	return levels, nil
+}
+
+// checkParent makes sure parent exists, isn't inside the subtree of moving
+// (zero when creating) and has room for a subtree levels deep below it
+func (u *taskUsecase) checkParent(ctx context.Context, parent, moving primitive.ObjectID, levels int) error {
+	chain, err := u.ancestors(ctx, parent)
+	if errors.Is(err, Domain.ErrNotFound) {
+		return Domain.ParentError("no such task")
+	}
+	if err != nil {
+		return err
+	}
+	for _, a := range chain {
+		if a == moving {
+			return Domain.ParentError("cannot move a task under itself or one of its subtasks")
+		}
+	}
+	if depth := len(chain) + levels; depth > u.opts.MaxDepth {
+		return Domain.ParentError(fmt.Sprintf("would nest subtasks %d levels deep; the limit is %d", depth, u.opts.MaxDepth))
+	}
+	return nil
+}
+
+// checkCompletion refuses to mark a task done while any of its subtasks is
+// open, unless the change is forced or the task is already done
+func (u *taskUsecase) checkCompletion(ctx context.Context, id primitive.ObjectID, change Domain.TaskChange) error {
+	if change.Force || change.Set["status"] != Domain.StatusDone {
+		return nil
+	}
+	children, err := u.repo.FindChildren(ctx, []primitive.ObjectID{id})
+	if err != nil {
+		return err
+	}
+	open := Domain.OpenSubtasks(children)
+	if open == 0 {
+		return nil
+	}
+	if t, err := u.repo.FindByID(ctx, id); err == nil && t.Status == Domain.StatusDone {
+		return nil
+	}
+	return Domain.OpenSubtasksError(open)
+}
+
+// bulkSubtaskRules applies the subtask rules to a batch, counting what the
+// batch itself does: a subtask completed or deleted in the same batch is no
+// longer open. The result maps op indexes to the rule they break.
+func (u *taskUsecase) bulkSubtaskRules(ctx context.Context, ops []BulkTaskOp) (map[int]error, error) {
+	completing := map[primitive.ObjectID]bool{}
+	deleting := map[primitive.ObjectID]bool{}
+	var check []primitive.ObjectID
+	for _, op := range ops {
+		if op.Invalid != nil {
+			continue
+		}
+		switch op.Op {
+		case "update":
+			if s, ok := op.Change.Set["status"].(string); ok && !op.Change.Force {
+				if norm, _ := Domain.NormalizeStatus(s); norm == Domain.StatusDone {
+					completing[op.ID] = true
+					check = append(check, op.ID)
+				}
+			}
+		case "delete":
+			deleting[op.ID] = true
+			check = append(check, op.ID)
+		}
+	}
+
+	errs := map[int]error{}
+	for i, op := range ops {
+		if op.Op != "create" || op.Task.ParentID == nil || op.Invalid != nil {
+			continue
+		}
+		if deleting[*op.Task.ParentID] {
+			errs[i] = Domain.ParentError("is deleted by this batch")
+		} else if err := u.checkParent(ctx, *op.Task.ParentID, primitive.NilObjectID, 1); err != nil {
+			errs[i] = err
+		}
+	}
+	if len(check) == 0 {
+		return errs, nil
+	}
+	children, err := u.repo.FindChildren(ctx, check)
+	if err != nil {
+		return nil, err
+	}
+	byParent := childrenByParent(children)
+	var reopened []primitive.ObjectID // completions with open subtasks
+	for i, op := range ops {
+		remaining, open := 0, 0
+		for _, c := range byParent[op.ID] {
+			if deleting[c.ID] {
+				continue
+			}
+			remaining++
+			if c.Status != Domain.StatusDone && !completing[c.ID] {
+				open++
+			}
+		}
+		switch {
+		case op.Op == "delete" && remaining > 0:
+			errs[i] = Domain.HasSubtasksError(remaining)
+		case op.Op == "update" && completing[op.ID] && open > 0:
+			errs[i] = Domain.OpenSubtasksError(open)
+			reopened = append(reopened, op.ID)
+		}
+	}
+	if len(reopened) == 0 {
+		return errs, nil
+	}
+	// completing a task that is already done changes nothing, so it passes
+	current, err := u.repo.FindByIDs(ctx, reopened)
+	if err != nil {
+		return nil, err
+	}
+	done := map[primitive.ObjectID]bool{}
+	for _, t := range current {
+		done[t.ID] = t.Status == Domain.StatusDone
+	}
+	for i, op := range ops {
+		if op.Op == "update" && done[op.ID] {
+			delete(errs, i)
+		}
+	}
+	return errs, nil
+}
+
+func (u *taskUsecase) ListSubtasks(ctx context.Context, id primitive.ObjectID) ([]Domain.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	if _, err := u.repo.FindByID(ctx, id); err != nil {
+		return nil, err
+	}
+	children, err := u.repo.FindChildren(ctx, []primitive.ObjectID{id})
+	if err != nil {
+		return nil, err
+	}
+	return u.withProgress(ctx, children)
+}
+
+// ListChildren lists the direct subtasks of every given parent in one
+// query, oldest first; unknown parents have none
+func (u *taskUsecase) ListChildren(ctx context.Context, parents []primitive.ObjectID) ([]Domain.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	children, err := u.repo.FindChildren(ctx, parents)
+	if err != nil {
+		return nil, err
+	}
+	return u.withProgress(ctx, children)
+}
+
+// MoveTask puts the task, with its whole subtree, under parent; a zero
+// parent makes it top-level
+func (u *taskUsecase) MoveTask(ctx context.Context, id, parent primitive.ObjectID) (Domain.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	t, err := u.repo.FindByID(ctx, id)
+	if err != nil {
+		return Domain.Task{}, err
+	}
+	if (t.ParentID == nil && parent.IsZero()) || (t.ParentID != nil && *t.ParentID == parent) {
+		tasks, err := u.withProgress(ctx, []Domain.Task{t})
+		if err != nil {
+			return Domain.Task{}, err
+		}
+		return tasks[0], nil
+	}
+	// the move only applies if no other move got there first
+	change := Domain.TaskChange{Set: map[string]interface{}{}, Expect: map[string]interface{}{"parent_id": nil}}
+	if t.ParentID != nil {
+		change.Expect["parent_id"] = *t.ParentID
+	}
+	if parent.IsZero() {
+		change.Unset = []string{"parent_id"}
+	} else {
+		levels, err := u.height(ctx, id)
+		if err != nil {
+			return Domain.Task{}, err
+		}
+		if err := u.checkParent(ctx, parent, id, levels); err != nil {
+			return Domain.Task{}, err
+		}
+		change.Set["parent_id"] = parent
+	}
+	return u.update(ctx, id, change)
+}
+
+// maxChecklistAttempts bounds retries when the checklist changes between
+// reading and writing it
+const maxChecklistAttempts = 3
+
+// editChecklist writes edit's result only if the checklist still holds what
+// was read, reading again when it doesn't. edit gets a copy it may change.
+func (u *taskUsecase) editChecklist(ctx context.Context, id primitive.ObjectID, edit func([]Domain.ChecklistItem) ([]Domain.ChecklistItem, error)) (Domain.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	for attempt := 1; ; attempt++ {
+		t, err := u.repo.FindByID(ctx, id)
+		if err != nil {
+			return Domain.Task{}, err
+		}
+		items, err := edit(append([]Domain.ChecklistItem(nil), t.Checklist...))
+		if err != nil {
+			return Domain.Task{}, err
+		}
+		change := Domain.TaskChange{Set: map[string]interface{}{}, Expect: map[string]interface{}{"checklist": nil}}
+		if len(t.Checklist) > 0 {
+			change.Expect["checklist"] = t.Checklist
+		}
+		if len(items) > 0 {
+			change.Set["checklist"] = items
+		} else {
+			change.Unset = []string{"checklist"}
+		}
+		updated, err := u.update(ctx, id, change)
+		if errors.Is(err, Domain.ErrPreconditionFailed) && attempt < maxChecklistAttempts {
+			continue
+		}
+		return updated, err
+	}
+}
+
+func (u *taskUsecase) AddChecklistItem(ctx context.Context, id primitive.ObjectID, text string) (Domain.Task, error) {
+	text, err := Domain.ValidateChecklistText(text)
+	if err != nil {
+		return Domain.Task{}, err
+	}
+	return u.editChecklist(ctx, id, func(items []Domain.ChecklistItem) ([]Domain.ChecklistItem, error) {
+		if len(items) >= Domain.MaxChecklistItems {
+			return nil, Domain.ChecklistFullError()
+		}
+		return append(items, Domain.ChecklistItem{ID: primitive.NewObjectID(), Text: text}), nil
+	})
+}
+
+func (u *taskUsecase) UpdateChecklistItem(ctx context.Context, id, itemID primitive.ObjectID, patch Domain.ChecklistItemPatch) (Domain.Task, error) {
+	if patch.Text != nil {
+		text, err := Domain.ValidateChecklistText(*patch.Text)
+		if err != nil {
+			return Domain.Task{}, err
+		}
+		patch.Text = &text
+	}
+	return u.editChecklist(ctx, id, func(items []Domain.ChecklistItem) ([]Domain.ChecklistItem, error) {
+		for i := range items {
+			if items[i].ID != itemID {
+				continue
+			}
+			if patch.Text != nil {
+				items[i].Text = *patch.Text
+			}
+			if patch.Done != nil {
+				items[i].Done = *patch.Done
+			}
+			return items, nil
+		}
+		return nil, Domain.ErrNotFound
+	})
+}
+
+func (u *taskUsecase) DeleteChecklistItem(ctx context.Context, id, itemID primitive.ObjectID) (Domain.Task, error) {
+	return u.editChecklist(ctx, id, func(items []Domain.ChecklistItem) ([]Domain.ChecklistItem, error) {
+		for i := range items {
+			if items[i].ID == itemID {
+				return append(items[:i], items[i+1:]...), nil
+			}
+		}
+		return nil, Domain.ErrNotFound
+	})
+}
@@ -2,6 +2,7 @@ package Usecases
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"task_manager/Domain"
@@ -11,48 +12,439 @@ import (
 )
 
 type TaskUsecase interface {
-	CreateTask(t Domain.Task) (Domain.Task, error)
-	ListTasks() ([]Domain.Task, error)
-	GetTaskByID(id primitive.ObjectID) (Domain.Task, error)
-	UpdateTask(id primitive.ObjectID, patch map[string]interface{}) (Domain.Task, error)
-	DeleteTask(id primitive.ObjectID) error
+	CreateTask(ctx context.Context, t Domain.Task) (Domain.Task, error)
+	// ListTasks lists the tasks that pass filter; filter labels are matched
+	// against the catalog ignoring case
+	ListTasks(ctx context.Context, filter Domain.TaskFilter) ([]Domain.Task, error)
+	GetTaskByID(ctx context.Context, id primitive.ObjectID) (Domain.Task, error)
+	// GetTasksByIDs loads several tasks in one query; missing IDs are left out
+	GetTasksByIDs(ctx context.Context, ids []primitive.ObjectID) ([]Domain.Task, error)
+	UpdateTask(ctx context.Context, id primitive.ObjectID, change Domain.TaskChange) (Domain.Task, error)
+	// ReplaceTask overwrites every field; force completes a task with open
+	// subtasks or starts one with open blockers
+	ReplaceTask(ctx context.Context, id primitive.ObjectID, t Domain.Task, force bool) (Domain.Task, error)
+	DeleteTask(ctx context.Context, id primitive.ObjectID) error
+	BulkTasks(ctx context.Context, ops []BulkTaskOp, stopOnError bool) (BulkTaskReport, error)
+	// ListSubtasks lists a task's direct subtasks, oldest first
+	ListSubtasks(ctx context.Context, id primitive.ObjectID) ([]Domain.Task, error)
+	// ListChildren lists the direct subtasks of several parents at once
+	ListChildren(ctx context.Context, parents []primitive.ObjectID) ([]Domain.Task, error)
+	// MoveTask makes the task and its subtree children of parent, or
+	// top-level when parent is zero
+	MoveTask(ctx context.Context, id, parent primitive.ObjectID) (Domain.Task, error)
+	AddChecklistItem(ctx context.Context, id primitive.ObjectID, text string) (Domain.Task, error)
+	UpdateChecklistItem(ctx context.Context, id, itemID primitive.ObjectID, patch Domain.ChecklistItemPatch) (Domain.Task, error)
+	DeleteChecklistItem(ctx context.Context, id, itemID primitive.ObjectID) (Domain.Task, error)
+	// ListDependencies loads the task's blockers and the tasks it blocks
+	ListDependencies(ctx context.Context, id primitive.ObjectID) (Domain.Dependencies, error)
+	// AddBlocker makes id wait on blocker, refusing edges that close a cycle
+	AddBlocker(ctx context.Context, id, blocker primitive.ObjectID) (Domain.Task, error)
+	RemoveBlocker(ctx context.Context, id, blocker primitive.ObjectID) (Domain.Task, error)
+	// Schedule plans the open tasks of the workspace in dependency order
+	Schedule(ctx context.Context) (Domain.Schedule, error)
+	// AddLabel tags a task with a label from the catalog
+	AddLabel(ctx context.Context, id primitive.ObjectID, name string) (Domain.Task, error)
+	RemoveLabel(ctx context.Context, id primitive.ObjectID, name string) (Domain.Task, error)
+}
+
+// TaskEventPublisher receives every change made through the task usecase
+type TaskEventPublisher interface {
+	Publish(e Domain.TaskEvent)
+}
+
+// TaskEventPublishers fans each event out to several publishers
+type TaskEventPublishers []TaskEventPublisher
+
+func (ps TaskEventPublishers) Publish(e Domain.TaskEvent) {
+	for _, p := range ps {
+		p.Publish(e)
+	}
 }
 
 type taskUsecase struct {
-	repo Repositories.TaskRepository
+	repo    Repositories.TaskRepository
+	labels  Repositories.LabelRepository
+	tx      Repositories.Transactor
+	events  TaskEventPublisher
+	opts    TaskOptions
 	timeout time.Duration
 }
 
-func NewTaskUsecase(r Repositories.TaskRepository) TaskUsecase {
-	return &taskUsecase{repo: r, timeout: 5 * time.Second}
+// NewTaskUsecase wires the repositories; tx and events may be nil
+func NewTaskUsecase(r Repositories.TaskRepository, labels Repositories.LabelRepository, tx Repositories.Transactor, events TaskEventPublisher, opts TaskOptions) TaskUsecase {
+	return &taskUsecase{repo: r, labels: labels, tx: tx, events: events, opts: opts, timeout: 5 * time.Second}
+}
+
+// writeTime stamps a write at the precision Mongo stores
+func writeTime() *time.Time {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	return &now
+}
+
+// stamp records the write time on a validated change; an empty change
+// writes nothing, so it is left alone
+func stamp(change Domain.TaskChange) {
+	if len(change.Set) > 0 || len(change.Unset) > 0 {
+		change.Set["updated_at"] = writeTime()
+	}
+}
+
+func (u *taskUsecase) publish(ctx context.Context, typ string, id primitive.ObjectID, t *Domain.Task) {
+	if u.events == nil {
+		return
+	}
+	ws, _ := Domain.WorkspaceFrom(ctx)
+	u.events.Publish(Domain.TaskEvent{Type: typ, WorkspaceID: ws, TaskID: id.Hex(), Task: t, At: time.Now().UTC()})
+}
+
+func (u *taskUsecase) CreateTask(ctx context.Context, t Domain.Task) (Domain.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	t, err := Domain.ValidateTask(t)
+	if err != nil {
+		return Domain.Task{}, err
+	}
+	if t.ParentID != nil {
+		if err := u.checkParent(ctx, *t.ParentID, primitive.NilObjectID, 1); err != nil {
+			return Domain.Task{}, err
+		}
+	}
+	if len(t.Labels) > 0 {
+		if t.Labels, err = u.resolveLabels(ctx, "labels", t.Labels); err != nil {
+			return Domain.Task{}, err
+		}
+	}
+	t.UpdatedAt = writeTime()
+	created, err := u.repo.Create(ctx, t)
+	if err != nil {
+		return Domain.Task{}, err
+	}
+	u.publish(ctx, Domain.EventTaskCreated, created.ID, &created)
+	return created, nil
+}
+
+func (u *taskUsecase) ListTasks(ctx context.Context, filter Domain.TaskFilter) ([]Domain.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	if len(filter.Labels) > 0 {
+		names, err := Domain.NormalizeLabelNames(filter.Labels)
+		if err != nil {
+			return nil, err
+		}
+		if filter.Labels, err = u.resolveLabels(ctx, "labels", names); err != nil {
+			return nil, err
+		}
+		tasks, err := u.repo.FindByLabels(ctx, filter.Labels, filter.MatchAll)
+		if err != nil {
+			return nil, err
+		}
+		return u.withProgress(ctx, tasks)
+	}
+	all, err := u.repo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// every subtask is in the full list
+	attachProgress(all, all)
+	return all, nil
+}
+
+func (u *taskUsecase) GetTaskByID(ctx context.Context, id primitive.ObjectID) (Domain.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	t, err := u.repo.FindByID(ctx, id)
+	if err != nil {
+		return Domain.Task{}, err
+	}
+	tasks, err := u.withProgress(ctx, []Domain.Task{t})
+	if err != nil {
+		return Domain.Task{}, err
+	}
+	return tasks[0], nil
 }
 
-func (u *taskUsecase) CreateTask(t Domain.Task) (Domain.Task, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), u.timeout)
+func (u *taskUsecase) GetTasksByIDs(ctx context.Context, ids []primitive.ObjectID) ([]Domain.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
 	defer cancel()
-	return u.repo.Create(ctx, t)
+	tasks, err := u.repo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return u.withProgress(ctx, tasks)
 }
 
-func (u *taskUsecase) ListTasks() ([]Domain.Task, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), u.timeout)
+func (u *taskUsecase) UpdateTask(ctx context.Context, id primitive.ObjectID, change Domain.TaskChange) (Domain.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
 	defer cancel()
-	return u.repo.FindAll(ctx)
+	change, err := Domain.ValidateTaskChange(change)
+	if err != nil {
+		return Domain.Task{}, err
+	}
+	if err := u.checkCompletion(ctx, id, change); err != nil {
+		return Domain.Task{}, err
+	}
+	if err := u.checkBlockers(ctx, id, change); err != nil {
+		return Domain.Task{}, err
+	}
+	return u.update(ctx, id, change)
+}
+
+func (u *taskUsecase) update(ctx context.Context, id primitive.ObjectID, change Domain.TaskChange) (Domain.Task, error) {
+	stamp(change)
+	updated, err := u.repo.Update(ctx, id, change)
+	if err != nil {
+		return Domain.Task{}, err
+	}
+	// the write went through, so a failed lookup only leaves progress out
+	if tasks, err := u.withProgress(ctx, []Domain.Task{updated}); err == nil {
+		updated = tasks[0]
+	}
+	u.publish(ctx, Domain.EventTaskUpdated, id, &updated)
+	return updated, nil
 }
 
-func (u *taskUsecase) GetTaskByID(id primitive.ObjectID) (Domain.Task, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), u.timeout)
+// ReplaceTask overwrites every field; optional fields left empty are removed
+func (u *taskUsecase) ReplaceTask(ctx context.Context, id primitive.ObjectID, t Domain.Task, force bool) (Domain.Task, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
 	defer cancel()
-	return u.repo.FindByID(ctx, id)
+	t, err := Domain.ValidateTask(t)
+	if err != nil {
+		return Domain.Task{}, err
+	}
+	change := Domain.ReplacementChange(t)
+	change.Force = force
+	if err := u.checkCompletion(ctx, id, change); err != nil {
+		return Domain.Task{}, err
+	}
+	if err := u.checkBlockers(ctx, id, change); err != nil {
+		return Domain.Task{}, err
+	}
+	return u.update(ctx, id, change)
 }
 
-func (u *taskUsecase) UpdateTask(id primitive.ObjectID, patch map[string]interface{}) (Domain.Task, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), u.timeout)
+// DeleteTask refuses to delete a task that has subtasks, so none is left
+// pointing at a missing parent. The tasks it blocked stop waiting on it.
+func (u *taskUsecase) DeleteTask(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
 	defer cancel()
-	return u.repo.Update(ctx, id, patch)
+	children, err := u.repo.FindChildren(ctx, []primitive.ObjectID{id})
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		return Domain.HasSubtasksError(len(children))
+	}
+	if err := u.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	if err := u.releaseBlocked(ctx, []primitive.ObjectID{id}); err != nil {
+		return err
+	}
+	u.publish(ctx, Domain.EventTaskDeleted, id, nil)
+	return nil
+}
+
+// MaxBulkOps caps the number of operations in one bulk request
+const MaxBulkOps = 500
+
+// Bulk result statuses
+const (
+	BulkOK         = "ok"
+	BulkFailed     = "failed"
+	BulkSkipped    = "skipped"     // not attempted because an earlier op failed
+	BulkRolledBack = "rolled_back" // not applied because the transaction was aborted
+)
+
+type BulkTaskOp struct {
+	Op     string // "create", "update" or "delete"
+	ID     primitive.ObjectID
+	Task   Domain.Task
+	Change Domain.TaskChange
+	// Invalid fails the op without checking or writing it; the caller sets it
+	// when it couldn't read the op
+	Invalid error
+}
+
+type BulkTaskResult struct {
+	Op     string
+	ID     primitive.ObjectID
+	Status string
+	Task   *Domain.Task
+	Err    error
 }
 
-func (u *taskUsecase) DeleteTask(id primitive.ObjectID) error {
-	ctx, cancel := context.WithTimeout(context.Background(), u.timeout)
+type BulkTaskReport struct {
+	Transactional bool
+	Results       []BulkTaskResult
+}
+
+var errBulkAborted = errors.New("bulk aborted")
+
+// BulkTasks validates every op, then writes the valid ones in a single batch.
+// With stopOnError the batch is ordered and stops at the first failure; inside
+// a transaction that failure also undoes the ops before it.
+func (u *taskUsecase) BulkTasks(ctx context.Context, ops []BulkTaskOp, stopOnError bool) (BulkTaskReport, error) {
+	if len(ops) == 0 || len(ops) > MaxBulkOps {
+		return BulkTaskReport{}, &Domain.ValidationError{Errors: []Domain.FieldError{
+			{Field: "operations", Message: "must contain between 1 and 500 operations"},
+		}}
+	}
+	ctx, cancel := context.WithTimeout(ctx, 6*u.timeout)
 	defer cancel()
-	return u.repo.Delete(ctx, id)
+
+	rules, err := u.bulkSubtaskRules(ctx, ops)
+	if err != nil {
+		return BulkTaskReport{}, err
+	}
+	if err := u.bulkBlockerRules(ctx, ops, rules); err != nil {
+		return BulkTaskReport{}, err
+	}
+	catalog, err := u.bulkLabels(ctx, ops)
+	if err != nil {
+		return BulkTaskReport{}, err
+	}
+	transactional := u.tx != nil && u.tx.SupportsTransactions()
+	report := BulkTaskReport{Transactional: transactional, Results: make([]BulkTaskResult, len(ops))}
+	var repoOps []Repositories.TaskWriteOp
+	var opIndex []int
+	failed := false
+	for i, op := range ops {
+		res := &report.Results[i]
+		res.Op, res.ID = op.Op, op.ID
+		if failed && stopOnError {
+			res.Status = BulkSkipped
+			continue
+		}
+		if op.Invalid != nil {
+			res.Status, res.Err = BulkFailed, op.Invalid
+			failed = true
+			continue
+		}
+		wop := Repositories.TaskWriteOp{Kind: op.Op, ID: op.ID}
+		var err error
+		switch op.Op {
+		case "create":
+			wop.Task, err = Domain.ValidateTask(op.Task)
+			if err == nil && len(wop.Task.Labels) > 0 {
+				wop.Task.Labels, err = catalog.resolve("labels", wop.Task.Labels)
+			}
+			wop.Task.UpdatedAt = writeTime()
+		case "update":
+			wop.Change, err = Domain.ValidateTaskChange(op.Change)
+			stamp(wop.Change)
+		case "delete":
+		default:
+			err = errors.New("unknown operation")
+		}
+		if err == nil {
+			err = rules[i]
+		}
+		if err != nil {
+			res.Status, res.Err = BulkFailed, err
+			failed = true
+			continue
+		}
+		repoOps = append(repoOps, wop)
+		opIndex = append(opIndex, i)
+	}
+
+	if failed && stopOnError && transactional {
+		rollBack(&report, opIndex)
+		return report, nil
+	}
+	if len(repoOps) == 0 {
+		return report, nil
+	}
+
+	run := func(ctx context.Context) error {
+		written, err := u.repo.BulkWrite(ctx, repoOps, stopOnError)
+		if err != nil {
+			return err
+		}
+		var fetch, deleted []primitive.ObjectID
+		writeFailed := false
+		for k, w := range written {
+			res := &report.Results[opIndex[k]]
+			res.ID = w.ID
+			switch {
+			case w.Skipped:
+				res.Status = BulkSkipped
+			case w.Err != nil:
+				res.Status, res.Err = BulkFailed, w.Err
+				writeFailed = true
+			default:
+				res.Status = BulkOK
+				if res.Op == "delete" {
+					deleted = append(deleted, w.ID)
+				} else {
+					fetch = append(fetch, w.ID)
+				}
+			}
+		}
+		if writeFailed && stopOnError && transactional {
+			return errBulkAborted
+		}
+		if err := u.releaseBlocked(ctx, deleted); err != nil {
+			return err
+		}
+		if len(fetch) == 0 {
+			return nil
+		}
+		tasks, err := u.repo.FindByIDs(ctx, fetch)
+		if err != nil {
+			return err
+		}
+		if tasks, err = u.withProgress(ctx, tasks); err != nil {
+			return err
+		}
+		byID := make(map[primitive.ObjectID]Domain.Task, len(tasks))
+		for _, t := range tasks {
+			byID[t.ID] = t
+		}
+		for _, k := range opIndex {
+			res := &report.Results[k]
+			if t, ok := byID[res.ID]; ok && res.Status == BulkOK && res.Op != "delete" {
+				res.Task = &t
+			}
+		}
+		return nil
+	}
+
+	if transactional {
+		err = u.tx.WithTransaction(ctx, run)
+	} else {
+		err = run(ctx)
+	}
+	if errors.Is(err, errBulkAborted) {
+		rollBack(&report, opIndex)
+		return report, nil
+	}
+	if err != nil {
+		return BulkTaskReport{}, err
+	}
+	for _, res := range report.Results {
+		if res.Status != BulkOK {
+			continue
+		}
+		switch res.Op {
+		case "create":
+			u.publish(ctx, Domain.EventTaskCreated, res.ID, res.Task)
+		case "update":
+			u.publish(ctx, Domain.EventTaskUpdated, res.ID, res.Task)
+		case "delete":
+			u.publish(ctx, Domain.EventTaskDeleted, res.ID, nil)
+		}
+	}
+	return report, nil
+}
+
+// rollBack marks every op that would have been applied as rolled back
+func rollBack(report *BulkTaskReport, opIndex []int) {
+	for _, k := range opIndex {
+		res := &report.Results[k]
+		if res.Status == BulkOK || res.Status == "" {
+			res.Status = BulkRolledBack
+			res.Task = nil
+		}
+	}
 }
@@ -11,23 +11,28 @@ import (
 )
 
 type UserUsecase interface {
-	Register(username, password string) (Domain.User, error)
-	Login(username, password string) (Domain.User, error)
-	Promote(username string) error
+	Register(ctx context.Context, username, password string) (Domain.User, error)
+	Login(ctx context.Context, username, password string) (Domain.User, error)
+	Promote(ctx context.Context, username string) error
 }
 
 type userUsecase struct {
 	repo Repositories.UserRepository
+	passwords Infrastructure.PasswordService
 	timeout time.Duration
 }
 
-func NewUserUsecase(r Repositories.UserRepository) UserUsecase {
-	return &userUsecase{repo: r, timeout: 5 * time.Second}
+func NewUserUsecase(r Repositories.UserRepository, p Infrastructure.PasswordService) UserUsecase {
+	return &userUsecase{repo: r, passwords: p, timeout: 5 * time.Second}
 }
 
-func (u *userUsecase) Register(username, password string) (Domain.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), u.timeout)
+func (u *userUsecase) Register(ctx context.Context, username, password string) (Domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
 	defer cancel()
+	username, err := Domain.ValidateCredentials(username, password)
+	if err != nil {
+		return Domain.User{}, err
+	}
 	// if first user, make admin
 	count, err := u.repo.CountUsers(ctx)
 	if err != nil {
@@ -37,7 +42,7 @@ func (u *userUsecase) Register(username, password string) (Domain.User, error) {
 	if count == 0 {
 		role = "admin"
 	}
-	hashed, err := Infrastructure.HashPassword(password)
+	hashed, err := u.passwords.HashPassword(password)
 	if err != nil {
 		return Domain.User{}, err
 	}
@@ -50,23 +55,23 @@ func (u *userUsecase) Register(username, password string) (Domain.User, error) {
 	return created, nil
 }
 
-func (u *userUsecase) Login(username, password string) (Domain.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), u.timeout)
+func (u *userUsecase) Login(ctx context.Context, username, password string) (Domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
 	defer cancel()
-	user, err := u.repo.FindByUsername(ctx, username)
+	user, err := u.repo.FindByUsername(ctx, Domain.NormalizeUsername(username))
 	if err != nil {
 		return Domain.User{}, errors.New("invalid credentials")
 	}
 	// compare
-	if err := Infrastructure.ComparePassword(user.Password, password); err != nil {
+	if err := u.passwords.ComparePassword(user.Password, password); err != nil {
 		return Domain.User{}, errors.New("invalid credentials")
 	}
 	user.Password = ""
 	return user, nil
 }
 
-func (u *userUsecase) Promote(username string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), u.timeout)
+func (u *userUsecase) Promote(ctx context.Context, username string) error {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
 	defer cancel()
-	return u.repo.UpdateRole(ctx, username, "admin")
+	return u.repo.UpdateRole(ctx, Domain.NormalizeUsername(username), "admin")
 }
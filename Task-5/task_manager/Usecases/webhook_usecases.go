@@ -0,0 +1,408 @@
+package Usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	mrand "math/rand"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"task_manager/Domain"
+	"task_manager/Infrastructure"
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WebhookUsecase interface {
+	Create(ctx context.Context, w Domain.Webhook) (Domain.Webhook, error)
+	List(ctx context.Context) ([]Domain.Webhook, error)
+	Get(ctx context.Context, id primitive.ObjectID) (Domain.Webhook, error)
+	Update(ctx context.Context, id primitive.ObjectID, patch WebhookPatch) (Domain.Webhook, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	ListDeliveries(ctx context.Context, webhookID primitive.ObjectID, limit int64) ([]Domain.WebhookDelivery, error)
+	Redeliver(ctx context.Context, webhookID, deliveryID primitive.ObjectID) (Domain.WebhookDelivery, error)
+
+	// Publish queues the event for every matching webhook. When the queue is
+	// full it waits briefly, then drops the event.
+	Publish(e Domain.TaskEvent)
+	// Run delivers queued events until ctx is done
+	Run(ctx context.Context)
+	// Healthy reports an error while the delivery worker isn't running
+	Healthy(ctx context.Context) error
+}
+
+// WebhookPatch holds the fields an update changes; nil means unchanged
+type WebhookPatch struct {
+	URL    *string
+	Events *[]string
+	Active *bool
+	Secret *string
+}
+
+// Delivery retry policy
+const (
+	WebhookMaxAttempts  = 8
+	webhookBaseBackoff  = 10 * time.Second
+	webhookMaxBackoff   = time.Hour
+	webhookLease        = time.Minute // must exceed the sender timeout
+	webhookPollInterval = 2 * time.Second
+	webhookWorkers      = 4
+	webhookPublishWait  = 100 * time.Millisecond
+)
+
+// WebhookDropStats is told the type of each event Publish drops
+type WebhookDropStats func(eventType string)
+
+type webhookUsecase struct {
+	hooks      Repositories.WebhookRepository
+	deliveries Repositories.WebhookDeliveryRepository
+	sender     Infrastructure.WebhookSender
+	queue      chan Domain.TaskEvent
+	kick       chan struct{}
+	running    atomic.Bool
+	timeout    time.Duration
+	dropped    WebhookDropStats
+}
+
+// NewWebhookUsecase builds the usecase; dropped may be nil
+func NewWebhookUsecase(h Repositories.WebhookRepository, d Repositories.WebhookDeliveryRepository, s Infrastructure.WebhookSender, dropped WebhookDropStats) WebhookUsecase {
+	return &webhookUsecase{
+		hooks:      h,
+		deliveries: d,
+		sender:     s,
+		dropped:    dropped,
+		queue:      make(chan Domain.TaskEvent, 1024),
+		kick:       make(chan struct{}, webhookWorkers),
+		timeout:    5 * time.Second,
+	}
+}
+
+func (u *webhookUsecase) Create(ctx context.Context, w Domain.Webhook) (Domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	w, err := Domain.ValidateWebhook(w)
+	if err != nil {
+		return Domain.Webhook{}, err
+	}
+	if w.Secret == "" {
+		if w.Secret, err = newSecret(); err != nil {
+			return Domain.Webhook{}, err
+		}
+	}
+	w.CreatedAt = time.Now().UTC()
+	return u.hooks.Create(ctx, w)
+}
+
+func (u *webhookUsecase) List(ctx context.Context) ([]Domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	return u.hooks.FindAll(ctx)
+}
+
+func (u *webhookUsecase) Get(ctx context.Context, id primitive.ObjectID) (Domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	return u.hooks.FindByID(ctx, id)
+}
+
+func (u *webhookUsecase) Update(ctx context.Context, id primitive.ObjectID, patch WebhookPatch) (Domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	current, err := u.hooks.FindByID(ctx, id)
+	if err != nil {
+		return Domain.Webhook{}, err
+	}
+	if patch.URL != nil {
+		current.URL = *patch.URL
+	}
+	if patch.Events != nil {
+		current.Events = *patch.Events
+	}
+	if patch.Secret != nil {
+		current.Secret = *patch.Secret
+		// an empty secret would sign with an empty key; rotate instead
+		if current.Secret == "" {
+			if current.Secret, err = newSecret(); err != nil {
+				return Domain.Webhook{}, err
+			}
+		}
+	}
+	if patch.Active != nil {
+		current.Active = *patch.Active
+	}
+	current, err = Domain.ValidateWebhook(current)
+	if err != nil {
+		return Domain.Webhook{}, err
+	}
+	return u.hooks.Update(ctx, id, map[string]interface{}{
+		"url":    current.URL,
+		"events": current.Events,
+		"secret": current.Secret,
+		"active": current.Active,
+	})
+}
+
+// Delete removes the webhook together with its delivery log
+func (u *webhookUsecase) Delete(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	if err := u.hooks.Delete(ctx, id); err != nil {
+		return err
+	}
+	return u.deliveries.DeleteByWebhook(ctx, id)
+}
+
+func (u *webhookUsecase) ListDeliveries(ctx context.Context, webhookID primitive.ObjectID, limit int64) ([]Domain.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	if _, err := u.hooks.FindByID(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	return u.deliveries.FindByWebhook(ctx, webhookID, limit)
+}
+
+// Redeliver queues a fresh copy of a past delivery; the original stays in the log
+func (u *webhookUsecase) Redeliver(ctx context.Context, webhookID, deliveryID primitive.ObjectID) (Domain.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	orig, err := u.deliveries.FindByID(ctx, deliveryID)
+	if err != nil {
+		return Domain.WebhookDelivery{}, err
+	}
+	if orig.WebhookID != webhookID {
+		return Domain.WebhookDelivery{}, Domain.ErrNotFound
+	}
+	now := time.Now().UTC()
+	d := Domain.WebhookDelivery{
+		ID:            primitive.NewObjectID(),
+		WebhookID:     orig.WebhookID,
+		EventID:       orig.EventID,
+		EventType:     orig.EventType,
+		Payload:       orig.Payload,
+		Status:        Domain.DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := u.deliveries.CreateMany(ctx, []Domain.WebhookDelivery{d}); err != nil {
+		return Domain.WebhookDelivery{}, err
+	}
+	u.wake()
+	return d, nil
+}
+
+// webhookPayload is the JSON body receivers get; ID is shared by every
+// webhook notified about the same event so receivers can deduplicate
+type webhookPayload struct {
+	ID      string          `json:"id"`
+	Type    string          `json:"type"`
+	TaskID  string          `json:"task_id"`
+	Task    *Domain.Task    `json:"task,omitempty"`
+	Comment *Domain.Comment `json:"comment,omitempty"`
+	At      time.Time       `json:"at"`
+}
+
+// Publish runs on the writer's goroutine, so a stalled worker slows writers
+// down by at most webhookPublishWait per event; events keep their order
+func (u *webhookUsecase) Publish(e Domain.TaskEvent) {
+	select {
+	case u.queue <- e:
+		return
+	default:
+	}
+	wait := time.NewTimer(webhookPublishWait)
+	defer wait.Stop()
+	select {
+	case u.queue <- e:
+	case <-wait.C:
+		slog.Warn("webhooks: queue full, dropping event", "event", e.Type, "task_id", e.TaskID)
+		if u.dropped != nil {
+			u.dropped(e.Type)
+		}
+	}
+}
+
+// enqueue stores one pending delivery per matching webhook
+func (u *webhookUsecase) enqueue(e Domain.TaskEvent) {
+	ctx, cancel := context.WithTimeout(Domain.WithWorkspace(context.Background(), e.WorkspaceID), u.timeout)
+	defer cancel()
+	hooks, err := u.hooks.FindActiveForEvent(ctx, e.Type)
+	if err != nil {
+		slog.Error("webhooks: lookup failed", "event", e.Type, "error", err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+	if e.At.IsZero() {
+		e.At = time.Now().UTC()
+	}
+	eventID := primitive.NewObjectID().Hex()
+	body, err := json.Marshal(webhookPayload{ID: eventID, Type: e.Type, TaskID: e.TaskID, Task: e.Task, Comment: e.Comment, At: e.At})
+	if err != nil {
+		slog.Error("webhooks: encode event", "event", e.Type, "error", err)
+		return
+	}
+	now := time.Now().UTC()
+	ds := make([]Domain.WebhookDelivery, len(hooks))
+	for i, h := range hooks {
+		ds[i] = Domain.WebhookDelivery{
+			ID:            primitive.NewObjectID(),
+			WebhookID:     h.ID,
+			EventID:       eventID,
+			EventType:     e.Type,
+			Payload:       string(body),
+			Status:        Domain.DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+	}
+	if err := u.deliveries.CreateMany(ctx, ds); err != nil {
+		slog.Error("webhooks: queue deliveries", "event", e.Type, "error", err)
+		return
+	}
+	u.wake()
+}
+
+func (u *webhookUsecase) wake() {
+	select {
+	case u.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (u *webhookUsecase) Healthy(ctx context.Context) error {
+	if !u.running.Load() {
+		return errors.New("webhook worker not running")
+	}
+	return nil
+}
+
+func (u *webhookUsecase) Run(ctx context.Context) {
+	u.running.Store(true)
+	defer u.running.Store(false)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case e := <-u.queue:
+				u.enqueue(e)
+			case <-ctx.Done():
+				// persist whatever is still buffered so it's sent after restart
+				for {
+					select {
+					case e := <-u.queue:
+						u.enqueue(e)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+	workersDone := make(chan struct{}, webhookWorkers)
+	for i := 0; i < webhookWorkers; i++ {
+		go func() {
+			u.work(ctx)
+			workersDone <- struct{}{}
+		}()
+	}
+	for i := 0; i < webhookWorkers; i++ {
+		<-workersDone
+	}
+	<-done
+}
+
+func (u *webhookUsecase) work(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	for {
+		d, err := u.deliveries.ClaimDue(ctx, time.Now().UTC(), webhookLease)
+		if err == nil {
+			u.attempt(ctx, d)
+			continue
+		}
+		if !errors.Is(err, Domain.ErrNotFound) && ctx.Err() == nil {
+			slog.Error("webhooks: claim failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-u.kick:
+		}
+	}
+}
+
+// attempt sends one claimed delivery and records the outcome
+func (u *webhookUsecase) attempt(ctx context.Context, d Domain.WebhookDelivery) {
+	hook, err := u.hooks.FindByID(Domain.WithWorkspace(ctx, d.WorkspaceID), d.WebhookID)
+	now := time.Now().UTC()
+	d.UpdatedAt = now
+	d.LeaseUntil = time.Time{}
+	switch {
+	case errors.Is(err, Domain.ErrNotFound):
+		d.Status, d.LastError = Domain.DeliveryDeadLetter, "webhook deleted"
+	case err != nil:
+		d.Status, d.LastError = Domain.DeliveryPending, err.Error()
+		d.NextAttemptAt = now.Add(webhookBaseBackoff)
+	case !hook.Active:
+		d.Status, d.LastError = Domain.DeliveryDeadLetter, "webhook inactive"
+	default:
+		headers := map[string]string{
+			"X-Webhook-Event":    d.EventType,
+			"X-Webhook-ID":       d.EventID,
+			"X-Webhook-Delivery": d.ID.Hex(),
+		}
+		status, serr := u.sender.Send(ctx, hook.URL, hook.Secret, headers, []byte(d.Payload))
+		d.Attempts++
+		d.LastStatus = status
+		if serr == nil && status >= 200 && status < 300 {
+			d.Status, d.LastError = Domain.DeliverySucceeded, ""
+			break
+		}
+		if serr != nil {
+			d.LastError = serr.Error()
+		} else {
+			d.LastError = "unexpected status " + strconv.Itoa(status)
+		}
+		if d.Attempts >= WebhookMaxAttempts {
+			d.Status = Domain.DeliveryDeadLetter
+		} else {
+			d.Status = Domain.DeliveryPending
+			d.NextAttemptAt = now.Add(backoff(d.Attempts))
+		}
+	}
+	// record the outcome even if we're shutting down
+	sctx, cancel := context.WithTimeout(context.Background(), u.timeout)
+	defer cancel()
+	if err := u.deliveries.Update(sctx, d); err != nil {
+		slog.Error("webhooks: record delivery", "delivery_id", d.ID.Hex(), "error", err)
+	}
+}
+
+// backoff doubles from webhookBaseBackoff per attempt, capped, with up to 20% jitter
+func backoff(attempts int) time.Duration {
+	d := time.Duration(float64(webhookBaseBackoff) * math.Pow(2, float64(attempts-1)))
+	if d > webhookMaxBackoff || d <= 0 {
+		d = webhookMaxBackoff
+	}
+	return d + time.Duration(mrand.Int63n(int64(d)/5+1))
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,322 @@
+package Usecases
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"task_manager/Domain"
+	"task_manager/Infrastructure"
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// hookRepo holds webhooks in memory; the rest of WebhookRepository is left nil
+type hookRepo struct {
+	Repositories.WebhookRepository
+	hooks map[primitive.ObjectID]Domain.Webhook
+}
+
+func (r *hookRepo) FindByID(ctx context.Context, id primitive.ObjectID) (Domain.Webhook, error) {
+	h, ok := r.hooks[id]
+	if !ok {
+		return Domain.Webhook{}, Domain.ErrNotFound
+	}
+	return h, nil
+}
+
+func (r *hookRepo) FindActiveForEvent(ctx context.Context, eventType string) ([]Domain.Webhook, error) {
+	var out []Domain.Webhook
+	for _, h := range r.hooks {
+		for _, e := range h.Events {
+			if h.Active && (e == eventType || e == "*") {
+				out = append(out, h)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+func (r *hookRepo) Update(ctx context.Context, id primitive.ObjectID, set map[string]interface{}) (Domain.Webhook, error) {
+	h := r.hooks[id]
+	h.URL, h.Events = set["url"].(string), set["events"].([]string)
+	h.Secret, h.Active = set["secret"].(string), set["active"].(bool)
+	r.hooks[id] = h
+	return h, nil
+}
+
+// deliveryRepo is an in-memory delivery queue
+type deliveryRepo struct {
+	mu sync.Mutex
+	ds []Domain.WebhookDelivery
+}
+
+func (r *deliveryRepo) CreateMany(ctx context.Context, ds []Domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ds = append(r.ds, ds...)
+	return nil
+}
+
+func (r *deliveryRepo) FindByID(ctx context.Context, id primitive.ObjectID) (Domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, d := range r.ds {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+	return Domain.WebhookDelivery{}, Domain.ErrNotFound
+}
+
+func (r *deliveryRepo) FindByWebhook(ctx context.Context, webhookID primitive.ObjectID, limit int64) ([]Domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []Domain.WebhookDelivery
+	for _, d := range r.ds {
+		if d.WebhookID == webhookID {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (r *deliveryRepo) ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (Domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, d := range r.ds {
+		due := d.Status == Domain.DeliveryPending && !d.NextAttemptAt.After(now)
+		expired := d.Status == Domain.DeliveryInFlight && d.LeaseUntil.Before(now)
+		if due || expired {
+			r.ds[i].Status, r.ds[i].LeaseUntil = Domain.DeliveryInFlight, now.Add(lease)
+			return r.ds[i], nil
+		}
+	}
+	return Domain.WebhookDelivery{}, Domain.ErrNotFound
+}
+
+func (r *deliveryRepo) Update(ctx context.Context, d Domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.ds {
+		if r.ds[i].ID == d.ID {
+			r.ds[i] = d
+			return nil
+		}
+	}
+	return Domain.ErrNotFound
+}
+
+func (r *deliveryRepo) DeleteByWebhook(ctx context.Context, webhookID primitive.ObjectID) error {
+	return nil
+}
+
+// receiver is a local webhook endpoint answering with statuses in turn,
+// then 200; it records what it was sent and whether the signature held
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []http.Header
+	badSig   int
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if !Infrastructure.VerifyWebhook(testSecret, r.Header.Get(Infrastructure.WebhookTimestampHeader), r.Header.Get(Infrastructure.WebhookSignatureHeader), body, time.Minute) {
+		rc.badSig++
+	}
+	rc.requests = append(rc.requests, r.Header.Clone())
+	status := http.StatusOK
+	if len(rc.requests) <= len(rc.statuses) {
+		status = rc.statuses[len(rc.requests)-1]
+	}
+	w.WriteHeader(status)
+}
+
+func newWebhookTest(t *testing.T, rc *receiver) (*webhookUsecase, Domain.Webhook, *deliveryRepo) {
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+	hook := Domain.Webhook{ID: primitive.NewObjectID(), URL: srv.URL, Events: []string{Domain.EventTaskCreated}, Secret: testSecret, Active: true}
+	hooks := &hookRepo{hooks: map[primitive.ObjectID]Domain.Webhook{hook.ID: hook}}
+	deliveries := &deliveryRepo{}
+	u := NewWebhookUsecase(hooks, deliveries, Infrastructure.NewWebhookSender(time.Second), nil).(*webhookUsecase)
+	return u, hook, deliveries
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	rc := &receiver{}
+	u, hook, deliveries := newWebhookTest(t, rc)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		u.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	u.Publish(Domain.TaskEvent{Type: Domain.EventTaskCreated, TaskID: "t1"})
+	u.Publish(Domain.TaskEvent{Type: Domain.EventTaskDeleted, TaskID: "t1"}) // not subscribed
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ds, _ := deliveries.FindByWebhook(ctx, hook.ID, 0)
+		if len(ds) == 1 && ds[0].Status == Domain.DeliverySucceeded {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("deliveries = %+v, want one succeeded", ds)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if len(rc.requests) != 1 || rc.badSig != 0 {
+		t.Fatalf("receiver got %d requests, %d with a bad signature; want 1 signed", len(rc.requests), rc.badSig)
+	}
+	if ev := rc.requests[0].Get("X-Webhook-Event"); ev != Domain.EventTaskCreated {
+		t.Errorf("X-Webhook-Event = %q", ev)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	fail := make([]int, WebhookMaxAttempts)
+	for i := range fail {
+		fail[i] = http.StatusServiceUnavailable
+	}
+	tests := []struct {
+		name     string
+		statuses []int
+		attempts int
+		want     string
+	}{
+		{name: "succeeds after retries", statuses: []int{500, 502}, attempts: 3, want: Domain.DeliverySucceeded},
+		{name: "dead after max attempts", statuses: fail, attempts: WebhookMaxAttempts, want: Domain.DeliveryDeadLetter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &receiver{statuses: tt.statuses}
+			u, hook, deliveries := newWebhookTest(t, rc)
+			ctx := context.Background()
+			d := Domain.WebhookDelivery{ID: primitive.NewObjectID(), WebhookID: hook.ID, EventID: "e1", EventType: Domain.EventTaskCreated, Payload: `{}`, Status: Domain.DeliveryPending}
+			deliveries.CreateMany(ctx, []Domain.WebhookDelivery{d})
+
+			for n := 1; d.Status == Domain.DeliveryPending; n++ {
+				if n > WebhookMaxAttempts {
+					t.Fatalf("still pending after %d attempts", WebhookMaxAttempts)
+				}
+				before := time.Now().UTC()
+				u.attempt(ctx, d)
+				d, _ = deliveries.FindByID(ctx, d.ID)
+				if d.Attempts != n {
+					t.Fatalf("attempts = %d after attempt %d", d.Attempts, n)
+				}
+				if d.Status != Domain.DeliveryPending {
+					break
+				}
+				// the backoff doubles from webhookBaseBackoff, plus up to 20% jitter
+				wait := d.NextAttemptAt.Sub(before)
+				least := time.Duration(float64(webhookBaseBackoff) * math.Pow(2, float64(n-1)))
+				if wait < least || wait > least+least/5+time.Second {
+					t.Errorf("attempt %d: next attempt in %s, want %s plus jitter", n, wait, least)
+				}
+				if d.LastError != "unexpected status "+strconv.Itoa(tt.statuses[n-1]) {
+					t.Errorf("attempt %d: last_error = %q", n, d.LastError)
+				}
+			}
+			if d.Status != tt.want || d.Attempts != tt.attempts {
+				t.Errorf("delivery ended %s after %d attempts, want %s after %d", d.Status, d.Attempts, tt.want, tt.attempts)
+			}
+			if len(rc.requests) != tt.attempts || rc.badSig != 0 {
+				t.Errorf("receiver got %d requests, %d with a bad signature", len(rc.requests), rc.badSig)
+			}
+		})
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	for attempts := 1; attempts <= 12; attempts++ {
+		least := time.Duration(float64(webhookBaseBackoff) * math.Pow(2, float64(attempts-1)))
+		if least > webhookMaxBackoff {
+			least = webhookMaxBackoff
+		}
+		if d := backoff(attempts); d < least || d > least+least/5 {
+			t.Errorf("backoff(%d) = %s, want between %s and %s", attempts, d, least, least+least/5)
+		}
+	}
+}
+
+func TestWebhookRedeliver(t *testing.T) {
+	rc := &receiver{}
+	u, hook, deliveries := newWebhookTest(t, rc)
+	ctx := context.Background()
+	orig := Domain.WebhookDelivery{
+		ID: primitive.NewObjectID(), WebhookID: hook.ID, EventID: "e1", EventType: Domain.EventTaskCreated,
+		Payload: `{"id":"e1"}`, Status: Domain.DeliveryDeadLetter, Attempts: WebhookMaxAttempts, LastStatus: 503,
+	}
+	deliveries.CreateMany(ctx, []Domain.WebhookDelivery{orig})
+
+	if _, err := u.Redeliver(ctx, primitive.NewObjectID(), orig.ID); !errors.Is(err, Domain.ErrNotFound) {
+		t.Errorf("Redeliver() with another webhook's id: error = %v, want ErrNotFound", err)
+	}
+	if _, err := u.Redeliver(ctx, hook.ID, primitive.NewObjectID()); !errors.Is(err, Domain.ErrNotFound) {
+		t.Errorf("Redeliver() of a missing delivery: error = %v, want ErrNotFound", err)
+	}
+
+	again, err := u.Redeliver(ctx, hook.ID, orig.ID)
+	if err != nil {
+		t.Fatalf("Redeliver() error = %v", err)
+	}
+	if again.ID == orig.ID || again.Status != Domain.DeliveryPending || again.Attempts != 0 ||
+		again.EventID != orig.EventID || again.Payload != orig.Payload {
+		t.Errorf("Redeliver() = %+v, want a fresh pending again of %+v", again, orig)
+	}
+	if got, _ := deliveries.FindByID(ctx, orig.ID); got.Status != Domain.DeliveryDeadLetter || got.Attempts != WebhookMaxAttempts {
+		t.Errorf("original = %+v, want it left as it was", got)
+	}
+
+	claimed, err := deliveries.ClaimDue(ctx, time.Now().UTC(), webhookLease)
+	if err != nil || claimed.ID != again.ID {
+		t.Fatalf("ClaimDue() = %v, %v, want the again", claimed.ID, err)
+	}
+	u.attempt(ctx, claimed)
+	if got, _ := deliveries.FindByID(ctx, again.ID); got.Status != Domain.DeliverySucceeded {
+		t.Errorf("again status = %s after sending, want succeeded", got.Status)
+	}
+	if len(rc.requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(rc.requests))
+	}
+	if id := rc.requests[0].Get("X-Webhook-ID"); id != orig.EventID {
+		t.Errorf("X-Webhook-ID = %q, want the original event id %q", id, orig.EventID)
+	}
+	if id := rc.requests[0].Get("X-Webhook-Delivery"); id != again.ID.Hex() {
+		t.Errorf("X-Webhook-Delivery = %q, want the again's id", id)
+	}
+}
+
+func TestWebhookUpdateRotatesEmptySecret(t *testing.T) {
+	u, hook, _ := newWebhookTest(t, &receiver{})
+	empty := ""
+	updated, err := u.Update(context.Background(), hook.ID, WebhookPatch{Secret: &empty})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if len(updated.Secret) != 64 || updated.Secret == testSecret {
+		t.Errorf("secret = %q, want a new generated one", updated.Secret)
+	}
+}
@@ -0,0 +1,218 @@
+package Usecases
+
+import (
+	"context"
+	"time"
+
+	"task_manager/Domain"
+	"task_manager/Repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WorkspaceUsecase interface {
+	// Create makes a workspace with owner as its first admin
+	Create(ctx context.Context, owner string, w Domain.Workspace) (Domain.Workspace, error)
+	// ListForUser returns the user's workspaces and their role in each
+	ListForUser(ctx context.Context, username string) ([]WorkspaceRole, error)
+	// Session resolves what a token for username carries. A zero workspaceID
+	// picks the user's oldest membership, or none if they have none; any
+	// other workspace must be one the user belongs to.
+	Session(ctx context.Context, username string, workspaceID primitive.ObjectID) (Domain.Session, error)
+	// Refresh reloads the session a token claims: the user's instance role
+	// and their role in the token's workspace as they are now. It fails with
+	// ErrNotFound once the user or that membership is gone.
+	Refresh(ctx context.Context, claimed Domain.Session) (Domain.Session, error)
+	// Members lists a workspace's members; actor must be one of them
+	Members(ctx context.Context, actor string, workspaceID primitive.ObjectID) ([]Domain.Membership, error)
+	// SetMember adds a user or changes their role; actor must be an admin
+	SetMember(ctx context.Context, actor string, m Domain.Membership) (Domain.Membership, error)
+	// RemoveMember takes a user out; actor must be an admin or the user
+	RemoveMember(ctx context.Context, actor string, workspaceID primitive.ObjectID, username string) error
+}
+
+// WorkspaceRole is a workspace together with the caller's role in it
+type WorkspaceRole struct {
+	Domain.Workspace
+	Role string `json:"role"`
+}
+
+type workspaceUsecase struct {
+	workspaces Repositories.WorkspaceRepository
+	members    Repositories.MembershipRepository
+	users      Repositories.UserRepository
+	timeout    time.Duration
+}
+
+func NewWorkspaceUsecase(w Repositories.WorkspaceRepository, m Repositories.MembershipRepository, u Repositories.UserRepository) WorkspaceUsecase {
+	return &workspaceUsecase{workspaces: w, members: m, users: u, timeout: 5 * time.Second}
+}
+
+func (u *workspaceUsecase) Create(ctx context.Context, owner string, w Domain.Workspace) (Domain.Workspace, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	w, err := Domain.ValidateWorkspace(w)
+	if err != nil {
+		return Domain.Workspace{}, err
+	}
+	w.CreatedAt = time.Now().UTC()
+	created, err := u.workspaces.Create(ctx, w)
+	if err != nil {
+		return Domain.Workspace{}, err
+	}
+	_, err = u.members.Upsert(ctx, Domain.Membership{WorkspaceID: created.ID, Username: owner, Role: Domain.RoleAdmin, CreatedAt: created.CreatedAt})
+	if err != nil {
+		return Domain.Workspace{}, err
+	}
+	return created, nil
+}
+
+func (u *workspaceUsecase) ListForUser(ctx context.Context, username string) ([]WorkspaceRole, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	ms, err := u.members.FindByUser(ctx, username)
+	if err != nil || len(ms) == 0 {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, len(ms))
+	for i, m := range ms {
+		ids[i] = m.WorkspaceID
+	}
+	ws, err := u.workspaces.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]Domain.Workspace, len(ws))
+	for _, w := range ws {
+		byID[w.ID] = w
+	}
+	out := make([]WorkspaceRole, 0, len(ms))
+	for _, m := range ms {
+		if w, ok := byID[m.WorkspaceID]; ok {
+			out = append(out, WorkspaceRole{Workspace: w, Role: m.Role})
+		}
+	}
+	return out, nil
+}
+
+func (u *workspaceUsecase) Session(ctx context.Context, username string, workspaceID primitive.ObjectID) (Domain.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	user, err := u.users.FindByUsername(ctx, username)
+	if err != nil {
+		return Domain.Session{}, err
+	}
+	s := Domain.Session{Username: user.Username, InstanceAdmin: user.Role == Domain.RoleAdmin}
+	var m Domain.Membership
+	if workspaceID.IsZero() {
+		ms, err := u.members.FindByUser(ctx, user.Username)
+		if err != nil || len(ms) == 0 {
+			return s, err
+		}
+		m = ms[0]
+	} else if m, err = u.members.Find(ctx, workspaceID, user.Username); err != nil {
+		return Domain.Session{}, err
+	}
+	s.Workspace, s.Role = m.WorkspaceID, m.Role
+	return s, nil
+}
+
+func (u *workspaceUsecase) Refresh(ctx context.Context, claimed Domain.Session) (Domain.Session, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	user, err := u.users.FindByUsername(ctx, claimed.Username)
+	if err != nil {
+		return Domain.Session{}, err
+	}
+	s := Domain.Session{Username: user.Username, InstanceAdmin: user.Role == Domain.RoleAdmin}
+	if claimed.Workspace.IsZero() {
+		return s, nil
+	}
+	m, err := u.members.Find(ctx, claimed.Workspace, user.Username)
+	if err != nil {
+		return Domain.Session{}, err
+	}
+	s.Workspace, s.Role = m.WorkspaceID, m.Role
+	return s, nil
+}
+
+// role returns actor's role in the workspace; outsiders get ErrNotFound so
+// they can't probe which workspaces exist
+func (u *workspaceUsecase) role(ctx context.Context, actor string, workspaceID primitive.ObjectID) (string, error) {
+	m, err := u.members.Find(ctx, workspaceID, actor)
+	if err != nil {
+		return "", err
+	}
+	return m.Role, nil
+}
+
+func (u *workspaceUsecase) Members(ctx context.Context, actor string, workspaceID primitive.ObjectID) ([]Domain.Membership, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	if _, err := u.role(ctx, actor, workspaceID); err != nil {
+		return nil, err
+	}
+	return u.members.FindByWorkspace(ctx, workspaceID)
+}
+
+func (u *workspaceUsecase) SetMember(ctx context.Context, actor string, m Domain.Membership) (Domain.Membership, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	if err := Domain.ValidateRole(m.Role); err != nil {
+		return Domain.Membership{}, err
+	}
+	role, err := u.role(ctx, actor, m.WorkspaceID)
+	if err != nil {
+		return Domain.Membership{}, err
+	}
+	if role != Domain.RoleAdmin {
+		return Domain.Membership{}, Domain.ErrForbidden
+	}
+	m.Username = Domain.NormalizeUsername(m.Username)
+	if _, err := u.users.FindByUsername(ctx, m.Username); err != nil {
+		return Domain.Membership{}, err
+	}
+	if m.Role != Domain.RoleAdmin {
+		if err := u.keepAnAdmin(ctx, m.WorkspaceID, m.Username); err != nil {
+			return Domain.Membership{}, err
+		}
+	}
+	m.CreatedAt = time.Now().UTC()
+	return u.members.Upsert(ctx, m)
+}
+
+func (u *workspaceUsecase) RemoveMember(ctx context.Context, actor string, workspaceID primitive.ObjectID, username string) error {
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+	username = Domain.NormalizeUsername(username)
+	role, err := u.role(ctx, actor, workspaceID)
+	if err != nil {
+		return err
+	}
+	if role != Domain.RoleAdmin && actor != username {
+		return Domain.ErrForbidden
+	}
+	if err := u.keepAnAdmin(ctx, workspaceID, username); err != nil {
+		return err
+	}
+	return u.members.Delete(ctx, workspaceID, username)
+}
+
+// keepAnAdmin fails if username is the workspace's only admin
+func (u *workspaceUsecase) keepAnAdmin(ctx context.Context, workspaceID primitive.ObjectID, username string) error {
+	ms, err := u.members.FindByWorkspace(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	for _, m := range ms {
+		if m.Role == Domain.RoleAdmin && m.Username != username {
+			return nil
+		}
+	}
+	for _, m := range ms {
+		if m.Username == username && m.Role == Domain.RoleAdmin {
+			return Domain.LastAdminError()
+		}
+	}
+	return nil
+}
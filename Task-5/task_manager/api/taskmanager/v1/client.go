@@ -0,0 +1,36 @@
+package taskmanagerv1
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Client bundles the task and user service clients over one connection
+type Client struct {
+	TaskServiceClient
+	UserServiceClient
+}
+
+// NewClient wraps an existing connection
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{TaskServiceClient: NewTaskServiceClient(cc), UserServiceClient: NewUserServiceClient(cc)}
+}
+
+// BearerToken sends Token with every call; pass it to
+// grpc.WithPerRPCCredentials. gRPC refuses to send it over a plaintext
+// connection unless AllowInsecure is set.
+type BearerToken struct {
+	Token string
+	// AllowInsecure permits sending the token over a plaintext connection
+	AllowInsecure bool
+}
+
+func (t BearerToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.Token}, nil
+}
+
+func (t BearerToken) RequireTransportSecurity() bool { return !t.AllowInsecure }
+
+var _ credentials.PerRPCCredentials = BearerToken{}
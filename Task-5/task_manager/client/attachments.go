@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+// Attachment is a file on a task. ContentType is what the server detected
+// from the content.
+type Attachment struct {
+	ID          string    `json:"id"`
+	TaskID      string    `json:"task_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	UploadedBy  string    `json:"uploaded_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AttachmentLink is a signed download URL, relative to the server
+type AttachmentLink struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ListAttachments returns the task's attachments
+func (c *Client) ListAttachments(ctx context.Context, taskID string) ([]Attachment, error) {
+	var out []Attachment
+	err := c.do(ctx, http.MethodGet, "/tasks/"+escape(taskID)+"/attachments", "", nil, &out)
+	return out, err
+}
+
+// GetAttachment returns the attachment's metadata
+func (c *Client) GetAttachment(ctx context.Context, taskID, id string) (Attachment, error) {
+	var out Attachment
+	err := c.do(ctx, http.MethodGet, "/tasks/"+escape(taskID)+"/attachments/"+escape(id), "", nil, &out)
+	return out, err
+}
+
+// UploadAttachment attaches content to the task under filename. The body is
+// streamed, so large files aren't held in memory. Content the task already
+// has returns the existing attachment.
+func (c *Client) UploadAttachment(ctx context.Context, taskID, filename string, content io.Reader) (Attachment, error) {
+	pr, pw := io.Pipe()
+	form := multipart.NewWriter(pw)
+	go func() {
+		part, err := form.CreateFormFile("file", filename)
+		if err == nil {
+			_, err = io.Copy(part, content)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/v1/tasks/"+escape(taskID)+"/attachments", pr)
+	if err != nil {
+		pr.Close()
+		return Attachment{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	var out Attachment
+	err = c.send(req, &out)
+	pr.Close()
+	return out, err
+}
+
+// DeleteAttachment removes the attachment
+func (c *Client) DeleteAttachment(ctx context.Context, taskID, id string) error {
+	return c.do(ctx, http.MethodDelete, "/tasks/"+escape(taskID)+"/attachments/"+escape(id), "", nil, nil)
+}
+
+// AttachmentLink signs a short-lived download URL for the attachment
+func (c *Client) AttachmentLink(ctx context.Context, taskID, id string) (AttachmentLink, error) {
+	var out AttachmentLink
+	err := c.do(ctx, http.MethodGet, "/tasks/"+escape(taskID)+"/attachments/"+escape(id)+"/link", "", nil, &out)
+	return out, err
+}
+
+// DownloadAttachment copies the attachment's content to w, through a signed
+// link
+func (c *Client) DownloadAttachment(ctx context.Context, taskID, id string, w io.Writer) (int64, error) {
+	link, err := c.AttachmentLink(ctx, taskID, id)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+link.URL, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.open(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return io.Copy(w, resp.Body)
+}
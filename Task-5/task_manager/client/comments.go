@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Comment is a comment on a task. Replies are filled in on top-level
+// comments; Edits holds earlier versions of the body, oldest first.
+type Comment struct {
+	ID        string        `json:"id"`
+	TaskID    string        `json:"task_id"`
+	ParentID  string        `json:"parent_id,omitempty"`
+	Author    string        `json:"author"`
+	Body      string        `json:"body"`
+	Mentions  []string      `json:"mentions,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	EditedAt  *time.Time    `json:"edited_at,omitempty"`
+	Edits     []CommentEdit `json:"edits,omitempty"`
+	Replies   []Comment     `json:"replies,omitempty"`
+}
+
+// CommentEdit is a replaced version of a comment body
+type CommentEdit struct {
+	Body     string    `json:"body"`
+	EditedBy string    `json:"edited_by"`
+	EditedAt time.Time `json:"edited_at"`
+}
+
+// CommentPage is one page of top-level comments; pass Next as after to get
+// the following page, it is empty on the last one
+type CommentPage struct {
+	Comments []Comment `json:"comments"`
+	Next     string    `json:"next,omitempty"`
+}
+
+// ListComments returns a page of the task's comments, oldest first. after is
+// the previous page's Next (empty for the first page); limit 0 uses the
+// server's default.
+func (c *Client) ListComments(ctx context.Context, taskID, after string, limit int) (CommentPage, error) {
+	q := url.Values{}
+	if after != "" {
+		q.Set("after", after)
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	path := "/tasks/" + escape(taskID) + "/comments"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	var out CommentPage
+	err := c.do(ctx, http.MethodGet, path, "", nil, &out)
+	return out, err
+}
+
+// AddComment comments on the task, or replies to parentID when it is set
+func (c *Client) AddComment(ctx context.Context, taskID, body, parentID string) (Comment, error) {
+	in := map[string]string{"body": body}
+	if parentID != "" {
+		in["parent_id"] = parentID
+	}
+	var out Comment
+	err := c.do(ctx, http.MethodPost, "/tasks/"+escape(taskID)+"/comments", "application/json", in, &out)
+	return out, err
+}
+
+// EditComment replaces the comment's body
+func (c *Client) EditComment(ctx context.Context, taskID, id, body string) (Comment, error) {
+	var out Comment
+	err := c.do(ctx, http.MethodPatch, "/tasks/"+escape(taskID)+"/comments/"+escape(id), "application/json", map[string]string{"body": body}, &out)
+	return out, err
+}
+
+// DeleteComment removes the comment and its replies
+func (c *Client) DeleteComment(ctx context.Context, taskID, id string) error {
+	return c.do(ctx, http.MethodDelete, "/tasks/"+escape(taskID)+"/comments/"+escape(id), "", nil, nil)
+}
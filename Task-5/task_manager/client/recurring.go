@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// RecurringTask is a template the server turns into tasks, one per
+// occurrence of its RRULE, ahead of time
+type RecurringTask struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Estimate    string   `json:"estimate,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	RRule       string   `json:"rrule"`
+	Start       string   `json:"start"`
+	// GeneratedThrough is the latest occurrence created as a task and
+	// NextOccurrence the first one not created yet
+	GeneratedThrough *time.Time  `json:"generated_through,omitempty"`
+	NextOccurrence   *time.Time  `json:"next_occurrence,omitempty"`
+	LastError        string      `json:"last_error,omitempty"`
+	Upcoming         []time.Time `json:"upcoming,omitempty"`
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+}
+
+// RecurringTaskInput creates a recurring task. RRule is an iCalendar RRULE
+// such as "FREQ=WEEKLY;BYDAY=MO,TH"; Start is a date or an RFC3339 time
+// whose time of day every occurrence keeps.
+type RecurringTaskInput struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Estimate    string   `json:"estimate,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	RRule       string   `json:"rrule"`
+	Start       string   `json:"start"`
+}
+
+// RecurringTaskPatch changes only the fields that are set
+type RecurringTaskPatch struct {
+	Title       *string   `json:"title,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	Estimate    *string   `json:"estimate,omitempty"`
+	Labels      *[]string `json:"labels,omitempty"`
+	RRule       *string   `json:"rrule,omitempty"`
+	Start       *string   `json:"start,omitempty"`
+}
+
+func futureQuery(future bool) string {
+	if future {
+		return "?future=true"
+	}
+	return ""
+}
+
+func (c *Client) ListRecurringTasks(ctx context.Context) ([]RecurringTask, error) {
+	var out []RecurringTask
+	err := c.do(ctx, http.MethodGet, "/recurring-tasks", "", nil, &out)
+	return out, err
+}
+
+func (c *Client) GetRecurringTask(ctx context.Context, id string) (RecurringTask, error) {
+	var out RecurringTask
+	err := c.do(ctx, http.MethodGet, "/recurring-tasks/"+escape(id), "", nil, &out)
+	return out, err
+}
+
+func (c *Client) CreateRecurringTask(ctx context.Context, in RecurringTaskInput) (RecurringTask, error) {
+	var out RecurringTask
+	err := c.do(ctx, http.MethodPost, "/recurring-tasks", "application/json", in, &out)
+	return out, err
+}
+
+// UpdateRecurringTask edits the template; with future the pending
+// occurrences already generated change too
+func (c *Client) UpdateRecurringTask(ctx context.Context, id string, p RecurringTaskPatch, future bool) (RecurringTask, error) {
+	var out RecurringTask
+	err := c.do(ctx, http.MethodPatch, "/recurring-tasks/"+escape(id)+futureQuery(future), "application/json", p, &out)
+	return out, err
+}
+
+// DeleteRecurringTask stops the template; with future its pending
+// occurrences still ahead are deleted too
+func (c *Client) DeleteRecurringTask(ctx context.Context, id string, future bool) error {
+	return c.do(ctx, http.MethodDelete, "/recurring-tasks/"+escape(id)+futureQuery(future), "", nil, nil)
+}
+
+// ListRecurringOccurrences lists the tasks generated from the template
+func (c *Client) ListRecurringOccurrences(ctx context.Context, id string) ([]Task, error) {
+	var out []Task
+	err := c.do(ctx, http.MethodGet, "/recurring-tasks/"+escape(id)+"/tasks", "", nil, &out)
+	return out, err
+}
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Task is a task as the API returns it
+type Task struct {
+	ID          string `json:"id"`
+	WorkspaceID string `json:"workspace_id,omitempty"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	DueDate     string `json:"due_date,omitempty"`
+	Status      string `json:"status"`
+	Estimate    string `json:"estimate,omitempty"`
+	ParentID    string `json:"parent_id,omitempty"`
+	// Checklist, BlockedBy, Labels and Progress are managed through their own
+	// endpoints
+	Checklist []ChecklistItem `json:"checklist,omitempty"`
+	BlockedBy []string        `json:"blocked_by,omitempty"`
+	Labels    []string        `json:"labels,omitempty"`
+	Progress  *Progress       `json:"progress,omitempty"`
+	// RecurrenceID and Occurrence are set on tasks generated from a
+	// recurring task
+	RecurrenceID string     `json:"recurrence_id,omitempty"`
+	Occurrence   *time.Time `json:"occurrence,omitempty"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+}
+
+// Label is an entry in the workspace's label catalog
+type Label struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description,omitempty"`
+	// Count is how many tasks carry the label
+	Count int64 `json:"count"`
+}
+
+type ChecklistItem struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	Done bool   `json:"done"`
+}
+
+// Progress counts a task's direct subtasks and checklist items
+type Progress struct {
+	Done     int `json:"done"`
+	Total    int `json:"total"`
+	Subtasks int `json:"subtasks"`
+}
+
+// TaskInput is the body of a create or full replacement. Title and Status
+// are required; DueDate is RFC3339 or YYYY-MM-DD and Estimate a duration such
+// as "4h30m". ParentID and Labels only apply on create; use the move and
+// label endpoints to change them.
+type TaskInput struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	DueDate     string   `json:"due_date,omitempty"`
+	Status      string   `json:"status"`
+	Estimate    string   `json:"estimate,omitempty"`
+	ParentID    string   `json:"parent_id,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+}
+
+// TaskPatch changes only the fields that are set; Remove lists optional
+// fields (description, due_date, estimate) to delete
+type TaskPatch struct {
+	Title       *string
+	Description *string
+	DueDate     *string
+	Status      *string
+	Estimate    *string
+	Remove      []string
+}
+
+// MarshalJSON writes an RFC 7396 merge patch: set fields as values and
+// removed fields as null
+func (p TaskPatch) MarshalJSON() ([]byte, error) {
+	m := map[string]interface{}{}
+	for name, v := range map[string]*string{"title": p.Title, "description": p.Description, "due_date": p.DueDate, "status": p.Status, "estimate": p.Estimate} {
+		if v != nil {
+			m[name] = *v
+		}
+	}
+	for _, name := range p.Remove {
+		m[name] = nil
+	}
+	return json.Marshal(m)
+}
+
+// ListTasks returns every task in the active workspace
+func (c *Client) ListTasks(ctx context.Context) ([]Task, error) {
+	var out []Task
+	err := c.do(ctx, http.MethodGet, "/tasks", "", nil, &out)
+	return out, err
+}
+
+// ListTasksByLabels returns the tasks carrying all of labels, or any of them
+// when matchAny is set
+func (c *Client) ListTasksByLabels(ctx context.Context, labels []string, matchAny bool) ([]Task, error) {
+	q := url.Values{"labels": {strings.Join(labels, ",")}}
+	if matchAny {
+		q.Set("match", "any")
+	}
+	var out []Task
+	err := c.do(ctx, http.MethodGet, "/tasks?"+q.Encode(), "", nil, &out)
+	return out, err
+}
+
+func (c *Client) GetTask(ctx context.Context, id string) (Task, error) {
+	var out Task
+	err := c.do(ctx, http.MethodGet, "/tasks/"+escape(id), "", nil, &out)
+	return out, err
+}
+
+func (c *Client) CreateTask(ctx context.Context, t TaskInput) (Task, error) {
+	var out Task
+	err := c.do(ctx, http.MethodPost, "/tasks", "application/json", t, &out)
+	return out, err
+}
+
+// ReplaceTask (PUT) overwrites the task; optional fields left empty are removed
+func (c *Client) ReplaceTask(ctx context.Context, id string, t TaskInput) (Task, error) {
+	var out Task
+	err := c.do(ctx, http.MethodPut, "/tasks/"+escape(id), "application/json", t, &out)
+	return out, err
+}
+
+// UpdateTask (PATCH) applies p as a merge patch
+func (c *Client) UpdateTask(ctx context.Context, id string, p TaskPatch) (Task, error) {
+	var out Task
+	err := c.do(ctx, http.MethodPatch, "/tasks/"+escape(id), "application/merge-patch+json", p, &out)
+	return out, err
+}
+
+func (c *Client) DeleteTask(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/tasks/"+escape(id), "", nil, nil)
+}
+
+// ListLabels returns the label catalog with the task count of each label
+func (c *Client) ListLabels(ctx context.Context) ([]Label, error) {
+	var out []Label
+	err := c.do(ctx, http.MethodGet, "/labels", "", nil, &out)
+	return out, err
+}
+
+// AddLabel tags the task with a catalog label and returns the task
+func (c *Client) AddLabel(ctx context.Context, id, label string) (Task, error) {
+	var out Task
+	err := c.do(ctx, http.MethodPost, "/tasks/"+escape(id)+"/labels", "application/json", map[string]string{"label": label}, &out)
+	return out, err
+}
+
+// RemoveLabel takes the label off the task and returns the task
+func (c *Client) RemoveLabel(ctx context.Context, id, label string) (Task, error) {
+	var out Task
+	err := c.do(ctx, http.MethodDelete, "/tasks/"+escape(id)+"/labels/"+escape(label), "", nil, &out)
+	return out, err
+}
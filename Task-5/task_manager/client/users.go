@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// User is a registered user; Role is their instance-wide role
+type User struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// Session is an issued token. Role is the role in Workspace, the active
+// workspace's ID; both are empty when the user belongs to no workspace.
+type Session struct {
+	Token     string `json:"token"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	Workspace string `json:"workspace,omitempty"`
+}
+
+// Workspace is one of the caller's workspaces with their role in it
+type Workspace struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Role      string    `json:"role"`
+}
+
+func (c *Client) Register(ctx context.Context, username, password string) (User, error) {
+	var out User
+	err := c.do(ctx, http.MethodPost, "/register", "application/json",
+		map[string]string{"username": username, "password": password}, &out)
+	return out, err
+}
+
+// Login exchanges credentials for a token with workspace active, or the
+// user's default workspace if it is empty. It doesn't set c.Token.
+func (c *Client) Login(ctx context.Context, username, password, workspace string) (Session, error) {
+	body := map[string]string{"username": username, "password": password}
+	if workspace != "" {
+		body["workspace"] = workspace
+	}
+	var out Session
+	err := c.do(ctx, http.MethodPost, "/login", "application/json", body, &out)
+	return out, err
+}
+
+// PromoteUser makes the user an instance admin (instance admins only)
+func (c *Client) PromoteUser(ctx context.Context, username string) error {
+	return c.do(ctx, http.MethodPost, "/users/"+escape(username)+"/promote", "", nil, nil)
+}
+
+// ListWorkspaces returns the caller's workspaces
+func (c *Client) ListWorkspaces(ctx context.Context) ([]Workspace, error) {
+	var out []Workspace
+	err := c.do(ctx, http.MethodGet, "/workspaces", "", nil, &out)
+	return out, err
+}
+
+// SwitchWorkspace issues a new token with the workspace active. It doesn't
+// set c.Token.
+func (c *Client) SwitchWorkspace(ctx context.Context, id string) (Session, error) {
+	var out Session
+	err := c.do(ctx, http.MethodPost, "/workspaces/"+escape(id)+"/switch", "", nil, &out)
+	return out, err
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"task_manager/Domain"
+	"task_manager/Repositories"
+	"task_manager/Repositories/mongoimpl"
+)
+
+// checkCmd reports data the server would reject or mishandle. With --fix it
+// repairs what has one right answer: status spellings that map to a
+// canonical status, and due dates that only need trimming. Everything else
+// is left for a person to decide.
+func checkCmd(fs *flag.FlagSet) action {
+	fix := fs.Bool("fix", false, "repair fixable problems (combine with --dry-run to preview)")
+	return func(ctx context.Context, e *env, r *report) error {
+		db, err := e.db()
+		if err != nil {
+			return err
+		}
+		if err := checkUsers(ctx, mongoimpl.NewUserRepository(db), r); err != nil {
+			return err
+		}
+		if n, err := mongoimpl.CountUnscopedData(ctx, db); err != nil {
+			return err
+		} else if n > 0 {
+			r.problem("unscoped_data", "tasks, webhooks, webhook_deliveries",
+				fmt.Sprintf("%d document(s) without a workspace are invisible to the API; run taskadmin migrate", n), false)
+		}
+		workspaces, err := mongoimpl.NewWorkspaceRepository(db).FindAll(ctx)
+		if err != nil {
+			return err
+		}
+		tasks := mongoimpl.NewTaskRepository(db)
+		for _, ws := range workspaces {
+			if err := checkTasks(Domain.WithWorkspace(ctx, ws.ID), tasks, r, *fix); err != nil {
+				return fmt.Errorf("workspace %s: %w", ws.ID.Hex(), err)
+			}
+		}
+		return nil
+	}
+}
+
+// checkUsers finds usernames that collide once normalized, and usernames
+// that can't log in because login normalizes what is typed
+func checkUsers(ctx context.Context, users Repositories.UserRepository, r *report) error {
+	all, err := users.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	byName := map[string][]string{}
+	for _, u := range all {
+		n := Domain.NormalizeUsername(u.Username)
+		byName[n] = append(byName[n], u.Username)
+	}
+	names := make([]string, 0, len(byName))
+	for n := range byName {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		switch spellings := byName[n]; {
+		case len(spellings) > 1:
+			r.problem("duplicate_username", n,
+				fmt.Sprintf("%d accounts (%s) normalize to the same name; run migrate to rename them", len(spellings), strings.Join(quoted(spellings), ", ")), false)
+		case spellings[0] != n:
+			r.problem("username_not_normalized", spellings[0],
+				fmt.Sprintf("can't log in because login looks up %q; run migrate to rename the account", n), false)
+		}
+	}
+	return nil
+}
+
+func quoted(ss []string) []string {
+	out := make([]string, len(ss))
+	for i, s := range ss {
+		out[i] = fmt.Sprintf("%q", s)
+	}
+	return out
+}
+
+// checkTasks looks at the tasks of the workspace in ctx
+func checkTasks(ctx context.Context, tasks Repositories.TaskRepository, r *report, fix bool) error {
+	all, err := tasks.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	ws, _ := Domain.WorkspaceFrom(ctx)
+	for _, t := range all {
+		target := "task " + t.ID.Hex() + " (workspace " + ws.Hex() + ")"
+		change := Domain.TaskChange{Set: map[string]interface{}{}, Expect: map[string]interface{}{}}
+		var repairs []problem
+
+		if status, ok := Domain.NormalizeStatus(t.Status); !ok {
+			r.problem("invalid_status", target, fmt.Sprintf("status %q is not pending, in_progress or done", t.Status), false)
+		} else if status != t.Status {
+			change.Set["status"], change.Expect["status"] = status, t.Status
+			repairs = append(repairs, problem{Kind: "noncanonical_status", Detail: fmt.Sprintf("status %q -> %q", t.Status, status)})
+		}
+
+		due := strings.TrimSpace(t.DueDate)
+		switch _, ok := Domain.ParseDueDate(due); {
+		case t.DueDate == "":
+		case due == "":
+			change.Unset, change.Expect["due_date"] = []string{"due_date"}, t.DueDate
+			repairs = append(repairs, problem{Kind: "blank_due_date", Detail: "blank due_date removed"})
+		case !ok:
+			r.problem("invalid_due_date", target, fmt.Sprintf("due_date %q is not RFC3339 or YYYY-MM-DD", t.DueDate), false)
+		case due != t.DueDate:
+			change.Set["due_date"], change.Expect["due_date"] = due, t.DueDate
+			repairs = append(repairs, problem{Kind: "untrimmed_due_date", Detail: fmt.Sprintf("due_date %q trimmed", t.DueDate)})
+		}
+
+		if len(repairs) == 0 {
+			continue
+		}
+		if !fix {
+			for _, p := range repairs {
+				r.problem(p.Kind, target, p.Detail, true)
+			}
+			continue
+		}
+		details := make([]string, len(repairs))
+		for i, p := range repairs {
+			details[i] = p.Detail
+		}
+		r.change("repair_task", target, strings.Join(details, "; "))
+		if r.DryRun {
+			continue
+		}
+		change.Set["updated_at"] = time.Now().UTC().Truncate(time.Millisecond)
+		if _, err := tasks.Update(ctx, t.ID, change); err != nil {
+			// changed or deleted since it was read; a rerun will look again
+			r.Changes = r.Changes[:len(r.Changes)-1]
+			r.problem("repair_failed", target, err.Error(), true)
+		}
+	}
+	return nil
+}
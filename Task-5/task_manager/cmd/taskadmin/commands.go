@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"task_manager/Domain"
+	"task_manager/Repositories/mongoimpl"
+)
+
+type action = func(ctx context.Context, e *env, r *report) error
+
+// password reads one line from stdin when fromStdin is set, and otherwise
+// generates one and records it in the report
+func password(e *env, r *report, fromStdin bool) (string, error) {
+	if !fromStdin {
+		b := make([]byte, 18)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		r.GeneratedPassword = base64.RawURLEncoding.EncodeToString(b)
+		return r.GeneratedPassword, nil
+	}
+	line, err := bufio.NewReader(e.stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func createAdminCmd(fs *flag.FlagSet) action {
+	username := fs.String("username", "", "username (required)")
+	fromStdin := fs.Bool("password-stdin", false, "read the password from stdin instead of generating one")
+	workspace := fs.String("workspace", "", "also create a workspace with this name, with the user as its admin")
+	return func(ctx context.Context, e *env, r *report) error {
+		if *username == "" {
+			return fmt.Errorf("%w: --username is required", errUsage)
+		}
+		pw, err := password(e, r, *fromStdin)
+		if err != nil {
+			return err
+		}
+		name, err := Domain.ValidateCredentials(*username, pw)
+		if err != nil {
+			return err
+		}
+		var ws Domain.Workspace
+		if *workspace != "" {
+			if ws, err = Domain.ValidateWorkspace(Domain.Workspace{Name: *workspace}); err != nil {
+				return err
+			}
+		}
+		db, err := e.db()
+		if err != nil {
+			return err
+		}
+		users := mongoimpl.NewUserRepository(db)
+		if _, err := users.FindByUsername(ctx, name); err == nil {
+			return fmt.Errorf("user %q already exists; use reset-password to recover it", name)
+		} else if !errors.Is(err, Domain.ErrNotFound) {
+			return err
+		}
+
+		r.change("create_user", name, "role admin")
+		if ws.Name != "" {
+			r.change("create_workspace", ws.Name, name+" as admin")
+		}
+		if r.DryRun {
+			r.GeneratedPassword = ""
+			return nil
+		}
+		hash, err := e.passwords.HashPassword(pw)
+		if err != nil {
+			return err
+		}
+		if _, err := users.Create(ctx, Domain.User{Username: name, Password: hash, Role: Domain.RoleAdmin}); err != nil {
+			return err
+		}
+		if ws.Name == "" {
+			return nil
+		}
+		ws.CreatedAt = time.Now().UTC()
+		created, err := mongoimpl.NewWorkspaceRepository(db).Create(ctx, ws)
+		if err != nil {
+			return err
+		}
+		_, err = mongoimpl.NewMembershipRepository(db).Upsert(ctx,
+			Domain.Membership{WorkspaceID: created.ID, Username: name, Role: Domain.RoleAdmin, CreatedAt: created.CreatedAt})
+		return err
+	}
+}
+
+func resetPasswordCmd(fs *flag.FlagSet) action {
+	username := fs.String("username", "", "username (required)")
+	fromStdin := fs.Bool("password-stdin", false, "read the new password from stdin instead of generating one")
+	return func(ctx context.Context, e *env, r *report) error {
+		if *username == "" {
+			return fmt.Errorf("%w: --username is required", errUsage)
+		}
+		pw, err := password(e, r, *fromStdin)
+		if err != nil {
+			return err
+		}
+		name, err := Domain.ValidateCredentials(*username, pw)
+		if err != nil {
+			return err
+		}
+		db, err := e.db()
+		if err != nil {
+			return err
+		}
+		users := mongoimpl.NewUserRepository(db)
+		if _, err := users.FindByUsername(ctx, name); err != nil {
+			return fmt.Errorf("user %q: %w", name, err)
+		}
+		r.change("reset_password", name, "")
+		if r.DryRun {
+			r.GeneratedPassword = ""
+			return nil
+		}
+		hash, err := e.passwords.HashPassword(pw)
+		if err != nil {
+			return err
+		}
+		return users.UpdatePassword(ctx, name, hash)
+	}
+}
+
+func indexesCmd(fs *flag.FlagSet) action {
+	return func(ctx context.Context, e *env, r *report) error {
+		db, err := e.db()
+		if err != nil {
+			return err
+		}
+		if r.DryRun {
+			r.Indexes, err = mongoimpl.Indexes(ctx, db)
+		} else {
+			r.Indexes, err = mongoimpl.EnsureIndexes(ctx, db)
+		}
+		if err != nil {
+			return err
+		}
+		for _, ix := range r.Indexes {
+			target := ix.Collection + "." + ix.Name
+			switch {
+			case ix.Error != "":
+				r.problem("index_failed", target, ix.Error, false)
+			case ix.Created || (r.DryRun && !ix.Exists):
+				r.change("create_index", target, "")
+			}
+		}
+		return nil
+	}
+}
+
+func migrateCmd(fs *flag.FlagSet) action {
+	return func(ctx context.Context, e *env, r *report) error {
+		db, err := e.db()
+		if err != nil {
+			return err
+		}
+		var renamed []mongoimpl.UsernameChange
+		if r.DryRun {
+			renamed, err = mongoimpl.PlanUsernameChanges(ctx, db)
+		} else {
+			renamed, err = mongoimpl.NormalizeUsernames(ctx, db)
+		}
+		for _, c := range renamed {
+			detail := "to " + c.To
+			if c.Collision {
+				detail += " (normalized name already taken)"
+			}
+			r.change("normalize_username", c.From, detail)
+		}
+		if err != nil {
+			return err
+		}
+		var n int64
+		if r.DryRun {
+			n, err = mongoimpl.CountUnscopedData(ctx, db)
+		} else {
+			n, err = mongoimpl.AdoptUnscopedData(ctx, db)
+		}
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			r.change("adopt_into_default_workspace", "tasks, webhooks, webhook_deliveries", fmt.Sprintf("%d document(s)", n))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"io"
+
+	"task_manager/Infrastructure"
+	"task_manager/Repositories/mongoimpl"
+	"task_manager/config"
+)
+
+// env is what commands work with. The database is connected on first use,
+// so usage errors are reported without one.
+type env struct {
+	cfg       config.Config
+	stdin     io.Reader
+	passwords Infrastructure.PasswordService
+	mongo     *mongoimpl.MongoClient
+}
+
+func (e *env) db() (*mongoimpl.MongoClient, error) {
+	if e.mongo != nil {
+		return e.mongo, nil
+	}
+	client, err := mongoimpl.NewMongoClient(e.cfg.Mongo.URI, e.cfg.Mongo.DB)
+	if err != nil {
+		return nil, err
+	}
+	// indexes are only created by the indexes command, so other commands
+	// (and every dry run) leave the schema alone
+	client.SkipIndexes = true
+	e.mongo = client
+	return client, nil
+}
+
+func (e *env) close() {
+	if e.mongo != nil {
+		_ = e.mongo.Close()
+	}
+}
@@ -0,0 +1,130 @@
+// Command taskadmin runs maintenance against the database directly, without
+// the HTTP API: bootstrapping and recovering admins, creating indexes,
+// migrating data and checking it for problems. It reads the same config as
+// the server (file, environment and flags).
+//
+//	taskadmin [config flags] <command> [flags]
+//	taskadmin create-admin --username root --workspace Ops
+//	taskadmin -mongo-uri mongodb://db:27017 check --fix --dry-run --json
+//
+// Exit status: 0 on success, 1 on failure, 2 for usage errors and 3 when
+// problems were found and left in place.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"task_manager/Infrastructure"
+	"task_manager/config"
+)
+
+// command is one subcommand. flags registers its flags and returns the
+// action, which may write only when the report is not a dry run.
+type command struct {
+	name, summary string
+	flags         func(fs *flag.FlagSet) action
+}
+
+var commands = []command{
+	{"create-admin", "create an instance admin, optionally with a first workspace", createAdminCmd},
+	{"reset-password", "set a new password for a user", resetPasswordCmd},
+	{"indexes", "create the indexes the server needs", indexesCmd},
+	{"migrate", "lowercase stored usernames and move data from before workspaces into the Default workspace", migrateCmd},
+	{"check", "look for duplicate usernames, invalid statuses and unparsable due dates", checkCmd},
+}
+
+const (
+	exitFailure  = 1
+	exitUsage    = 2
+	exitProblems = 3
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	cfg, rest, err := config.LoadCommand("taskadmin", args)
+	if errors.Is(err, flag.ErrHelp) {
+		usage(stderr)
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "taskadmin:", err)
+		return exitUsage
+	}
+	if len(rest) == 0 || rest[0] == "help" {
+		usage(stderr)
+		return exitUsage
+	}
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == rest[0] {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(stderr, "taskadmin: unknown command %q\n", rest[0])
+		usage(stderr)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("taskadmin "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dryRun := fs.Bool("dry-run", false, "report what would change without writing")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	action := cmd.flags(fs)
+	if err := fs.Parse(rest[1:]); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "taskadmin %s: unexpected argument %q\n", cmd.name, fs.Arg(0))
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	e := &env{cfg: cfg, stdin: stdin, passwords: Infrastructure.NewPasswordService(cfg.Auth.BcryptCost)}
+	defer e.close()
+
+	r := &report{Command: cmd.name, DryRun: *dryRun}
+	runErr := action(ctx, e, r)
+	if errors.Is(runErr, errUsage) {
+		fmt.Fprintf(stderr, "taskadmin %s: %v\n", cmd.name, runErr)
+		fs.Usage()
+		return exitUsage
+	}
+	if runErr != nil {
+		r.Error = runErr.Error()
+	}
+	if *asJSON {
+		r.writeJSON(stdout)
+	} else {
+		r.writeText(stdout)
+	}
+	switch {
+	case runErr != nil:
+		return exitFailure
+	case len(r.Problems) > 0:
+		return exitProblems
+	}
+	return 0
+}
+
+// errUsage reports a missing or invalid flag
+var errUsage = errors.New("invalid usage")
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: taskadmin [config flags] <command> [--dry-run] [--json] [flags]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-15s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w, "\nConfig flags and environment variables are the server's; see taskadmin -h.")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"task_manager/Repositories/mongoimpl"
+)
+
+// report is what a command did, or in a dry run would do, and what it found
+type report struct {
+	Command string `json:"command"`
+	DryRun  bool   `json:"dry_run"`
+	// Changes are writes made, or planned in a dry run
+	Changes []change `json:"changes"`
+	// Problems are issues found and left in place
+	Problems []problem               `json:"problems"`
+	Indexes  []mongoimpl.IndexStatus `json:"indexes,omitempty"`
+	// GeneratedPassword is set when the command chose a password; it is
+	// shown only once
+	GeneratedPassword string `json:"generated_password,omitempty"`
+	Error             string `json:"error,omitempty"`
+}
+
+type change struct {
+	Kind   string `json:"kind"`
+	Target string `json:"target"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type problem struct {
+	Kind   string `json:"kind"`
+	Target string `json:"target"`
+	Detail string `json:"detail"`
+	// Fixable problems are repaired by check --fix
+	Fixable bool `json:"fixable"`
+}
+
+func (r *report) change(kind, target, detail string) {
+	r.Changes = append(r.Changes, change{Kind: kind, Target: target, Detail: detail})
+}
+
+func (r *report) problem(kind, target, detail string, fixable bool) {
+	r.Problems = append(r.Problems, problem{Kind: kind, Target: target, Detail: detail, Fixable: fixable})
+}
+
+func (r *report) writeJSON(w io.Writer) {
+	if r.Changes == nil {
+		r.Changes = []change{}
+	}
+	if r.Problems == nil {
+		r.Problems = []problem{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(r)
+}
+
+func (r *report) writeText(w io.Writer) {
+	verb := "changed"
+	if r.DryRun {
+		verb = "would change (dry run)"
+	}
+	for _, c := range r.Changes {
+		line := fmt.Sprintf("%s: %s %s", verb, c.Kind, c.Target)
+		if c.Detail != "" {
+			line += ": " + c.Detail
+		}
+		fmt.Fprintln(w, line)
+	}
+	for _, ix := range r.Indexes {
+		state := "ok"
+		switch {
+		case ix.Error != "":
+			state = "failed: " + ix.Error
+		case ix.Created:
+			state = "created"
+		case !ix.Exists:
+			state = "missing"
+		}
+		fmt.Fprintf(w, "index %s.%s: %s\n", ix.Collection, ix.Name, state)
+	}
+	for _, p := range r.Problems {
+		fix := ""
+		if p.Fixable {
+			fix = " (check --fix repairs this)"
+		}
+		fmt.Fprintf(w, "problem: %s %s: %s%s\n", p.Kind, p.Target, p.Detail, fix)
+	}
+	if r.GeneratedPassword != "" {
+		fmt.Fprintf(w, "generated password: %s\n", r.GeneratedPassword)
+	}
+	if r.Error != "" {
+		fmt.Fprintln(w, "error:", r.Error)
+		return
+	}
+	summary := []string{fmt.Sprintf("%d change(s)", len(r.Changes))}
+	if r.DryRun {
+		summary[0] += " planned"
+	}
+	summary = append(summary, fmt.Sprintf("%d problem(s)", len(r.Problems)))
+	fmt.Fprintf(w, "%s: %s\n", r.Command, strings.Join(summary, ", "))
+}
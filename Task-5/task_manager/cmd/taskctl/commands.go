@@ -0,0 +1,639 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"task_manager/client"
+)
+
+type action = func(ctx context.Context, args []string) error
+
+func loginCmd(fs *flag.FlagSet, a *app) action {
+	url := fs.String("url", "", "server URL (default the profile's, or "+defaultURL+")")
+	username := fs.String("username", "", "username (prompted if empty)")
+	workspace := fs.String("workspace", "", "workspace ID to activate (default your oldest)")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from stdin")
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 0 {
+			return errUsage
+		}
+		cfg, err := a.load()
+		if err != nil {
+			return err
+		}
+		name, p := a.profile(cfg)
+		if p == nil {
+			p = &profile{}
+		}
+		switch {
+		case *url != "":
+			p.URL = *url
+		case p.URL == "":
+			p.URL = defaultURL
+		}
+		if *username != "" {
+			p.Username = *username
+		}
+		in := bufio.NewReader(a.stdin)
+		if p.Username == "" {
+			if p.Username, err = prompt(a, in, "Username: "); err != nil {
+				return err
+			}
+		}
+		var password string
+		if *passwordStdin {
+			password, err = in.ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+			password = strings.TrimRight(password, "\r\n")
+		} else if password, err = readPassword(ctx, a, in); err != nil {
+			return err
+		}
+
+		s, err := client.New(p.URL).Login(ctx, p.Username, password, *workspace)
+		if client.StatusCode(err) == http.StatusUnauthorized {
+			return errors.New("login failed: invalid credentials")
+		}
+		if err != nil {
+			return err
+		}
+		p.Token, p.Username, p.Workspace = s.Token, s.Username, s.Workspace
+		cfg.Profiles[name] = p
+		cfg.Current = name
+		if err := a.save(cfg); err != nil {
+			return err
+		}
+		if s.Workspace == "" {
+			return a.message("Logged in to %s as %s, with no workspace", p.URL, s.Username)
+		}
+		return a.message("Logged in to %s as %s, workspace %s (%s)", p.URL, s.Username, s.Workspace, s.Role)
+	}
+}
+
+func prompt(a *app, in *bufio.Reader, label string) (string, error) {
+	fmt.Fprint(a.stderr, label)
+	line, err := in.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func tasksListCmd(fs *flag.FlagSet, a *app) action {
+	status := fs.String("status", "", "only tasks with this status")
+	labels := fs.String("labels", "", "only tasks with all of these comma-separated labels")
+	matchAny := fs.Bool("any", false, "with --labels, tasks with any of the labels")
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 0 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		var tasks []client.Task
+		if *labels != "" {
+			tasks, err = c.ListTasksByLabels(ctx, strings.Split(*labels, ","), *matchAny)
+		} else {
+			tasks, err = c.ListTasks(ctx)
+		}
+		if err != nil {
+			return err
+		}
+		if *status != "" {
+			kept := tasks[:0]
+			for _, t := range tasks {
+				if t.Status == *status {
+					kept = append(kept, t)
+				}
+			}
+			tasks = kept
+		}
+		return a.printTasks(tasks)
+	}
+}
+
+func tasksGetCmd(fs *flag.FlagSet, a *app) action {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		t, err := c.GetTask(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		return a.printTask(t)
+	}
+}
+
+func tasksCreateCmd(fs *flag.FlagSet, a *app) action {
+	var in client.TaskInput
+	fs.StringVar(&in.Title, "title", "", "title (required)")
+	fs.StringVar(&in.Description, "description", "", "description")
+	fs.StringVar(&in.DueDate, "due", "", "due date, RFC3339 or YYYY-MM-DD")
+	fs.StringVar(&in.Status, "status", "pending", "pending, in_progress or done")
+	fs.StringVar(&in.Estimate, "estimate", "", "estimated duration, e.g. 4h30m")
+	fs.StringVar(&in.ParentID, "parent", "", "create as a subtask of this task ID")
+	labels := fs.String("labels", "", "comma-separated labels from the catalog")
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 0 || in.Title == "" {
+			return errUsage
+		}
+		if *labels != "" {
+			in.Labels = strings.Split(*labels, ",")
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		t, err := c.CreateTask(ctx, in)
+		if err != nil {
+			return err
+		}
+		return a.printTask(t)
+	}
+}
+
+func tasksUpdateCmd(fs *flag.FlagSet, a *app) action {
+	title := fs.String("title", "", "new title")
+	description := fs.String("description", "", "new description")
+	due := fs.String("due", "", "new due date, RFC3339 or YYYY-MM-DD")
+	status := fs.String("status", "", "new status")
+	estimate := fs.String("estimate", "", "new estimated duration, e.g. 4h30m")
+	clearDescription := fs.Bool("clear-description", false, "remove the description")
+	clearDue := fs.Bool("clear-due", false, "remove the due date")
+	clearEstimate := fs.Bool("clear-estimate", false, "remove the estimate")
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return errUsage
+		}
+		// only flags given on the command line are sent
+		var p client.TaskPatch
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "title":
+				p.Title = title
+			case "description":
+				p.Description = description
+			case "due":
+				p.DueDate = due
+			case "status":
+				p.Status = status
+			case "estimate":
+				p.Estimate = estimate
+			}
+		})
+		if *clearDescription {
+			p.Description, p.Remove = nil, append(p.Remove, "description")
+		}
+		if *clearDue {
+			p.DueDate, p.Remove = nil, append(p.Remove, "due_date")
+		}
+		if *clearEstimate {
+			p.Estimate, p.Remove = nil, append(p.Remove, "estimate")
+		}
+		if p.Title == nil && p.Description == nil && p.DueDate == nil && p.Status == nil && p.Estimate == nil && len(p.Remove) == 0 {
+			return fmt.Errorf("nothing to update; pass at least one of --title, --description, --due, --status, --estimate, --clear-description, --clear-due, --clear-estimate")
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		t, err := c.UpdateTask(ctx, args[0], p)
+		if err != nil {
+			return err
+		}
+		return a.printTask(t)
+	}
+}
+
+func tasksDeleteCmd(fs *flag.FlagSet, a *app) action {
+	return func(ctx context.Context, args []string) error {
+		if len(args) == 0 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		for _, id := range args {
+			if err := c.DeleteTask(ctx, id); err != nil {
+				return fmt.Errorf("%s: %w", id, err)
+			}
+			if err := a.message("Deleted %s", id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func tasksLabelCmd(fs *flag.FlagSet, a *app) action {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 2 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		t, err := c.AddLabel(ctx, args[0], args[1])
+		if err != nil {
+			return err
+		}
+		return a.printTask(t)
+	}
+}
+
+func tasksUnlabelCmd(fs *flag.FlagSet, a *app) action {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 2 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		t, err := c.RemoveLabel(ctx, args[0], args[1])
+		if err != nil {
+			return err
+		}
+		return a.printTask(t)
+	}
+}
+
+func labelsListCmd(fs *flag.FlagSet, a *app) action {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 0 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		labels, err := c.ListLabels(ctx)
+		if err != nil {
+			return err
+		}
+		return a.printLabels(labels)
+	}
+}
+
+func commentsListCmd(fs *flag.FlagSet, a *app) action {
+	after := fs.String("after", "", "start after this comment ID, the next cursor of the previous page")
+	limit := fs.Int("limit", 0, "top-level comments per page (server default 20, max 100)")
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		page, err := c.ListComments(ctx, args[0], *after, *limit)
+		if err != nil {
+			return err
+		}
+		return a.printComments(page)
+	}
+}
+
+func commentsAddCmd(fs *flag.FlagSet, a *app) action {
+	replyTo := fs.String("reply-to", "", "reply to this top-level comment ID")
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 2 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		cm, err := c.AddComment(ctx, args[0], args[1], *replyTo)
+		if err != nil {
+			return err
+		}
+		return a.printComment(cm)
+	}
+}
+
+func commentsEditCmd(fs *flag.FlagSet, a *app) action {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 3 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		cm, err := c.EditComment(ctx, args[0], args[1], args[2])
+		if err != nil {
+			return err
+		}
+		return a.printComment(cm)
+	}
+}
+
+func commentsDeleteCmd(fs *flag.FlagSet, a *app) action {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 2 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		if err := c.DeleteComment(ctx, args[0], args[1]); err != nil {
+			return err
+		}
+		return a.message("Deleted %s", args[1])
+	}
+}
+
+func attachmentsListCmd(fs *flag.FlagSet, a *app) action {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		as, err := c.ListAttachments(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		return a.printAttachments(as)
+	}
+}
+
+func attachmentsUploadCmd(fs *flag.FlagSet, a *app) action {
+	name := fs.String("name", "", "file name to store (default the file's base name)")
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 2 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if *name == "" {
+			*name = filepath.Base(args[1])
+		}
+		at, err := c.UploadAttachment(ctx, args[0], *name, f)
+		if err != nil {
+			return err
+		}
+		return a.printAttachment(at)
+	}
+}
+
+func attachmentsDownloadCmd(fs *flag.FlagSet, a *app) action {
+	output := fs.String("o", "", "write to this file, - for stdout (default the attachment's name)")
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 2 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		path := *output
+		if path == "" {
+			at, err := c.GetAttachment(ctx, args[0], args[1])
+			if err != nil {
+				return err
+			}
+			path = filepath.Base(at.Filename)
+		}
+		if path == "-" {
+			_, err = c.DownloadAttachment(ctx, args[0], args[1], a.stdout)
+			return err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		n, err := c.DownloadAttachment(ctx, args[0], args[1], f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(path)
+			return err
+		}
+		// stdout is free for the file, so the note goes to stderr
+		fmt.Fprintf(a.stderr, "Saved %s (%d bytes)\n", path, n)
+		return nil
+	}
+}
+
+func attachmentsDeleteCmd(fs *flag.FlagSet, a *app) action {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 2 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		if err := c.DeleteAttachment(ctx, args[0], args[1]); err != nil {
+			return err
+		}
+		return a.message("Deleted %s", args[1])
+	}
+}
+
+func usersPromoteCmd(fs *flag.FlagSet, a *app) action {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		if err := c.PromoteUser(ctx, args[0]); err != nil {
+			return err
+		}
+		return a.message("Promoted %s to instance admin", args[0])
+	}
+}
+
+// profilesCmd lists profile names for shell completion
+func profilesCmd(fs *flag.FlagSet, a *app) action {
+	return func(ctx context.Context, args []string) error {
+		cfg, err := a.load()
+		if err != nil {
+			return err
+		}
+		for _, name := range cfg.names() {
+			fmt.Fprintln(a.stdout, name)
+		}
+		return nil
+	}
+}
+
+func recurringListCmd(fs *flag.FlagSet, a *app) action {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 0 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		rs, err := c.ListRecurringTasks(ctx)
+		if err != nil {
+			return err
+		}
+		return a.printRecurringTasks(rs)
+	}
+}
+
+func recurringGetCmd(fs *flag.FlagSet, a *app) action {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		r, err := c.GetRecurringTask(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		return a.printRecurringTask(r)
+	}
+}
+
+func recurringCreateCmd(fs *flag.FlagSet, a *app) action {
+	var in client.RecurringTaskInput
+	fs.StringVar(&in.Title, "title", "", "title (required)")
+	fs.StringVar(&in.Description, "description", "", "description")
+	fs.StringVar(&in.Estimate, "estimate", "", "estimated duration, e.g. 4h30m")
+	fs.StringVar(&in.RRule, "rrule", "", "iCalendar RRULE, e.g. FREQ=WEEKLY;BYDAY=MO,TH (required)")
+	fs.StringVar(&in.Start, "start", "", "first possible occurrence, RFC3339 or YYYY-MM-DD (required)")
+	labels := fs.String("labels", "", "comma-separated labels from the catalog")
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 0 || in.Title == "" || in.RRule == "" || in.Start == "" {
+			return errUsage
+		}
+		if *labels != "" {
+			in.Labels = strings.Split(*labels, ",")
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		r, err := c.CreateRecurringTask(ctx, in)
+		if err != nil {
+			return err
+		}
+		return a.printRecurringTask(r)
+	}
+}
+
+func recurringUpdateCmd(fs *flag.FlagSet, a *app) action {
+	title := fs.String("title", "", "new title")
+	description := fs.String("description", "", "new description, empty to remove it")
+	estimate := fs.String("estimate", "", "new estimated duration, empty to remove it")
+	labels := fs.String("labels", "", "new comma-separated labels, empty to remove them all")
+	rrule := fs.String("rrule", "", "new RRULE")
+	start := fs.String("start", "", "new start, RFC3339 or YYYY-MM-DD")
+	future := fs.Bool("future", false, "also update the pending occurrences already generated")
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return errUsage
+		}
+		// only flags given on the command line are sent
+		var p client.RecurringTaskPatch
+		set := false
+		fs.Visit(func(f *flag.Flag) {
+			set = set || f.Name != "future"
+			switch f.Name {
+			case "title":
+				p.Title = title
+			case "description":
+				p.Description = description
+			case "estimate":
+				p.Estimate = estimate
+			case "labels":
+				l := []string{}
+				if *labels != "" {
+					l = strings.Split(*labels, ",")
+				}
+				p.Labels = &l
+			case "rrule":
+				p.RRule = rrule
+			case "start":
+				p.Start = start
+			}
+		})
+		if !set {
+			return fmt.Errorf("nothing to update; pass at least one of --title, --description, --estimate, --labels, --rrule, --start")
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		r, err := c.UpdateRecurringTask(ctx, args[0], p, *future)
+		if err != nil {
+			return err
+		}
+		return a.printRecurringTask(r)
+	}
+}
+
+func recurringDeleteCmd(fs *flag.FlagSet, a *app) action {
+	future := fs.Bool("future", false, "also delete the pending occurrences still ahead")
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		if err := c.DeleteRecurringTask(ctx, args[0], *future); err != nil {
+			return err
+		}
+		return a.message("Deleted %s", args[0])
+	}
+}
+
+func recurringTasksCmd(fs *flag.FlagSet, a *app) action {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return errUsage
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		tasks, err := c.ListRecurringOccurrences(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		return a.printTasks(tasks)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Load the scripts with, for example:
+//
+//	source <(taskctl completion bash)                        # ~/.bashrc
+//	source <(taskctl completion zsh)                         # ~/.zshrc
+//	taskctl completion fish > ~/.config/fish/completions/taskctl.fish
+func completionCmd(fs *flag.FlagSet, a *app) action {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return errUsage
+		}
+		root := commands()
+		switch args[0] {
+		case "bash":
+			writeBash(a.stdout, root, false)
+		case "zsh":
+			writeBash(a.stdout, root, true)
+		case "fish":
+			writeFish(a.stdout, root)
+		default:
+			return errUsage
+		}
+		return nil
+	}
+}
+
+// node is a command path with what can follow it
+type node struct {
+	path  string // "tasks list"; "" for the root
+	leaf  bool
+	words []string
+	flags []*flag.Flag
+}
+
+// walk lists every visible command path with its subcommands or flags
+func walk(cmd *command, path string, out *[]node) {
+	n := node{path: path, leaf: cmd.sub == nil, words: cmd.values}
+	fs := (&app{stderr: io.Discard}).flagSet("")
+	if cmd.sub == nil {
+		cmd.flags(fs, &app{})
+	}
+	fs.VisitAll(func(f *flag.Flag) { n.flags = append(n.flags, f) })
+	for _, c := range cmd.sub {
+		if c.hidden {
+			continue
+		}
+		n.words = append(n.words, c.name)
+		walk(c, strings.TrimSpace(path+" "+c.name), out)
+	}
+	*out = append(*out, n)
+}
+
+func nodes(root *command) []node {
+	var out []node
+	walk(root, "", &out)
+	sort.Slice(out, func(i, j int) bool { return out[i].path < out[j].path })
+	return out
+}
+
+func isBool(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func dashed(f *flag.Flag) string {
+	if len(f.Name) == 1 {
+		return "-" + f.Name
+	}
+	return "--" + f.Name
+}
+
+// writeBash writes a bash script; zsh loads the same one through bashcompinit
+func writeBash(w io.Writer, root *command, zsh bool) {
+	if zsh {
+		fmt.Fprintln(w, "autoload -U +X bashcompinit && bashcompinit")
+	}
+	valued := map[string]bool{}
+	for _, n := range nodes(root) {
+		for _, f := range n.flags {
+			if !isBool(f) {
+				valued[dashed(f)] = true
+			}
+		}
+	}
+	var valuedList []string
+	for f := range valued {
+		valuedList = append(valuedList, f)
+	}
+	sort.Strings(valuedList)
+
+	fmt.Fprintf(w, `_taskctl() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+	case "$prev" in
+	--server) COMPREPLY=($(compgen -W "$(taskctl __profiles 2>/dev/null)" -- "$cur")); return ;;
+	-o|--output) COMPREPLY=($(compgen -W "table json yaml" -- "$cur")); return ;;
+	--config) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+	%s) return ;;
+	esac
+	# the command path is the words so far, minus flags and their values
+	local path="" i w skip=""
+	for ((i = 1; i < COMP_CWORD; i++)); do
+		w="${COMP_WORDS[i]}"
+		if [[ -n $skip ]]; then skip=""; continue; fi
+		case "$w" in
+		%s) skip=1; continue ;;
+		-*) continue ;;
+		esac
+		path="${path:+$path }$w"
+	done
+	local words
+	case "$path" in
+`, strings.Join(valuedList, "|"), strings.Join(valuedList, "|"))
+	for _, n := range nodes(root) {
+		var flags []string
+		for _, f := range n.flags {
+			flags = append(flags, dashed(f))
+		}
+		pattern := fmt.Sprintf("%q", n.path)
+		if n.leaf {
+			// a leaf keeps completing flags after its positional arguments
+			pattern += fmt.Sprintf("|%q*", n.path+" ")
+		}
+		fmt.Fprintf(w, "\t%s) words=%q ;;\n", pattern, strings.Join(append(n.words, flags...), " "))
+	}
+	fmt.Fprint(w, `	*) return ;;
+	esac
+	COMPREPLY=($(compgen -W "$words" -- "$cur"))
+}
+complete -F _taskctl taskctl
+`)
+}
+
+func writeFish(w io.Writer, root *command) {
+	fmt.Fprintln(w, "complete -c taskctl -f")
+	fmt.Fprintln(w, `complete -c taskctl -l server -x -a "(taskctl __profiles 2>/dev/null)" -d "profile or server URL"`)
+	fmt.Fprintln(w, `complete -c taskctl -s o -l output -x -a "table json yaml" -d "output format"`)
+	fmt.Fprintln(w, `complete -c taskctl -l config -r -F -d "config file"`)
+	for _, n := range nodes(root) {
+		if n.path == "" {
+			for _, c := range root.sub {
+				if !c.hidden {
+					fmt.Fprintf(w, "complete -c taskctl -n __fish_use_subcommand -a %s -d %q\n", c.name, c.summary)
+				}
+			}
+			continue
+		}
+		parts := strings.Fields(n.path)
+		cond := "__fish_seen_subcommand_from " + parts[len(parts)-1]
+		if !n.leaf {
+			sub := find(root, parts[0])
+			var names []string
+			for _, c := range sub.sub {
+				names = append(names, c.name)
+			}
+			// offer subcommands until one is typed
+			cond += "; and not __fish_seen_subcommand_from " + strings.Join(names, " ")
+			for _, c := range sub.sub {
+				fmt.Fprintf(w, "complete -c taskctl -n %q -a %s -d %q\n", cond, c.name, c.summary)
+			}
+			continue
+		}
+		for _, v := range n.words {
+			fmt.Fprintf(w, "complete -c taskctl -n %q -a %s\n", cond, v)
+		}
+		for _, f := range n.flags {
+			switch f.Name {
+			case "server", "o", "output", "config":
+				continue
+			}
+			arg := ""
+			if !isBool(f) {
+				arg = " -r"
+			}
+			fmt.Fprintf(w, "complete -c taskctl -n %q -l %s%s -d %q\n", cond, f.Name, arg, f.Usage)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"task_manager/client"
+)
+
+const defaultURL = "http://localhost:8080"
+
+// profile is one server and the token issued by it
+type profile struct {
+	URL       string `yaml:"url"`
+	Username  string `yaml:"username,omitempty"`
+	Workspace string `yaml:"workspace,omitempty"`
+	Token     string `yaml:"token,omitempty"`
+}
+
+// configFile is the YAML file holding the profiles; it contains tokens, so
+// it is written owner-only
+type configFile struct {
+	Current  string              `yaml:"current,omitempty"`
+	Profiles map[string]*profile `yaml:"profiles,omitempty"`
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ".taskctl.yaml"
+	}
+	return filepath.Join(dir, "taskctl", "config.yaml")
+}
+
+func (a *app) path() string {
+	switch {
+	case a.configPath != "":
+		return a.configPath
+	case os.Getenv("TASKCTL_CONFIG") != "":
+		return os.Getenv("TASKCTL_CONFIG")
+	}
+	return defaultConfigPath()
+}
+
+// load reads the config file; a missing file is an empty config
+func (a *app) load() (*configFile, error) {
+	cfg := &configFile{Profiles: map[string]*profile{}}
+	b, err := os.ReadFile(a.path())
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", a.path(), err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]*profile{}
+	}
+	return cfg, nil
+}
+
+func (a *app) save(cfg *configFile) error {
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	path := a.path()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	// write then rename, so an interrupted save can't lose the other profiles
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// profileName picks the profile: --server, then $TASKCTL_SERVER, then the
+// last login, then "default"
+func (a *app) profileName(cfg *configFile) string {
+	for _, name := range []string{a.server, os.Getenv("TASKCTL_SERVER"), cfg.Current} {
+		if name != "" {
+			return name
+		}
+	}
+	return "default"
+}
+
+// profile returns the selected profile. A name that is a URL and has no
+// profile yet gets an empty one pointing at it.
+func (a *app) profile(cfg *configFile) (string, *profile) {
+	name := a.profileName(cfg)
+	if p, ok := cfg.Profiles[name]; ok {
+		return name, p
+	}
+	if strings.Contains(name, "://") {
+		return name, &profile{URL: name}
+	}
+	return name, nil
+}
+
+// client makes an API client for the selected profile
+func (a *app) client() (*client.Client, error) {
+	cfg, err := a.load()
+	if err != nil {
+		return nil, err
+	}
+	name, p := a.profile(cfg)
+	if p == nil {
+		return nil, fmt.Errorf("no profile %q; run taskctl login --server %s --url URL", name, name)
+	}
+	c := client.New(p.URL)
+	c.Token = p.Token
+	return c, nil
+}
+
+func (cfg *configFile) names() []string {
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,228 @@
+// Command taskctl manages tasks from the terminal over the REST API.
+//
+//	taskctl login --server prod --url https://tasks.example.com --username alice
+//	taskctl tasks list -o json
+//	taskctl tasks update 665f... --status done
+//
+// Profiles (server URL and token) are kept in a config file; see taskctl help.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+
+	"task_manager/client"
+)
+
+// command is a node in the command tree. Leaves have flags; the tree also
+// drives usage and the completion scripts.
+type command struct {
+	name    string
+	args    string   // positional arguments, for usage
+	values  []string // fixed choices for the positional argument, for completion
+	summary string
+	sub     []*command
+	// flags registers the command's own flags and returns what to run once
+	// they are parsed
+	flags  func(fs *flag.FlagSet, a *app) action
+	hidden bool
+}
+
+func commands() *command {
+	return &command{name: "taskctl", sub: []*command{
+		{name: "login", summary: "log in and store the token in the selected profile", flags: loginCmd},
+		{name: "tasks", summary: "manage tasks in the active workspace", sub: []*command{
+			{name: "list", summary: "list tasks", flags: tasksListCmd},
+			{name: "get", args: "ID", summary: "show a task", flags: tasksGetCmd},
+			{name: "create", summary: "create a task", flags: tasksCreateCmd},
+			{name: "update", args: "ID", summary: "change the given fields of a task", flags: tasksUpdateCmd},
+			{name: "delete", args: "ID...", summary: "delete tasks", flags: tasksDeleteCmd},
+			{name: "label", args: "ID LABEL", summary: "tag a task with a label", flags: tasksLabelCmd},
+			{name: "unlabel", args: "ID LABEL", summary: "take a label off a task", flags: tasksUnlabelCmd},
+		}},
+		{name: "comments", summary: "discuss a task", sub: []*command{
+			{name: "list", args: "TASK_ID", summary: "list a task's comments with their replies", flags: commentsListCmd},
+			{name: "add", args: "TASK_ID BODY", summary: "comment on a task", flags: commentsAddCmd},
+			{name: "edit", args: "TASK_ID COMMENT_ID BODY", summary: "change a comment", flags: commentsEditCmd},
+			{name: "delete", args: "TASK_ID COMMENT_ID", summary: "delete a comment and its replies", flags: commentsDeleteCmd},
+		}},
+		{name: "attachments", summary: "manage a task's files", sub: []*command{
+			{name: "list", args: "TASK_ID", summary: "list a task's attachments", flags: attachmentsListCmd},
+			{name: "upload", args: "TASK_ID FILE", summary: "attach a file to a task", flags: attachmentsUploadCmd},
+			{name: "download", args: "TASK_ID ATTACHMENT_ID", summary: "save an attachment", flags: attachmentsDownloadCmd},
+			{name: "delete", args: "TASK_ID ATTACHMENT_ID", summary: "delete an attachment", flags: attachmentsDeleteCmd},
+		}},
+		{name: "recurring", summary: "manage recurring tasks", sub: []*command{
+			{name: "list", summary: "list recurring tasks with their next occurrence", flags: recurringListCmd},
+			{name: "get", args: "ID", summary: "show a recurring task and its upcoming occurrences", flags: recurringGetCmd},
+			{name: "create", summary: "create a recurring task", flags: recurringCreateCmd},
+			{name: "update", args: "ID", summary: "change the given fields of a recurring task", flags: recurringUpdateCmd},
+			{name: "delete", args: "ID", summary: "stop a recurring task", flags: recurringDeleteCmd},
+			{name: "tasks", args: "ID", summary: "list the tasks generated from a recurring task", flags: recurringTasksCmd},
+		}},
+		{name: "labels", summary: "browse the label catalog", sub: []*command{
+			{name: "list", summary: "list labels with their task counts", flags: labelsListCmd},
+		}},
+		{name: "users", summary: "manage users", sub: []*command{
+			{name: "promote", args: "USERNAME", summary: "make a user an instance admin", flags: usersPromoteCmd},
+		}},
+		{name: "completion", args: "bash|zsh|fish", values: []string{"bash", "zsh", "fish"}, summary: "print a shell completion script", flags: completionCmd},
+		{name: "__profiles", hidden: true, flags: profilesCmd},
+	}}
+}
+
+// errUsage makes main print usage and exit 2
+var errUsage = errors.New("usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	a := &app{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	err := a.run(ctx, commands(), os.Args[1:])
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	default:
+		fmt.Fprintln(os.Stderr, "taskctl:", err)
+		if client.StatusCode(err) == http.StatusUnauthorized {
+			fmt.Fprintln(os.Stderr, "taskctl: the token is missing or expired; run taskctl login")
+		}
+		os.Exit(1)
+	}
+}
+
+// run walks args down the command tree and runs the leaf it names. Global
+// flags may appear anywhere.
+func (a *app) run(ctx context.Context, root *command, args []string) error {
+	cmd, path := root, []string{root.name}
+	for {
+		fs := a.flagSet(strings.Join(path, " "))
+		if cmd.sub != nil {
+			fs.Usage = func() { a.usage(cmd, path) }
+			rest, err := parseInterspersed(fs, args, 1)
+			if err != nil {
+				return err
+			}
+			if len(rest) == 0 || rest[0] == "help" {
+				a.usage(cmd, path)
+				if len(rest) == 0 {
+					return errUsage
+				}
+				return nil
+			}
+			next := find(cmd, rest[0])
+			if next == nil {
+				fmt.Fprintf(a.stderr, "%s: unknown command %q\n", strings.Join(path, " "), rest[0])
+				a.usage(cmd, path)
+				return errUsage
+			}
+			cmd, path, args = next, append(path, next.name), rest[1:]
+			continue
+		}
+		action := cmd.flags(fs, a)
+		fs.Usage = func() { a.usage(cmd, path); fs.PrintDefaults() }
+		rest, err := parseInterspersed(fs, args, -1)
+		if err != nil {
+			return err
+		}
+		if _, err := a.format(); err != nil {
+			return err
+		}
+		err = action(ctx, rest)
+		if errors.Is(err, errUsage) {
+			fs.Usage()
+		}
+		return err
+	}
+}
+
+func find(cmd *command, name string) *command {
+	for _, c := range cmd.sub {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// flagSet makes a flag set with the global flags registered
+func (a *app) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.StringVar(&a.configPath, "config", a.configPath, "config file (default "+defaultConfigPath()+", or $TASKCTL_CONFIG)")
+	fs.StringVar(&a.server, "server", a.server, "profile to use, or a server URL (default the last login, or $TASKCTL_SERVER)")
+	fs.StringVar(&a.output, "o", a.output, "output format: table, json or yaml")
+	fs.StringVar(&a.output, "output", a.output, "output format: table, json or yaml")
+	return fs
+}
+
+// parseInterspersed parses flags before and between positional arguments,
+// stopping once max positionals are collected (-1 for no limit); what
+// follows is returned untouched
+func parseInterspersed(fs *flag.FlagSet, args []string, max int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		if max >= 0 && len(positional) == max {
+			return append(positional, args...), nil
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...), nil
+		}
+		positional = append(positional, args[0])
+		if max >= 0 && len(positional) == max {
+			return append(positional, args[1:]...), nil
+		}
+		args = args[1:]
+	}
+}
+
+func (a *app) usage(cmd *command, path []string) {
+	w := a.stderr
+	name := strings.Join(path, " ")
+	if cmd.sub == nil {
+		fmt.Fprintf(w, "Usage: %s\n\n%s\n\nFlags:\n", strings.TrimSpace(name+" [flags] "+cmd.args), cmd.summary)
+		return
+	}
+	fmt.Fprintf(w, "Usage: %s <command> [flags]\n\nCommands:\n", name)
+	for _, c := range cmd.sub {
+		if !c.hidden {
+			fmt.Fprintf(w, "  %-12s %s\n", c.name, c.summary)
+		}
+	}
+	if len(path) == 1 {
+		fmt.Fprint(w, globalHelp)
+	}
+}
+
+const globalHelp = `
+Global flags (accepted by every command):
+  --server NAME|URL   profile to use; a URL works without a profile
+  -o, --output FMT    table (default), json or yaml; or $TASKCTL_OUTPUT
+  --config PATH       config file
+
+Profiles live in the config file. "taskctl login --server NAME --url URL"
+creates or refreshes one and makes it the default.
+`
+
+// app holds the global flags and the streams commands write to
+type app struct {
+	configPath, server, output string
+	stdin                      *os.File
+	stdout, stderr             io.Writer
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"task_manager/client"
+)
+
+// format returns the output format: -o, then $TASKCTL_OUTPUT, then table
+func (a *app) format() (string, error) {
+	f := a.output
+	if f == "" {
+		f = os.Getenv("TASKCTL_OUTPUT")
+	}
+	switch f {
+	case "", "table":
+		return "table", nil
+	case "json", "yaml":
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown output format %q (use table, json or yaml)", f)
+}
+
+// print writes v as JSON or YAML, or calls table for the table format
+func (a *app) print(v interface{}, table func(w io.Writer)) error {
+	f, err := a.format()
+	if err != nil {
+		return err
+	}
+	switch f {
+	case "json":
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "yaml":
+		// going through JSON keeps the API's field names
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var generic interface{}
+		if err := json.Unmarshal(b, &generic); err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(a.stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(generic); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	tw := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	table(tw)
+	return tw.Flush()
+}
+
+// message is printed for commands without a result, in the table format only
+// so JSON and YAML output stays machine-readable
+func (a *app) message(format string, args ...interface{}) error {
+	f, err := a.format()
+	if err != nil || f != "table" {
+		return err
+	}
+	_, err = fmt.Fprintf(a.stdout, format+"\n", args...)
+	return err
+}
+
+func (a *app) printTasks(tasks []client.Task) error {
+	if tasks == nil {
+		tasks = []client.Task{}
+	}
+	return a.print(tasks, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tSTATUS\tDUE\tTITLE")
+		for _, t := range tasks {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.ID, t.Status, orDash(t.DueDate), oneLine(t.Title))
+		}
+	})
+}
+
+func (a *app) printLabels(labels []client.Label) error {
+	if labels == nil {
+		labels = []client.Label{}
+	}
+	return a.print(labels, func(w io.Writer) {
+		fmt.Fprintln(w, "NAME\tCOLOR\tTASKS\tDESCRIPTION")
+		for _, l := range labels {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", l.Name, l.Color, l.Count, orDash(oneLine(l.Description)))
+		}
+	})
+}
+
+// printComments lists top-level comments with their replies indented below
+func (a *app) printComments(page client.CommentPage) error {
+	if page.Comments == nil {
+		page.Comments = []client.Comment{}
+	}
+	return a.print(page, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tAUTHOR\tCREATED\tBODY")
+		for _, cm := range page.Comments {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", cm.ID, cm.Author, commentTime(cm), oneLine(cm.Body))
+			for _, r := range cm.Replies {
+				fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", r.ID, r.Author, commentTime(r), oneLine(r.Body))
+			}
+		}
+		if page.Next != "" {
+			fmt.Fprintf(w, "\nMore: --after %s\n", page.Next)
+		}
+	})
+}
+
+func (a *app) printComment(cm client.Comment) error {
+	return a.print(cm, func(w io.Writer) {
+		fmt.Fprintf(w, "ID:\t%s\n", cm.ID)
+		fmt.Fprintf(w, "Task:\t%s\n", cm.TaskID)
+		if cm.ParentID != "" {
+			fmt.Fprintf(w, "Reply to:\t%s\n", cm.ParentID)
+		}
+		fmt.Fprintf(w, "Author:\t%s\n", cm.Author)
+		fmt.Fprintf(w, "Created:\t%s\n", commentTime(cm))
+		if len(cm.Mentions) > 0 {
+			fmt.Fprintf(w, "Mentions:\t%s\n", strings.Join(cm.Mentions, ", "))
+		}
+		fmt.Fprintf(w, "Body:\t%s\n", oneLine(cm.Body))
+	})
+}
+
+func (a *app) printAttachments(as []client.Attachment) error {
+	if as == nil {
+		as = []client.Attachment{}
+	}
+	return a.print(as, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tNAME\tTYPE\tSIZE\tUPLOADED BY")
+		for _, at := range as {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", at.ID, oneLine(at.Filename), at.ContentType, at.Size, at.UploadedBy)
+		}
+	})
+}
+
+func (a *app) printAttachment(at client.Attachment) error {
+	return a.print(at, func(w io.Writer) {
+		fmt.Fprintf(w, "ID:\t%s\n", at.ID)
+		fmt.Fprintf(w, "Task:\t%s\n", at.TaskID)
+		fmt.Fprintf(w, "Name:\t%s\n", oneLine(at.Filename))
+		fmt.Fprintf(w, "Type:\t%s\n", at.ContentType)
+		fmt.Fprintf(w, "Size:\t%d\n", at.Size)
+		fmt.Fprintf(w, "SHA-256:\t%s\n", at.SHA256)
+		fmt.Fprintf(w, "Uploaded by:\t%s\n", at.UploadedBy)
+		fmt.Fprintf(w, "Created:\t%s\n", at.CreatedAt.Local().Format("2006-01-02 15:04"))
+	})
+}
+
+func (a *app) printRecurringTasks(rs []client.RecurringTask) error {
+	if rs == nil {
+		rs = []client.RecurringTask{}
+	}
+	return a.print(rs, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tRRULE\tNEXT\tTITLE")
+		for _, r := range rs {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.ID, r.RRule, occurrenceTime(r.NextOccurrence), oneLine(r.Title))
+		}
+	})
+}
+
+func (a *app) printRecurringTask(r client.RecurringTask) error {
+	return a.print(r, func(w io.Writer) {
+		fmt.Fprintf(w, "ID:\t%s\n", r.ID)
+		fmt.Fprintf(w, "Title:\t%s\n", oneLine(r.Title))
+		fmt.Fprintf(w, "RRule:\t%s\n", r.RRule)
+		fmt.Fprintf(w, "Start:\t%s\n", r.Start)
+		if r.Estimate != "" {
+			fmt.Fprintf(w, "Estimate:\t%s\n", r.Estimate)
+		}
+		if len(r.Labels) > 0 {
+			fmt.Fprintf(w, "Labels:\t%s\n", strings.Join(r.Labels, ", "))
+		}
+		fmt.Fprintf(w, "Generated through:\t%s\n", occurrenceTime(r.GeneratedThrough))
+		for i, at := range r.Upcoming {
+			label := ""
+			if i == 0 {
+				label = "Upcoming:"
+			}
+			fmt.Fprintf(w, "%s\t%s\n", label, occurrenceTime(&at))
+		}
+		if r.LastError != "" {
+			fmt.Fprintf(w, "Last error:\t%s\n", oneLine(r.LastError))
+		}
+		if r.Description != "" {
+			fmt.Fprintf(w, "Description:\t%s\n", oneLine(r.Description))
+		}
+	})
+}
+
+// occurrenceTime shows an occurrence in the offset of the template's start
+func occurrenceTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04 -07:00")
+}
+
+// commentTime is when the comment was written, marked when it was edited since
+func commentTime(cm client.Comment) string {
+	s := cm.CreatedAt.Local().Format("2006-01-02 15:04")
+	if cm.EditedAt != nil {
+		s += " (edited)"
+	}
+	return s
+}
+
+func (a *app) printTask(t client.Task) error {
+	return a.print(t, func(w io.Writer) {
+		fmt.Fprintf(w, "ID:\t%s\n", t.ID)
+		fmt.Fprintf(w, "Title:\t%s\n", oneLine(t.Title))
+		fmt.Fprintf(w, "Status:\t%s\n", t.Status)
+		fmt.Fprintf(w, "Due:\t%s\n", orDash(t.DueDate))
+		if t.Estimate != "" {
+			fmt.Fprintf(w, "Estimate:\t%s\n", t.Estimate)
+		}
+		if t.ParentID != "" {
+			fmt.Fprintf(w, "Parent:\t%s\n", t.ParentID)
+		}
+		if len(t.BlockedBy) > 0 {
+			fmt.Fprintf(w, "Blocked by:\t%s\n", strings.Join(t.BlockedBy, ", "))
+		}
+		if len(t.Labels) > 0 {
+			fmt.Fprintf(w, "Labels:\t%s\n", strings.Join(t.Labels, ", "))
+		}
+		if t.Progress != nil {
+			fmt.Fprintf(w, "Progress:\t%d/%d\n", t.Progress.Done, t.Progress.Total)
+		}
+		if t.UpdatedAt != nil {
+			fmt.Fprintf(w, "Updated:\t%s\n", t.UpdatedAt.Local().Format("2006-01-02 15:04:05"))
+		}
+		if t.Description != "" {
+			fmt.Fprintf(w, "Description:\t%s\n", oneLine(t.Description))
+		}
+	})
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// oneLine keeps a cell from breaking the table
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
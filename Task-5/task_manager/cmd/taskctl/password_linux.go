@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// readPassword prompts for the password with echo turned off when stdin is
+// a terminal. Interrupting restores echo before returning.
+func readPassword(ctx context.Context, a *app, in *bufio.Reader) (string, error) {
+	fd := int(a.stdin.Fd())
+	state, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		// not a terminal: read the line as is
+		return prompt(a, in, "Password: ")
+	}
+	quiet := *state
+	quiet.Lflag &^= unix.ECHO
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, &quiet); err != nil {
+		return "", err
+	}
+	defer unix.IoctlSetTermios(fd, unix.TCSETS, state)
+	fmt.Fprint(a.stderr, "Password: ")
+	type result struct {
+		line string
+		err  error
+	}
+	read := make(chan result, 1)
+	go func() {
+		line, err := in.ReadString('\n')
+		read <- result{line, err}
+	}()
+	select {
+	case <-ctx.Done():
+		fmt.Fprintln(a.stderr)
+		return "", ctx.Err()
+	case r := <-read:
+		fmt.Fprintln(a.stderr)
+		if r.err != nil {
+			return "", r.err
+		}
+		return strings.TrimRight(r.line, "\r\n"), nil
+	}
+}
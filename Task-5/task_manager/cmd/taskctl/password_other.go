@@ -0,0 +1,16 @@
+//go:build !linux
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+)
+
+// readPassword prompts for the password. Echo can't be turned off here, so
+// prefer --password-stdin on a shared screen.
+func readPassword(_ context.Context, a *app, in *bufio.Reader) (string, error) {
+	fmt.Fprintln(a.stderr, "warning: the password will be shown as you type it; --password-stdin avoids this")
+	return prompt(a, in, "Password: ")
+}
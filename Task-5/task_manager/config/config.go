@@ -0,0 +1,459 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the single typed configuration for the server. It is loaded
+// once at startup (see Load) and handed to each component.
+type Config struct {
+	HTTP        HTTP        `yaml:"http" toml:"http" json:"http"`
+	Mongo       Mongo       `yaml:"mongo" toml:"mongo" json:"mongo"`
+	Auth        Auth        `yaml:"auth" toml:"auth" json:"auth"`
+	OpenAPI     OpenAPI     `yaml:"openapi" toml:"openapi" json:"openapi"`
+	Events      Events      `yaml:"events" toml:"events" json:"events"`
+	Webhooks    Webhooks    `yaml:"webhooks" toml:"webhooks" json:"webhooks"`
+	Metrics     Metrics     `yaml:"metrics" toml:"metrics" json:"metrics"`
+	Log         Log         `yaml:"log" toml:"log" json:"log"`
+	Tracing     Tracing     `yaml:"tracing" toml:"tracing" json:"tracing"`
+	Idempotency Idempotency `yaml:"idempotency" toml:"idempotency" json:"idempotency"`
+	RateLimit   RateLimit   `yaml:"rate_limit" toml:"rate_limit" json:"rate_limit"`
+	GraphQL     GraphQL     `yaml:"graphql" toml:"graphql" json:"graphql"`
+	GRPC        GRPC        `yaml:"grpc" toml:"grpc" json:"grpc"`
+	API         API         `yaml:"api" toml:"api" json:"api"`
+	Cache       Cache       `yaml:"cache" toml:"cache" json:"cache"`
+	Tasks       Tasks       `yaml:"tasks" toml:"tasks" json:"tasks"`
+	Attachments Attachments `yaml:"attachments" toml:"attachments" json:"attachments"`
+	Recurrence  Recurrence  `yaml:"recurrence" toml:"recurrence" json:"recurrence"`
+}
+
+type HTTP struct {
+	Addr            string   `yaml:"addr" toml:"addr" json:"addr"`
+	ReadTimeout     Duration `yaml:"read_timeout" toml:"read_timeout" json:"read_timeout"`
+	WriteTimeout    Duration `yaml:"write_timeout" toml:"write_timeout" json:"write_timeout"`
+	IdleTimeout     Duration `yaml:"idle_timeout" toml:"idle_timeout" json:"idle_timeout"`
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" json:"shutdown_timeout"`
+	// TrustedProxies are the proxy addresses or CIDRs whose X-Forwarded-For is
+	// believed; with none, the client IP is the connection's remote address
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies" json:"trusted_proxies"`
+}
+
+type Mongo struct {
+	URI string `yaml:"uri" toml:"uri" json:"uri"`
+	DB  string `yaml:"db" toml:"db" json:"db"`
+}
+
+type Auth struct {
+	JWTSecret  Secret   `yaml:"jwt_secret" toml:"jwt_secret" json:"jwt_secret"`
+	TokenTTL   Duration `yaml:"token_ttl" toml:"token_ttl" json:"token_ttl"`
+	BcryptCost int      `yaml:"bcrypt_cost" toml:"bcrypt_cost" json:"bcrypt_cost"`
+}
+
+type OpenAPI struct {
+	Validate bool `yaml:"validate" toml:"validate" json:"validate"`
+}
+
+type Events struct {
+	ReplayBuffer int `yaml:"replay_buffer" toml:"replay_buffer" json:"replay_buffer"`
+}
+
+type Webhooks struct {
+	Timeout Duration `yaml:"timeout" toml:"timeout" json:"timeout"`
+}
+
+type Metrics struct {
+	Enabled bool `yaml:"enabled" toml:"enabled" json:"enabled"`
+}
+
+type Log struct {
+	Level  string `yaml:"level" toml:"level" json:"level"`    // debug, info, warn or error
+	Format string `yaml:"format" toml:"format" json:"format"` // json or text
+}
+
+type Idempotency struct {
+	TTL Duration `yaml:"ttl" toml:"ttl" json:"ttl"` // how long a key's response is kept
+}
+
+type RateLimit struct {
+	Enabled       bool   `yaml:"enabled" toml:"enabled" json:"enabled"`
+	Store         string `yaml:"store" toml:"store" json:"store"` // memory or mongo
+	Public        Rate   `yaml:"public" toml:"public" json:"public"`
+	Authenticated Rate   `yaml:"authenticated" toml:"authenticated" json:"authenticated"`
+	Admin         Rate   `yaml:"admin" toml:"admin" json:"admin"`
+}
+
+type GraphQL struct {
+	Enabled       bool `yaml:"enabled" toml:"enabled" json:"enabled"`
+	MaxDepth      int  `yaml:"max_depth" toml:"max_depth" json:"max_depth"`
+	MaxComplexity int  `yaml:"max_complexity" toml:"max_complexity" json:"max_complexity"` // fields, times page size for lists
+}
+
+type GRPC struct {
+	Enabled bool   `yaml:"enabled" toml:"enabled" json:"enabled"`
+	Addr    string `yaml:"addr" toml:"addr" json:"addr"` // separate from http.addr
+}
+
+type API struct {
+	// LegacyRoutes also serves every /v1 route at its old unversioned path,
+	// with deprecation headers
+	LegacyRoutes     bool   `yaml:"legacy_routes" toml:"legacy_routes" json:"legacy_routes"`
+	LegacyDeprecated Date   `yaml:"legacy_deprecated" toml:"legacy_deprecated" json:"legacy_deprecated"`
+	LegacySunset     Date   `yaml:"legacy_sunset" toml:"legacy_sunset" json:"legacy_sunset"` // empty until decided
+	DeprecationLink  string `yaml:"deprecation_link" toml:"deprecation_link" json:"deprecation_link"`
+}
+
+type Cache struct {
+	Enabled bool     `yaml:"enabled" toml:"enabled" json:"enabled"`
+	Size    int      `yaml:"size" toml:"size" json:"size"` // entries: one per task and per subtask list, plus the full list
+	TTL     Duration `yaml:"ttl" toml:"ttl" json:"ttl"`    // bounds staleness from writes by other replicas
+}
+
+type Tasks struct {
+	MaxDepth int `yaml:"max_depth" toml:"max_depth" json:"max_depth"` // levels of subtasks, top-level tasks included
+}
+
+type Attachments struct {
+	Store        string   `yaml:"store" toml:"store" json:"store"`          // local or gridfs
+	Dir          string   `yaml:"dir" toml:"dir" json:"dir"`                // local store root
+	MaxSize      int      `yaml:"max_size" toml:"max_size" json:"max_size"` // bytes per file
+	AllowedTypes []string `yaml:"allowed_types" toml:"allowed_types" json:"allowed_types"`
+	LinkTTL      Duration `yaml:"link_ttl" toml:"link_ttl" json:"link_ttl"`
+	SigningKey   Secret   `yaml:"signing_key" toml:"signing_key" json:"signing_key"` // empty uses auth.jwt_secret
+}
+
+type Recurrence struct {
+	Horizon  Duration `yaml:"horizon" toml:"horizon" json:"horizon"`    // how far ahead occurrences become tasks
+	Interval Duration `yaml:"interval" toml:"interval" json:"interval"` // how often the worker looks for due templates
+}
+
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter" json:"exporter"` // none, stdout or otlp
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint" json:"endpoint"` // OTLP/HTTP URL, e.g. http://collector:4318
+	ServiceName string  `yaml:"service_name" toml:"service_name" json:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio" json:"sample_ratio"`
+}
+
+// DefaultJWTSecret is only meant for local development; Warnings flags it
+const DefaultJWTSecret = "change_this_secret"
+
+// V1ReleaseDate is when the /v1 routes shipped and the unversioned paths were
+// deprecated. It is a fact about this release, not a deployment setting, so it
+// is the default for api.legacy_deprecated; it only changes with the code.
+const V1ReleaseDate = "2026-10-19"
+
+// Default returns the values used when nothing else is configured
+func Default() Config {
+	return Config{
+		HTTP: HTTP{
+			Addr:            ":8080",
+			ReadTimeout:     Duration(15 * time.Second),
+			WriteTimeout:    Duration(30 * time.Second),
+			IdleTimeout:     Duration(60 * time.Second),
+			ShutdownTimeout: Duration(20 * time.Second),
+		},
+		Mongo: Mongo{URI: "mongodb://localhost:27017", DB: "taskdb"},
+		Auth: Auth{
+			JWTSecret:  Secret(DefaultJWTSecret),
+			TokenTTL:   Duration(24 * time.Hour),
+			BcryptCost: bcrypt.DefaultCost,
+		},
+		Events:      Events{ReplayBuffer: 1000},
+		Webhooks:    Webhooks{Timeout: Duration(10 * time.Second)},
+		Metrics:     Metrics{Enabled: true},
+		Log:         Log{Level: "info", Format: "json"},
+		Tracing:     Tracing{Exporter: "none", ServiceName: "task_manager", SampleRatio: 1},
+		Idempotency: Idempotency{TTL: Duration(24 * time.Hour)},
+		RateLimit: RateLimit{
+			Enabled:       true,
+			Store:         "memory",
+			Public:        Rate{Limit: 20, Period: Duration(time.Minute)},
+			Authenticated: Rate{Limit: 300, Period: Duration(time.Minute)},
+			Admin:         Rate{Limit: 120, Period: Duration(time.Minute)},
+		},
+		GraphQL: GraphQL{Enabled: true, MaxDepth: 10, MaxComplexity: 1000},
+		GRPC:    GRPC{Enabled: true, Addr: ":9090"},
+		API:     API{LegacyRoutes: true, LegacyDeprecated: mustDate(V1ReleaseDate)},
+		Cache:   Cache{Enabled: true, Size: 1000, TTL: Duration(30 * time.Second)},
+		Tasks:   Tasks{MaxDepth: 5},
+		Attachments: Attachments{
+			Store:        "local",
+			Dir:          "attachments",
+			MaxSize:      10 << 20,
+			AllowedTypes: []string{"image/*", "text/plain", "application/pdf", "application/zip", "application/x-gzip"},
+			LinkTTL:      Duration(5 * time.Minute),
+		},
+		Recurrence: Recurrence{Horizon: Duration(14 * 24 * time.Hour), Interval: Duration(time.Minute)},
+	}
+}
+
+// Validate reports every invalid value at once
+func (c Config) Validate() error {
+	var errs []string
+	bad := func(key, msg string) { errs = append(errs, key+": "+msg) }
+	if c.HTTP.Addr == "" {
+		bad("http.addr", "must not be empty")
+	}
+	durations := []struct {
+		key string
+		d   Duration
+	}{
+		{"http.read_timeout", c.HTTP.ReadTimeout},
+		{"http.write_timeout", c.HTTP.WriteTimeout},
+		{"http.idle_timeout", c.HTTP.IdleTimeout},
+		{"http.shutdown_timeout", c.HTTP.ShutdownTimeout},
+		{"auth.token_ttl", c.Auth.TokenTTL},
+		{"webhooks.timeout", c.Webhooks.Timeout},
+		{"idempotency.ttl", c.Idempotency.TTL},
+		{"cache.ttl", c.Cache.TTL},
+		{"attachments.link_ttl", c.Attachments.LinkTTL},
+		{"recurrence.horizon", c.Recurrence.Horizon},
+		{"recurrence.interval", c.Recurrence.Interval},
+	}
+	for _, d := range durations {
+		if d.d <= 0 {
+			bad(d.key, "must be positive")
+		}
+	}
+	if u, err := url.Parse(c.Mongo.URI); err != nil || (u.Scheme != "mongodb" && u.Scheme != "mongodb+srv") {
+		bad("mongo.uri", "must be a mongodb:// or mongodb+srv:// URI")
+	}
+	if c.Mongo.DB == "" {
+		bad("mongo.db", "must not be empty")
+	}
+	if len(c.Auth.JWTSecret) < 16 {
+		bad("auth.jwt_secret", "must be at least 16 characters")
+	}
+	if c.Auth.BcryptCost < bcrypt.MinCost || c.Auth.BcryptCost > bcrypt.MaxCost {
+		bad("auth.bcrypt_cost", fmt.Sprintf("must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost))
+	}
+	if c.Events.ReplayBuffer < 1 {
+		bad("events.replay_buffer", "must be at least 1")
+	}
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		bad("log.level", "must be debug, info, warn or error")
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		bad("log.format", "must be json or text")
+	}
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		bad("tracing.exporter", "must be none, stdout or otlp")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		bad("tracing.sample_ratio", "must be between 0 and 1")
+	}
+	for _, p := range c.HTTP.TrustedProxies {
+		if net.ParseIP(p) == nil {
+			if _, _, err := net.ParseCIDR(p); err != nil {
+				bad("http.trusted_proxies", p+" is not an IP address or CIDR")
+			}
+		}
+	}
+	if c.RateLimit.Store != "memory" && c.RateLimit.Store != "mongo" {
+		bad("rate_limit.store", "must be memory or mongo")
+	}
+	if c.GraphQL.MaxDepth < 1 {
+		bad("graphql.max_depth", "must be at least 1")
+	}
+	if c.GraphQL.MaxComplexity < 1 {
+		bad("graphql.max_complexity", "must be at least 1")
+	}
+	if c.Cache.Size < 1 {
+		bad("cache.size", "must be at least 1")
+	}
+	if c.Tasks.MaxDepth < 1 {
+		bad("tasks.max_depth", "must be at least 1")
+	}
+	switch c.Attachments.Store {
+	case "local":
+		if c.Attachments.Dir == "" {
+			bad("attachments.dir", "must be set for the local store")
+		}
+	case "gridfs":
+	default:
+		bad("attachments.store", "must be local or gridfs")
+	}
+	if c.Attachments.MaxSize < 1 {
+		bad("attachments.max_size", "must be at least 1")
+	}
+	if len(c.Attachments.AllowedTypes) == 0 {
+		bad("attachments.allowed_types", "must not be empty")
+	}
+	if k := c.Attachments.SigningKey; k != "" && len(k) < 16 {
+		bad("attachments.signing_key", "must be at least 16 characters")
+	}
+	if c.API.LegacyRoutes && c.API.LegacyDeprecated.T().IsZero() {
+		bad("api.legacy_deprecated", "is required while api.legacy_routes is enabled")
+	}
+	if !c.API.LegacySunset.T().IsZero() && !c.API.LegacySunset.T().After(c.API.LegacyDeprecated.T()) {
+		bad("api.legacy_sunset", "must be after api.legacy_deprecated")
+	}
+	if c.GRPC.Enabled && (c.GRPC.Addr == "" || c.GRPC.Addr == c.HTTP.Addr) {
+		bad("grpc.addr", "must be set and differ from http.addr")
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New("invalid config: " + strings.Join(errs, "; "))
+}
+
+// Warnings lists settings that are valid but unsafe for production
+func (c Config) Warnings() []string {
+	var w []string
+	if c.Auth.JWTSecret.Value() == DefaultJWTSecret {
+		w = append(w, "auth.jwt_secret is the built-in development default; set JWT_SECRET")
+	}
+	return w
+}
+
+// Redacted returns a copy that is safe to log: secrets are masked and the
+// Mongo URI loses its password
+func (c Config) Redacted() Config {
+	if u, err := url.Parse(c.Mongo.URI); err == nil && u.User != nil {
+		if _, has := u.User.Password(); has {
+			u.User = url.UserPassword(u.User.Username(), "REDACTED")
+			c.Mongo.URI = u.String()
+		}
+	}
+	return c
+}
+
+// String renders the redacted config as YAML
+func (c Config) String() string {
+	b, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
+
+// Secret is a string that never prints its value
+type Secret string
+
+func (s Secret) Value() string { return string(s) }
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "[REDACTED]"
+}
+
+func (s Secret) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
+
+func (s *Secret) UnmarshalText(b []byte) error {
+	*s = Secret(b)
+	return nil
+}
+
+// Rate is a request budget written "<limit>/<period>", e.g. "100/1m"; "off"
+// disables it
+type Rate struct {
+	Limit  int
+	Period Duration
+}
+
+func (r Rate) Enabled() bool { return r.Limit > 0 && r.Period > 0 }
+
+func (r Rate) String() string {
+	if !r.Enabled() {
+		return "off"
+	}
+	return strconv.Itoa(r.Limit) + "/" + r.Period.String()
+}
+
+func (r Rate) MarshalText() ([]byte, error) { return []byte(r.String()), nil }
+
+func (r *Rate) UnmarshalText(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	if s == "off" || s == "0" {
+		*r = Rate{}
+		return nil
+	}
+	limit, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return fmt.Errorf("rate %q: want <limit>/<period>, e.g. 100/1m", s)
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 1 {
+		return fmt.Errorf("rate %q: limit must be a positive integer", s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("rate %q: period must be a positive duration", s)
+	}
+	*r = Rate{Limit: n, Period: Duration(d)}
+	return nil
+}
+
+// Duration accepts Go duration strings such as "15s" in every source
+type Duration time.Duration
+
+func (d Duration) D() time.Duration { return time.Duration(d) }
+
+func (d Duration) String() string { return time.Duration(d).String() }
+
+func (d Duration) MarshalText() ([]byte, error) { return []byte(d.String()), nil }
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Date accepts YYYY-MM-DD or RFC3339; empty is the zero time
+type Date time.Time
+
+func (d Date) T() time.Time { return time.Time(d) }
+
+func (d Date) String() string {
+	t := d.T()
+	switch {
+	case t.IsZero():
+		return ""
+	case t.Equal(t.Truncate(24*time.Hour)) && t.Location() == time.UTC:
+		return t.Format(time.DateOnly)
+	}
+	return t.Format(time.RFC3339)
+}
+
+func (d Date) MarshalText() ([]byte, error) { return []byte(d.String()), nil }
+
+// mustDate parses a date constant; it panics on a typo
+func mustDate(s string) Date {
+	var d Date
+	if err := d.UnmarshalText([]byte(s)); err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func (d *Date) UnmarshalText(b []byte) error {
+	s := strings.TrimSpace(string(b))
+	if s == "" {
+		*d = Date{}
+		return nil
+	}
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			*d = Date(t)
+			return nil
+		}
+	}
+	return fmt.Errorf("date %q: want YYYY-MM-DD or RFC3339", s)
+}
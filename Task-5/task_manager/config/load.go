@@ -0,0 +1,185 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// setting binds one value to its environment variable and flag
+type setting struct {
+	env, flag, usage string
+	ptr              interface{}
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"HTTP_ADDR", "http-addr", "listen address", &c.HTTP.Addr},
+		{"HTTP_READ_TIMEOUT", "http-read-timeout", "request read timeout", &c.HTTP.ReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", "http-write-timeout", "response write timeout", &c.HTTP.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", "http-idle-timeout", "keep-alive idle timeout", &c.HTTP.IdleTimeout},
+		{"HTTP_TRUSTED_PROXIES", "http-trusted-proxies", "comma-separated proxy IPs/CIDRs allowed to set X-Forwarded-For", &c.HTTP.TrustedProxies},
+		{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "time allowed to drain requests on shutdown", &c.HTTP.ShutdownTimeout},
+		{"MONGO_URI", "mongo-uri", "MongoDB connection URI", &c.Mongo.URI},
+		{"MONGO_DB", "mongo-db", "MongoDB database name", &c.Mongo.DB},
+		{"JWT_SECRET", "jwt-secret", "HMAC secret for signing tokens", &c.Auth.JWTSecret},
+		{"JWT_TTL", "jwt-ttl", "token lifetime", &c.Auth.TokenTTL},
+		{"BCRYPT_COST", "bcrypt-cost", "bcrypt work factor for new passwords", &c.Auth.BcryptCost},
+		{"OPENAPI_VALIDATE", "openapi-validate", "reject requests that don't match the OpenAPI document", &c.OpenAPI.Validate},
+		{"EVENTS_REPLAY_BUFFER", "events-replay-buffer", "task events kept for Last-Event-ID resume", &c.Events.ReplayBuffer},
+		{"WEBHOOK_TIMEOUT", "webhook-timeout", "timeout for one webhook delivery attempt", &c.Webhooks.Timeout},
+		{"METRICS_ENABLED", "metrics-enabled", "serve Prometheus metrics on /metrics", &c.Metrics.Enabled},
+		{"LOG_LEVEL", "log-level", "debug, info, warn or error", &c.Log.Level},
+		{"LOG_FORMAT", "log-format", "json or text", &c.Log.Format},
+		{"TRACING_EXPORTER", "tracing-exporter", "none, stdout or otlp", &c.Tracing.Exporter},
+		{"TRACING_ENDPOINT", "tracing-endpoint", "OTLP/HTTP endpoint URL", &c.Tracing.Endpoint},
+		{"TRACING_SERVICE_NAME", "tracing-service-name", "service.name resource attribute", &c.Tracing.ServiceName},
+		{"TRACING_SAMPLE_RATIO", "tracing-sample-ratio", "fraction of new traces to record", &c.Tracing.SampleRatio},
+		{"IDEMPOTENCY_TTL", "idempotency-ttl", "how long Idempotency-Key responses are kept", &c.Idempotency.TTL},
+		{"RATE_LIMIT_ENABLED", "rate-limit-enabled", "enforce per-group rate limits", &c.RateLimit.Enabled},
+		{"RATE_LIMIT_STORE", "rate-limit-store", "memory (per replica) or mongo (shared)", &c.RateLimit.Store},
+		{"RATE_LIMIT_PUBLIC", "rate-limit-public", "per-IP budget for public routes, e.g. 20/1m", &c.RateLimit.Public},
+		{"RATE_LIMIT_AUTHENTICATED", "rate-limit-authenticated", "per-user budget for authenticated routes", &c.RateLimit.Authenticated},
+		{"RATE_LIMIT_ADMIN", "rate-limit-admin", "extra per-user budget for admin routes", &c.RateLimit.Admin},
+		{"GRAPHQL_ENABLED", "graphql-enabled", "serve POST /graphql", &c.GraphQL.Enabled},
+		{"GRAPHQL_MAX_DEPTH", "graphql-max-depth", "deepest selection nesting a GraphQL query may use", &c.GraphQL.MaxDepth},
+		{"GRAPHQL_MAX_COMPLEXITY", "graphql-max-complexity", "highest estimated cost a GraphQL query may have", &c.GraphQL.MaxComplexity},
+		{"GRPC_ENABLED", "grpc-enabled", "serve the gRPC API", &c.GRPC.Enabled},
+		{"GRPC_ADDR", "grpc-addr", "gRPC listen address", &c.GRPC.Addr},
+		{"API_LEGACY_ROUTES", "api-legacy-routes", "also serve /v1 routes at their unversioned paths", &c.API.LegacyRoutes},
+		{"API_LEGACY_DEPRECATED", "api-legacy-deprecated", "date sent in the Deprecation header on unversioned paths", &c.API.LegacyDeprecated},
+		{"API_LEGACY_SUNSET", "api-legacy-sunset", "date sent in the Sunset header on unversioned paths", &c.API.LegacySunset},
+		{"API_DEPRECATION_LINK", "api-deprecation-link", "URL of the migration notice for unversioned paths", &c.API.DeprecationLink},
+		{"CACHE_ENABLED", "cache-enabled", "cache task reads in memory", &c.Cache.Enabled},
+		{"CACHE_SIZE", "cache-size", "most task cache entries kept", &c.Cache.Size},
+		{"CACHE_TTL", "cache-ttl", "how long a cached task read is served", &c.Cache.TTL},
+		{"TASKS_MAX_DEPTH", "tasks-max-depth", "levels of subtasks allowed, top-level tasks included", &c.Tasks.MaxDepth},
+		{"ATTACHMENTS_STORE", "attachments-store", "local (files under attachments-dir) or gridfs", &c.Attachments.Store},
+		{"ATTACHMENTS_DIR", "attachments-dir", "directory of the local attachment store", &c.Attachments.Dir},
+		{"ATTACHMENTS_MAX_SIZE", "attachments-max-size", "largest attachment accepted, in bytes", &c.Attachments.MaxSize},
+		{"ATTACHMENTS_ALLOWED_TYPES", "attachments-allowed-types", "comma-separated media types accepted, e.g. image/*,application/pdf", &c.Attachments.AllowedTypes},
+		{"ATTACHMENTS_LINK_TTL", "attachments-link-ttl", "how long a signed download link works", &c.Attachments.LinkTTL},
+		{"ATTACHMENTS_SIGNING_KEY", "attachments-signing-key", "HMAC key for download links; defaults to the JWT secret", &c.Attachments.SigningKey},
+		{"RECURRENCE_HORIZON", "recurrence-horizon", "how far ahead recurring task occurrences are created", &c.Recurrence.Horizon},
+		{"RECURRENCE_INTERVAL", "recurrence-interval", "how often the recurrence worker looks for due templates", &c.Recurrence.Interval},
+	}
+}
+
+// Load builds the config from defaults, then the config file, then
+// environment variables, then command-line flags; later sources win. The
+// file comes from -config or CONFIG_FILE and may be YAML or TOML.
+func Load(args []string) (Config, error) {
+	cfg, _, err := LoadCommand("task_manager", args)
+	return cfg, err
+}
+
+// LoadCommand is Load for a tool with its own arguments: the config flags
+// come first and the arguments after them are returned
+func LoadCommand(name string, args []string) (Config, []string, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	raw := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		raw[s.flag] = fs.String(s.flag, "", s.usage+" (env "+s.env+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return Config{}, nil, err
+		}
+	}
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.env); ok && v != "" {
+			if err := set(s.ptr, v); err != nil {
+				return Config{}, nil, fmt.Errorf("env %s: %w", s.env, err)
+			}
+		}
+	}
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == f.Name && flagErr == nil {
+				if err := set(s.ptr, *raw[s.flag]); err != nil {
+					flagErr = fmt.Errorf("flag -%s: %w", s.flag, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return Config{}, nil, flagErr
+	}
+	return cfg, fs.Args(), cfg.Validate()
+}
+
+func (c *Config) loadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		err = dec.Decode(c)
+	case ".toml":
+		err = toml.NewDecoder(bytes.NewReader(b)).DisallowUnknownFields().Decode(c)
+	default:
+		return fmt.Errorf("config file %s: unsupported extension (use .yaml, .yml or .toml)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// set parses v into the pointed-to setting
+func set(ptr interface{}, v string) error {
+	switch p := ptr.(type) {
+	case *string:
+		*p = v
+	case *[]string:
+		*p = nil
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*p = append(*p, item)
+			}
+		}
+	case *int:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*p = n
+	case *float64:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		*p = f
+	case *bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*p = b
+	case encoding.TextUnmarshaler:
+		return p.UnmarshalText([]byte(v))
+	default:
+		return fmt.Errorf("unsupported setting type %T", ptr)
+	}
+	return nil
+}
@@ -15,10 +15,11 @@ import (
 type Controller struct {
 	UserSvc *data.UserService
 	TaskSvc *data.TaskService
+	Tokens  *middleware.TokenService
 }
 
-func NewController(us *data.UserService, ts *data.TaskService) *Controller {
-	return &Controller{UserSvc: us, TaskSvc: ts}
+func NewController(us *data.UserService, ts *data.TaskService, tokens *middleware.TokenService) *Controller {
+	return &Controller{UserSvc: us, TaskSvc: ts, Tokens: tokens}
 }
 
 // Register user: POST /register
@@ -54,7 +55,7 @@ func (ctr *Controller) Login(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
-	token, err := middleware.GenerateToken(user.Username, user.Role)
+	token, err := ctr.Tokens.GenerateToken(user.Username, user.Role)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
 		return
@@ -17,12 +17,14 @@ type UserService struct {
 	coll   *mongo.Collection
 	client *mongo.Client
 	timeout time.Duration
+	cost    int
 }
 
 var userSvc *UserService
 
-// InitUserService initializes user service (call once)
-func InitUserService(uri, dbName string) error {
+// InitUserService initializes user service (call once); cost is the bcrypt
+// work factor for new passwords
+func InitUserService(uri, dbName string, cost int) error {
 	if uri == "" {
 		uri = "mongodb://localhost:27017"
 	}
@@ -45,7 +47,7 @@ func InitUserService(uri, dbName string) error {
 		Keys:    bson.D{{Key: "username", Value: 1}},
 		Options: options.Index().SetUnique(true),
 	})
-	userSvc = &UserService{coll: coll, client: client, timeout: 5 * time.Second}
+	userSvc = &UserService{coll: coll, client: client, timeout: 5 * time.Second, cost: cost}
 	return nil
 }
 
@@ -74,7 +76,7 @@ func (s *UserService) CreateUser(username, password string) (*models.User, error
 	}
 
 	// Hash password
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), s.cost)
 	if err != nil {
 		return nil, err
 	}
@@ -3,23 +3,28 @@ package main
 import (
 	"log"
 	"os"
+	"task_manager/config"
 	"task_manager/controllers"
 	"task_manager/data"
+	"task_manager/middleware"
 	"task_manager/router"
 )
 
 func main() {
-	uri := os.Getenv("MONGO_URI")
-	db := os.Getenv("MONGO_DB")
-	if db == "" {
-		db = "taskdb"
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("config: %v", err)
 	}
+	for _, w := range cfg.Warnings() {
+		log.Printf("config warning: %s", w)
+	}
+	tokens := middleware.NewTokenService(cfg.Auth.JWTSecret.Value(), cfg.Auth.TokenTTL.D())
 
 	// init services
-	if err := data.InitUserService(uri, db); err != nil {
+	if err := data.InitUserService(cfg.Mongo.URI, cfg.Mongo.DB, cfg.Auth.BcryptCost); err != nil {
 		log.Fatalf("failed to init user service: %v", err)
 	}
-	if err := data.InitTaskService(uri, db); err != nil {
+	if err := data.InitTaskService(cfg.Mongo.URI, cfg.Mongo.DB); err != nil {
 		log.Fatalf("failed to init task service: %v", err)
 	}
 	defer func() {
@@ -27,11 +32,11 @@ func main() {
 		_ = data.GetTaskService().Close()
 	}()
 
-	ctrl := controllers.NewController(data.GetUserService(), data.GetTaskService())
-	r := router.SetupRouter(ctrl)
+	ctrl := controllers.NewController(data.GetUserService(), data.GetTaskService(), tokens)
+	r := router.SetupRouter(ctrl, tokens)
 
-	log.Println("Server running on :8080")
-	if err := r.Run(":8080"); err != nil {
+	log.Printf("Server running on %s", cfg.HTTP.Addr)
+	if err := r.Run(cfg.HTTP.Addr); err != nil {
 		log.Fatalf("server failed: %v", err)
 	}
 }
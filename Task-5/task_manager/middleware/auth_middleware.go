@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
@@ -17,32 +16,35 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-var jwtSecret []byte
+// TokenService signs and checks tokens with one secret; build it once at
+// startup and share it between the login handler and AuthMiddleware
+type TokenService struct {
+	secret []byte
+	ttl    time.Duration
+}
 
-func init() {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "default_secret_change_me"
-	}
-	jwtSecret = []byte(secret)
+// NewTokenService signs tokens with secret; each token is valid for ttl
+func NewTokenService(secret string, ttl time.Duration) *TokenService {
+	return &TokenService{secret: []byte(secret), ttl: ttl}
 }
 
-// GenerateToken generates JWT for a username and role with 24h expiry
-func GenerateToken(username, role string) (string, error) {
+// GenerateToken generates JWT for a username and role, valid for the
+// configured lifetime
+func (s *TokenService) GenerateToken(username, role string) (string, error) {
 	claims := Claims{
 		Username: username,
 		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return token.SignedString(s.secret)
 }
 
 // AuthMiddleware ensures token validity and attaches claims to context
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(tokens *TokenService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		header := c.GetHeader("Authorization")
 		if header == "" {
@@ -56,7 +58,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 		tokenStr := parts[1]
 		token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
+			return tokens.secret, nil
 		})
 		if err != nil || !token.Valid {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token", "details": err.Error()})
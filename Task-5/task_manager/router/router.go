@@ -7,7 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter(ctrl *controllers.Controller) *gin.Engine {
+func SetupRouter(ctrl *controllers.Controller, tokens *middleware.TokenService) *gin.Engine {
 	r := gin.Default()
 
 	// public auth routes
@@ -16,7 +16,7 @@ func SetupRouter(ctrl *controllers.Controller) *gin.Engine {
 
 	// protected routes
 	protected := r.Group("/")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthMiddleware(tokens))
 
 	// task routes (authenticated)
 	protected.GET("/tasks", ctrl.GetTasks)